@@ -36,6 +36,8 @@ func main() {
 		cmd.Teardown,
 		cmd.NewBuildCommand(appName, action.Build),
 		cmd.NewCustomizeCommand(appName, action.Customize),
+		cmd.NewBundleCommand(appName, action.BundleCreate),
+		cmd.NewCacheCommand(appName, action.CacheList, action.CachePrune),
 		cmd.NewVersionCommand(appName))
 
 	if err := application.Run(context.Background(), os.Args); err != nil {
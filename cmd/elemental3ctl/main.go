@@ -36,6 +36,9 @@ func main() {
 		cmd.Teardown,
 		cmd.NewInstallCommand(appName, action.Install),
 		cmd.NewUpgradeCommand(appName, action.Upgrade),
+		cmd.NewRollbackCommand(appName, action.Rollback),
+		cmd.NewSnapshotsCommand(appName, action.ListSnapshots, action.ShowSnapshot, action.DeleteSnapshot),
+		cmd.NewBootCheckCommand(appName, action.BootCheckMarkGood, action.BootCheckStatus),
 		cmd.NewKernelModulesCommand(appName, action.ManageKernelModules),
 		cmd.NewUnpackImageCommand(appName, action.Unpack),
 		cmd.NewBuildInstallerCommand(appName, action.BuildInstaller),
@@ -20,18 +20,28 @@ package build
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/suse/elemental/v3/internal/config"
 	"github.com/suse/elemental/v3/internal/image"
 	imginstall "github.com/suse/elemental/v3/internal/image/install"
 	"github.com/suse/elemental/v3/pkg/bootloader"
+	"github.com/suse/elemental/v3/pkg/bundle"
+	"github.com/suse/elemental/v3/pkg/cache"
 	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/diskimage"
+	"github.com/suse/elemental/v3/pkg/events"
 	"github.com/suse/elemental/v3/pkg/fips"
 	"github.com/suse/elemental/v3/pkg/firmware"
 	"github.com/suse/elemental/v3/pkg/install"
 	"github.com/suse/elemental/v3/pkg/manifest/resolver"
+	"github.com/suse/elemental/v3/pkg/registry"
+	"github.com/suse/elemental/v3/pkg/reproducible"
+	"github.com/suse/elemental/v3/pkg/sbom"
+	"github.com/suse/elemental/v3/pkg/signature"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
 	"github.com/suse/elemental/v3/pkg/unpack"
@@ -46,27 +56,143 @@ type Builder struct {
 	System        *sys.System
 	ConfigManager configManager
 	Local         bool
+	// LocalSource is the store local images are read from
+	LocalSource unpack.LocalSource
+	// SignaturePolicy defines the signatures required for the OS image, it is also
+	// recorded in the installed deployment
+	SignaturePolicy *signature.Policy
+	// Bundle is the bundle the OS image is read from, if any
+	Bundle *bundle.Bundle
+	// Unprivileged composes the disk image from partition images instead of installing
+	// to a loop device, so neither loop devices nor root privileges are required
+	Unprivileged bool
+	// Reproducible builds bit-for-bit identical images from the same inputs, it implies an
+	// unprivileged build and records the build inputs in a manifest next to the image
+	Reproducible bool
+	// Cache stores the pulled image layers across builds, nil disables caching
+	Cache *cache.Cache
+	// Registries defines how OS images are pulled, it is also recorded in the installed deployment
+	Registries *registry.Config
 }
 
 func (b *Builder) Run(ctx context.Context, d *image.Definition, output config.Output) error {
 	logger := b.System.Logger()
 	runner := b.System.Runner()
 
+	ev := b.System.Events()
+
 	logger.Info("Configuring image components")
+	ev.Start(events.PhaseConfigure)
 	rm, err := b.ConfigManager.ConfigureComponents(ctx, d.Configuration, output)
+	ev.Finish(events.PhaseConfigure, err)
 	if err != nil {
 		logger.Error("Configuring image components failed")
 		return err
 	}
 
-	logger.Info("Creating RAW disk image")
-	if err = createDisk(runner, d.Image, d.Configuration.Installation.RAW.DiskSize); err != nil {
-		logger.Error("Creating RAW disk image failed")
+	format, err := diskimage.ParseFormat(d.Image.ImageType)
+	if err != nil {
+		logger.Error("Parsing image type failed")
 		return err
 	}
 
+	sb, err := config.NewSBOM(rm, d.Configuration, logger)
+	if err != nil {
+		logger.Error("Preparing SBOM failed")
+		return err
+	}
+
+	rawImage := rawImagePath(d.Image.OutputImageName, format)
+	if format.IsConverted() {
+		defer func() {
+			if rmErr := b.System.FS().RemoveAll(rawImage); rmErr != nil {
+				logger.Error("Removing intermediate RAW disk image failed: %v", rmErr)
+			}
+		}()
+	}
+
+	var repro *reproducible.Build
+	var overlays string
+	if b.Reproducible {
+		repro, overlays, err = b.newReproducibleBuild(rm, d, output)
+		if err == nil {
+			err = repro.Export()
+		}
+		if err != nil {
+			logger.Error("Setting up reproducible build failed")
+			return err
+		}
+	}
+
+	var manifest *reproducible.Manifest
+	ev.Start(events.PhaseInstall)
+	if b.Unprivileged || repro != nil {
+		var dep *deployment.Deployment
+		dep, err = b.installImage(ctx, rawImage, rm, d, output, repro, sb)
+		if err == nil && repro != nil {
+			manifest = b.buildManifest(repro, rm, d, dep, output, overlays)
+		}
+	} else {
+		logger.Info("Creating RAW disk image")
+		if err = createDisk(runner, rawImage, d.Configuration.Installation.RAW.DiskSize); err != nil {
+			logger.Error("Creating RAW disk image failed")
+			return err
+		}
+		err = b.install(ctx, rawImage, rm, d, output, sb)
+	}
+	ev.Finish(events.PhaseInstall, err)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Installation complete")
+
+	if format.IsConverted() {
+		ev.Start(events.PhaseConvert)
+		err = diskimage.Convert(ctx, b.System, rawImage, d.Image.OutputImageName, format, d.Image.Compress)
+		ev.Finish(events.PhaseConvert, err)
+		if err != nil {
+			logger.Error("Converting disk image failed")
+			return err
+		}
+	}
+
+	logger.Info("Writing SBOM")
+	if err = sb.Write(b.System.FS(), d.Image.OutputImageName); err != nil {
+		logger.Error("Writing SBOM failed")
+		return err
+	}
+
+	if manifest != nil {
+		logger.Info("Writing build manifest")
+		if err = manifest.Write(b.System.FS(), d.Image.OutputImageName); err != nil {
+			logger.Error("Writing build manifest failed")
+			return err
+		}
+	}
+
+	if ev.Enabled() {
+		digest, err := vfs.FileDigest(b.System.FS(), d.Image.OutputImageName)
+		if err != nil {
+			logger.Error("Computing image digest failed")
+			return err
+		}
+		ev.Digest(filepath.Base(d.Image.OutputImageName), digest)
+	}
+
+	return nil
+}
+
+// install installs the OS into the given RAW disk image, the OS packages are added to the given SBOM
+func (b *Builder) install(
+	ctx context.Context, rawImage string, rm *resolver.ResolvedManifest, d *image.Definition, output config.Output,
+	sb *sbom.SBOM,
+) error {
+	logger := b.System.Logger()
+	runner := b.System.Runner()
+
 	logger.Info("Attaching loop device to RAW disk image")
-	device, err := attachDevice(runner, d.Image)
+	device, err := attachDevice(runner, rawImage)
 	if err != nil {
 		logger.Error("Attaching loop device failed")
 		return err
@@ -77,51 +203,149 @@ func (b *Builder) Run(ctx context.Context, d *image.Definition, output config.Ou
 		}
 	}()
 
-	err = vfs.MkdirAll(b.System.FS(), output.OverlaysDir(), vfs.DirPerm)
+	dep, installer, err := b.prepareInstallation(ctx, device, rm, d, output, nil, sb)
 	if err != nil {
-		logger.Error("Failed creating overlay dir")
 		return err
 	}
 
+	logger.Info("Installing OS")
+	if err = installer.Install(dep); err != nil {
+		logger.Error("Installation failed")
+		return err
+	}
+
+	return nil
+}
+
+// installImage installs the OS into a new RAW disk image composed from partition images, the image
+// is built reproducibly if repro is set and the OS packages are added to the given SBOM
+func (b *Builder) installImage(
+	ctx context.Context, rawImage string, rm *resolver.ResolvedManifest, d *image.Definition, output config.Output,
+	repro *reproducible.Build, sb *sbom.SBOM,
+) (*deployment.Deployment, error) {
+	logger := b.System.Logger()
+
+	var size deployment.MiB
+	if diskSize := d.Configuration.Installation.RAW.DiskSize; diskSize != "" {
+		if !diskSize.IsValid() {
+			return nil, fmt.Errorf("invalid disk size definition '%s'", diskSize)
+		}
+		mib, err := diskSize.ToMiB()
+		if err != nil {
+			return nil, fmt.Errorf("parsing disk size: %w", err)
+		}
+		size = deployment.MiB(mib)
+	}
+
+	dep, installer, err := b.prepareInstallation(ctx, rawImage, rm, d, output, repro, sb, deployment.CheckDiskDevice)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Installing OS into RAW disk image without privileges")
+	if err = installer.InstallImage(dep, rawImage, size); err != nil {
+		logger.Error("Installation failed")
+		return nil, err
+	}
+
+	return dep, nil
+}
+
+// buildManifest returns the manifest of a reproducible build recording the digests of its inputs
+func (b *Builder) buildManifest(
+	repro *reproducible.Build, rm *resolver.ResolvedManifest, d *image.Definition, dep *deployment.Deployment,
+	output config.Output, overlays string,
+) *reproducible.Manifest {
+	return reproducible.NewManifest(repro,
+		reproducible.Artifact{
+			Name:      "os",
+			Reference: rm.CorePlatform.Components.OperatingSystem.Image.PinnedBase(),
+			Digest:    dep.SourceOS.GetDigest(),
+		},
+		reproducible.Artifact{Name: "release-manifest", Reference: d.Configuration.Release.ManifestURI},
+		reproducible.Artifact{Name: "overlays", Reference: output.OverlaysDir(), Digest: overlays},
+	)
+}
+
+// newReproducibleBuild returns the reproducible build settings seeded with the OS image, the release
+// manifest, the installation settings and the digest of the configured overlays, so images built from
+// different configurations do not share partition and filesystem UUIDs. The overlays digest is also
+// returned to be recorded in the build manifest.
+func (b *Builder) newReproducibleBuild(
+	rm *resolver.ResolvedManifest, d *image.Definition, output config.Output,
+) (*reproducible.Build, string, error) {
+	err := vfs.MkdirAll(b.System.FS(), output.OverlaysDir(), vfs.DirPerm)
+	if err != nil {
+		return nil, "", fmt.Errorf("creating overlays directory: %w", err)
+	}
+	overlays, err := reproducible.DirDigest(b.System.FS(), output.OverlaysDir())
+	if err != nil {
+		return nil, "", fmt.Errorf("digesting overlays: %w", err)
+	}
+	installation, err := json.Marshal(d.Configuration.Installation)
+	if err != nil {
+		return nil, "", fmt.Errorf("marshalling installation settings: %w", err)
+	}
+
+	repro, err := reproducible.New(
+		rm.CorePlatform.Components.OperatingSystem.Image.PinnedBase(), d.Configuration.Release.ManifestURI,
+		string(installation), overlays,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	return repro, overlays, nil
+}
+
+// prepareInstallation returns the deployment and the installer to install the OS to the given device
+func (b *Builder) prepareInstallation(
+	ctx context.Context, device string, rm *resolver.ResolvedManifest, d *image.Definition, output config.Output,
+	repro *reproducible.Build, sb *sbom.SBOM, excludeChecks ...deployment.SanitizeDeployment,
+) (*deployment.Deployment, *install.Installer, error) {
+	logger := b.System.Logger()
+
+	err := vfs.MkdirAll(b.System.FS(), output.OverlaysDir(), vfs.DirPerm)
+	if err != nil {
+		logger.Error("Failed creating overlay dir")
+		return nil, nil, err
+	}
+
 	logger.Info("Preparing installation setup")
 	dep, err := newDeployment(
 		b.System,
 		device,
-		rm.CorePlatform.Components.OperatingSystem.Image.Base,
+		rm.CorePlatform.Components.OperatingSystem.Image.PinnedBase(),
 		&d.Configuration.Installation,
 		output,
+		excludeChecks,
 	)
 	if err != nil {
 		logger.Error("Preparing installation setup failed")
-		return err
+		return nil, nil, err
 	}
+	dep.Security.SignaturePolicy = b.SignaturePolicy
+	dep.Registries = b.Registries
 
-	boot, err := bootloader.New(dep.BootConfig.Bootloader, b.System)
+	boot, err := bootloader.New(dep.BootConfig.Bootloader, b.System, dep.BootloaderOpts(b.System)...)
 	if err != nil {
 		logger.Error("Parsing boot config failed")
-		return err
+		return nil, nil, err
 	}
 
-	unpackOpts := unpack.WithLocal(b.Local)
+	unpackOpts := []unpack.Opt{
+		unpack.WithLocal(b.Local), unpack.WithLocalSource(b.LocalSource), unpack.WithBundle(b.Bundle), unpack.WithCache(b.Cache),
+	}
 	manager := firmware.NewEfiBootManager(b.System)
 	upgrader := upgrade.New(
 		ctx, b.System, upgrade.WithBootManager(manager), upgrade.WithBootloader(boot),
-		upgrade.WithUnpackOpts(unpackOpts),
+		upgrade.WithUnpackOpts(unpackOpts...), upgrade.WithSBOM(sb),
 	)
 	installer := install.New(
 		ctx, b.System, install.WithUpgrader(upgrader),
-		install.WithUnpackOpts(unpackOpts),
+		install.WithUnpackOpts(unpackOpts...), install.WithBootloader(boot),
+		install.WithReproducible(repro), install.WithSBOM(sb),
 	)
-
-	logger.Info("Installing OS")
-	if err = installer.Install(dep); err != nil {
-		logger.Error("Installation failed")
-		return err
-	}
-
-	logger.Info("Installation complete")
-
-	return nil
+	return dep, installer, nil
 }
 
 func newDeployment(
@@ -129,6 +353,7 @@ func newDeployment(
 	installationDevice, osImage string,
 	installation *imginstall.Installation,
 	output config.Output,
+	excludeChecks []deployment.SanitizeDeployment,
 	customPartitions ...*deployment.Partition,
 ) (*deployment.Deployment, error) {
 	deploymentOpts := []deployment.Opt{
@@ -146,9 +371,11 @@ func newDeployment(
 
 	d := deployment.New(deploymentOpts...)
 
-	d.Disks[0].Device = installationDevice
+	d.GetSystemDisk().Device = installationDevice
 	d.BootConfig.Bootloader = installation.Bootloader
 	d.BootConfig.KernelCmdline = installation.KernelCmdLine
+	d.BootConfig.BootTries = installation.BootTries
+	d.BootConfig.UKI = installation.UKI
 	d.Security.CryptoPolicy = installation.CryptoPolicy
 
 	if d.IsFipsEnabled() {
@@ -169,14 +396,23 @@ func newDeployment(
 	}
 	d.OverlayTree = overlaySource
 
-	if err = d.Sanitize(system); err != nil {
+	if err = d.Sanitize(system, excludeChecks...); err != nil {
 		return nil, fmt.Errorf("sanitizing deployment: %w", err)
 	}
 
 	return d, nil
 }
 
-func createDisk(runner sys.Runner, img image.Image, diskSize imginstall.DiskSize) error {
+// rawImagePath returns the path of the RAW disk image the OS is installed to, RAW images
+// of other formats are intermediate files next to the output image
+func rawImagePath(outputImage string, format diskimage.Format) string {
+	if format.IsConverted() {
+		return outputImage + ".raw"
+	}
+	return outputImage
+}
+
+func createDisk(runner sys.Runner, rawImage string, diskSize imginstall.DiskSize) error {
 	const defaultSize = "10G"
 
 	if diskSize == "" {
@@ -185,12 +421,12 @@ func createDisk(runner sys.Runner, img image.Image, diskSize imginstall.DiskSize
 		return fmt.Errorf("invalid disk size definition '%s'", diskSize)
 	}
 
-	_, err := runner.Run("truncate", "-s", string(diskSize), img.OutputImageName)
+	_, err := runner.Run("truncate", "-s", string(diskSize), rawImage)
 	return err
 }
 
-func attachDevice(runner sys.Runner, img image.Image) (string, error) {
-	out, err := runner.Run("losetup", "-f", "--show", img.OutputImageName)
+func attachDevice(runner sys.Runner, rawImage string) (string, error) {
+	out, err := runner.Run("losetup", "-f", "--show", rawImage)
 	if err != nil {
 		return "", err
 	}
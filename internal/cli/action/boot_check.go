@@ -0,0 +1,143 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+
+	cmdpkg "github.com/suse/elemental/v3/internal/cli/cmd"
+	"github.com/suse/elemental/v3/pkg/bootcheck"
+	"github.com/suse/elemental/v3/pkg/bootloader"
+	"github.com/suse/elemental/v3/pkg/cleanstack"
+	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/sys"
+)
+
+func BootCheckMarkGood(_ context.Context, cmd *cli.Command) (err error) {
+	args := &cmdpkg.BootCheckArgs
+	cleanup := cleanstack.NewCleanStack()
+	defer func() { err = cleanup.Cleanup(err) }()
+
+	s, d, checker, err := setupBootCheck(cmd, cleanup)
+	if err != nil {
+		return err
+	}
+
+	err = checker.RunChecks()
+	if err != nil {
+		s.Logger().Error("Health checks failed, boot not marked as successful")
+		if args.RebootOnFailure {
+			rebootOnFailure(s, d, checker)
+		}
+		return err
+	}
+
+	return checker.MarkGood(d)
+}
+
+func BootCheckStatus(_ context.Context, cmd *cli.Command) error {
+	args := &cmdpkg.BootCheckArgs
+	_, d, checker, err := setupBootCheck(cmd, nil)
+	if err != nil {
+		return err
+	}
+
+	status, err := checker.Status(d)
+	if err != nil {
+		return err
+	}
+
+	w := outputWriter(cmd)
+	switch args.Format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
+	case "text", "":
+		if !status.Enabled {
+			_, err = fmt.Fprintln(w, "Boot counting: disabled")
+			return err
+		}
+		_, err = fmt.Fprintf(
+			w, "Boot counting: enabled\nTries left: %d\nFallback entry: %s\nFell back: %t\n",
+			status.TriesLeft, status.FallbackID, status.FellBack,
+		)
+		if err != nil || status.BootedSnapshot == 0 {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "Booted snapshot: %d\nDefault snapshot: %d\n", status.BootedSnapshot, status.DefaultSnapshot)
+		return err
+	default:
+		return fmt.Errorf("unknown output format '%s'", args.Format)
+	}
+}
+
+// setupBootCheck parses the deployment of the running system and returns a boot checker for it. The ESP
+// mirrors are mounted and kept in sync until the given cleanup stack is run, a nil cleanup stack
+// is only meant for read only checks.
+func setupBootCheck(
+	cmd *cli.Command, cleanup *cleanstack.CleanStack,
+) (*sys.System, *deployment.Deployment, *bootcheck.Checker, error) {
+	if cmd.Root().Metadata == nil || cmd.Root().Metadata["system"] == nil {
+		return nil, nil, nil, fmt.Errorf("error setting up initial configuration")
+	}
+	s := cmd.Root().Metadata["system"].(*sys.System)
+
+	d, err := deployment.Parse(s, "/")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("parsing deployment: %w", err)
+	} else if d == nil {
+		return nil, nil, nil, fmt.Errorf("deployment not found")
+	}
+
+	var b bootloader.Bootloader
+	if cleanup == nil {
+		b, err = newBootloader(s, d)
+	} else {
+		b, err = mirroredBootloader(s, d, cleanup)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return s, d, bootcheck.New(s, bootcheck.WithBootloader(b)), nil
+}
+
+// rebootOnFailure reboots the system if the boot counter has attempts left, so the bootloader
+// eventually falls back to the previous snapshot
+func rebootOnFailure(s *sys.System, d *deployment.Deployment, checker *bootcheck.Checker) {
+	status, err := checker.Status(d)
+	if err != nil {
+		s.Logger().Error("Could not read boot counting status: %v", err)
+		return
+	}
+	if !status.Enabled || status.FellBack {
+		s.Logger().Warn("No boot attempts left to fall back to, not rebooting")
+		return
+	}
+
+	s.Logger().Info("Rebooting to consume the next boot attempt")
+	_, err = s.Runner().Run("systemctl", "reboot")
+	if err != nil {
+		s.Logger().Error("Reboot failed: %v", err)
+	}
+}
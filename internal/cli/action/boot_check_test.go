@@ -0,0 +1,113 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/suse/elemental/v3/internal/cli/action"
+	"github.com/suse/elemental/v3/internal/cli/cmd"
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+var _ = Describe("Boot check action", Label("boot-check"), func() {
+	var s *sys.System
+	var tfs vfs.FS
+	var cleanup func()
+	var err error
+	var cliCmd *cli.Command
+	var out *bytes.Buffer
+
+	BeforeEach(func() {
+		cmd.BootCheckArgs = cmd.BootCheckFlags{}
+		out = &bytes.Buffer{}
+		tfs, cleanup, err = sysmock.TestFS(map[string]string{
+			"/etc/elemental/deployment.yaml": "snapshotter:\n  name: overwrite\nbootloader:\n  name: none\n" +
+				"disks:\n- partitions:\n  - label: EFI\n    role: efi\n    mountPoint: /boot\n",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		s, err = sys.NewSystem(
+			sys.WithFS(tfs),
+			sys.WithLogger(log.New(log.WithDiscardAll())),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		cliCmd = &cli.Command{
+			Writer: out,
+			Metadata: map[string]any{
+				"system": s,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		cleanup()
+	})
+	It("fails if no sys.System instance is in metadata", func() {
+		cliCmd.Metadata["system"] = nil
+		Expect(action.BootCheckMarkGood(context.Background(), cliCmd)).NotTo(Succeed())
+		Expect(action.BootCheckStatus(context.Background(), cliCmd)).NotTo(Succeed())
+	})
+	It("fails if the deployment file does not exist", func() {
+		Expect(tfs.RemoveAll("/etc/elemental")).To(Succeed())
+		err = action.BootCheckStatus(context.Background(), cliCmd)
+		Expect(err).To(MatchError("deployment not found"))
+	})
+	It("reports boot counting as disabled", func() {
+		Expect(action.BootCheckStatus(context.Background(), cliCmd)).To(Succeed())
+		Expect(out.String()).To(Equal("Boot counting: disabled\n"))
+	})
+	It("marks the boot as good when boot counting is disabled", func() {
+		Expect(action.BootCheckMarkGood(context.Background(), cliCmd)).To(Succeed())
+	})
+	It("mounts the ESP mirrors only to mark the boot as good", func() {
+		runner := sysmock.NewRunner()
+		runner.SideEffect = func(cmd string, args ...string) ([]byte, error) {
+			if cmd == "lsblk" {
+				return nil, fmt.Errorf("lsblk failed")
+			}
+			return []byte{}, nil
+		}
+		s, err = sys.NewSystem(sys.WithFS(tfs), sys.WithRunner(runner), sys.WithLogger(log.New(log.WithDiscardAll())))
+		Expect(err).NotTo(HaveOccurred())
+		cliCmd.Metadata["system"] = s
+		Expect(tfs.WriteFile("/etc/elemental/deployment.yaml", []byte(
+			"snapshotter:\n  name: overwrite\nbootloader:\n  name: none\n"+
+				"disks:\n- partitions:\n  - label: EFI\n    role: efi\n    mountPoint: /boot\n"+
+				"- partitions:\n  - role: efi\n    mirror: true\n    uuid: 5c1f9a2e\n",
+		), vfs.FilePerm)).To(Succeed())
+
+		Expect(action.BootCheckStatus(context.Background(), cliCmd)).To(Succeed())
+		err = action.BootCheckMarkGood(context.Background(), cliCmd)
+		Expect(err).To(MatchError(ContainSubstring("setting up ESP mirrors: finding partition '5c1f9a2e'")))
+	})
+	It("fails with an unknown output format", func() {
+		cmd.BootCheckArgs.Format = "xml"
+		err = action.BootCheckStatus(context.Background(), cliCmd)
+		Expect(err).To(MatchError("unknown output format 'xml'"))
+	})
+})
@@ -22,7 +22,6 @@ import (
 	"fmt"
 	"os/signal"
 	"path/filepath"
-	"slices"
 	"syscall"
 	"time"
 
@@ -33,20 +32,22 @@ import (
 	"github.com/suse/elemental/v3/internal/config"
 	v0 "github.com/suse/elemental/v3/internal/config/v0"
 	"github.com/suse/elemental/v3/internal/image"
+	"github.com/suse/elemental/v3/pkg/diskimage"
 	"github.com/suse/elemental/v3/pkg/helm"
-	"github.com/suse/elemental/v3/pkg/http"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/platform"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
+	"github.com/suse/elemental/v3/pkg/unpack"
 )
 
-func Build(ctx context.Context, cmd *cli.Command) error {
+func Build(ctx context.Context, cmd *cli.Command) (err error) {
 	args := &cmdpkg.BuildArgs
 
 	if cmd.Root().Metadata == nil || cmd.Root().Metadata["system"] == nil {
 		return fmt.Errorf("error setting up initial configuration")
 	}
 	system := cmd.Root().Metadata["system"].(*sys.System)
+	defer func() { system.Events().Done(cmd.Name, err) }()
 	logger := system.Logger()
 
 	ctxCancel, cancelFunc := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
@@ -82,6 +83,40 @@ func Build(ctx context.Context, cmd *cli.Command) error {
 		}
 	}()
 
+	policy, err := signaturePolicyFromFlags(system.FS(), args.SignaturePolicy, args.SignatureKeys)
+	if err != nil {
+		logger.Error("Loading signature policy failed")
+		return err
+	}
+
+	b, err := openBundle(ctxCancel, system, args.Bundle, args.Local)
+	if err != nil {
+		logger.Error("Opening bundle failed")
+		return err
+	}
+	if b != nil {
+		defer func() { _ = b.Close() }()
+	}
+
+	downloader, err := downloaderFromFlags(system.FS(), args.DownloadMirrors, args.DownloadCABundle)
+	if err != nil {
+		logger.Error("Setting up downloader failed")
+		return err
+	}
+
+	registries, err := registriesFromFlags(system.FS(), args.RegistryConfig)
+	if err != nil {
+		logger.Error("Loading registries configuration failed")
+		return err
+	}
+
+	c, err := cacheFromFlags(system.FS(), args.CacheDir, args.CacheMaxSize, args.NoCache)
+	if err != nil {
+		logger.Error("Setting up cache failed")
+		return err
+	}
+	defer trimCache(system, c)
+
 	valuesResolver := &helm.ValuesResolver{
 		FS:        system.FS(),
 		ValuesDir: v0.Dir(args.ConfigDir).HelmValuesDir(),
@@ -90,14 +125,26 @@ func Build(ctx context.Context, cmd *cli.Command) error {
 	configManager := config.NewManager(
 		system,
 		config.NewHelm(system.FS(), valuesResolver, logger, output.OverlaysDir()),
-		config.WithDownloadFunc(http.DownloadFile),
+		config.WithDownloadFunc(downloader.Download),
 		config.WithLocal(args.Local),
+		config.WithLocalSource(unpack.LocalSource(args.LocalSource)),
+		config.WithSignaturePolicy(policy),
+		config.WithBundle(b),
+		config.WithCache(c),
+		config.WithRegistries(registries),
 	)
 
 	builder := &build.Builder{
-		System:        system,
-		ConfigManager: configManager,
-		Local:         args.Local,
+		System:          system,
+		ConfigManager:   configManager,
+		Local:           args.Local,
+		LocalSource:     unpack.LocalSource(args.LocalSource),
+		SignaturePolicy: policy,
+		Bundle:          b,
+		Unprivileged:    args.Unprivileged,
+		Reproducible:    args.Reproducible,
+		Cache:           c,
+		Registries:      registries,
 	}
 
 	logger.Info("Starting build process for %s %s image", definition.Image.Platform.String(), definition.Image.ImageType)
@@ -116,15 +163,23 @@ func validateArgs(fs vfs.FS, args *cmdpkg.BuildFlags) error {
 		return fmt.Errorf("reading config directory: %w", err)
 	}
 
-	validImageTypes := []string{image.TypeRAW}
-	if !slices.Contains(validImageTypes, args.ImageType) {
+	format, err := diskimage.ParseFormat(args.ImageType)
+	if err != nil {
 		return fmt.Errorf("image type %q not supported", args.ImageType)
 	}
 
+	if args.Compress && !format.SupportsCompression() {
+		return fmt.Errorf("compression is not supported for image type %q", args.ImageType)
+	}
+
 	if _, err := platform.Parse(args.Platform); err != nil {
 		return fmt.Errorf("malformed platform %q", args.Platform)
 	}
 
+	if _, err := unpack.ParseLocalSource(args.LocalSource); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -150,6 +205,7 @@ func parseImageDefinition(f vfs.FS, args *cmdpkg.BuildFlags) (*image.Definition,
 			ImageType:       args.ImageType,
 			Platform:        p,
 			OutputImageName: outputPath,
+			Compress:        args.Compress,
 		},
 		Configuration: conf,
 	}, nil
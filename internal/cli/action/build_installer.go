@@ -28,18 +28,23 @@ import (
 	cmdpkg "github.com/suse/elemental/v3/internal/cli/cmd"
 	"github.com/suse/elemental/v3/pkg/bootloader"
 	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/diskimage"
 	"github.com/suse/elemental/v3/pkg/installer"
+	"github.com/suse/elemental/v3/pkg/reproducible"
+	"github.com/suse/elemental/v3/pkg/sbom"
+	"github.com/suse/elemental/v3/pkg/secureboot"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/unpack"
 )
 
-func BuildInstaller(ctx context.Context, cmd *cli.Command) error {
+func BuildInstaller(ctx context.Context, cmd *cli.Command) (err error) {
 	var s *sys.System
 	args := &cmdpkg.InstallerArgs
 	if cmd.Root().Metadata == nil || cmd.Root().Metadata["system"] == nil {
 		return fmt.Errorf("error setting up initial configuration")
 	}
 	s = cmd.Root().Metadata["system"].(*sys.System)
+	defer func() { s.Events().Done(cmd.Name, err) }()
 
 	s.Logger().Info("Starting build installer action with args: %+v", args)
 
@@ -102,6 +107,14 @@ func digestInstallerDeploymentSetup(s *sys.System, flags *cmdpkg.InstallerFlags)
 		return nil, fmt.Errorf("failed applying install flags to deployment description: %w", err)
 	}
 
+	// the keys sign the media at build time, the installer media only embeds the certificate
+	if conf := secureBootFromFlags(flags); conf != nil {
+		if d.Security == nil {
+			d.Security = &deployment.SecurityConfig{}
+		}
+		d.Security.SecureBoot = conf
+	}
+
 	err = d.Sanitize(s, deployment.CheckDiskDevice)
 	if err != nil {
 		return nil, fmt.Errorf("inconsistent deployment setup found: %w", err)
@@ -115,10 +128,53 @@ func digestInstallerMedia(ctx context.Context, s *sys.System, flags *cmdpkg.Inst
 		return nil, err
 	}
 
-	media := installer.NewMedia(
-		ctx, s, mType,
-		installer.WithUnpackOpts(unpack.WithLocal(flags.Local), unpack.WithVerify(flags.Verify)),
-	)
+	mediaOpts := []installer.Option{
+		installer.WithUnpackOpts(
+			unpack.WithLocal(flags.Local), unpack.WithLocalSource(unpack.LocalSource(flags.LocalSource)),
+			unpack.WithVerify(flags.Verify),
+		),
+		installer.WithSBOM(sbom.New()),
+	}
+
+	if mType == installer.Disk {
+		format, err := diskimage.ParseFormat(flags.Type)
+		if err != nil {
+			return nil, err
+		}
+		mediaOpts = append(mediaOpts, installer.WithDiskFormat(format, flags.Compress))
+	} else if flags.Compress {
+		return nil, fmt.Errorf("compression is only supported for disk images")
+	}
+
+	var repro *reproducible.Build
+	if flags.Reproducible {
+		repro, err = reproducible.New(flags.OperatingSystemImage)
+		if err == nil {
+			err = repro.Export()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("setting up reproducible build: %w", err)
+		}
+		mediaOpts = append(mediaOpts, installer.WithReproducible(repro))
+	}
+
+	if conf := secureBootFromFlags(flags); conf != nil {
+		signer := secureboot.NewSigner(s, *conf)
+		if err = signer.CheckKeyPair(); err != nil {
+			return nil, fmt.Errorf("invalid Secure Boot signing keys: %w", err)
+		}
+		blOpts := []bootloader.Opt{bootloader.WithSigner(signer)}
+		if repro != nil {
+			blOpts = append(blOpts, bootloader.WithLiveID(installer.LiveID(repro)))
+		}
+		bl, err := bootloader.New(bootloader.BootGrub, s, blOpts...)
+		if err != nil {
+			return nil, err
+		}
+		mediaOpts = append(mediaOpts, installer.WithBootloader(bl))
+	}
+
+	media := installer.NewMedia(ctx, s, mType, mediaOpts...)
 
 	if flags.Name != "" {
 		media.Name = flags.Name
@@ -184,3 +240,15 @@ func applyInstallSpec(s *sys.System, d *deployment.Deployment, flags cmdpkg.Inst
 	}
 	return nil
 }
+
+// secureBootFromFlags returns the Secure Boot signing configuration set by flags, nil if none is set
+func secureBootFromFlags(flags *cmdpkg.InstallerFlags) *secureboot.Config {
+	if flags.SecureBootKey == "" && flags.SecureBootCert == "" {
+		return nil
+	}
+	return &secureboot.Config{
+		Key:       flags.SecureBootKey,
+		Cert:      flags.SecureBootCert,
+		EnrollMOK: flags.EnrollMOK,
+	}
+}
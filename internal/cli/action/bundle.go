@@ -0,0 +1,144 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	containerregistry "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/urfave/cli/v3"
+
+	cmdpkg "github.com/suse/elemental/v3/internal/cli/cmd"
+	"github.com/suse/elemental/v3/internal/config"
+	"github.com/suse/elemental/v3/pkg/bundle"
+	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+func BundleCreate(ctx context.Context, cmd *cli.Command) error {
+	args := &cmdpkg.BundleArgs
+
+	if cmd.Root().Metadata == nil || cmd.Root().Metadata["system"] == nil {
+		return fmt.Errorf("error setting up initial configuration")
+	}
+	system := cmd.Root().Metadata["system"].(*sys.System)
+	logger := system.Logger()
+	fs := system.FS()
+
+	ctxCancel, cancelFunc := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+	defer cancelFunc()
+
+	platform, err := containerregistry.ParsePlatform(args.Platform)
+	if err != nil {
+		return fmt.Errorf("malformed platform %q: %w", args.Platform, err)
+	}
+
+	conf, err := config.Parse(fs, args.ConfigDir)
+	if err != nil {
+		logger.Error("Parsing image configuration failed")
+		return fmt.Errorf("parsing configuration directory %s: %w", args.ConfigDir, err)
+	}
+
+	policy, err := signaturePolicyFromFlags(fs, args.SignaturePolicy, args.SignatureKeys)
+	if err != nil {
+		logger.Error("Loading signature policy failed")
+		return err
+	}
+
+	registries, err := registriesFromFlags(fs, args.RegistryConfig)
+	if err != nil {
+		logger.Error("Loading registries configuration failed")
+		return err
+	}
+
+	output, err := config.NewOutput(fs, "", "")
+	if err != nil {
+		logger.Error("Creating working directory failed")
+		return err
+	}
+	defer func() {
+		if rmErr := output.Cleanup(fs); rmErr != nil {
+			logger.Error("Cleaning up working directory failed: %v", rmErr)
+		}
+	}()
+
+	bundleDir := args.OutputPath
+	tarball := isTarball(args.OutputPath)
+	if tarball {
+		bundleDir, err = vfs.TempDir(fs, "", "bundle-")
+		if err != nil {
+			return fmt.Errorf("creating bundle directory: %w", err)
+		}
+		defer func() {
+			_ = vfs.ForceRemoveAll(fs, bundleDir)
+		}()
+	}
+
+	b, err := bundle.New(system, bundleDir, *platform, bundle.WithRegistries(registries))
+	if err != nil {
+		logger.Error("Creating bundle failed")
+		return err
+	}
+
+	downloader, err := downloaderFromFlags(fs, args.DownloadMirrors, args.DownloadCABundle)
+	if err != nil {
+		logger.Error("Setting up downloader failed")
+		return err
+	}
+
+	manager := config.NewManager(
+		system, nil, config.WithDownloadFunc(downloader.Download), config.WithSignaturePolicy(policy),
+		config.WithRegistries(registries),
+	)
+
+	logger.Info("Bundling components for %s", platform.String())
+	if err = manager.BundleComponents(ctxCancel, conf, output, b); err != nil {
+		logger.Error("Bundling components failed")
+		return err
+	}
+
+	if tarball {
+		logger.Info("Writing bundle tarball %s", args.OutputPath)
+		if err = b.Pack(ctxCancel, args.OutputPath); err != nil {
+			logger.Error("Writing bundle tarball failed")
+			return err
+		}
+	}
+
+	logger.Info("Bundle created at %s", args.OutputPath)
+	return nil
+}
+
+// openBundle opens the bundle given by the --bundle flag, returns nil if the flag is not set
+func openBundle(ctx context.Context, s *sys.System, path string, local bool) (*bundle.Bundle, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if local {
+		return nil, fmt.Errorf("--bundle and --local are mutually exclusive")
+	}
+	return bundle.Open(ctx, s, path)
+}
+
+func isTarball(path string) bool {
+	return strings.HasSuffix(path, ".tar") || strings.HasSuffix(path, ".tar.gz")
+}
@@ -0,0 +1,137 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/urfave/cli/v3"
+
+	cmdpkg "github.com/suse/elemental/v3/internal/cli/cmd"
+	"github.com/suse/elemental/v3/pkg/cache"
+	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+func CacheList(_ context.Context, cmd *cli.Command) error {
+	args := &cmdpkg.CacheArgs
+
+	if cmd.Root().Metadata == nil || cmd.Root().Metadata["system"] == nil {
+		return fmt.Errorf("error setting up initial configuration")
+	}
+	system := cmd.Root().Metadata["system"].(*sys.System)
+
+	entries, err := cache.New(system.FS(), args.CacheDir).List()
+	if err != nil {
+		system.Logger().Error("Listing cache failed")
+		return err
+	}
+	return writeCacheTable(outputWriter(cmd), entries)
+}
+
+func CachePrune(_ context.Context, cmd *cli.Command) error {
+	args := &cmdpkg.CacheArgs
+
+	if cmd.Root().Metadata == nil || cmd.Root().Metadata["system"] == nil {
+		return fmt.Errorf("error setting up initial configuration")
+	}
+	system := cmd.Root().Metadata["system"].(*sys.System)
+	logger := system.Logger()
+
+	maxSize, err := parseCacheSize(args.MaxSize)
+	if err != nil {
+		return err
+	}
+
+	removed, err := cache.New(system.FS(), args.CacheDir).Prune(maxSize)
+	if err != nil {
+		logger.Error("Pruning cache failed")
+		return err
+	}
+	var freed int64
+	for _, e := range removed {
+		freed += e.Size
+	}
+	logger.Info("Removed %d blobs from the cache, %s freed", len(removed), units.BytesSize(float64(freed)))
+	return nil
+}
+
+// cacheFromFlags returns the cache defined by the --cache-dir, --cache-max-size and --no-cache
+// flags, it returns nil if caching is disabled
+func cacheFromFlags(fs vfs.FS, dir, maxSize string, disabled bool) (*cache.Cache, error) {
+	if disabled || dir == "" {
+		return nil, nil
+	}
+	size, err := parseCacheSize(maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return cache.New(fs, dir, cache.WithMaxSize(size)), nil
+}
+
+// trimCache prunes the given cache to its size limit, failing to do so is not fatal
+func trimCache(s *sys.System, c *cache.Cache) {
+	if c == nil {
+		return
+	}
+	removed, err := c.Trim()
+	if err != nil {
+		s.Logger().Warn("Pruning cache %s failed: %v", c.Dir(), err)
+		return
+	}
+	if len(removed) > 0 {
+		s.Logger().Info("Removed %d least recently used blobs from the cache", len(removed))
+	}
+}
+
+func parseCacheSize(size string) (int64, error) {
+	if size == "" || size == "0" {
+		return 0, nil
+	}
+	bytes, err := units.RAMInBytes(size)
+	if err != nil || bytes < 0 {
+		return 0, fmt.Errorf("invalid cache size %q", size)
+	}
+	return bytes, nil
+}
+
+func writeCacheTable(w io.Writer, entries []cache.Blob) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, err := fmt.Fprintln(tw, "DIGEST\tSIZE\tLAST USED")
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, e := range entries {
+		_, err = fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Digest, units.BytesSize(float64(e.Size)), e.LastUsed.Format(time.DateTime))
+		if err != nil {
+			return err
+		}
+		total += e.Size
+	}
+	if err = tw.Flush(); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "\n%d blobs, %s\n", len(entries), units.BytesSize(float64(total)))
+	return err
+}
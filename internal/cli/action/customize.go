@@ -33,19 +33,25 @@ import (
 	v0 "github.com/suse/elemental/v3/internal/config/v0"
 	"github.com/suse/elemental/v3/internal/customize"
 	"github.com/suse/elemental/v3/internal/image"
+	"github.com/suse/elemental/v3/pkg/bundle"
+	"github.com/suse/elemental/v3/pkg/cache"
 	"github.com/suse/elemental/v3/pkg/extractor"
 	"github.com/suse/elemental/v3/pkg/helm"
 	"github.com/suse/elemental/v3/pkg/http"
+	"github.com/suse/elemental/v3/pkg/registry"
+	"github.com/suse/elemental/v3/pkg/signature"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/platform"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
+	"github.com/suse/elemental/v3/pkg/unpack"
 )
 
-func Customize(ctx context.Context, cmd *cli.Command) error {
+func Customize(ctx context.Context, cmd *cli.Command) (err error) {
 	if cmd.Root().Metadata == nil || cmd.Root().Metadata["system"] == nil {
 		return fmt.Errorf("error setting up initial configuration")
 	}
 	system := cmd.Root().Metadata["system"].(*sys.System)
+	defer func() { system.Events().Done(cmd.Name, err) }()
 	logger := system.Logger()
 	fs := system.FS()
 	args := &cmdpkg.CustomizeArgs
@@ -76,7 +82,23 @@ func Customize(ctx context.Context, cmd *cli.Command) error {
 	ctxCancel, cancelFunc := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
 	defer cancelFunc()
 
-	customizeRunner, err := setupCustomizeRunner(ctxCancel, system, args, output)
+	b, err := openBundle(ctxCancel, system, args.Bundle, args.Local)
+	if err != nil {
+		logger.Error("Opening bundle failed")
+		return err
+	}
+	if b != nil {
+		defer func() { _ = b.Close() }()
+	}
+
+	c, err := cacheFromFlags(fs, args.CacheDir, args.CacheMaxSize, args.NoCache)
+	if err != nil {
+		logger.Error("Setting up cache failed")
+		return err
+	}
+	defer trimCache(system, c)
+
+	customizeRunner, err := setupCustomizeRunner(ctxCancel, system, args, output, b, c)
 	if err != nil {
 		logger.Error("Setting up customization runner failed")
 		return err
@@ -115,20 +137,47 @@ func setupCustomizeRunner(
 	s *sys.System,
 	args *cmdpkg.CustomizeFlags,
 	output config.Output,
+	b *bundle.Bundle,
+	c *cache.Cache,
 ) (*customize.Runner, error) {
-	extr, err := setupFileExtractor(ctx, s, output, args.Local)
+	policy, err := signaturePolicyFromFlags(s.FS(), args.SignaturePolicy, args.SignatureKeys)
+	if err != nil {
+		return nil, fmt.Errorf("loading signature policy: %w", err)
+	}
+
+	registries, err := registriesFromFlags(s.FS(), args.RegistryConfig)
+	if err != nil {
+		return nil, fmt.Errorf("loading registries configuration: %w", err)
+	}
+
+	localSource, err := unpack.ParseLocalSource(args.LocalSource)
+	if err != nil {
+		return nil, err
+	}
+
+	extr, err := setupFileExtractor(ctx, s, output, args.Local, localSource, policy, b, c, registries)
 	if err != nil {
 		return nil, fmt.Errorf("setting up file extractor: %w", err)
 	}
 
+	downloader, err := downloaderFromFlags(s.FS(), args.DownloadMirrors, args.DownloadCABundle)
+	if err != nil {
+		return nil, fmt.Errorf("setting up downloader: %w", err)
+	}
+
 	return &customize.Runner{
-		System:        s,
-		ConfigManager: setupConfigManager(s, args.ConfigDir, output, args.Local),
-		FileExtractor: extr,
+		System:          s,
+		ConfigManager:   setupConfigManager(s, args.ConfigDir, output, args.Local, localSource, policy, b, downloader, c, registries),
+		FileExtractor:   extr,
+		SignaturePolicy: policy,
+		Registries:      registries,
 	}, nil
 }
 
-func setupConfigManager(s *sys.System, configDir string, output config.Output, local bool) *config.Manager {
+func setupConfigManager(
+	s *sys.System, configDir string, output config.Output, local bool, localSource unpack.LocalSource,
+	policy *signature.Policy, b *bundle.Bundle, downloader *http.Downloader, c *cache.Cache, registries *registry.Config,
+) *config.Manager {
 	valuesResolver := &helm.ValuesResolver{
 		FS:        s.FS(),
 		ValuesDir: v0.Dir(configDir).HelmValuesDir(),
@@ -137,12 +186,20 @@ func setupConfigManager(s *sys.System, configDir string, output config.Output, l
 	return config.NewManager(
 		s,
 		config.NewHelm(s.FS(), valuesResolver, s.Logger(), output.OverlaysDir()),
-		config.WithDownloadFunc(http.DownloadFile),
+		config.WithDownloadFunc(downloader.Download),
 		config.WithLocal(local),
+		config.WithLocalSource(localSource),
+		config.WithSignaturePolicy(policy),
+		config.WithBundle(b),
+		config.WithCache(c),
+		config.WithRegistries(registries),
 	)
 }
 
-func setupFileExtractor(ctx context.Context, s *sys.System, outDir config.Output, local bool) (extr *extractor.OCIFileExtractor, err error) {
+func setupFileExtractor(
+	ctx context.Context, s *sys.System, outDir config.Output, local bool, localSource unpack.LocalSource,
+	policy *signature.Policy, b *bundle.Bundle, c *cache.Cache, registries *registry.Config,
+) (extr *extractor.OCIFileExtractor, err error) {
 	const isoSearchGlob = "/iso/uc-base-kernel-default-iso*.iso"
 
 	if err := vfs.MkdirAll(s.FS(), outDir.ISOStoreDir(), vfs.DirPerm); err != nil {
@@ -155,6 +212,11 @@ func setupFileExtractor(ctx context.Context, s *sys.System, outDir config.Output
 		extractor.WithFS(s.FS()),
 		extractor.WithContext(ctx),
 		extractor.WithLocal(local),
+		extractor.WithLocalSource(localSource),
+		extractor.WithSignaturePolicy(policy),
+		extractor.WithBundle(b),
+		extractor.WithCache(c),
+		extractor.WithRegistries(registries),
 	)
 }
 
@@ -174,6 +236,7 @@ func digestCustomizeDefinition(f vfs.FS, args *cmdpkg.CustomizeFlags, imagePath
 			ImageType:       args.MediaType,
 			Platform:        p,
 			OutputImageName: imagePath,
+			Compress:        args.Compress,
 		},
 		Configuration: conf,
 	}, nil
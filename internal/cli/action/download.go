@@ -0,0 +1,39 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+
+	"github.com/suse/elemental/v3/pkg/http"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+// downloaderFromFlags returns the downloader of remote files defined by the --download-mirror and
+// --download-ca-bundle flags
+func downloaderFromFlags(fs vfs.FS, mirrors []string, caBundle string) (*http.Downloader, error) {
+	opts := []http.Option{http.WithMirrors(mirrors...)}
+	if caBundle != "" {
+		pem, err := fs.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		opts = append(opts, http.WithCABundle(pem))
+	}
+	return http.NewDownloader(opts...)
+}
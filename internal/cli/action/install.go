@@ -27,6 +27,7 @@ import (
 	"go.yaml.in/yaml/v3"
 
 	cmdpkg "github.com/suse/elemental/v3/internal/cli/cmd"
+	"github.com/suse/elemental/v3/pkg/block/lsblk"
 	"github.com/suse/elemental/v3/pkg/bootloader"
 	"github.com/suse/elemental/v3/pkg/crypto"
 	"github.com/suse/elemental/v3/pkg/deployment"
@@ -41,13 +42,14 @@ import (
 	"github.com/suse/elemental/v3/pkg/upgrade"
 )
 
-func Install(ctx context.Context, cmd *cli.Command) error {
+func Install(ctx context.Context, cmd *cli.Command) (err error) {
 	var s *sys.System
 	args := &cmdpkg.InstallArgs
 	if cmd.Root().Metadata == nil || cmd.Root().Metadata["system"] == nil {
 		return fmt.Errorf("error setting up initial configuration")
 	}
 	s = cmd.Root().Metadata["system"].(*sys.System)
+	defer func() { s.Events().Done(cmd.Name, err) }()
 
 	s.Logger().Info("Starting install action")
 	s.Logger().Debug("Install action called with args: %+v", args)
@@ -85,7 +87,7 @@ func Install(ctx context.Context, cmd *cli.Command) error {
 }
 
 func initInstaller(ctx context.Context, s *sys.System, d *deployment.Deployment, args *cmdpkg.InstallFlags) (*install.Installer, error) {
-	bootloader, err := bootloader.New(d.BootConfig.Bootloader, s)
+	bootloader, err := bootloader.New(d.BootConfig.Bootloader, s, d.BootloaderOpts(s)...)
 	if err != nil {
 		s.Logger().Error("Parsing boot config failed")
 		return nil, err
@@ -97,7 +99,9 @@ func initInstaller(ctx context.Context, s *sys.System, d *deployment.Deployment,
 		return nil, err
 	}
 
-	unpackOpts := []unpack.Opt{unpack.WithVerify(args.Verify), unpack.WithLocal(args.Local)}
+	unpackOpts := []unpack.Opt{
+		unpack.WithVerify(args.Verify), unpack.WithLocal(args.Local), unpack.WithLocalSource(unpack.LocalSource(args.LocalSource)),
+	}
 	manager := firmware.NewEfiBootManager(s)
 	upgrader := upgrade.New(
 		ctx, s, upgrade.WithBootManager(manager), upgrade.WithBootloader(bootloader),
@@ -128,11 +132,8 @@ func loadDescriptionFile(s *sys.System, file string, d *deployment.Deployment) e
 
 // setBootloader configures the bootloader for the given deployment with the given flags
 func setBootloader(s *sys.System, d *deployment.Deployment, bootloaderType, cmdline string, createEntry bool) {
-	disk := d.GetSystemDisk()
-	if createEntry && disk != nil {
-		d.Firmware.BootEntries = []*firmware.EfiBootEntry{
-			firmware.DefaultBootEntry(s.Platform(), disk.Device),
-		}
+	if createEntry {
+		d.Firmware.BootEntries = d.DefaultBootEntries(s.Platform())
 	}
 
 	if d.BootConfig == nil {
@@ -211,7 +212,26 @@ func applyInstallFlags(s *sys.System, d *deployment.Deployment, flags *cmdpkg.In
 		}
 	}
 
+	policy, err := signaturePolicyFromFlags(s.FS(), flags.SignaturePolicy, flags.SignatureKeys)
+	if err != nil {
+		return fmt.Errorf("loading signature policy: %w", err)
+	}
+	if policy != nil {
+		d.Security.SignaturePolicy = policy
+	}
+
+	registries, err := registriesFromFlags(s.FS(), flags.RegistryConfig)
+	if err != nil {
+		return fmt.Errorf("loading registries configuration: %w", err)
+	}
+	if registries != nil {
+		d.Registries = registries
+	}
+
 	setBootloader(s, d, flags.Bootloader, flags.KernelCmdline, flags.CreateBootEntry)
+	if flags.UKI {
+		d.BootConfig.UKI = true
+	}
 
 	if flags.Snapshotter != "" {
 		d.Snapshotter.Name = flags.Snapshotter
@@ -227,7 +247,12 @@ func applyInstallFlags(s *sys.System, d *deployment.Deployment, flags *cmdpkg.In
 		}
 	}
 
-	err := d.Sanitize(s)
+	err = d.ResolveDisks(s, lsblk.NewLsDevice(s))
+	if err != nil {
+		return fmt.Errorf("selecting target disks: %w", err)
+	}
+
+	err = d.Sanitize(s)
 	if err != nil {
 		return fmt.Errorf("inconsistent deployment setup found: %w", err)
 	}
@@ -109,4 +109,20 @@ var _ = Describe("Install action", Label("install"), func() {
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("image source type not supported"))
 	})
+	It("fails if the signature key can't be read", func() {
+		cmd.InstallArgs.Target = "/dev/device"
+		cmd.InstallArgs.OperatingSystemImage = "my.registry.org/my/image:test"
+		cmd.InstallArgs.SignatureKeys = []string{"/configDir/cosign.pub"}
+		err = action.Install(context.Background(), cliCmd)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("reading signature key"))
+	})
+	It("fails if the signature key is not a valid public key", func() {
+		cmd.InstallArgs.Target = "/dev/device"
+		cmd.InstallArgs.OperatingSystemImage = "my.registry.org/my/image:test"
+		cmd.InstallArgs.SignatureKeys = []string{"/dev/device"}
+		err = action.Install(context.Background(), cliCmd)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("invalid signature key"))
+	})
 })
@@ -0,0 +1,32 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"github.com/suse/elemental/v3/pkg/registry"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+// registriesFromFlags loads the registries configuration given by the --registry-config flag,
+// it returns nil if the flag is not set
+func registriesFromFlags(fs vfs.FS, path string) (*registry.Config, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return registry.Load(fs, path)
+}
@@ -34,13 +34,14 @@ import (
 	"github.com/suse/elemental/v3/pkg/sys"
 )
 
-func Reset(ctx context.Context, cmd *cli.Command) error {
+func Reset(ctx context.Context, cmd *cli.Command) (err error) {
 	var s *sys.System
 	args := &cmdpkg.InstallArgs
 	if cmd.Root().Metadata == nil || cmd.Root().Metadata["system"] == nil {
 		return fmt.Errorf("error setting up initial configuration")
 	}
 	s = cmd.Root().Metadata["system"].(*sys.System)
+	defer func() { s.Events().Done(cmd.Name, err) }()
 
 	s.Logger().Info("Starting reset action")
 	s.Logger().Debug("Reset action called with args: %+v", args)
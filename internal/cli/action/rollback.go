@@ -0,0 +1,101 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+
+	cmdpkg "github.com/suse/elemental/v3/internal/cli/cmd"
+	"github.com/suse/elemental/v3/pkg/bootloader"
+	"github.com/suse/elemental/v3/pkg/cleanstack"
+	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/rollback"
+	"github.com/suse/elemental/v3/pkg/sys"
+)
+
+func Rollback(_ context.Context, cmd *cli.Command) (err error) {
+	var s *sys.System
+	args := &cmdpkg.RollbackArgs
+	if cmd.Root().Metadata == nil || cmd.Root().Metadata["system"] == nil {
+		return fmt.Errorf("error setting up initial configuration")
+	}
+	s = cmd.Root().Metadata["system"].(*sys.System)
+
+	s.Logger().Info("Starting rollback action with args: %+v", args)
+
+	if args.To < 0 {
+		return fmt.Errorf("invalid snapshot ID: %d", args.To)
+	}
+
+	d, err := deployment.Parse(s, "/")
+	if err != nil {
+		return fmt.Errorf("parsing deployment: %w", err)
+	} else if d == nil {
+		return fmt.Errorf("deployment not found")
+	}
+
+	cleanup := cleanstack.NewCleanStack()
+	defer func() { err = cleanup.Cleanup(err) }()
+
+	b, err := mirroredBootloader(s, d, cleanup)
+	if err != nil {
+		return err
+	}
+
+	id, err := rollback.New(s, rollback.WithBootloader(b)).Rollback(d, args.To)
+	if err != nil {
+		s.Logger().Error("Rollback failed")
+		return err
+	}
+
+	s.Logger().Info("Rollback completed, snapshot %d will be booted on next reboot", id)
+
+	return nil
+}
+
+// newBootloader returns the bootloader of the given deployment
+func newBootloader(s *sys.System, d *deployment.Deployment) (bootloader.Bootloader, error) {
+	bootloaderName := bootloader.BootNone
+	if d.BootConfig != nil {
+		bootloaderName = d.BootConfig.Bootloader
+	}
+	b, err := bootloader.New(bootloaderName, s, d.BootloaderOpts(s)...)
+	if err != nil {
+		s.Logger().Error("Parsing boot config failed")
+		return nil, err
+	}
+	return b, nil
+}
+
+// mirroredBootloader returns the bootloader of the given deployment wrapped to keep the ESP mirrors
+// in sync. The mirrors are mounted until the given cleanup stack is run.
+func mirroredBootloader(s *sys.System, d *deployment.Deployment, cleanup *cleanstack.CleanStack) (bootloader.Bootloader, error) {
+	b, err := newBootloader(s, d)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err = bootloader.MountMirrors(s, cleanup, b, d.GetMirrorPartitions(deployment.EFI).UUIDs()...)
+	if err != nil {
+		return nil, fmt.Errorf("setting up ESP mirrors: %w", err)
+	}
+	return b, nil
+}
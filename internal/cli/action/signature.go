@@ -0,0 +1,67 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/suse/elemental/v3/pkg/signature"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+// signaturePolicyFromFlags loads the signature policy defined by the --signature-policy and
+// --signature-key flags. Given public keys are trusted for any image. Returns nil if no flag is set.
+func signaturePolicyFromFlags(fs vfs.FS, policyFile string, keyFiles []string) (*signature.Policy, error) {
+	var err error
+
+	if policyFile == "" && len(keyFiles) == 0 {
+		return nil, nil
+	}
+
+	policy := &signature.Policy{}
+	if policyFile != "" {
+		policy, err = signature.LoadPolicy(fs, policyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(keyFiles) == 0 {
+		return policy, nil
+	}
+
+	i := slices.IndexFunc(policy.Scopes, func(s signature.Scope) bool { return s.Scope == "" })
+	if i < 0 {
+		policy.Scopes = append(policy.Scopes, signature.Scope{})
+		i = len(policy.Scopes) - 1
+	}
+	for _, keyFile := range keyFiles {
+		key, err := fs.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading signature key: %w", err)
+		}
+		policy.Scopes[i].PublicKeys = append(policy.Scopes[i].PublicKeys, string(key))
+	}
+
+	err = policy.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature key: %w", err)
+	}
+	return policy, nil
+}
@@ -0,0 +1,219 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	cmdpkg "github.com/suse/elemental/v3/internal/cli/cmd"
+	"github.com/suse/elemental/v3/pkg/cleanstack"
+	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/snapper"
+	"github.com/suse/elemental/v3/pkg/sys"
+)
+
+const snapperRootConfig = "root"
+
+type snapshotInfo struct {
+	ID      int    `json:"id"`
+	Date    string `json:"date"`
+	Image   string `json:"image,omitempty"`
+	Digest  string `json:"digest,omitempty"`
+	Default bool   `json:"default"`
+	Active  bool   `json:"active"`
+}
+
+func ListSnapshots(_ context.Context, cmd *cli.Command) error {
+	args := &cmdpkg.SnapshotsArgs
+	if cmd.Root().Metadata == nil || cmd.Root().Metadata["system"] == nil {
+		return fmt.Errorf("error setting up initial configuration")
+	}
+	s := cmd.Root().Metadata["system"].(*sys.System)
+
+	snaps, err := snapper.New(s).ListSnapshots("/", snapperRootConfig)
+	if err != nil {
+		return fmt.Errorf("listing snapshots: %w", err)
+	}
+
+	infos := make([]snapshotInfo, 0, len(snaps))
+	for _, snap := range snaps {
+		info := snapshotInfo{
+			ID:      snap.Number,
+			Date:    snap.Date,
+			Default: snap.Default,
+			Active:  snap.Active,
+		}
+		if p := snap.Provenance(); p.Image != "" {
+			info.Image = p.Image
+			info.Digest = p.Digest
+			infos = append(infos, info)
+			continue
+		}
+		// Snapshots created before provenance was recorded as userdata
+		d, err := deployment.Parse(s, snapper.SnapshotPath("/", snap.Number))
+		if err != nil {
+			s.Logger().Warn("Failed parsing deployment of snapshot %d: %v", snap.Number, err)
+		} else if d != nil && d.SourceOS != nil {
+			info.Image = d.SourceOS.String()
+			info.Digest = d.SourceOS.GetDigest()
+		}
+		infos = append(infos, info)
+	}
+
+	w := outputWriter(cmd)
+	switch args.Format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infos)
+	case "table", "":
+		return writeSnapshotsTable(w, infos)
+	default:
+		return fmt.Errorf("unknown output format '%s'", args.Format)
+	}
+}
+
+func ShowSnapshot(_ context.Context, cmd *cli.Command) error {
+	if cmd.Root().Metadata == nil || cmd.Root().Metadata["system"] == nil {
+		return fmt.Errorf("error setting up initial configuration")
+	}
+	s := cmd.Root().Metadata["system"].(*sys.System)
+
+	id, err := snapshotIDArg(cmd)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(snapper.SnapshotPath("/", id), deployment.File)
+	data, err := s.FS().ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading deployment file of snapshot %d: %w", id, err)
+	}
+
+	_, err = outputWriter(cmd).Write(data)
+	return err
+}
+
+func DeleteSnapshot(_ context.Context, cmd *cli.Command) (err error) {
+	if cmd.Root().Metadata == nil || cmd.Root().Metadata["system"] == nil {
+		return fmt.Errorf("error setting up initial configuration")
+	}
+	s := cmd.Root().Metadata["system"].(*sys.System)
+
+	id, err := snapshotIDArg(cmd)
+	if err != nil {
+		return err
+	}
+
+	d, err := deployment.Parse(s, "/")
+	if err != nil {
+		return fmt.Errorf("parsing deployment: %w", err)
+	} else if d == nil {
+		return fmt.Errorf("deployment not found")
+	}
+
+	esp := d.GetEfiPartition()
+	if esp == nil {
+		return fmt.Errorf("no %s partition defined in deployment", deployment.EfiLabel)
+	}
+
+	cleanup := cleanstack.NewCleanStack()
+	defer func() { err = cleanup.Cleanup(err) }()
+
+	b, err := mirroredBootloader(s, d, cleanup)
+	if err != nil {
+		return err
+	}
+
+	snap := snapper.New(s)
+	err = snap.Delete("/", id)
+	if err != nil {
+		return fmt.Errorf("deleting snapshot: %w", err)
+	}
+
+	snaps, err := snap.ListSnapshots("/", snapperRootConfig)
+	if err != nil {
+		return fmt.Errorf("listing snapshots: %w", err)
+	}
+	keep := make([]int, 0, len(snaps))
+	for _, sn := range snaps {
+		keep = append(keep, sn.Number)
+	}
+
+	err = b.Prune("/", esp.MountPoint, keep)
+	if err != nil {
+		return fmt.Errorf("pruning boot entries: %w", err)
+	}
+
+	s.Logger().Info("Snapshot %d deleted", id)
+	return nil
+}
+
+// snapshotIDArg parses the snapshot ID from the first positional argument of the given command
+func snapshotIDArg(cmd *cli.Command) (int, error) {
+	args := cmd.Args()
+	if args == nil || args.Len() != 1 {
+		return 0, fmt.Errorf("expected a single snapshot ID argument")
+	}
+	id, err := strconv.Atoi(args.First())
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid snapshot ID: %s", args.First())
+	}
+	return id, nil
+}
+
+// outputWriter returns the writer of the root command, defaults to stdout
+func outputWriter(cmd *cli.Command) io.Writer {
+	if cmd.Root().Writer != nil {
+		return cmd.Root().Writer
+	}
+	return os.Stdout
+}
+
+func writeSnapshotsTable(w io.Writer, infos []snapshotInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, err := fmt.Fprintln(tw, "ID\tDATE\tIMAGE\tDIGEST\tSTATUS")
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		status := ""
+		switch {
+		case info.Default && info.Active:
+			status = "active,default"
+		case info.Default:
+			status = "default"
+		case info.Active:
+			status = "active"
+		}
+		_, err = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", info.ID, info.Date, info.Image, info.Digest, status)
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,121 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/suse/elemental/v3/internal/cli/action"
+	"github.com/suse/elemental/v3/internal/cli/cmd"
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+const snapperListOut = `{
+  "root": [
+    {"number": 0, "default": false, "active": false, "userdata": null},
+    {"number": 1, "default": false, "active": false, "date": "2025-01-01 10:00:00",
+     "userdata": {"image": "oci://registry.org/my/os:v1", "image-digest": "sha256:abcdef"}},
+    {"number": 2, "default": true, "active": true, "userdata": null, "date": "2025-02-01 10:00:00"}
+  ]
+}`
+
+var _ = Describe("Snapshots action", Label("snapshots"), func() {
+	var s *sys.System
+	var tfs vfs.FS
+	var runner *sysmock.Runner
+	var cleanup func()
+	var err error
+	var cliCmd *cli.Command
+	var out *bytes.Buffer
+
+	BeforeEach(func() {
+		cmd.SnapshotsArgs = cmd.SnapshotsFlags{}
+		out = &bytes.Buffer{}
+		runner = sysmock.NewRunner()
+		runner.SideEffect = func(command string, _ ...string) ([]byte, error) {
+			if command == "snapper" {
+				return []byte(snapperListOut), nil
+			}
+			return []byte{}, nil
+		}
+		tfs, cleanup, err = sysmock.TestFS(map[string]string{
+			"/.snapshots/2/snapshot/etc/elemental/deployment.yaml": "sourceOS:\n  uri: oci://registry.org/my/os:v2\n",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		s, err = sys.NewSystem(
+			sys.WithFS(tfs), sys.WithRunner(runner),
+			sys.WithLogger(log.New(log.WithDiscardAll())),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		cliCmd = &cli.Command{
+			Writer: out,
+			Metadata: map[string]any{
+				"system": s,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		cleanup()
+	})
+	It("fails if no sys.System instance is in metadata", func() {
+		cliCmd.Metadata["system"] = nil
+		Expect(action.ListSnapshots(context.Background(), cliCmd)).NotTo(Succeed())
+		Expect(action.ShowSnapshot(context.Background(), cliCmd)).NotTo(Succeed())
+		Expect(action.DeleteSnapshot(context.Background(), cliCmd)).NotTo(Succeed())
+	})
+	It("lists snapshots as a table", func() {
+		Expect(action.ListSnapshots(context.Background(), cliCmd)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("ID"))
+		Expect(out.String()).To(MatchRegexp(`2\s+2025-02-01 10:00:00\s+oci://registry.org/my/os:v2\s+active,default`))
+	})
+	It("lists snapshots as JSON", func() {
+		cmd.SnapshotsArgs.Format = "json"
+		Expect(action.ListSnapshots(context.Background(), cliCmd)).To(Succeed())
+		var snaps []map[string]any
+		Expect(json.Unmarshal(out.Bytes(), &snaps)).To(Succeed())
+		Expect(snaps).To(HaveLen(2))
+		Expect(snaps[0]["image"]).To(Equal("oci://registry.org/my/os:v1"))
+		Expect(snaps[0]["digest"]).To(Equal("sha256:abcdef"))
+		Expect(snaps[1]["image"]).To(Equal("oci://registry.org/my/os:v2"))
+		Expect(snaps[1]["default"]).To(BeTrue())
+	})
+	It("fails to list snapshots with an unknown format", func() {
+		cmd.SnapshotsArgs.Format = "xml"
+		err = action.ListSnapshots(context.Background(), cliCmd)
+		Expect(err).To(MatchError("unknown output format 'xml'"))
+	})
+	It("fails to show a snapshot without an ID", func() {
+		err = action.ShowSnapshot(context.Background(), cliCmd)
+		Expect(err).To(MatchError("expected a single snapshot ID argument"))
+	})
+	It("fails to delete a snapshot without an ID", func() {
+		err = action.DeleteSnapshot(context.Background(), cliCmd)
+		Expect(err).To(MatchError("expected a single snapshot ID argument"))
+	})
+})
@@ -42,6 +42,7 @@ func Unpack(ctx context.Context, cmd *cli.Command) error {
 
 	unpacker := unpack.NewOCIUnpacker(s, args.Image,
 		unpack.WithLocalOCI(args.Local),
+		unpack.WithLocalSourceOCI(unpack.LocalSource(args.LocalSource)),
 		unpack.WithPlatformRefOCI(args.Platform),
 		unpack.WithVerifyOCI(args.Verify))
 
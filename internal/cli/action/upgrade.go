@@ -19,9 +19,12 @@ package action
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os/signal"
 	"syscall"
+	"text/tabwriter"
 
 	"github.com/urfave/cli/v3"
 
@@ -30,20 +33,39 @@ import (
 	"github.com/suse/elemental/v3/pkg/deployment"
 	"github.com/suse/elemental/v3/pkg/firmware"
 	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/transaction"
 	"github.com/suse/elemental/v3/pkg/unpack"
 	"github.com/suse/elemental/v3/pkg/upgrade"
 )
 
-func Upgrade(ctx context.Context, cmd *cli.Command) error {
+func Upgrade(ctx context.Context, cmd *cli.Command) (err error) {
 	var s *sys.System
 	args := &cmdpkg.UpgradeArgs
 	if cmd.Root().Metadata == nil || cmd.Root().Metadata["system"] == nil {
 		return fmt.Errorf("error setting up initial configuration")
 	}
 	s = cmd.Root().Metadata["system"].(*sys.System)
+	defer func() { s.Events().Done(cmd.Name, err) }()
 
 	s.Logger().Info("Starting upgrade action with args: %+v", args)
 
+	if err := validateUpgradeFlags(args); err != nil {
+		return err
+	}
+
+	if args.ApplyStaged || args.DiscardStaged {
+		return upgradeStaged(ctx, s, args)
+	}
+
+	b, err := openBundle(ctx, s, args.Bundle, args.Local)
+	if err != nil {
+		s.Logger().Error("Opening bundle failed")
+		return err
+	}
+	if b != nil {
+		defer func() { _ = b.Close() }()
+	}
+
 	d, err := digestUpgradeSetup(s, args)
 	if err != nil {
 		s.Logger().Error("Failed to collect upgrade setup")
@@ -60,7 +82,7 @@ func Upgrade(ctx context.Context, cmd *cli.Command) error {
 		stop()
 	}()
 
-	bootloader, err := bootloader.New(d.BootConfig.Bootloader, s)
+	bootloader, err := bootloader.New(d.BootConfig.Bootloader, s, d.BootloaderOpts(s)...)
 	if err != nil {
 		s.Logger().Error("Parsing boot config failed")
 		return err
@@ -69,9 +91,31 @@ func Upgrade(ctx context.Context, cmd *cli.Command) error {
 	manager := firmware.NewEfiBootManager(s)
 	upgrader := upgrade.New(
 		ctxCancel, s, upgrade.WithBootloader(bootloader), upgrade.WithBootManager(manager),
-		upgrade.WithUnpackOpts(unpack.WithVerify(args.Verify), unpack.WithLocal(args.Local)),
+		upgrade.WithUnpackOpts(
+			unpack.WithVerify(args.Verify), unpack.WithLocal(args.Local),
+			unpack.WithLocalSource(unpack.LocalSource(args.LocalSource)), unpack.WithBundle(b),
+		),
 	)
 
+	if args.DryRun {
+		report, err := upgrader.DryRun(d)
+		if err != nil {
+			s.Logger().Error("Upgrade dry-run failed")
+			return err
+		}
+		return writeMergeReport(outputWriter(cmd), report, args.Format)
+	}
+
+	if args.Stage {
+		err = upgrader.Stage(d)
+		if err != nil {
+			s.Logger().Error("Staging upgrade failed")
+			return err
+		}
+		s.Logger().Info("Upgrade staged, run 'upgrade --apply-staged' to activate it")
+		return nil
+	}
+
 	err = upgrader.Upgrade(d)
 	if err != nil {
 		s.Logger().Error("Upgrade failed")
@@ -83,6 +127,73 @@ func Upgrade(ctx context.Context, cmd *cli.Command) error {
 	return nil
 }
 
+// upgradeStaged applies or discards a previously staged upgrade
+func upgradeStaged(ctx context.Context, s *sys.System, args *cmdpkg.UpgradeFlags) error {
+	d, err := deployment.Parse(s, "/")
+	if err != nil {
+		return fmt.Errorf("parsing deployment: %w", err)
+	} else if d == nil {
+		return fmt.Errorf("deployment not found")
+	}
+
+	ctxCancel, stop := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	bootloader, err := bootloader.New(d.BootConfig.Bootloader, s, d.BootloaderOpts(s)...)
+	if err != nil {
+		s.Logger().Error("Parsing boot config failed")
+		return err
+	}
+
+	upgrader := upgrade.New(
+		ctxCancel, s, upgrade.WithBootloader(bootloader), upgrade.WithBootManager(firmware.NewEfiBootManager(s)),
+	)
+
+	if args.DiscardStaged {
+		err = upgrader.DiscardStaged(d)
+		if err != nil {
+			s.Logger().Error("Discarding staged upgrade failed")
+			return err
+		}
+		s.Logger().Info("Staged upgrade discarded")
+		return nil
+	}
+
+	err = upgrader.ApplyStaged(d)
+	if err != nil {
+		s.Logger().Error("Applying staged upgrade failed")
+		return err
+	}
+	s.Logger().Info("Staged upgrade applied, it will be booted on next reboot")
+	return nil
+}
+
+// validateUpgradeFlags checks the staging flags are not combined and the OS image is set when required
+func validateUpgradeFlags(args *cmdpkg.UpgradeFlags) error {
+	modes := 0
+	for _, set := range []bool{args.Stage, args.ApplyStaged, args.DiscardStaged, args.DryRun} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return fmt.Errorf("--stage, --apply-staged, --discard-staged and --dry-run are mutually exclusive")
+	}
+	if args.Format != "" && args.Format != "text" && args.Format != "json" {
+		return fmt.Errorf("unknown output format '%s'", args.Format)
+	}
+	if args.ApplyStaged || args.DiscardStaged {
+		if args.OperatingSystemImage != "" {
+			return fmt.Errorf("--os-image can't be used with --apply-staged or --discard-staged")
+		}
+		return nil
+	}
+	if args.OperatingSystemImage == "" {
+		return fmt.Errorf("--os-image is required")
+	}
+	return nil
+}
+
 func digestUpgradeSetup(s *sys.System, flags *cmdpkg.UpgradeFlags) (*deployment.Deployment, error) {
 	d, err := deployment.Parse(s, "/")
 	if err != nil {
@@ -96,6 +207,8 @@ func digestUpgradeSetup(s *sys.System, flags *cmdpkg.UpgradeFlags) (*deployment.
 		return nil, fmt.Errorf("failed parsing OS source URI ('%s'): %w", flags.OperatingSystemImage, err)
 	}
 	d.SourceOS = srcOS
+	// the rollback record only describes the snapshot it was written in
+	d.Rollback = nil
 
 	if flags.Overlay != "" {
 		overlay, err := deployment.NewSrcFromURI(flags.Overlay)
@@ -109,13 +222,30 @@ func digestUpgradeSetup(s *sys.System, flags *cmdpkg.UpgradeFlags) (*deployment.
 		d.CfgScript = flags.ConfigScript
 	}
 
+	policy, err := signaturePolicyFromFlags(s.FS(), flags.SignaturePolicy, flags.SignatureKeys)
+	if err != nil {
+		return nil, fmt.Errorf("loading signature policy: %w", err)
+	}
+	if policy != nil {
+		if d.Security == nil {
+			d.Security = &deployment.SecurityConfig{}
+		}
+		d.Security.SignaturePolicy = policy
+	}
+
+	registries, err := registriesFromFlags(s.FS(), flags.RegistryConfig)
+	if err != nil {
+		return nil, fmt.Errorf("loading registries configuration: %w", err)
+	}
+	if registries != nil {
+		d.Registries = registries
+	}
+
 	if flags.CreateBootEntry {
 		if d.Firmware == nil {
 			d.Firmware = &deployment.FirmwareConfig{}
 		}
-		d.Firmware.BootEntries = []*firmware.EfiBootEntry{
-			firmware.DefaultBootEntry(s.Platform(), d.Disks[0].Device),
-		}
+		d.Firmware.BootEntries = d.DefaultBootEntries(s.Platform())
 	}
 
 	err = d.Sanitize(s, deployment.CheckDiskDevice)
@@ -124,3 +254,31 @@ func digestUpgradeSetup(s *sys.System, flags *cmdpkg.UpgradeFlags) (*deployment.
 	}
 	return d, nil
 }
+
+// writeMergeReport writes the given dry-run report in the requested format
+func writeMergeReport(w io.Writer, report *transaction.MergeReport, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	if report.IsEmpty() {
+		_, err := fmt.Fprintln(w, "No changes in snapshotted RW volumes")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, err := fmt.Fprintln(tw, "VOLUME\tPOLICY\tCHANGE\tPATH")
+	if err != nil {
+		return err
+	}
+	for _, vol := range report.Volumes {
+		for _, change := range vol.Changes {
+			_, err = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", vol.Path, vol.MergePolicy, change.Change, change.Path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Flush()
+}
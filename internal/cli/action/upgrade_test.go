@@ -94,4 +94,64 @@ var _ = Describe("Upgrade action", Label("upgrade"), func() {
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("image source type not supported"))
 	})
+	It("fails if the signature policy can't be read", func() {
+		cmd.UpgradeArgs.OperatingSystemImage = "my.registry.org/my/image:test"
+		cmd.UpgradeArgs.SignaturePolicy = "/etc/elemental/policy.yaml"
+		err = action.Upgrade(context.Background(), cliCmd)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("reading signature policy"))
+	})
+	It("fails if the bundle is combined with local images", func() {
+		cmd.UpgradeArgs.OperatingSystemImage = "my.registry.org/my/image:test"
+		cmd.UpgradeArgs.Bundle = "/bundle.tar"
+		cmd.UpgradeArgs.Local = true
+		err = action.Upgrade(context.Background(), cliCmd)
+		Expect(err).To(MatchError("--bundle and --local are mutually exclusive"))
+	})
+	It("fails if the bundle does not exist", func() {
+		cmd.UpgradeArgs.OperatingSystemImage = "my.registry.org/my/image:test"
+		cmd.UpgradeArgs.Bundle = "/bundle.tar"
+		err = action.Upgrade(context.Background(), cliCmd)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("opening bundle"))
+	})
+	It("fails if no OS image is given", func() {
+		err = action.Upgrade(context.Background(), cliCmd)
+		Expect(err).To(MatchError("--os-image is required"))
+	})
+	It("fails if staging flags are combined", func() {
+		cmd.UpgradeArgs.Stage = true
+		cmd.UpgradeArgs.DiscardStaged = true
+		err = action.Upgrade(context.Background(), cliCmd)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("mutually exclusive"))
+	})
+	It("fails if dry-run is combined with staging", func() {
+		cmd.UpgradeArgs.OperatingSystemImage = "my.registry.org/my/image:test"
+		cmd.UpgradeArgs.Stage = true
+		cmd.UpgradeArgs.DryRun = true
+		err = action.Upgrade(context.Background(), cliCmd)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("mutually exclusive"))
+	})
+	It("fails if the report format is unknown", func() {
+		cmd.UpgradeArgs.OperatingSystemImage = "my.registry.org/my/image:test"
+		cmd.UpgradeArgs.DryRun = true
+		cmd.UpgradeArgs.Format = "yaml"
+		err = action.Upgrade(context.Background(), cliCmd)
+		Expect(err).To(MatchError("unknown output format 'yaml'"))
+	})
+	It("fails if an OS image is given when applying a staged upgrade", func() {
+		cmd.UpgradeArgs.ApplyStaged = true
+		cmd.UpgradeArgs.OperatingSystemImage = "my.registry.org/my/image:test"
+		err = action.Upgrade(context.Background(), cliCmd)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("can't be used with --apply-staged"))
+	})
+	It("fails to apply a staged upgrade if the deployment file does not exist", func() {
+		Expect(tfs.RemoveAll("/etc/elemental")).To(Succeed())
+		cmd.UpgradeArgs.ApplyStaged = true
+		err = action.Upgrade(context.Background(), cliCmd)
+		Expect(err).To(MatchError("deployment not found"))
+	})
 })
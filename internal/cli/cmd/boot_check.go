@@ -0,0 +1,70 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+)
+
+type BootCheckFlags struct {
+	RebootOnFailure bool
+	Format          string
+}
+
+var BootCheckArgs BootCheckFlags
+
+func NewBootCheckCommand(appName string, markGoodAction, statusAction func(context.Context, *cli.Command) error) *cli.Command {
+	return &cli.Command{
+		Name:      "boot-check",
+		Usage:     "Check the health of the current boot",
+		UsageText: fmt.Sprintf("%s boot-check COMMAND", appName),
+		Commands: []*cli.Command{
+			{
+				Name:      "mark-good",
+				Usage:     "Run the health checks and mark the current boot as successful",
+				UsageText: fmt.Sprintf("%s boot-check mark-good [OPTIONS]", appName),
+				Action:    markGoodAction,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:        "reboot-on-failure",
+						Usage:       "Reboot the system if a health check fails and boot attempts are left",
+						Destination: &BootCheckArgs.RebootOnFailure,
+					},
+				},
+			},
+			{
+				Name:      "status",
+				Usage:     "Show the boot counting status",
+				UsageText: fmt.Sprintf("%s boot-check status [OPTIONS]", appName),
+				Action:    statusAction,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "format",
+						Aliases:     []string{"f"},
+						Value:       "text",
+						Usage:       "Output format [text, json]",
+						Destination: &BootCheckArgs.Format,
+					},
+				},
+			},
+		},
+	}
+}
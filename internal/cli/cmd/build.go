@@ -23,15 +23,30 @@ import (
 	"runtime"
 
 	"github.com/urfave/cli/v3"
+
+	"github.com/suse/elemental/v3/pkg/cache"
 )
 
 type BuildFlags struct {
-	ImageType  string
-	Platform   string
-	ConfigDir  string
-	BuildDir   string
-	OutputPath string
-	Local      bool
+	ImageType        string
+	Compress         bool
+	Platform         string
+	ConfigDir        string
+	BuildDir         string
+	OutputPath       string
+	Local            bool
+	LocalSource      string
+	Bundle           string
+	SignaturePolicy  string
+	SignatureKeys    []string
+	Unprivileged     bool
+	Reproducible     bool
+	DownloadMirrors  []string
+	DownloadCABundle string
+	RegistryConfig   string
+	CacheDir         string
+	CacheMaxSize     string
+	NoCache          bool
 }
 
 var BuildArgs BuildFlags
@@ -46,10 +61,15 @@ func NewBuildCommand(appName string, action func(context.Context, *cli.Command)
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:        "image-type",
-				Usage:       "Type of image artifact to build (RAW or ISO)",
+				Usage:       "Type of image artifact to build, 'raw', 'qcow2', 'vhdx', 'vmdk' or 'vhd'",
 				Destination: &BuildArgs.ImageType,
 				Required:    true,
 			},
+			&cli.BoolFlag{
+				Name:        "compress",
+				Usage:       "Compress the disk image, only supported for 'qcow2' and 'vmdk' types",
+				Destination: &BuildArgs.Compress,
+			},
 			&cli.StringFlag{
 				Name:        "platform",
 				Usage:       "Target platform",
@@ -80,6 +100,64 @@ func NewBuildCommand(appName string, action func(context.Context, *cli.Command)
 				Usage:       "Load OCI images from the local container storage instead of a remote registry",
 				Destination: &BuildArgs.Local,
 			},
+			localSourceFlag(&BuildArgs.LocalSource),
+			&cli.StringFlag{
+				Name:        "bundle",
+				Usage:       "Path to a bundle OCI images are read from instead of a remote registry",
+				Destination: &BuildArgs.Bundle,
+			},
+			&cli.StringFlag{
+				Name:        "signature-policy",
+				Usage:       "Path to a signature policy file OCI images are verified against",
+				Destination: &BuildArgs.SignaturePolicy,
+			},
+			&cli.StringSliceFlag{
+				Name:        "signature-key",
+				Usage:       "Path to a public key OCI images must be signed with, can be repeated",
+				Destination: &BuildArgs.SignatureKeys,
+			},
+			&cli.StringSliceFlag{
+				Name:        "download-mirror",
+				Usage:       "Base URL files are downloaded from if their own URL fails, can be repeated",
+				Destination: &BuildArgs.DownloadMirrors,
+			},
+			&cli.StringFlag{
+				Name:        "download-ca-bundle",
+				Usage:       "Path to a PEM file of CA certificates trusted for HTTPS downloads in addition to the system ones",
+				Destination: &BuildArgs.DownloadCABundle,
+			},
+			&cli.StringFlag{
+				Name:        "registry-config",
+				Usage:       "Path to a registries configuration file with the credentials, mirrors and TLS settings used to pull OCI images",
+				Destination: &BuildArgs.RegistryConfig,
+			},
+			&cli.StringFlag{
+				Name:        "cache-dir",
+				Usage:       "Directory of the cache of images and downloaded files reused across runs",
+				Destination: &BuildArgs.CacheDir,
+				Value:       cache.DefaultDir(),
+			},
+			&cli.StringFlag{
+				Name:        "cache-max-size",
+				Usage:       "Size the cache is pruned to after the run, e.g. 20G, 0 disables pruning",
+				Destination: &BuildArgs.CacheMaxSize,
+				Value:       "20G",
+			},
+			&cli.BoolFlag{
+				Name:        "no-cache",
+				Usage:       "Do not read or write the cache of images and downloaded files",
+				Destination: &BuildArgs.NoCache,
+			},
+			&cli.BoolFlag{
+				Name:        "unprivileged",
+				Usage:       "Compose the disk image from partition images, no loop devices or root privileges are required",
+				Destination: &BuildArgs.Unprivileged,
+			},
+			&cli.BoolFlag{
+				Name:        "reproducible",
+				Usage:       "Build a bit-for-bit reproducible image honouring SOURCE_DATE_EPOCH, implies --unprivileged",
+				Destination: &BuildArgs.Reproducible,
+			},
 		},
 	}
 }
@@ -29,6 +29,7 @@ type InstallerFlags struct {
 	OperatingSystemImage string
 	ConfigScript         string
 	Local                bool
+	LocalSource          string
 	Verify               bool
 	Name                 string
 	OutputDir            string
@@ -36,6 +37,11 @@ type InstallerFlags struct {
 	Label                string
 	KernelCmdLine        string
 	Type                 string
+	Compress             bool
+	Reproducible         bool
+	SecureBootKey        string
+	SecureBootCert       string
+	EnrollMOK            bool
 }
 
 var InstallerArgs InstallerFlags
@@ -84,11 +90,17 @@ func NewBuildInstallerCommand(appName string, action func(context.Context, *cli.
 				Usage:       "Verify OCI ssl",
 				Destination: &InstallerArgs.Verify,
 			},
+			&cli.StringFlag{
+				Name:        "registry-config",
+				Usage:       "Path to a registries configuration file with the credentials, mirrors and TLS settings used to pull OCI images",
+				Destination: &InstallerArgs.InstallSpec.RegistryConfig,
+			},
 			&cli.BoolFlag{
 				Name:        "local",
 				Usage:       "Load OCI images from the local container storage instead of a remote registry",
 				Destination: &InstallerArgs.Local,
 			},
+			localSourceFlag(&InstallerArgs.LocalSource),
 			&cli.StringFlag{
 				Name:        "output",
 				Usage:       "Location for the temporary build-time files and the resulting image",
@@ -123,10 +135,35 @@ func NewBuildInstallerCommand(appName string, action func(context.Context, *cli.
 			},
 			&cli.StringFlag{
 				Name:        "type",
-				Usage:       "Type of the installer media, 'iso' or 'raw'",
+				Usage:       "Type of the installer media, 'iso', 'raw', 'qcow2', 'vhdx', 'vmdk' or 'vhd'",
 				Destination: &InstallerArgs.Type,
 				Required:    true,
 			},
+			&cli.BoolFlag{
+				Name:        "compress",
+				Usage:       "Compress the disk image, only supported for 'qcow2' and 'vmdk' types",
+				Destination: &InstallerArgs.Compress,
+			},
+			&cli.BoolFlag{
+				Name:        "reproducible",
+				Usage:       "Build a bit-for-bit reproducible installer media honouring SOURCE_DATE_EPOCH",
+				Destination: &InstallerArgs.Reproducible,
+			},
+			&cli.StringFlag{
+				Name:        "secure-boot-key",
+				Usage:       "Path to the PEM encoded private key to sign the bootloader, kernels and UKIs with",
+				Destination: &InstallerArgs.SecureBootKey,
+			},
+			&cli.StringFlag{
+				Name:        "secure-boot-cert",
+				Usage:       "Path to the PEM encoded certificate of the Secure Boot signing key",
+				Destination: &InstallerArgs.SecureBootCert,
+			},
+			&cli.BoolFlag{
+				Name:        "enroll-mok",
+				Usage:       "Request the enrollment of the Secure Boot certificate as MOK on installation",
+				Destination: &InstallerArgs.EnrollMOK,
+			},
 		},
 	}
 }
@@ -0,0 +1,101 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/urfave/cli/v3"
+)
+
+type BundleFlags struct {
+	ConfigDir        string
+	OutputPath       string
+	Platform         string
+	SignaturePolicy  string
+	SignatureKeys    []string
+	DownloadMirrors  []string
+	DownloadCABundle string
+	RegistryConfig   string
+}
+
+var BundleArgs BundleFlags
+
+func NewBundleCommand(appName string, createAction func(context.Context, *cli.Command) error) *cli.Command {
+	return &cli.Command{
+		Name:      "bundle",
+		Usage:     "Manage bundles of images for disconnected environments",
+		UsageText: fmt.Sprintf("%s bundle COMMAND", appName),
+		Commands: []*cli.Command{
+			{
+				Name:      "create",
+				Usage:     "Create a bundle with all the images and files required by a configuration",
+				UsageText: fmt.Sprintf("%s bundle create [OPTIONS]", appName),
+				Action:    createAction,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "config-dir",
+						Usage:       "Full path to the image configuration directory",
+						Destination: &BundleArgs.ConfigDir,
+						Value:       "/config",
+					},
+					&cli.StringFlag{
+						Name:        "output",
+						Aliases:     []string{"o"},
+						Usage:       "Path of the bundle, an OCI layout directory or a .tar or .tar.gz tarball",
+						Destination: &BundleArgs.OutputPath,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "platform",
+						Usage:       "Target platform of the bundled images",
+						Destination: &BundleArgs.Platform,
+						Value:       fmt.Sprintf("linux/%s", runtime.GOARCH),
+					},
+					&cli.StringFlag{
+						Name:        "signature-policy",
+						Usage:       "Path to a signature policy file the release manifest images are verified against",
+						Destination: &BundleArgs.SignaturePolicy,
+					},
+					&cli.StringSliceFlag{
+						Name:        "signature-key",
+						Usage:       "Path to a public key the release manifest images must be signed with, can be repeated",
+						Destination: &BundleArgs.SignatureKeys,
+					},
+					&cli.StringSliceFlag{
+						Name:        "download-mirror",
+						Usage:       "Base URL files are downloaded from if their own URL fails, can be repeated",
+						Destination: &BundleArgs.DownloadMirrors,
+					},
+					&cli.StringFlag{
+						Name:        "download-ca-bundle",
+						Usage:       "Path to a PEM file of CA certificates trusted for HTTPS downloads in addition to the system ones",
+						Destination: &BundleArgs.DownloadCABundle,
+					},
+					&cli.StringFlag{
+						Name:        "registry-config",
+						Usage:       "Path to a registries configuration file with the credentials, mirrors and TLS settings used to pull OCI images",
+						Destination: &BundleArgs.RegistryConfig,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/suse/elemental/v3/pkg/cache"
+)
+
+type CacheFlags struct {
+	CacheDir string
+	MaxSize  string
+}
+
+var CacheArgs CacheFlags
+
+func NewCacheCommand(appName string, listAction, pruneAction func(context.Context, *cli.Command) error) *cli.Command {
+	return &cli.Command{
+		Name:      "cache",
+		Usage:     "Manage the cache of images and downloaded files",
+		UsageText: fmt.Sprintf("%s cache COMMAND", appName),
+		Commands: []*cli.Command{
+			{
+				Name:      "list",
+				Usage:     "List the cached blobs, least recently used first",
+				UsageText: fmt.Sprintf("%s cache list [OPTIONS]", appName),
+				Action:    listAction,
+				Flags:     []cli.Flag{cacheDirFlag()},
+			},
+			{
+				Name:      "prune",
+				Usage:     "Remove the least recently used blobs from the cache",
+				UsageText: fmt.Sprintf("%s cache prune [OPTIONS]", appName),
+				Action:    pruneAction,
+				Flags: []cli.Flag{
+					cacheDirFlag(),
+					&cli.StringFlag{
+						Name:        "max-size",
+						Usage:       "Size the cache is pruned to, e.g. 10G, by default the whole cache is removed",
+						Destination: &CacheArgs.MaxSize,
+						Value:       "0",
+					},
+				},
+			},
+		},
+	}
+}
+
+func cacheDirFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:        "cache-dir",
+		Usage:       "Directory of the cache of images and downloaded files",
+		Destination: &CacheArgs.CacheDir,
+		Value:       cache.DefaultDir(),
+	}
+}
@@ -23,17 +23,29 @@ import (
 	"runtime"
 	"slices"
 
+	"github.com/suse/elemental/v3/pkg/cache"
 	"github.com/suse/elemental/v3/pkg/installer"
 	"github.com/urfave/cli/v3"
 )
 
 type CustomizeFlags struct {
-	ConfigDir  string
-	OutputPath string
-	Mode       string
-	Platform   string
-	MediaType  string
-	Local      bool
+	ConfigDir        string
+	OutputPath       string
+	Mode             string
+	Platform         string
+	MediaType        string
+	Compress         bool
+	Local            bool
+	LocalSource      string
+	Bundle           string
+	SignaturePolicy  string
+	SignatureKeys    []string
+	DownloadMirrors  []string
+	DownloadCABundle string
+	RegistryConfig   string
+	CacheDir         string
+	CacheMaxSize     string
+	NoCache          bool
 }
 
 var CustomizeArgs CustomizeFlags
@@ -55,10 +67,15 @@ func NewCustomizeCommand(appName string, action func(context.Context, *cli.Comma
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:        "type",
-				Usage:       "Type of the installer media, 'iso' or 'raw'",
+				Usage:       "Type of the installer media, 'iso', 'raw', 'qcow2', 'vhdx', 'vmdk' or 'vhd'",
 				Destination: &CustomizeArgs.MediaType,
 				Value:       installer.ISO.String(),
 			},
+			&cli.BoolFlag{
+				Name:        "compress",
+				Usage:       "Compress the disk image, only supported for 'qcow2' and 'vmdk' types",
+				Destination: &CustomizeArgs.Compress,
+			},
 			&cli.StringFlag{
 				Name:        "config-dir",
 				Usage:       "Full path to the image configuration directory",
@@ -90,6 +107,54 @@ func NewCustomizeCommand(appName string, action func(context.Context, *cli.Comma
 				Usage:       "Load OCI images from the local container storage instead of a remote registry",
 				Destination: &CustomizeArgs.Local,
 			},
+			localSourceFlag(&CustomizeArgs.LocalSource),
+			&cli.StringFlag{
+				Name:        "bundle",
+				Usage:       "Path to a bundle OCI images are read from instead of a remote registry",
+				Destination: &CustomizeArgs.Bundle,
+			},
+			&cli.StringFlag{
+				Name:        "signature-policy",
+				Usage:       "Path to a signature policy file OCI images are verified against",
+				Destination: &CustomizeArgs.SignaturePolicy,
+			},
+			&cli.StringSliceFlag{
+				Name:        "signature-key",
+				Usage:       "Path to a public key OCI images must be signed with, can be repeated",
+				Destination: &CustomizeArgs.SignatureKeys,
+			},
+			&cli.StringSliceFlag{
+				Name:        "download-mirror",
+				Usage:       "Base URL files are downloaded from if their own URL fails, can be repeated",
+				Destination: &CustomizeArgs.DownloadMirrors,
+			},
+			&cli.StringFlag{
+				Name:        "download-ca-bundle",
+				Usage:       "Path to a PEM file of CA certificates trusted for HTTPS downloads in addition to the system ones",
+				Destination: &CustomizeArgs.DownloadCABundle,
+			},
+			&cli.StringFlag{
+				Name:        "registry-config",
+				Usage:       "Path to a registries configuration file with the credentials, mirrors and TLS settings used to pull OCI images",
+				Destination: &CustomizeArgs.RegistryConfig,
+			},
+			&cli.StringFlag{
+				Name:        "cache-dir",
+				Usage:       "Directory of the cache of images and downloaded files reused across runs",
+				Destination: &CustomizeArgs.CacheDir,
+				Value:       cache.DefaultDir(),
+			},
+			&cli.StringFlag{
+				Name:        "cache-max-size",
+				Usage:       "Size the cache is pruned to after the run, e.g. 20G, 0 disables pruning",
+				Destination: &CustomizeArgs.CacheMaxSize,
+				Value:       "20G",
+			},
+			&cli.BoolFlag{
+				Name:        "no-cache",
+				Usage:       "Do not read or write the cache of images and downloaded files",
+				Destination: &CustomizeArgs.NoCache,
+			},
 		},
 	}
 }
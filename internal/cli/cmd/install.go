@@ -32,9 +32,14 @@ type InstallFlags struct {
 	Overlay              string
 	CreateBootEntry      bool
 	Bootloader           string
+	UKI                  bool
 	KernelCmdline        string
 	Verify               bool
 	Local                bool
+	LocalSource          string
+	SignaturePolicy      string
+	SignatureKeys        []string
+	RegistryConfig       string
 	CryptoPolicy         string
 	Snapshotter          string
 }
@@ -84,9 +89,14 @@ func NewInstallCommand(appName string, action func(context.Context, *cli.Command
 				Name:        "bootloader",
 				Aliases:     []string{"b"},
 				Value:       "grub",
-				Usage:       "Bundled bootloader to install to ESP",
+				Usage:       "Bundled bootloader to install to ESP (grub, systemd-boot or none)",
 				Destination: &InstallArgs.Bootloader,
 			},
+			&cli.BoolFlag{
+				Name:        "uki",
+				Usage:       "Boot a Unified Kernel Image built per snapshot, requires systemd-boot",
+				Destination: &InstallArgs.UKI,
+			},
 			&cli.StringFlag{
 				Name:        "cmdline",
 				Value:       "",
@@ -104,6 +114,22 @@ func NewInstallCommand(appName string, action func(context.Context, *cli.Command
 				Usage:       "Load OCI images from the local container storage instead of a remote registry",
 				Destination: &InstallArgs.Local,
 			},
+			localSourceFlag(&InstallArgs.LocalSource),
+			&cli.StringFlag{
+				Name:        "signature-policy",
+				Usage:       "Path to a signature policy file OCI images are verified against",
+				Destination: &InstallArgs.SignaturePolicy,
+			},
+			&cli.StringSliceFlag{
+				Name:        "signature-key",
+				Usage:       "Path to a public key OCI images must be signed with, can be repeated",
+				Destination: &InstallArgs.SignatureKeys,
+			},
+			&cli.StringFlag{
+				Name:        "registry-config",
+				Usage:       "Path to a registries configuration file with the credentials, mirrors and TLS settings used to pull OCI images",
+				Destination: &InstallArgs.RegistryConfig,
+			},
 			&cli.StringFlag{
 				Name:        "crypto-policy",
 				Usage:       "Set the crypto policy of the installed system [default, fips]",
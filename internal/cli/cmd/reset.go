@@ -62,9 +62,14 @@ func NewResetCommand(appName string, action func(context.Context, *cli.Command)
 				Name:        "bootloader",
 				Aliases:     []string{"b"},
 				Value:       "grub",
-				Usage:       "Bundled bootloader to install to ESP",
+				Usage:       "Bundled bootloader to install to ESP (grub, systemd-boot or none)",
 				Destination: &InstallArgs.Bootloader,
 			},
+			&cli.BoolFlag{
+				Name:        "uki",
+				Usage:       "Boot a Unified Kernel Image built per snapshot, requires systemd-boot",
+				Destination: &InstallArgs.UKI,
+			},
 			&cli.StringFlag{
 				Name:        "cmdline",
 				Value:       "",
@@ -93,6 +98,7 @@ func NewResetCommand(appName string, action func(context.Context, *cli.Command)
 				Usage:       "Load OCI images from the local container storage instead of a remote registry",
 				Destination: &InstallArgs.Local,
 			},
+			localSourceFlag(&InstallArgs.LocalSource),
 		},
 	}
 }
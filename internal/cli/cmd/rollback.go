@@ -0,0 +1,47 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+)
+
+type RollbackFlags struct {
+	To int
+}
+
+var RollbackArgs RollbackFlags
+
+func NewRollbackCommand(appName string, action func(context.Context, *cli.Command) error) *cli.Command {
+	return &cli.Command{
+		Name:      "rollback",
+		Usage:     "Rollback the system to a previous snapshot",
+		UsageText: fmt.Sprintf("%s rollback [OPTIONS]", appName),
+		Action:    action,
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:        "to",
+				Usage:       "ID of the snapshot to rollback to, defaults to the snapshot preceding the current default one",
+				Destination: &RollbackArgs.To,
+			},
+		},
+	}
+}
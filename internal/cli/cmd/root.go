@@ -24,6 +24,7 @@ import (
 
 	"github.com/urfave/cli/v3"
 
+	"github.com/suse/elemental/v3/pkg/events"
 	"github.com/suse/elemental/v3/pkg/log"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
@@ -32,7 +33,8 @@ import (
 const Usage = "Install and upgrade immutable operating systems"
 
 var (
-	logFile *os.File
+	logFile    *os.File
+	eventsFile *os.File
 )
 
 func GlobalFlags() []cli.Flag {
@@ -45,11 +47,35 @@ func GlobalFlags() []cli.Flag {
 			Name:  "log-file",
 			Usage: "Save logs to file, accepts path to file or stdout/stderr",
 		},
+		&cli.StringFlag{
+			Name:  "output-format",
+			Usage: "Output format of the progress of the command, 'text' logs or 'json' events written to stdout",
+			Value: "text",
+		},
+		&cli.IntFlag{
+			Name:  "events-fd",
+			Usage: "Write the JSON events to the given file descriptor instead of stdout, implies '--output-format json'",
+		},
+	}
+}
+
+// localSourceFlag returns the flag selecting the store local images are read from
+func localSourceFlag(destination *string) cli.Flag {
+	return &cli.StringFlag{
+		Name:        "local-source",
+		Usage:       "Store local OCI images are read from with --local, 'docker', 'podman' or 'containers-storage'",
+		Destination: destination,
+		Value:       "docker",
 	}
 }
 
 func Setup(ctx context.Context, cmd *cli.Command) (context.Context, error) {
-	s, err := sys.NewSystem()
+	emitter, err := newEmitter(cmd)
+	if err != nil {
+		return ctx, err
+	}
+
+	s, err := sys.NewSystem(sys.WithEvents(emitter), sys.WithLogger(events.WrapLogger(log.New(), emitter)))
 	if err != nil {
 		return ctx, err
 	}
@@ -70,6 +96,10 @@ func Setup(ctx context.Context, cmd *cli.Command) (context.Context, error) {
 }
 
 func Teardown(_ context.Context, _ *cli.Command) error {
+	if eventsFile != nil {
+		_ = eventsFile.Close()
+	}
+
 	if logFile != nil {
 		return logFile.Close()
 	}
@@ -77,6 +107,28 @@ func Teardown(_ context.Context, _ *cli.Command) error {
 	return nil
 }
 
+// newEmitter returns the emitter of the JSON events requested by the output flags, nil
+// if events are not requested
+func newEmitter(cmd *cli.Command) (*events.Emitter, error) {
+	fd := cmd.Int("events-fd")
+	switch format := cmd.String("output-format"); {
+	case fd < 0:
+		return nil, fmt.Errorf("invalid events file descriptor %d", fd)
+	case fd > 0:
+		eventsFile = os.NewFile(uintptr(fd), "events")
+		if eventsFile == nil {
+			return nil, fmt.Errorf("invalid events file descriptor %d", fd)
+		}
+		return events.New(eventsFile), nil
+	case format == "json":
+		return events.New(os.Stdout), nil
+	case format == "text" || format == "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown output format '%s'", format)
+	}
+}
+
 func SetLoggerTarget(s *sys.System, cmd *cli.Command) error {
 	logPath := cmd.String("log-file")
 	switch logPath {
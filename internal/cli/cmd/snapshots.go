@@ -0,0 +1,68 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+)
+
+type SnapshotsFlags struct {
+	Format string
+}
+
+var SnapshotsArgs SnapshotsFlags
+
+func NewSnapshotsCommand(appName string, listAction, showAction, deleteAction func(context.Context, *cli.Command) error) *cli.Command {
+	return &cli.Command{
+		Name:      "snapshots",
+		Usage:     "Inspect and manage the OS snapshots",
+		UsageText: fmt.Sprintf("%s snapshots COMMAND", appName),
+		Commands: []*cli.Command{
+			{
+				Name:      "list",
+				Usage:     "List the OS snapshots",
+				UsageText: fmt.Sprintf("%s snapshots list [OPTIONS]", appName),
+				Action:    listAction,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "format",
+						Aliases:     []string{"f"},
+						Value:       "table",
+						Usage:       "Output format [table, json]",
+						Destination: &SnapshotsArgs.Format,
+					},
+				},
+			},
+			{
+				Name:      "show",
+				Usage:     "Show the deployment details stored in a snapshot",
+				UsageText: fmt.Sprintf("%s snapshots show ID", appName),
+				Action:    showAction,
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete a snapshot and its boot entries",
+				UsageText: fmt.Sprintf("%s snapshots delete ID", appName),
+				Action:    deleteAction,
+			},
+		},
+	}
+}
@@ -26,11 +26,12 @@ import (
 )
 
 type UnpackFlags struct {
-	Image     string
-	TargetDir string
-	Platform  string
-	Local     bool
-	Verify    bool
+	Image       string
+	TargetDir   string
+	Platform    string
+	Local       bool
+	LocalSource string
+	Verify      bool
 }
 
 var UnpackArgs UnpackFlags
@@ -72,6 +73,7 @@ func NewUnpackImageCommand(appName string, action func(context.Context, *cli.Com
 				Usage:       "Use local image",
 				Destination: &UnpackArgs.Local,
 			},
+			localSourceFlag(&UnpackArgs.LocalSource),
 		},
 	}
 }
@@ -31,6 +31,16 @@ type UpgradeFlags struct {
 	Verify               bool
 	CreateBootEntry      bool
 	Local                bool
+	LocalSource          string
+	Bundle               string
+	SignaturePolicy      string
+	SignatureKeys        []string
+	RegistryConfig       string
+	Stage                bool
+	ApplyStaged          bool
+	DiscardStaged        bool
+	DryRun               bool
+	Format               string
 }
 
 var UpgradeArgs UpgradeFlags
@@ -44,9 +54,8 @@ func NewUpgradeCommand(appName string, action func(context.Context, *cli.Command
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:        "os-image",
-				Usage:       "URI to the image containing the operating system",
+				Usage:       "URI to the image containing the operating system, required unless applying or discarding a staged upgrade",
 				Destination: &UpgradeArgs.OperatingSystemImage,
-				Required:    true,
 			},
 			&cli.StringFlag{
 				Name:        "config",
@@ -74,6 +83,53 @@ func NewUpgradeCommand(appName string, action func(context.Context, *cli.Command
 				Usage:       "Load OCI images from the local container storage instead of a remote registry",
 				Destination: &UpgradeArgs.Local,
 			},
+			localSourceFlag(&UpgradeArgs.LocalSource),
+			&cli.StringFlag{
+				Name:        "bundle",
+				Usage:       "Path to a bundle OCI images are read from instead of a remote registry",
+				Destination: &UpgradeArgs.Bundle,
+			},
+			&cli.StringFlag{
+				Name:        "signature-policy",
+				Usage:       "Path to a signature policy file OCI images are verified against",
+				Destination: &UpgradeArgs.SignaturePolicy,
+			},
+			&cli.StringSliceFlag{
+				Name:        "signature-key",
+				Usage:       "Path to a public key OCI images must be signed with, can be repeated",
+				Destination: &UpgradeArgs.SignatureKeys,
+			},
+			&cli.StringFlag{
+				Name:        "registry-config",
+				Usage:       "Path to a registries configuration file with the credentials, mirrors and TLS settings used to pull OCI images",
+				Destination: &UpgradeArgs.RegistryConfig,
+			},
+			&cli.BoolFlag{
+				Name:        "stage",
+				Usage:       "Prepare the upgraded snapshot without activating it",
+				Destination: &UpgradeArgs.Stage,
+			},
+			&cli.BoolFlag{
+				Name:        "apply-staged",
+				Usage:       "Activate the staged snapshot, it is booted on next reboot",
+				Destination: &UpgradeArgs.ApplyStaged,
+			},
+			&cli.BoolFlag{
+				Name:        "discard-staged",
+				Usage:       "Delete the staged snapshot",
+				Destination: &UpgradeArgs.DiscardStaged,
+			},
+			&cli.BoolFlag{
+				Name:        "dry-run",
+				Usage:       "Report the changes the upgrade would apply to the snapshotted RW volumes without committing a snapshot",
+				Destination: &UpgradeArgs.DryRun,
+			},
+			&cli.StringFlag{
+				Name:        "format",
+				Value:       "text",
+				Usage:       "Output format of the dry-run report [text, json]",
+				Destination: &UpgradeArgs.Format,
+			},
 		},
 	}
 }
@@ -22,12 +22,8 @@ import (
 	"fmt"
 
 	"github.com/urfave/cli/v3"
-)
 
-var (
-	version = "v0.0.1"
-	// gitCommit is the git sha1
-	gitCommit = ""
+	"github.com/suse/elemental/v3/pkg/version"
 )
 
 func NewVersionCommand(appName string) *cli.Command {
@@ -37,12 +33,7 @@ func NewVersionCommand(appName string) *cli.Command {
 		Usage:     "Inspect program version",
 		UsageText: fmt.Sprintf("%s version", appName),
 		Action: func(_ context.Context, _ *cli.Command) error {
-			commit := gitCommit
-			if len(commit) > 7 {
-				commit = gitCommit[:7]
-			}
-
-			fmt.Printf("%s+g%s\n", version, commit)
+			fmt.Println(version.String())
 
 			return nil
 		},
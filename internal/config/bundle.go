@@ -0,0 +1,217 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/suse/elemental/v3/internal/image"
+	"github.com/suse/elemental/v3/pkg/bundle"
+	"github.com/suse/elemental/v3/pkg/helm"
+	"github.com/suse/elemental/v3/pkg/manifest/resolver"
+	"github.com/suse/elemental/v3/pkg/manifest/source"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+type bundler interface {
+	AddImage(ctx context.Context, ref string, kind bundle.Kind) error
+	AddFile(url, file string, kind bundle.Kind) error
+}
+
+type bundleChart struct {
+	name       string
+	version    string
+	repository string
+	images     []string
+}
+
+// BundleComponents adds all the images and files required to configure the components of the given
+// configuration to the bundle: release manifests, OS and ISO images, systemd extensions, Helm charts
+// including their container images and remote Kubernetes manifests.
+func (m *Manager) BundleComponents(ctx context.Context, conf *image.Configuration, output Output, b bundler) error {
+	fs := m.system.FS()
+
+	rm, err := m.resolveManifest(conf, output)
+	if err != nil {
+		return err
+	}
+
+	extensions, err := enabledExtensions(rm, conf, m.system.Logger())
+	if err != nil {
+		return fmt.Errorf("filtering enabled systemd extensions: %w", err)
+	}
+
+	charts, err := bundleCharts(rm, conf)
+	if err != nil {
+		return err
+	}
+
+	downloadDir, err := vfs.TempDir(fs, "", "bundle-downloads-")
+	if err != nil {
+		return fmt.Errorf("creating downloads directory: %w", err)
+	}
+	defer func() {
+		_ = fs.RemoveAll(downloadDir)
+	}()
+
+	images := map[string]bool{}
+	addImage := func(ref string, kind bundle.Kind) error {
+		if images[ref] {
+			return nil
+		}
+		images[ref] = true
+		return b.AddImage(ctx, ref, kind)
+	}
+	files := map[string]string{}
+	addFile := func(url, digest string, kind bundle.Kind) (string, error) {
+		if path, ok := files[url]; ok {
+			return path, nil
+		}
+		path := filepath.Join(downloadDir, fmt.Sprintf("%d-%s", len(files), filepath.Base(url)))
+		files[url] = path
+		if err := m.downloadFile(ctx, fs, url, path, digest); err != nil {
+			return "", fmt.Errorf("downloading '%s': %w", url, err)
+		}
+		return path, b.AddFile(url, path, kind)
+	}
+
+	rmSrc, err := source.ParseFromURI(conf.Release.ManifestURI)
+	if err != nil {
+		return fmt.Errorf("parsing release manifest uri: %w", err)
+	}
+	if rmSrc.Type() == source.OCI {
+		if err = addImage(rmSrc.URI(), bundle.ReleaseManifest); err != nil {
+			return fmt.Errorf("bundling release manifest: %w", err)
+		}
+	}
+	if rm.ProductExtension != nil {
+		if err = addImage(rm.ProductExtension.CorePlatform.PinnedImage(), bundle.ReleaseManifest); err != nil {
+			return fmt.Errorf("bundling core platform release manifest: %w", err)
+		}
+	}
+
+	osImage := rm.CorePlatform.Components.OperatingSystem.Image
+	if err = addImage(osImage.PinnedBase(), bundle.OperatingSystem); err != nil {
+		return fmt.Errorf("bundling OS image: %w", err)
+	}
+	if err = addImage(osImage.PinnedISO(), bundle.ISO); err != nil {
+		return fmt.Errorf("bundling ISO image: %w", err)
+	}
+
+	for _, extension := range extensions {
+		if isRemoteURL(extension.Image) {
+			_, err = addFile(extension.Image, extension.Digest, bundle.Extension)
+		} else {
+			err = addImage(extension.PinnedImage(), bundle.Extension)
+		}
+		if err != nil {
+			return fmt.Errorf("bundling systemd extension %s: %w", extension.Name, err)
+		}
+	}
+
+	for _, chart := range charts {
+		if err = m.bundleChart(chart, addImage, addFile); err != nil {
+			return fmt.Errorf("bundling helm chart %s: %w", chart.name, err)
+		}
+	}
+
+	for _, manifest := range conf.Kubernetes.RemoteManifests {
+		if _, err = addFile(manifest, "", bundle.K8sManifest); err != nil {
+			return fmt.Errorf("bundling kubernetes manifest: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// bundleChart adds the chart archive, or image for charts in OCI registries, and the container
+// images of the given chart
+func (m *Manager) bundleChart(
+	chart bundleChart,
+	addImage func(string, bundle.Kind) error,
+	addFile func(string, string, bundle.Kind) (string, error),
+) error {
+	if repository, ok := strings.CutPrefix(chart.repository, "oci://"); ok {
+		ref := fmt.Sprintf("%s/%s:%s", strings.TrimSuffix(repository, "/"), chart.name, chart.version)
+		if err := addImage(ref, bundle.HelmChart); err != nil {
+			return err
+		}
+	} else {
+		indexURL := strings.TrimSuffix(chart.repository, "/") + "/index.yaml"
+		index, err := addFile(indexURL, "", bundle.HelmChart)
+		if err != nil {
+			return fmt.Errorf("fetching repository index: %w", err)
+		}
+		data, err := m.system.FS().ReadFile(index)
+		if err != nil {
+			return fmt.Errorf("reading repository index: %w", err)
+		}
+		chartURL, err := helm.ChartURL(data, chart.repository, chart.name, chart.version)
+		if err != nil {
+			return err
+		}
+		if _, err = addFile(chartURL, "", bundle.HelmChart); err != nil {
+			return err
+		}
+	}
+
+	for _, img := range chart.images {
+		if err := addImage(img, bundle.ContainerImage); err != nil {
+			return fmt.Errorf("bundling container image: %w", err)
+		}
+	}
+	return nil
+}
+
+// bundleCharts lists the enabled Helm charts from the release manifest and the user defined ones
+func bundleCharts(rm *resolver.ResolvedManifest, conf *image.Configuration) ([]bundleChart, error) {
+	var charts []bundleChart
+
+	enabled, repositories, err := enabledHelmCharts(rm, conf.Release.Components.HelmCharts, nil)
+	if err != nil {
+		return nil, fmt.Errorf("filtering enabled helm charts: %w", err)
+	}
+	for _, c := range enabled {
+		repository, ok := repositories[c.Repository]
+		if !ok {
+			return nil, fmt.Errorf("repository not found for chart: %s", c.Chart)
+		}
+		chart := bundleChart{name: c.Chart, version: c.Version, repository: repository}
+		for _, img := range c.Images {
+			chart.images = append(chart.images, img.Image)
+		}
+		charts = append(charts, chart)
+	}
+
+	if conf.Kubernetes.Helm == nil {
+		return charts, nil
+	}
+
+	repositories = conf.Kubernetes.Helm.ChartRepositories()
+	for _, c := range conf.Kubernetes.Helm.Charts {
+		repository, ok := repositories[c.RepositoryName]
+		if !ok {
+			return nil, fmt.Errorf("repository not found for chart: %s", c.Name)
+		}
+		charts = append(charts, bundleChart{name: c.Name, version: c.Version, repository: repository})
+	}
+	return charts, nil
+}
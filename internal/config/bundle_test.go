@@ -0,0 +1,167 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/internal/image"
+	"github.com/suse/elemental/v3/internal/image/kubernetes"
+	"github.com/suse/elemental/v3/internal/image/release"
+	"github.com/suse/elemental/v3/pkg/bundle"
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/manifest/api"
+	"github.com/suse/elemental/v3/pkg/manifest/api/core"
+	"github.com/suse/elemental/v3/pkg/manifest/api/product"
+	"github.com/suse/elemental/v3/pkg/manifest/resolver"
+	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+type bundlerMock struct {
+	images []string
+	files  []string
+}
+
+func (b *bundlerMock) AddImage(_ context.Context, ref string, kind bundle.Kind) error {
+	b.images = append(b.images, fmt.Sprintf("%s %s", kind, ref))
+	return nil
+}
+
+func (b *bundlerMock) AddFile(url, _ string, kind bundle.Kind) error {
+	b.files = append(b.files, fmt.Sprintf("%s %s", kind, url))
+	return nil
+}
+
+var _ = Describe("Bundle", func() {
+	var fs vfs.FS
+	var cleanup func()
+	var system *sys.System
+	var rm *resolver.ResolvedManifest
+	var conf *image.Configuration
+	var downloaded []string
+
+	BeforeEach(func() {
+		var err error
+		fs, cleanup, err = sysmock.TestFS(nil)
+		Expect(err).NotTo(HaveOccurred())
+		system, err = sys.NewSystem(sys.WithFS(fs), sys.WithLogger(log.New(log.WithDiscardAll())))
+		Expect(err).NotTo(HaveOccurred())
+		downloaded = nil
+
+		rm = &resolver.ResolvedManifest{
+			CorePlatform: &core.ReleaseManifest{
+				Components: core.Components{
+					OperatingSystem: &core.OperatingSystem{Image: core.Image{
+						Base: "registry.example.com/os:6.2", BaseDigest: "sha256:" + strings.Repeat("a", 64),
+						ISO: "registry.example.com/iso:6.2",
+					}},
+					Systemd: api.Systemd{Extensions: []api.SystemdExtension{
+						{Name: "rke2", Image: "registry.example.com/rke2:1.34"},
+						{Name: "tools", Image: "https://example.com/tools.raw", Required: true},
+						{Name: "unused", Image: "registry.example.com/unused:1.0"},
+					}},
+				},
+			},
+			ProductExtension: &product.ReleaseManifest{
+				CorePlatform: &product.CorePlatform{Image: "registry.example.com/core-release:6.2"},
+				Components: product.Components{Helm: &api.Helm{
+					Charts: []*api.HelmChart{
+						{Chart: "metallb", Version: "0.15.2", Repository: "metallb", Images: []api.HelmChartImage{
+							{Name: "controller", Image: "quay.io/metallb/controller:v0.15.2"},
+						}},
+						{Chart: "operator", Version: "1.0.0", Repository: "suse"},
+					},
+					Repositories: []*api.HelmRepository{
+						{Name: "metallb", URL: "https://metallb.github.io/metallb"},
+						{Name: "suse", URL: "oci://registry.example.com/charts"},
+					},
+				}},
+			},
+		}
+		conf = &image.Configuration{
+			Kubernetes: kubernetes.Kubernetes{
+				RemoteManifests: []string{"https://example.com/manifest.yaml"},
+				Helm: &kubernetes.Helm{
+					Charts:       []*kubernetes.HelmChart{{Name: "apache", RepositoryName: "bitnami", Version: "10.0.0"}},
+					Repositories: []*kubernetes.HelmRepository{{Name: "bitnami", URL: "oci://registry-1.docker.io/bitnamicharts"}},
+				},
+			},
+			Release: release.Release{
+				ManifestURI: "oci://registry.example.com/product-release:1.0",
+				Components: release.Components{
+					SystemdExtensions: []release.SystemdExtension{{Name: "rke2"}},
+					HelmCharts:        []release.HelmChart{{Name: "metallb"}, {Name: "operator"}},
+				},
+			},
+		}
+	})
+	AfterEach(func() {
+		cleanup()
+	})
+	It("Bundles all enabled components", func() {
+		download := func(_ context.Context, fs vfs.FS, url, path, _ string) error {
+			downloaded = append(downloaded, url)
+			data := ""
+			if strings.HasSuffix(url, "index.yaml") {
+				data = "entries:\n  metallb:\n  - version: 0.15.2\n    urls:\n    - metallb-0.15.2.tgz\n"
+			}
+			return fs.WriteFile(path, []byte(data), vfs.FilePerm)
+		}
+		m := NewManager(system, nil,
+			WithManifestResolver(&resolverMock{resolveFunc: func(string) (*resolver.ResolvedManifest, error) {
+				return rm, nil
+			}}),
+			WithDownloadFunc(download),
+		)
+
+		b := &bundlerMock{}
+		Expect(m.BundleComponents(context.Background(), conf, Output{RootPath: "/_out"}, b)).To(Succeed())
+		Expect(b.images).To(Equal([]string{
+			"release-manifest registry.example.com/product-release:1.0",
+			"release-manifest registry.example.com/core-release:6.2",
+			"os registry.example.com/os:6.2@sha256:" + strings.Repeat("a", 64),
+			"iso registry.example.com/iso:6.2",
+			"extension registry.example.com/rke2:1.34",
+			"image quay.io/metallb/controller:v0.15.2",
+			"helm-chart registry.example.com/charts/operator:1.0.0",
+			"helm-chart registry-1.docker.io/bitnamicharts/apache:10.0.0",
+		}))
+		Expect(b.files).To(Equal([]string{
+			"extension https://example.com/tools.raw",
+			"helm-chart https://metallb.github.io/metallb/index.yaml",
+			"helm-chart https://metallb.github.io/metallb/metallb-0.15.2.tgz",
+			"kubernetes-manifest https://example.com/manifest.yaml",
+		}))
+		Expect(downloaded).To(HaveLen(4))
+	})
+	It("Fails for charts without repository", func() {
+		conf.Kubernetes.Helm.Repositories = nil
+		m := NewManager(system, nil, WithManifestResolver(&resolverMock{resolveFunc: func(string) (*resolver.ResolvedManifest, error) {
+			return rm, nil
+		}}))
+		err := m.BundleComponents(context.Background(), conf, Output{RootPath: "/_out"}, &bundlerMock{})
+		Expect(err).To(MatchError("repository not found for chart: apache"))
+	})
+})
@@ -41,6 +41,7 @@ const (
 	updateLinkerCacheUnitName   = "update-linker-cache.service"
 	k8sResourcesUnitName        = "k8s-resource-installer.service"
 	k8sConfigUnitName           = "k8s-config-installer.service"
+	bootCheckUnitName           = "elemental-boot-check.service"
 )
 
 var (
@@ -61,17 +62,22 @@ var (
 
 	//go:embed templates/k8s-vip.yaml.tpl
 	k8sVIPManifestTpl string
+
+	//go:embed templates/elemental-boot-check.service
+	bootCheckUnit string
 )
 
 // configureIgnition writes the Ignition configuration file including:
 // * Predefined Butane configuration
 // * Kubernetes configuration and deployment files
 // * Systemd extensions
+// * Boot health check service
 func (m *Manager) configureIgnition(conf *image.Configuration, output Output, k8sScript, k8sConfScript string, ext []api.SystemdExtension) error {
 	if len(conf.ButaneConfig) == 0 &&
 		k8sScript == "" &&
 		k8sConfScript == "" &&
-		len(ext) == 0 {
+		len(ext) == 0 &&
+		conf.Installation.BootTries == 0 {
 		m.system.Logger().Info("No ignition configuration required")
 		return nil
 	}
@@ -141,6 +147,10 @@ func (m *Manager) configureIgnition(conf *image.Configuration, output Output, k8
 		config.AddSystemdUnit(updateLinkerCacheUnitName, updateLinkerCacheUnit, true)
 	}
 
+	if conf.Installation.BootTries > 0 {
+		config.AddSystemdUnit(bootCheckUnitName, bootCheckUnit, true)
+	}
+
 	ignitionFile := filepath.Join(output.FirstbootConfigDir(), image.IgnitionFilePath())
 	return butane.WriteIgnitionFile(m.system, config, ignitionFile)
 }
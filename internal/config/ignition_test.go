@@ -151,6 +151,20 @@ passwd:
 		Expect(ignition).NotTo(ContainSubstring("Kubernetes Config Installer"))
 	})
 
+	It("Writes the boot health check service via Ignition if boot counting is enabled", func() {
+		conf := &image.Configuration{}
+		conf.Installation.BootTries = 3
+		ignitionFile := filepath.Join(output.FirstbootConfigDir(), image.IgnitionFilePath())
+
+		Expect(m.configureIgnition(conf, output, "", "", nil)).To(Succeed())
+
+		ignition, err := system.FS().ReadFile(ignitionFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ignition).To(ContainSubstring("elemental-boot-check.service"))
+		Expect(ignition).To(ContainSubstring("boot-check mark-good"))
+		Expect(ignition).NotTo(ContainSubstring("/etc/elemental/extensions.yaml"))
+	})
+
 	It("Fails to translate a butaneConfig with a wrong version or variant", func() {
 		var butane map[string]any
 
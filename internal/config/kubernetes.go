@@ -114,7 +114,7 @@ func (m *Manager) setupManifests(ctx context.Context, k *kubernetes.Kubernetes,
 	for _, manifest := range k.RemoteManifests {
 		path := filepath.Join(manifestsDir, filepath.Base(manifest))
 
-		if err := m.downloadFile(ctx, fs, manifest, path); err != nil {
+		if err := m.fetchFile(ctx, manifest, path, ""); err != nil {
 			return "", fmt.Errorf("downloading remote Kubernetes manifest '%s': %w", manifest, err)
 		}
 	}
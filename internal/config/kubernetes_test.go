@@ -145,7 +145,7 @@ var _ = Describe("Kubernetes", func() {
 				},
 			}
 
-			dlFunc := func(ctx context.Context, fs vfs.FS, url, path string) error {
+			dlFunc := func(ctx context.Context, fs vfs.FS, url, path, _ string) error {
 				return nil
 			}
 
@@ -182,7 +182,7 @@ var _ = Describe("Kubernetes", func() {
 				},
 			}
 
-			dlFunc := func(ctx context.Context, fs vfs.FS, url, path string) error {
+			dlFunc := func(ctx context.Context, fs vfs.FS, url, path, _ string) error {
 				return nil
 			}
 
@@ -227,7 +227,7 @@ var _ = Describe("Kubernetes", func() {
 		})
 
 		It("Succeeds to configure RKE2 without additional resources", func() {
-			dlFunc := func(ctx context.Context, fs vfs.FS, url, path string) error {
+			dlFunc := func(ctx context.Context, fs vfs.FS, url, path, _ string) error {
 				return nil
 			}
 
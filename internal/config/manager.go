@@ -24,15 +24,20 @@ import (
 
 	"github.com/suse/elemental/v3/internal/image"
 
+	"github.com/suse/elemental/v3/pkg/bundle"
+	"github.com/suse/elemental/v3/pkg/cache"
 	"github.com/suse/elemental/v3/pkg/extractor"
 	"github.com/suse/elemental/v3/pkg/http"
 	"github.com/suse/elemental/v3/pkg/manifest/resolver"
 	"github.com/suse/elemental/v3/pkg/manifest/source"
+	"github.com/suse/elemental/v3/pkg/registry"
+	"github.com/suse/elemental/v3/pkg/signature"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
+	"github.com/suse/elemental/v3/pkg/unpack"
 )
 
-type downloadFunc func(ctx context.Context, fs vfs.FS, url, path string) error
+type downloadFunc func(ctx context.Context, fs vfs.FS, url, path, digest string) error
 
 type helmConfigurator interface {
 	Configure(conf *image.Configuration, manifest *resolver.ResolvedManifest) ([]string, error)
@@ -45,6 +50,14 @@ type releaseManifestResolver interface {
 type Manager struct {
 	system *sys.System
 	local  bool
+	// localSource is the store local release manifest and systemd extension images are read from
+	localSource unpack.LocalSource
+	policy      *signature.Policy
+	bundle      *bundle.Bundle
+	cache       *cache.Cache
+	// registries configures the access to the registries of the release manifest and
+	// systemd extension images
+	registries *registry.Config
 
 	rmResolver   releaseManifestResolver
 	downloadFile downloadFunc
@@ -71,6 +84,46 @@ func WithLocal(local bool) Opts {
 	}
 }
 
+// WithLocalSource sets the store local release manifest and systemd extension
+// images are read from
+func WithLocalSource(source unpack.LocalSource) Opts {
+	return func(m *Manager) {
+		m.localSource = source
+	}
+}
+
+// WithSignaturePolicy sets the signature policy the release manifest and
+// systemd extension images are verified against
+func WithSignaturePolicy(policy *signature.Policy) Opts {
+	return func(m *Manager) {
+		m.policy = policy
+	}
+}
+
+// WithBundle sets the bundle the release manifest and systemd extensions are
+// read from instead of pulling or downloading them
+func WithBundle(b *bundle.Bundle) Opts {
+	return func(m *Manager) {
+		m.bundle = b
+	}
+}
+
+// WithCache sets the cache the release manifest and systemd extension images and the
+// downloaded files pinned by digest are read from and stored to
+func WithCache(c *cache.Cache) Opts {
+	return func(m *Manager) {
+		m.cache = c
+	}
+}
+
+// WithRegistries sets the credentials, mirrors and TLS settings used to pull the release
+// manifest and systemd extension images
+func WithRegistries(c *registry.Config) Opts {
+	return func(m *Manager) {
+		m.registries = c
+	}
+}
+
 func NewManager(sys *sys.System, helm helmConfigurator, opts ...Opts) *Manager {
 	m := &Manager{
 		system: sys,
@@ -91,17 +144,9 @@ func NewManager(sys *sys.System, helm helmConfigurator, opts ...Opts) *Manager {
 // ConfigureComponents configures the components defined in the provided configuration
 // and returns the resolved release manifest from said configuration.
 func (m *Manager) ConfigureComponents(ctx context.Context, conf *image.Configuration, output Output) (rm *resolver.ResolvedManifest, err error) {
-	if m.rmResolver == nil {
-		defaultResolver, err := defaultManifestResolver(m.system.FS(), output, m.local)
-		if err != nil {
-			return nil, fmt.Errorf("using default release manifest resolver: %w", err)
-		}
-		m.rmResolver = defaultResolver
-	}
-
-	rm, err = m.rmResolver.Resolve(conf.Release.ManifestURI)
+	rm, err = m.resolveManifest(conf, output)
 	if err != nil {
-		return nil, fmt.Errorf("resolving release manifest at uri '%s': %w", conf.Release.ManifestURI, err)
+		return nil, err
 	}
 
 	if err = m.configureNetworkOnFirstboot(conf, output); err != nil {
@@ -135,7 +180,23 @@ func (m *Manager) ConfigureComponents(ctx context.Context, conf *image.Configura
 	return rm, nil
 }
 
-func defaultManifestResolver(fs vfs.FS, out Output, local bool) (res *resolver.Resolver, err error) {
+func (m *Manager) resolveManifest(conf *image.Configuration, output Output) (*resolver.ResolvedManifest, error) {
+	if m.rmResolver == nil {
+		defaultResolver, err := m.defaultManifestResolver(output)
+		if err != nil {
+			return nil, fmt.Errorf("using default release manifest resolver: %w", err)
+		}
+		m.rmResolver = defaultResolver
+	}
+
+	rm, err := m.rmResolver.Resolve(conf.Release.ManifestURI)
+	if err != nil {
+		return nil, fmt.Errorf("resolving release manifest at uri '%s': %w", conf.Release.ManifestURI, err)
+	}
+	return rm, nil
+}
+
+func (m *Manager) defaultManifestResolver(out Output) (res *resolver.Resolver, err error) {
 	const (
 		globPattern = "release_manifest*.yaml"
 	)
@@ -146,14 +207,50 @@ func defaultManifestResolver(fs vfs.FS, out Output, local bool) (res *resolver.R
 	}
 
 	manifestsDir := out.ReleaseManifestsStoreDir()
-	if err := vfs.MkdirAll(fs, manifestsDir, 0700); err != nil {
+	if err := vfs.MkdirAll(m.system.FS(), manifestsDir, 0700); err != nil {
 		return nil, fmt.Errorf("creating release manifest store '%s': %w", manifestsDir, err)
 	}
 
-	extr, err := extractor.New(searchPaths, extractor.WithStore(manifestsDir), extractor.WithLocal(local))
+	extr, err := extractor.New(
+		searchPaths, extractor.WithStore(manifestsDir), extractor.WithLocal(m.local),
+		extractor.WithLocalSource(m.localSource), extractor.WithSignaturePolicy(m.policy), extractor.WithBundle(m.bundle),
+		extractor.WithCache(m.cache), extractor.WithRegistries(m.registries),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("initializing OCI release manifest extractor: %w", err)
 	}
 
 	return resolver.New(source.NewReader(extr)), nil
 }
+
+// fetchFile downloads the file at the given URL to the given path, the file is read
+// from the bundle instead if any is set. The file is verified against the given digest, if any,
+// and files pinned by digest are read from and stored to the cache, if any is set.
+func (m *Manager) fetchFile(ctx context.Context, url, path, digest string) error {
+	if m.bundle != nil {
+		if err := m.bundle.ExtractFile(url, path); err != nil {
+			return err
+		}
+		return checkFileDigest(m.system.FS(), path, digest)
+	}
+
+	if m.cache != nil && digest != "" {
+		if ok, err := m.cache.Get(digest, path); err != nil {
+			return err
+		} else if ok {
+			m.system.Logger().Info("Using cached %s", url)
+			return nil
+		}
+	}
+
+	if err := m.downloadFile(ctx, m.system.FS(), url, path, digest); err != nil {
+		return err
+	}
+
+	if m.cache != nil && digest != "" {
+		if err := m.cache.Put(digest, path); err != nil {
+			m.system.Logger().Warn("Failed caching %s: %v", url, err)
+		}
+	}
+	return nil
+}
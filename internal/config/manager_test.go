@@ -195,7 +195,7 @@ passwd:
 
 				panic("missing release manifest")
 			}}),
-			WithDownloadFunc(func(ctx context.Context, fs vfs.FS, url, path string) error {
+			WithDownloadFunc(func(ctx context.Context, fs vfs.FS, url, path, _ string) error {
 				_, err := fs.Create(filepath.Join(path))
 				return err
 			}),
@@ -319,7 +319,7 @@ passwd:
 			system,
 			&helmConfiguratorMock{configureFunc: defaultHelmFunc},
 			WithManifestResolver(&resolverMock{resolveFunc: defaultResolveFunc}),
-			WithDownloadFunc(func(ctx context.Context, fs vfs.FS, url, path string) error {
+			WithDownloadFunc(func(ctx context.Context, fs vfs.FS, url, path, _ string) error {
 				return fmt.Errorf("download unavailable")
 			}),
 		)
@@ -0,0 +1,48 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/suse/elemental/v3/internal/image"
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/manifest/resolver"
+	"github.com/suse/elemental/v3/pkg/sbom"
+)
+
+// NewSBOM returns an SBOM listing the resolved release manifests and the systemd extensions and
+// helm charts enabled by the given configuration
+func NewSBOM(rm *resolver.ResolvedManifest, conf *image.Configuration, logger log.Logger) (*sbom.SBOM, error) {
+	sb := sbom.New()
+	sb.AddReleaseManifest(conf.Release.ManifestURI, rm)
+
+	extensions, err := enabledExtensions(rm, conf, logger)
+	if err != nil {
+		return nil, fmt.Errorf("filtering enabled systemd extensions: %w", err)
+	}
+	sb.AddExtensions(extensions...)
+
+	charts, repositories, err := enabledHelmCharts(rm, conf.Release.Components.HelmCharts, nil)
+	if err != nil {
+		return nil, fmt.Errorf("filtering enabled helm charts: %w", err)
+	}
+	sb.AddHelmCharts(charts, repositories)
+
+	return sb, nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/internal/image"
+	"github.com/suse/elemental/v3/internal/image/release"
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/manifest/api"
+	"github.com/suse/elemental/v3/pkg/manifest/api/core"
+	"github.com/suse/elemental/v3/pkg/manifest/api/product"
+	"github.com/suse/elemental/v3/pkg/manifest/resolver"
+	"github.com/suse/elemental/v3/pkg/sbom"
+)
+
+var _ = Describe("SBOM", func() {
+	It("Lists release manifests and enabled components", func() {
+		rm := &resolver.ResolvedManifest{
+			CorePlatform: &core.ReleaseManifest{
+				Metadata: &api.Metadata{Name: "core", Version: "1.0"},
+				Components: core.Components{
+					OperatingSystem: &core.OperatingSystem{
+						Image: core.Image{
+							Base:       "registry.example.com/os:6.2",
+							BaseDigest: "sha256:4c1b5d3e2a6f7089b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5d6e",
+							ISO:        "registry.example.com/iso:6.2",
+						},
+					},
+					Systemd: api.Systemd{
+						Extensions: []api.SystemdExtension{
+							{Name: "elemental3ctl", Image: "registry.example.com/elemental3ctl:3.0", Required: true},
+							{Name: "debugging-toolkit", Image: "registry.example.com/debugging-toolkit:1.0"},
+						},
+					},
+				},
+			},
+			ProductExtension: &product.ReleaseManifest{
+				Metadata:     &api.Metadata{Name: "product", Version: "2.0"},
+				CorePlatform: &product.CorePlatform{Image: "registry.example.com/core-manifest:1.0"},
+				Components: product.Components{
+					Helm: &api.Helm{
+						Charts:       []*api.HelmChart{{Chart: "longhorn", Version: "1.9.0", Repository: "longhorn"}},
+						Repositories: []*api.HelmRepository{{Name: "longhorn", URL: "https://charts.example.com/longhorn"}},
+					},
+				},
+			},
+		}
+		conf := &image.Configuration{
+			Release: release.Release{
+				ManifestURI: "oci://registry.example.com/product-manifest:2.0",
+				Components: release.Components{
+					HelmCharts: []release.HelmChart{{Name: "longhorn"}},
+				},
+			},
+		}
+
+		sb, err := NewSBOM(rm, conf, log.New(log.WithDiscardAll()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sb.Components()).To(ConsistOf(
+			sbom.Component{
+				Kind: sbom.ReleaseManifest, Name: "product", Version: "2.0",
+				Reference: "oci://registry.example.com/product-manifest:2.0",
+			},
+			sbom.Component{
+				Kind: sbom.ReleaseManifest, Name: "core", Version: "1.0",
+				Reference: "registry.example.com/core-manifest:1.0",
+			},
+			sbom.Component{
+				Kind: sbom.OSImage, Name: "registry.example.com/os", Version: "6.2",
+				Reference: "registry.example.com/os:6.2@sha256:4c1b5d3e2a6f7089b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5d6e",
+				Digest:    "sha256:4c1b5d3e2a6f7089b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5d6e",
+			},
+			sbom.Component{
+				Kind: sbom.ISOImage, Name: "registry.example.com/iso", Version: "6.2",
+				Reference: "registry.example.com/iso:6.2",
+			},
+			sbom.Component{
+				Kind: sbom.Extension, Name: "elemental3ctl", Version: "3.0",
+				Reference: "registry.example.com/elemental3ctl:3.0",
+			},
+			sbom.Component{
+				Kind: sbom.HelmChart, Name: "longhorn", Version: "1.9.0",
+				Reference: "https://charts.example.com/longhorn", PURL: "pkg:helm/longhorn@1.9.0",
+			},
+		))
+	})
+})
@@ -50,7 +50,7 @@ func (m *Manager) downloadSystemExtensions(ctx context.Context, extensions []api
 
 		if isRemoteURL(extension.Image) {
 			extensionPath := filepath.Join(extensionsDir, filepath.Base(extension.Image))
-			if err := m.downloadFile(ctx, fs, extension.Image, extensionPath); err != nil {
+			if err := m.fetchFile(ctx, extension.Image, extensionPath, extension.Digest); err != nil {
 				return fmt.Errorf("downloading systemd extension %s: %w", extension.Name, err)
 			}
 
@@ -74,6 +74,23 @@ func isRemoteURL(s string) bool {
 	return u.Scheme == "http" || u.Scheme == "https"
 }
 
+// checkFileDigest verifies the file at the given path matches the given digest, nothing
+// is checked if the digest is empty
+func checkFileDigest(fs vfs.FS, path, digest string) error {
+	if digest == "" {
+		return nil
+	}
+
+	got, err := vfs.FileDigest(fs, path)
+	if err != nil {
+		return err
+	}
+	if got != digest {
+		return fmt.Errorf("digest mismatch for '%s': expected %s, got %s", path, digest, got)
+	}
+	return nil
+}
+
 func (m *Manager) unpackExtension(ctx context.Context, extension api.SystemdExtension, extensionsDir string) error {
 	fs := m.system.FS()
 
@@ -85,7 +102,11 @@ func (m *Manager) unpackExtension(ctx context.Context, extension api.SystemdExte
 		_ = fs.RemoveAll(tempDir)
 	}()
 
-	unpacker := unpack.NewOCIUnpacker(m.system, extension.Image, unpack.WithLocalOCI(m.local))
+	unpacker := unpack.NewOCIUnpacker(
+		m.system, extension.PinnedImage(), unpack.WithLocalOCI(m.local), unpack.WithLocalSourceOCI(m.localSource),
+		unpack.WithSignaturePolicyOCI(m.policy), unpack.WithBundleOCI(m.bundle), unpack.WithCacheOCI(m.cache),
+		unpack.WithRegistriesOCI(m.registries),
+	)
 	if _, err = unpacker.Unpack(ctx, tempDir); err != nil {
 		return fmt.Errorf("unpacking extension: %w", err)
 	}
@@ -18,6 +18,9 @@ limitations under the License.
 package config
 
 import (
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -28,6 +31,7 @@ import (
 	"github.com/suse/elemental/v3/pkg/manifest/api/core"
 	"github.com/suse/elemental/v3/pkg/manifest/api/product"
 	"github.com/suse/elemental/v3/pkg/manifest/resolver"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
 )
 
 var _ = Describe("Systemd extensions", func() {
@@ -40,6 +44,20 @@ var _ = Describe("Systemd extensions", func() {
 		Expect(isRemoteURL("raw:///etc/extension.raw")).To(BeFalse(), "custom")
 	})
 
+	It("Verifies the digest of downloaded extensions", func() {
+		fs, cleanup, err := sysmock.TestFS(map[string]string{"/ext.raw": "extension"})
+		Expect(err).NotTo(HaveOccurred())
+		defer cleanup()
+
+		digest := "sha256:1d4c6d2a1e2ca1e1a1b5f9f0b10d42ce1e2ad6c4b2b4f6d4e9a8c8b7cd0b9f3f"
+		h, _, err := v1.SHA256(strings.NewReader("extension"))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(checkFileDigest(fs, "/ext.raw", "")).To(Succeed())
+		Expect(checkFileDigest(fs, "/ext.raw", h.String())).To(Succeed())
+		Expect(checkFileDigest(fs, "/ext.raw", digest)).To(MatchError(ContainSubstring("digest mismatch for '/ext.raw'")))
+	})
+
 	Describe("Filtering", func() {
 		It("Fails to list enabled Helm charts", func() {
 			rm := &resolver.ResolvedManifest{
@@ -264,6 +264,7 @@ var _ = Describe("Configuration", Label("configuration"), func() {
 		invalidInstallYAML := `
 schema: v0
 bootloader: invalid
+bootTries: 10
 raw:
   diskSize: 35X
 `
@@ -272,8 +273,9 @@ raw:
 		_, err := Parse(fs, configDir)
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("validating configuration"))
-		Expect(err.Error()).To(ContainSubstring("field \"Configuration.Installation.Bootloader\" must be one of [grub none], but got \"invalid\""))
+		Expect(err.Error()).To(ContainSubstring("field \"Configuration.Installation.Bootloader\" must be one of [grub systemd-boot none], but got \"invalid\""))
 		Expect(err.Error()).To(ContainSubstring("field \"Configuration.Installation.RAW.DiskSize\" must be a valid disk size (e.g., 10G, 500M), but got \"35X\""))
+		Expect(err.Error()).To(ContainSubstring("field \"Configuration.Installation.BootTries\": invalid number of boot tries 10, must be between 0 and 9"))
 	})
 
 	It("Fails on missing required release configuration", func() {
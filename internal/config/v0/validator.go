@@ -27,6 +27,7 @@ import (
 
 	"github.com/suse/elemental/v3/internal/image"
 	"github.com/suse/elemental/v3/internal/image/install"
+	"github.com/suse/elemental/v3/pkg/bootcount"
 )
 
 var (
@@ -38,6 +39,7 @@ func getValidator() *validator.Validate {
 	once.Do(func() {
 		validate = validator.New(validator.WithRequiredStructEnabled())
 		_ = validate.RegisterValidation("disksize", validateDiskSize)
+		_ = validate.RegisterValidation("boot_tries", validateBootTries)
 	})
 	return validate
 }
@@ -53,6 +55,10 @@ func validateDiskSize(fl validator.FieldLevel) bool {
 	return diskSize.IsValid()
 }
 
+func validateBootTries(fl validator.FieldLevel) bool {
+	return bootcount.Validate(int(fl.Field().Int())) == nil
+}
+
 func Validate(conf *image.Configuration) error {
 	err := getValidator().Struct(conf)
 	if err == nil {
@@ -70,6 +76,8 @@ func Validate(conf *image.Configuration) error {
 				messages = append(messages, fmt.Sprintf("field %q must be one of [%s], but got %q", vErr.Namespace(), vErr.Param(), vErr.Value()))
 			case "disksize":
 				messages = append(messages, fmt.Sprintf("field %q must be a valid disk size (e.g., 10G, 500M), but got %q", vErr.Namespace(), vErr.Value()))
+			case "boot_tries":
+				messages = append(messages, fmt.Sprintf("field %q: %v", vErr.Namespace(), bootcount.Validate(vErr.Value().(int))))
 			case "url":
 				messages = append(messages, fmt.Sprintf("field %q must be a valid URL, but got %q", vErr.Namespace(), vErr.Value()))
 			case "hostname":
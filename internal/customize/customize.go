@@ -30,9 +30,12 @@ import (
 	"github.com/suse/elemental/v3/internal/image/install"
 	"github.com/suse/elemental/v3/internal/template"
 	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/diskimage"
 	"github.com/suse/elemental/v3/pkg/fips"
 	"github.com/suse/elemental/v3/pkg/installer"
 	"github.com/suse/elemental/v3/pkg/manifest/resolver"
+	"github.com/suse/elemental/v3/pkg/registry"
+	"github.com/suse/elemental/v3/pkg/signature"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
 )
@@ -61,6 +64,10 @@ type Runner struct {
 	ConfigManager configManager
 	FileExtractor ociFileExtractor
 	Media         media
+	// SignaturePolicy is recorded in the customized deployment to verify OS images on upgrades
+	SignaturePolicy *signature.Policy
+	// Registries is recorded in the customized deployment to pull OS images on installs and upgrades
+	Registries *registry.Config
 }
 
 func (r *Runner) Run(ctx context.Context, def *image.Definition, output config.Output) (err error) {
@@ -73,7 +80,7 @@ func (r *Runner) Run(ctx context.Context, def *image.Definition, output config.O
 		return err
 	}
 
-	containerImage := rm.CorePlatform.Components.OperatingSystem.Image.ISO
+	containerImage := rm.CorePlatform.Components.OperatingSystem.Image.PinnedISO()
 	logger.Info("Extracting ISO from container image %s", containerImage)
 	iso, err := r.FileExtractor.ExtractFrom(containerImage)
 	if err != nil {
@@ -94,6 +101,9 @@ func (r *Runner) Run(ctx context.Context, def *image.Definition, output config.O
 		logger.Error("Parsing media type failed")
 		return err
 	}
+	if mediaType != installer.Disk && def.Image.Compress {
+		return fmt.Errorf("compression is only supported for disk images")
+	}
 
 	dep, err := parseDeployment(
 		r.System.FS(),
@@ -107,9 +117,18 @@ func (r *Runner) Run(ctx context.Context, def *image.Definition, output config.O
 		logger.Error("Parsing customization deployment failed")
 		return err
 	}
+	dep.Security.SignaturePolicy = r.SignaturePolicy
+	dep.Registries = r.Registries
+
+	sb, err := config.NewSBOM(rm, def.Configuration, logger)
+	if err != nil {
+		logger.Error("Preparing SBOM failed")
+		return err
+	}
 
 	mediaOpts := []installer.Option{
 		installer.WithOutputFile(def.Image.OutputImageName),
+		installer.WithSBOM(sb),
 	}
 	if mediaType == installer.Disk {
 		diskSizeStr := def.Configuration.Installation.RAW.DiskSize
@@ -124,6 +143,12 @@ func (r *Runner) Run(ctx context.Context, def *image.Definition, output config.O
 			return fmt.Errorf("could not parse disk size '%s': %w", diskSizeStr, err)
 		}
 		mediaOpts = append(mediaOpts, installer.WithRawDiskSize(deployment.MiB(diskMiB)))
+
+		format, err := diskimage.ParseFormat(def.Image.ImageType)
+		if err != nil {
+			return err
+		}
+		mediaOpts = append(mediaOpts, installer.WithDiskFormat(format, def.Image.Compress))
 	}
 
 	// TODO(ipetrov117): Consider refactoring installer.Media, as right now
@@ -210,6 +235,8 @@ func parseDeployment(
 	d.BootConfig = &deployment.BootConfig{
 		Bootloader:    install.Bootloader,
 		KernelCmdline: install.KernelCmdLine,
+		BootTries:     install.BootTries,
+		UKI:           install.UKI,
 	}
 
 	d.Security = &deployment.SecurityConfig{
@@ -48,6 +48,8 @@ type Image struct {
 	ImageType       string
 	Platform        *platform.Platform
 	OutputImageName string
+	// Compress compresses disk images of formats supporting it
+	Compress bool
 }
 
 type Network struct {
@@ -60,11 +60,13 @@ func (d DiskSize) ToMiB() (uint, error) {
 
 type Installation struct {
 	SchemaVersion string        `yaml:"schema"`
-	Bootloader    string        `yaml:"bootloader" validate:"omitempty,oneof=grub none"`
+	Bootloader    string        `yaml:"bootloader" validate:"omitempty,oneof=grub systemd-boot none"`
 	KernelCmdLine string        `yaml:"kernelCmdLine"`
 	RAW           RAW           `yaml:"raw"`
 	ISO           ISO           `yaml:"iso"`
 	CryptoPolicy  crypto.Policy `yaml:"cryptoPolicy" validate:"omitempty,oneof=fips default"`
+	BootTries     int           `yaml:"bootTries" validate:"omitempty,boot_tries"`
+	UKI           bool          `yaml:"uki"`
 }
 
 type RAW struct {
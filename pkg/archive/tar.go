@@ -25,6 +25,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	iofs "io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -169,6 +170,90 @@ func ExtractTar(ctx context.Context, s *sys.System, body io.Reader, target strin
 	return nil
 }
 
+// CreateTarball archives the regular files and directories under the given source into a .tar or
+// .tar.gz tarball. Compression is only based on the tarball file name extension.
+func CreateTarball(ctx context.Context, s *sys.System, source, tarball string) (err error) {
+	f, err := s.FS().Create(tarball)
+	if err != nil {
+		return fmt.Errorf("creating tarball: %w", err)
+	}
+	defer func() {
+		e := f.Close()
+		if err == nil && e != nil {
+			err = fmt.Errorf("closing tarball: %w", e)
+		}
+	}()
+
+	var out io.Writer = f
+	if strings.HasSuffix(tarball, "tar.gz") {
+		gz := gzip.NewWriter(f)
+		defer func() {
+			e := gz.Close()
+			if err == nil && e != nil {
+				err = fmt.Errorf("closing gzip stream: %w", e)
+			}
+		}()
+		out = gz
+	}
+
+	tw := tar.NewWriter(out)
+	defer func() {
+		e := tw.Close()
+		if err == nil && e != nil {
+			err = fmt.Errorf("closing tar stream: %w", e)
+		}
+	}()
+
+	return vfs.WalkDirFs(s.FS(), source, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stop writing tar, context cancelled")
+		default:
+		}
+
+		rel, err := filepath.Rel(source, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			s.Logger().Warn("Ignoring non regular file '%s'", path)
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return fmt.Errorf("creating tar header for %s: %w", path, err)
+		}
+		header.Name = rel
+		if d.IsDir() {
+			header.Name += "/"
+		}
+		if err = tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("writing tar header for %s: %w", path, err)
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		src, err := s.FS().Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		if _, err = io.Copy(tw, src); err != nil {
+			return fmt.Errorf("writing %s to tar: %w", path, err)
+		}
+		return nil
+	})
+}
+
 func copyFile(ctx context.Context, s *sys.System, path string, mode os.FileMode, src io.Reader) (err error) {
 	dir := filepath.Dir(path)
 	info, err := s.FS().Lstat(dir)
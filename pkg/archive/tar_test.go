@@ -116,6 +116,20 @@ var _ = Describe("Archive", Label("archive"), func() {
 		Expect(ok).To(BeFalse())
 	})
 
+	It("Creates a tar.gz file from a directory", func() {
+		Expect(vfs.MkdirAll(tfs, "/src/blobs/sha256", vfs.DirPerm)).To(Succeed())
+		Expect(tfs.WriteFile("/src/index.json", []byte("{}"), vfs.FilePerm)).To(Succeed())
+		Expect(tfs.WriteFile("/src/blobs/sha256/blob", []byte("data"), vfs.FilePerm)).To(Succeed())
+
+		Expect(archive.CreateTarball(context.Background(), s, "/src", "/data/bundle.tar.gz")).To(Succeed())
+		Expect(archive.ExtractTarball(context.Background(), s, "/data/bundle.tar.gz", "/root")).To(Succeed())
+		data, err := tfs.ReadFile("/root/blobs/sha256/blob")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("data"))
+		ok, _ := vfs.Exists(tfs, "/root/index.json")
+		Expect(ok).To(BeTrue())
+	})
+
 	It("fails to extract files of wrong type", func() {
 		Expect(archive.ExtractTarball(context.Background(), s, "/data/test.tar", "/root")).NotTo(Succeed())
 		Expect(archive.ExtractTarball(context.Background(), s, "/data/test.tar.bz2", "/root")).NotTo(Succeed())
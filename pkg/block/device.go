@@ -26,6 +26,7 @@ import (
 )
 
 type Device interface {
+	GetAllDisks() (DiskList, error)
 	GetAllPartitions() (PartitionList, error)
 	GetDevicePartitions(device string) (PartitionList, error)
 	GetDeviceSectorSize(device string) (uint, error)
@@ -47,6 +48,20 @@ type Partition struct {
 
 type PartitionList []*Partition
 
+// Disk struct represents a whole disk device with its hardware attributes, size in MiB
+type Disk struct {
+	Path       string
+	Size       uint
+	Model      string
+	Serial     string
+	WWN        string
+	Transport  string
+	Rotational bool
+	Removable  bool
+}
+
+type DiskList []*Disk
+
 // GetByName gets a partitions by its name from the PartitionList
 func (pl PartitionList) GetByName(name string) *Partition {
 	var part *Partition
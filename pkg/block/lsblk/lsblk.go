@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/suse/elemental/v3/pkg/block"
 	"github.com/suse/elemental/v3/pkg/sys"
@@ -50,6 +51,33 @@ type jPart struct {
 
 type jParts []*block.Partition
 
+type jDisk struct {
+	Path       string `json:"path,omitempty"`
+	Size       uint64 `json:"size,omitempty"`
+	Model      string `json:"model,omitempty"`
+	Serial     string `json:"serial,omitempty"`
+	WWN        string `json:"wwn,omitempty"`
+	Transport  string `json:"tran,omitempty"`
+	Rotational jBool  `json:"rota,omitempty"`
+	Removable  jBool  `json:"rm,omitempty"`
+	Type       string `json:"type,omitempty"`
+}
+
+// jBool is a boolean reported by lsblk, older lsblk versions report booleans as "0" or "1" strings
+type jBool bool
+
+func (b *jBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "1", "true":
+		*b = true
+	case "0", "false", "null", "":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean value: %s", string(data))
+	}
+	return nil
+}
+
 func (p jPart) Partition() *block.Partition {
 	// Converts B to MB
 	return &block.Partition{
@@ -103,6 +131,43 @@ func unmarshalLsblk(lsblkOut []byte) ([]*block.Partition, error) {
 	return parts, nil
 }
 
+func unmarshalDisks(lsblkOut []byte) (block.DiskList, error) {
+	var objmap map[string]*json.RawMessage
+	err := json.Unmarshal(lsblkOut, &objmap)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := objmap["blockdevices"]; !ok {
+		return nil, errors.New("invalid json object, no 'blockdevices' key found")
+	}
+
+	var jDisks []jDisk
+	err = json.Unmarshal(*objmap["blockdevices"], &jDisks)
+	if err != nil {
+		return nil, err
+	}
+
+	var disks block.DiskList
+	for _, d := range jDisks {
+		if d.Type != "disk" {
+			continue
+		}
+		// Converts B to MB
+		disks = append(disks, &block.Disk{
+			Path:       d.Path,
+			Size:       uint(d.Size / (1024 * 1024)),
+			Model:      strings.TrimSpace(d.Model),
+			Serial:     strings.TrimSpace(d.Serial),
+			WWN:        d.WWN,
+			Transport:  d.Transport,
+			Rotational: bool(d.Rotational),
+			Removable:  bool(d.Removable),
+		})
+	}
+	return disks, nil
+}
+
 func unmarshalSectorSize(lsblkOut []byte) (uint, error) {
 	var objmap map[string]*json.RawMessage
 	err := json.Unmarshal(lsblkOut, &objmap)
@@ -128,6 +193,16 @@ func unmarshalSectorSize(lsblkOut []byte) (uint, error) {
 	return devices[0].SectorSize, nil
 }
 
+// GetAllDisks gets a slice of all disk devices found in the host including their hardware attributes
+func (l lsDevice) GetAllDisks() (block.DiskList, error) {
+	out, err := l.runner.Run("lsblk", "-p", "-b", "-d", "-J", "--output", "PATH,SIZE,MODEL,SERIAL,WWN,TRAN,ROTA,RM,TYPE")
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalDisks(out)
+}
+
 // GetAllPartitions gets a slice of all partition devices found in the host
 // mapped into a v1.PartitionList object.
 func (l lsDevice) GetAllPartitions() (block.PartitionList, error) {
@@ -49,6 +49,37 @@ const noSectorSizeLsblk = `{
 }
 `
 
+const disksLsblk = `{
+   "blockdevices": [
+      {
+         "path": "/dev/sda",
+         "size": 500107862016,
+         "model": "Samsung SSD 860 ",
+         "serial": "S3Z9NB0K123456",
+         "wwn": "0x5002538e40a1b2c3",
+         "tran": "sata",
+         "rota": false,
+         "rm": false,
+         "type": "disk"
+      },{
+         "path": "/dev/sdb",
+         "size": 16013852672,
+         "model": "USB Flash",
+         "serial": "0123",
+         "wwn": null,
+         "tran": "usb",
+         "rota": "1",
+         "rm": "1",
+         "type": "disk"
+      },{
+         "path": "/dev/loop0",
+         "size": 1048576,
+         "type": "loop"
+      }
+   ]
+}
+`
+
 const fullLsblkTmpl = `{
    "blockdevices": [
       {
@@ -179,6 +210,29 @@ var _ = Describe("BlockDevice", Label("lsblk"), func() {
 			Expect(err).To(HaveOccurred())
 		})
 	})
+	Describe("GetAllDisks", func() {
+		BeforeEach(func() {
+			json = disksLsblk
+		})
+		It("lists all disks found by lsblk", func() {
+			dl, err := b.GetAllDisks()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dl).To(HaveLen(2))
+			Expect(*dl[0]).To(Equal(block.Disk{
+				Path: "/dev/sda", Size: 476940, Model: "Samsung SSD 860",
+				Serial: "S3Z9NB0K123456", WWN: "0x5002538e40a1b2c3", Transport: "sata",
+			}))
+			Expect(dl[1].Path).To(Equal("/dev/sdb"))
+			Expect(dl[1].Rotational).To(BeTrue())
+			Expect(dl[1].Removable).To(BeTrue())
+			Expect(dl[1].WWN).To(BeEmpty())
+		})
+		It("lsblk call fails", func() {
+			lsblkErr = fmt.Errorf("new lsblk error")
+			_, err := b.GetAllDisks()
+			Expect(err).To(HaveOccurred())
+		})
+	})
 	Describe("GetAllPartitions", func() {
 		BeforeEach(func() {
 			json = fmt.Sprintf(fullLsblkTmpl, partsPortionLslbkOut, diskPortionLsblkOut)
@@ -26,6 +26,7 @@ import (
 var _ block.Device = (*Device)(nil)
 
 type Device struct {
+	disks      block.DiskList
 	partitions block.PartitionList
 	sectorSize uint
 	err        error
@@ -39,10 +40,18 @@ func (m *Device) SetPartitions(partitions block.PartitionList) {
 	m.partitions = partitions
 }
 
+func (m *Device) SetDisks(disks block.DiskList) {
+	m.disks = disks
+}
+
 func (m *Device) SetError(err error) {
 	m.err = err
 }
 
+func (m Device) GetAllDisks() (block.DiskList, error) {
+	return m.disks, m.err
+}
+
 func (m Device) GetAllPartitions() (block.PartitionList, error) {
 	return m.partitions, m.err
 }
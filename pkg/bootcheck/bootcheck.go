@@ -0,0 +1,185 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootcheck
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/suse/elemental/v3/pkg/bootloader"
+	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/rollback"
+	"github.com/suse/elemental/v3/pkg/snapper"
+	"github.com/suse/elemental/v3/pkg/sys"
+)
+
+// ChecksDir is the directory including the executables that are run as health checks
+// before marking a boot as successful
+const ChecksDir = "/etc/elemental/boot-check.d"
+
+const rootConfig = "root"
+
+type Option func(*Checker)
+
+type Checker struct {
+	s    *sys.System
+	b    bootloader.Bootloader
+	snap *snapper.Snapper
+	root string
+}
+
+// Status describes the boot counting state of the current boot
+type Status struct {
+	Enabled         bool   `json:"enabled"`
+	TriesLeft       int    `json:"triesLeft,omitempty"`
+	FallbackID      string `json:"fallback,omitempty"`
+	FellBack        bool   `json:"fellBack"`
+	BootedSnapshot  int    `json:"bootedSnapshot,omitempty"`
+	DefaultSnapshot int    `json:"defaultSnapshot,omitempty"`
+}
+
+func WithBootloader(b bootloader.Bootloader) Option {
+	return func(c *Checker) {
+		c.b = b
+	}
+}
+
+// WithRoot sets the root path of the system to check, defaults to '/'
+func WithRoot(root string) Option {
+	return func(c *Checker) {
+		c.root = root
+	}
+}
+
+func New(s *sys.System, opts ...Option) *Checker {
+	c := &Checker{
+		s:    s,
+		snap: snapper.New(s),
+		root: "/",
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	if c.b == nil {
+		c.b = bootloader.NewNone(s)
+	}
+	return c
+}
+
+// RunChecks runs all the executables found in the health checks directory in lexical order.
+// It stops on the first failing check.
+func (c Checker) RunChecks() error {
+	checksDir := filepath.Join(c.root, ChecksDir)
+	entries, err := c.s.FS().ReadDir(checksDir)
+	if err != nil {
+		c.s.Logger().Debug("No health checks found in '%s': %v", checksDir, err)
+		return nil
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.Mode()&0111 == 0 {
+			c.s.Logger().Warn("Skipping non executable health check '%s'", entry.Name())
+			continue
+		}
+
+		c.s.Logger().Info("Running health check '%s'", entry.Name())
+		out, err := c.s.Runner().Run(filepath.Join(checksDir, entry.Name()))
+		c.s.Logger().Debug("health check output: %s", string(out))
+		if err != nil {
+			return fmt.Errorf("health check '%s' failed: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Status returns the boot counting state of the current boot
+func (c Checker) Status(d *deployment.Deployment) (*Status, error) {
+	esp := d.GetEfiPartition()
+	if esp == nil {
+		return nil, fmt.Errorf("no %s partition defined in deployment", deployment.EfiLabel)
+	}
+
+	counter, err := c.b.GetBootCounter(filepath.Join(c.root, esp.MountPoint))
+	if err != nil {
+		return nil, fmt.Errorf("reading boot counter: %w", err)
+	}
+
+	status := &Status{}
+	if counter != nil {
+		status.Enabled = true
+		status.TriesLeft = counter.TriesLeft
+		status.FallbackID = counter.FallbackID
+	}
+
+	if d.Snapshotter != nil && d.Snapshotter.Name != "snapper" {
+		return status, nil
+	}
+
+	snaps, err := c.snap.ListSnapshots(c.root, rootConfig)
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshots: %w", err)
+	}
+	status.BootedSnapshot = snaps.GetActive()
+	status.DefaultSnapshot = snaps.GetDefault()
+	status.FellBack = counter != nil && counter.IsExhausted() &&
+		strconv.Itoa(status.BootedSnapshot) == counter.FallbackID
+
+	return status, nil
+}
+
+// MarkGood marks the current boot as successful by disabling boot counting. If the fallback
+// snapshot was booted because the default one failed to boot, the fallback snapshot is set
+// as the default one.
+func (c Checker) MarkGood(d *deployment.Deployment) error {
+	esp := d.GetEfiPartition()
+	if esp == nil {
+		return fmt.Errorf("no %s partition defined in deployment", deployment.EfiLabel)
+	}
+
+	status, err := c.Status(d)
+	if err != nil {
+		return err
+	}
+	if !status.Enabled {
+		c.s.Logger().Info("Boot counting is not enabled, nothing to do")
+		return nil
+	}
+
+	if status.FellBack {
+		c.s.Logger().Warn(
+			"Snapshot %d failed to boot, setting fallback snapshot %d as default",
+			status.DefaultSnapshot, status.BootedSnapshot,
+		)
+		_, err = rollback.New(c.s, rollback.WithBootloader(c.b), rollback.WithRoot(c.root)).Rollback(d, status.BootedSnapshot)
+		if err != nil {
+			return fmt.Errorf("rolling back to snapshot %d: %w", status.BootedSnapshot, err)
+		}
+	}
+
+	err = c.b.SetBootCounter(filepath.Join(c.root, esp.MountPoint), 0, "")
+	if err != nil {
+		return fmt.Errorf("disabling boot counter: %w", err)
+	}
+	c.s.Logger().Info("Boot marked as successful")
+	return nil
+}
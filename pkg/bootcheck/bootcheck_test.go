@@ -0,0 +1,185 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootcheck_test
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/joho/godotenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/bootcheck"
+	"github.com/suse/elemental/v3/pkg/bootloader"
+	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+func TestBootCheckSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Boot check test suite")
+}
+
+const snapperListTmpl = `{
+  "root": [
+    {"number": 1, "default": false, "active": %t, "userdata": null},
+    {"number": 2, "default": true, "active": %t, "userdata": null}
+  ]
+}`
+
+var _ = Describe("BootCheck", Label("bootcheck"), func() {
+	var runner *sysmock.Runner
+	var fs vfs.FS
+	var cleanup func()
+	var s *sys.System
+	var d *deployment.Deployment
+	var c *bootcheck.Checker
+	var bootedFallback bool
+
+	BeforeEach(func() {
+		var err error
+		bootedFallback = false
+		runner = sysmock.NewRunner()
+		fs, cleanup, err = sysmock.TestFS(map[string]any{
+			"/boot/grubenv":          "entries=active 2 1\nboot_tries_left=2\nboot_fallback=1",
+			"/boot/loader/entries/1": "display_name=OS (1)\nlinux=/os/6.14/vmlinuz\ninitrd=/os/6.14/initrd\ncmdline=snapshot1",
+			"/boot/loader/entries/2": "display_name=OS (2)\nlinux=/os/6.15/vmlinuz\ninitrd=/os/6.15/initrd\ncmdline=snapshot2",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		s, err = sys.NewSystem(
+			sys.WithRunner(runner), sys.WithFS(fs),
+			sys.WithLogger(log.New(log.WithDiscardAll())),
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		runner.SideEffect = func(command string, args ...string) ([]byte, error) {
+			switch command {
+			case "snapper":
+				if slices.Contains(args, "list") {
+					return fmt.Appendf(nil, snapperListTmpl, bootedFallback, !bootedFallback), nil
+				}
+				return []byte{}, nil
+			case "grub2-editenv":
+				env := map[string]string{}
+				if data, err := fs.ReadFile(args[0]); err == nil {
+					env, _ = godotenv.UnmarshalBytes(data)
+				}
+				switch args[1] {
+				case "list":
+					return fs.ReadFile(args[0])
+				case "set":
+					for _, kv := range args[2:] {
+						k, v, _ := strings.Cut(kv, "=")
+						env[k] = v
+					}
+				case "unset":
+					for _, k := range args[2:] {
+						delete(env, k)
+					}
+				}
+				lines := []string{}
+				for k, v := range env {
+					lines = append(lines, fmt.Sprintf("%s=%s", k, v))
+				}
+				return nil, fs.WriteFile(args[0], []byte(strings.Join(lines, "\n")), vfs.FilePerm)
+			}
+			return []byte{}, nil
+		}
+
+		d = deployment.DefaultDeployment()
+		d.SourceOS = deployment.NewOCISrc("registry.org/my/os:v2")
+		Expect(d.WriteDeploymentFile(s, "/.snapshots/1/snapshot")).To(Succeed())
+
+		c = bootcheck.New(s, bootcheck.WithBootloader(bootloader.NewGrub(s)))
+	})
+	AfterEach(func() {
+		cleanup()
+	})
+	It("reports the boot counting status", func() {
+		status, err := c.Status(d)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*status).To(Equal(bootcheck.Status{
+			Enabled: true, TriesLeft: 2, FallbackID: "1", BootedSnapshot: 2, DefaultSnapshot: 2,
+		}))
+	})
+	It("marks the current boot as good", func() {
+		Expect(c.MarkGood(d)).To(Succeed())
+		status, err := c.Status(d)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Enabled).To(BeFalse())
+		Expect(runner.IncludesCmds([][]string{{"snapper", "--no-dbus", "modify", "--default", "1"}})).NotTo(Succeed())
+	})
+	It("sets the fallback snapshot as default when booted after exhausting all tries", func() {
+		bootedFallback = true
+		Expect(fs.WriteFile("/boot/grubenv", []byte("entries=active 2 1\nboot_tries_left=0\nboot_fallback=1"), vfs.FilePerm)).To(Succeed())
+
+		status, err := c.Status(d)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.FellBack).To(BeTrue())
+
+		Expect(c.MarkGood(d)).To(Succeed())
+		Expect(runner.IncludesCmds([][]string{{"snapper", "--no-dbus", "modify", "--default", "1"}})).To(Succeed())
+
+		activeEntry, err := fs.ReadFile("/boot/loader/entries/active")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(activeEntry)).To(ContainSubstring("cmdline=snapshot1"))
+	})
+	It("makes the read-only fallback snapshot writable to record the rollback", func() {
+		bootedFallback = true
+		Expect(fs.WriteFile("/boot/grubenv", []byte("entries=active 2 1\nboot_tries_left=0\nboot_fallback=1"), vfs.FilePerm)).To(Succeed())
+
+		Expect(c.MarkGood(d)).To(Succeed())
+		Expect(runner.MatchMilestones([][]string{
+			{"snapper", "--no-dbus", "modify", "--read-write", "1"},
+			{"snapper", "--no-dbus", "modify", "--default", "1"},
+			{"snapper", "--no-dbus", "modify", "--read-only", "1"},
+			{"grub2-editenv", "/boot/grubenv", "unset"},
+		})).To(Succeed())
+
+		dep, err := deployment.Parse(s, "/.snapshots/1/snapshot")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Rollback).NotTo(BeNil())
+		Expect(dep.Rollback.FromSnapshot).To(Equal(2))
+	})
+	It("runs executable health checks and stops on failure", func() {
+		Expect(vfs.MkdirAll(fs, bootcheck.ChecksDir, vfs.DirPerm)).To(Succeed())
+		Expect(fs.WriteFile(bootcheck.ChecksDir+"/10-ok", []byte{}, 0755)).To(Succeed())
+		Expect(fs.WriteFile(bootcheck.ChecksDir+"/20-fail", []byte{}, 0755)).To(Succeed())
+		Expect(fs.WriteFile(bootcheck.ChecksDir+"/30-skipped", []byte{}, 0755)).To(Succeed())
+		Expect(fs.WriteFile(bootcheck.ChecksDir+"/README", []byte{}, vfs.FilePerm)).To(Succeed())
+		runner.SideEffect = func(command string, _ ...string) ([]byte, error) {
+			if strings.HasSuffix(command, "fail") {
+				return nil, fmt.Errorf("check failed")
+			}
+			return []byte{}, nil
+		}
+
+		err := c.RunChecks()
+		Expect(err).To(MatchError("health check '20-fail' failed: check failed"))
+		Expect(runner.CmdsMatch([][]string{
+			{bootcheck.ChecksDir + "/10-ok"},
+			{bootcheck.ChecksDir + "/20-fail"},
+		})).To(Succeed())
+	})
+})
@@ -0,0 +1,32 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootcount
+
+import "fmt"
+
+// MaxTries is the maximum number of boot attempts supported by boot counting
+const MaxTries = 9
+
+// Validate checks the given number of boot tries is within the supported range, 0 disables
+// boot counting.
+func Validate(tries int) error {
+	if tries < 0 || tries > MaxTries {
+		return fmt.Errorf("invalid number of boot tries %d, must be between 0 and %d", tries, MaxTries)
+	}
+	return nil
+}
@@ -20,21 +20,71 @@ package bootloader
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 
+	"github.com/suse/elemental/v3/pkg/secureboot"
 	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
 )
 
 type Bootloader interface {
 	Install(rootPath, espDir, espLabel, entryID, kernelCmdline, recKernelCmdline string) error
 	InstallLive(rootPath, espDir, kernelCmdline string) error
 	Prune(rootPath, espDir string, keepEntryIDs []int) error
+	SetDefault(espDir, entryID string) error
+	SetBootCounter(espDir string, tries int, fallbackID string) error
+	GetBootCounter(espDir string) (*BootCounter, error)
 }
 
 const (
 	BootNone = "none"
 	BootGrub = "grub"
+	// BootSystemdBoot is the systemd-boot bootloader using Boot Loader Specification entries
+	BootSystemdBoot = "systemd-boot"
 )
 
+type bootEntry struct {
+	Linux       string
+	Initrd      string
+	CmdLine     string
+	DisplayName string
+	ID          string
+	// EFI is the Unified Kernel Image booted by the entry, if any
+	EFI string
+}
+
+// Options defines the optional settings of a bootloader
+type Options struct {
+	// UKI enables booting Unified Kernel Images instead of separate kernel and initrd files
+	UKI bool
+	// Signer signs the installed EFI binaries, kernels and UKIs with custom Secure Boot keys
+	Signer *secureboot.Signer
+	// LiveID is the identifier of live media used to find the boot device, a random one
+	// is generated if empty
+	LiveID string
+}
+
+type Opt func(*Options)
+
+// WithUKI enables or disables booting Unified Kernel Images
+func WithUKI(uki bool) Opt {
+	return func(o *Options) {
+		o.UKI = uki
+	}
+}
+
+// BootCounter describes the boot counting state of the default boot entry. Once no tries
+// are left the fallback entry is booted instead of the default one.
+type BootCounter struct {
+	TriesLeft  int
+	FallbackID string
+}
+
+// IsExhausted returns true if all boot attempts of the default entry were consumed
+func (bc BootCounter) IsExhausted() bool {
+	return bc.TriesLeft <= 0
+}
+
 type None struct {
 	s *sys.System
 }
@@ -58,13 +108,196 @@ func (n *None) Prune(_, _ string, _ []int) error {
 	return nil
 }
 
-func New(name string, s *sys.System) (Bootloader, error) {
+func (n *None) SetDefault(_, _ string) error {
+	n.s.Logger().Info("Skipping bootloader default entry update")
+	return nil
+}
+
+func (n *None) SetBootCounter(_ string, _ int, _ string) error {
+	n.s.Logger().Info("Skipping bootloader boot counter update")
+	return nil
+}
+
+func (n *None) GetBootCounter(_ string) (*BootCounter, error) {
+	return nil, nil
+}
+
+// WithSigner signs the installed EFI binaries with the given Secure Boot signer
+func WithSigner(signer *secureboot.Signer) Opt {
+	return func(o *Options) {
+		o.Signer = signer
+	}
+}
+
+// WithLiveID sets the identifier of live media instead of generating a random one
+func WithLiveID(id string) Opt {
+	return func(o *Options) {
+		o.LiveID = id
+	}
+}
+
+func New(name string, s *sys.System, opts ...Opt) (Bootloader, error) {
+	o := &Options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	switch name {
 	case BootNone:
 		return NewNone(s), nil
 	case BootGrub:
-		return NewGrub(s), nil
+		if o.UKI {
+			return nil, fmt.Errorf("UKI boot mode with bootloader '%s': %w", name, errors.ErrUnsupported)
+		}
+		grub := NewGrub(s)
+		grub.signer = o.Signer
+		grub.liveID = o.LiveID
+		return grub, nil
+	case BootSystemdBoot:
+		return NewSystemdBoot(s, opts...), nil
 	}
 
 	return nil, fmt.Errorf("new bootloader '%s': %w", name, errors.ErrUnsupported)
 }
+
+// readIDAndName parses OS ID and OS name from os-relese file. Returns error of no OS ID is found.
+func readIDAndName(s *sys.System, rootPath string) (osID string, displayName string, err error) {
+	s.Logger().Info("Reading OS Release")
+
+	osVars, err := vfs.LoadEnvFile(s.FS(), filepath.Join(rootPath, OsReleasePath))
+	if err != nil {
+		return "", "", fmt.Errorf("loading %s vars: %w", OsReleasePath, err)
+	}
+
+	var ok bool
+	if osID, ok = osVars["ID"]; !ok {
+		return "", "", fmt.Errorf("%s ID not set", OsReleasePath)
+	}
+
+	displayName, ok = osVars["PRETTY_NAME"]
+	if !ok {
+		displayName, ok = osVars["VARIANT"]
+		if !ok {
+			displayName = osVars["NAME"]
+		}
+	}
+	return osID, displayName, nil
+}
+
+// installKernelInitrd copies the kernel and initrd to the given ESP path.
+//
+// This function takes a rootPath to find and copy kernel and initrd from there. The espDir parameter
+// is the target path where artifacts will be copied to. The subfolder specifies the location under espDir
+// where artifacts will be copied (mostly used on live images to specify a "boot" folder).
+//
+// Returns a bootEntry including the kernel and initrd paths relative to espDir and the OS display name.
+func installKernelInitrd(s *sys.System, rootPath, espDir, subfolder string) (bootEntry, error) {
+	s.Logger().Info("Installing kernel/initrd")
+	entry := bootEntry{}
+
+	osID, displayName, err := readIDAndName(s, rootPath)
+	if err != nil {
+		return entry, fmt.Errorf("failed parsing OS release: %w", err)
+	}
+
+	kernel, kernelVersion, err := vfs.FindKernel(s.FS(), rootPath)
+	if err != nil {
+		return entry, fmt.Errorf("finding kernel: %w", err)
+	}
+
+	targetDir := filepath.Join(espDir, subfolder, osID, kernelVersion)
+	err = vfs.MkdirAll(s.FS(), targetDir, vfs.DirPerm)
+	if err != nil {
+		return entry, fmt.Errorf("creating kernel dir '%s': %w", targetDir, err)
+	}
+
+	err = vfs.CopyFile(s.FS(), kernel, targetDir)
+	if err != nil {
+		return entry, fmt.Errorf("copying kernel '%s': %w", kernel, err)
+	}
+
+	// Copy kernel .hmac in order to enable FIPS.
+	kernelHmac, err := vfs.FindKernelHmac(s.FS(), kernel)
+	if err != nil {
+		return entry, fmt.Errorf("finding kernel hmac '%s': %w", kernel, err)
+	}
+
+	err = vfs.CopyFile(s.FS(), kernelHmac, targetDir)
+	if err != nil {
+		return entry, fmt.Errorf("copying kernel hmac '%s': %w", kernelHmac, err)
+	}
+
+	initrdPath := filepath.Join(filepath.Dir(kernel), Initrd)
+	if exists, _ := vfs.Exists(s.FS(), initrdPath); !exists {
+		return entry, fmt.Errorf("initrd not found")
+	}
+
+	err = vfs.CopyFile(s.FS(), initrdPath, targetDir)
+	if err != nil {
+		return entry, fmt.Errorf("copying initrd '%s': %w", initrdPath, err)
+	}
+	entry.Linux = filepath.Join("/", subfolder, osID, kernelVersion, filepath.Base(kernel))
+	entry.Initrd = filepath.Join("/", subfolder, osID, kernelVersion, Initrd)
+	entry.DisplayName = displayName
+
+	return entry, nil
+}
+
+// kernelVersion returns the kernel version of the given kernel path within the ESP
+func kernelVersion(linux string) string {
+	linuxDir, _ := filepath.Split(linux)
+	return filepath.Base(linuxDir)
+}
+
+// pruneKernels removes all kernel directories of the installed OS from the ESP which are not
+// included in the given active kernel versions.
+func pruneKernels(s *sys.System, rootPath, espDir string, activeKernels map[string]bool) error {
+	osVars, err := vfs.LoadEnvFile(s.FS(), filepath.Join(rootPath, OsReleasePath))
+	if err != nil {
+		return fmt.Errorf("loading %s vars: %w", OsReleasePath, err)
+	}
+
+	var (
+		ok   bool
+		osID string
+	)
+	if osID, ok = osVars["ID"]; !ok {
+		return fmt.Errorf("%s ID not set", OsReleasePath)
+	}
+
+	// look for older kernels
+	kernelDir := filepath.Join(espDir, osID)
+	kernelDirs, err := s.FS().ReadDir(kernelDir)
+	if err != nil {
+		return fmt.Errorf("reading sub-directories: %w", err)
+	}
+
+	for _, dirEntry := range kernelDirs {
+		if !dirEntry.IsDir() {
+			continue
+		}
+
+		if _, ok := activeKernels[dirEntry.Name()]; !ok {
+			path := filepath.Join(kernelDir, dirEntry.Name())
+			err := s.FS().RemoveAll(path)
+			if err != nil {
+				return fmt.Errorf("failed removing old kernel '%s': %w", path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// sign signs the given EFI binaries and verifies their signatures if a signer is set
+func sign(signer *secureboot.Signer, paths ...string) error {
+	if signer == nil {
+		return nil
+	}
+	for _, path := range paths {
+		if err := signer.Sign(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
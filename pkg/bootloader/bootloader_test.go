@@ -25,8 +25,10 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/suse/elemental/v3/pkg/bootloader"
+	"github.com/suse/elemental/v3/pkg/cleanstack"
 	"github.com/suse/elemental/v3/pkg/log"
 	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
 )
 
 func TestBootloaderSuite(t *testing.T) {
@@ -45,16 +47,85 @@ var _ = Describe("Bootloader tests", Label("bootloader", "grub", "none"), func()
 		Expect(err).NotTo(HaveOccurred())
 	})
 	It("Successfully creates a new bootloader", func() {
-		for _, name := range []string{"none", "grub"} {
+		for _, name := range []string{"none", "grub", "systemd-boot"} {
 			b, err := bootloader.New(name, s)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(b).NotTo(BeNil())
 		}
 	})
+	It("New() returns unsupported error for UKI boot mode with grub", func() {
+		b, err := bootloader.New("grub", s, bootloader.WithUKI(true))
+		Expect(b).To(BeNil())
+		Expect(errors.Is(err, errors.ErrUnsupported)).To(BeTrue(), err.Error())
+	})
 	It("New() returns unsupported error for unknown bootloader", func() {
 		b, err := bootloader.New("bogus", s)
 		Expect(b).To(BeNil())
 		Expect(err).To(HaveOccurred())
 		Expect(errors.Is(err, errors.ErrUnsupported)).To(BeTrue(), err.Error())
 	})
+	It("Keeps ESP mirrors in sync", func() {
+		runner := sysmock.NewRunner()
+		s, err := sys.NewSystem(sys.WithRunner(runner), sys.WithLogger(log.New(log.WithDiscardAll())))
+		Expect(err).NotTo(HaveOccurred())
+
+		none := bootloader.NewNone(s)
+		Expect(bootloader.NewMirrored(s, none)).To(Equal(none))
+
+		b := bootloader.NewMirrored(s, none, "/mirror1", "/mirror2")
+		Expect(b.Install("/root", "/root/boot/efi", "EFI", "1", "cmdline", "")).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{
+			{"rsync", "--archive", "--recursive", "--no-links", "--delete", "/root/boot/efi/", "/mirror1/"},
+			{"rsync", "--archive", "--recursive", "--no-links", "--delete", "/root/boot/efi/", "/mirror2/"},
+		})).To(Succeed())
+
+		runner.ClearCmds()
+		Expect(b.InstallLive("/root", "/root/boot/efi", "cmdline")).To(Succeed())
+		Expect(runner.GetCmds()).To(BeEmpty())
+
+		runner.ReturnError = errors.New("rsync failed")
+		Expect(b.SetDefault("/root/boot/efi", "1")).To(MatchError(ContainSubstring("syncing ESP to mirror '/mirror1'")))
+	})
+	It("Mounts ESP mirrors to keep them in sync", func() {
+		runner := sysmock.NewRunner()
+		mounter := sysmock.NewMounter()
+		fs, fsCleanup, err := sysmock.TestFS(nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer fsCleanup()
+		s, err := sys.NewSystem(
+			sys.WithRunner(runner), sys.WithMounter(mounter), sys.WithFS(fs),
+			sys.WithLogger(log.New(log.WithDiscardAll())),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		runner.SideEffect = func(cmd string, args ...string) ([]byte, error) {
+			if cmd == "lsblk" {
+				return []byte(`{"blockdevices": [{
+					"partuuid": "5c1f9a2e-0b7d-4f3a-9e6c-1d2b3a4c5d6e", "path": "/dev/mirror1",
+					"pkname": "/dev/mirror", "type": "part"
+				}]}`), nil
+			}
+			return []byte{}, nil
+		}
+
+		cleanup := cleanstack.NewCleanStack()
+		none := bootloader.NewNone(s)
+		b, err := bootloader.MountMirrors(s, cleanup, none)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b).To(Equal(none))
+
+		b, err = bootloader.MountMirrors(s, cleanup, none, "5c1f9a2e-0b7d-4f3a-9e6c-1d2b3a4c5d6e")
+		Expect(err).NotTo(HaveOccurred())
+		mounts, err := mounter.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mounts).To(HaveLen(1))
+		Expect(mounts[0].Device).To(Equal("/dev/mirror1"))
+
+		Expect(b.SetBootCounter("/boot/efi", 0, "")).To(Succeed())
+		Expect(runner.IncludesCmds([][]string{
+			{"rsync", "--archive", "--recursive", "--no-links", "--delete"},
+		})).To(Succeed())
+
+		Expect(cleanup.Cleanup(nil)).To(Succeed())
+		Expect(mounter.List()).To(BeEmpty())
+	})
 })
@@ -30,28 +30,24 @@ import (
 
 	"github.com/joho/godotenv"
 
+	"github.com/suse/elemental/v3/pkg/bootcount"
 	"github.com/suse/elemental/v3/pkg/rsync"
+	"github.com/suse/elemental/v3/pkg/secureboot"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/platform"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
 )
 
 type Grub struct {
-	s *sys.System
-}
-
-type grubBootEntry struct {
-	Linux       string
-	Initrd      string
-	CmdLine     string
-	DisplayName string
-	ID          string
+	s      *sys.System
+	signer *secureboot.Signer
+	liveID string
 }
 
 type Option func(*Grub)
 
 func NewGrub(s *sys.System, opts ...Option) *Grub {
-	g := &Grub{s}
+	g := &Grub{s: s}
 
 	for _, opt := range opts {
 		opt(g)
@@ -68,6 +64,9 @@ const (
 
 	liveBootPath = "/boot"
 	grubEnvFile  = "grubenv"
+
+	bootTriesVar    = "boot_tries_left"
+	bootFallbackVar = "boot_fallback"
 )
 
 //go:embed grubtemplates/grub.cfg
@@ -88,7 +87,7 @@ func (g *Grub) InstallLive(rootPath, target, kernelCmdLine string) error {
 		return fmt.Errorf("installing grub config: %w", err)
 	}
 
-	entry, err := g.installKernelInitrd(rootPath, target, liveBootPath)
+	entry, err := installKernelInitrd(g.s, rootPath, target, liveBootPath)
 	if err != nil {
 		return fmt.Errorf("installing kernel+initrd: %w", err)
 	}
@@ -118,6 +117,11 @@ func (g *Grub) InstallLive(rootPath, target, kernelCmdLine string) error {
 		return fmt.Errorf("installing elemental EFI apps: %w", err)
 	}
 
+	err = sign(g.signer, append(g.grubBinaries(efiEntryDir), filepath.Join(target, entry.Linux))...)
+	if err != nil {
+		return fmt.Errorf("signing boot artifacts: %w", err)
+	}
+
 	return nil
 }
 
@@ -133,7 +137,7 @@ func (g *Grub) Install(rootPath, espDir, espLabel, entryID, kernelCmdline, recKe
 		return fmt.Errorf("installing grub config: %w", err)
 	}
 
-	entry, err := g.installKernelInitrd(rootPath, espDir, "")
+	entry, err := installKernelInitrd(g.s, rootPath, espDir, "")
 	if err != nil {
 		return fmt.Errorf("installing kernel+initrd: %w", err)
 	}
@@ -141,11 +145,11 @@ func (g *Grub) Install(rootPath, espDir, espLabel, entryID, kernelCmdline, recKe
 	displayName := entry.DisplayName
 	entry.ID = entryID
 	entry.CmdLine = kernelCmdline
-	entries := []*grubBootEntry{&entry}
+	entries := []*bootEntry{&entry}
 
 	// append default entry
 	entry.DisplayName = fmt.Sprintf("%s (%s)", displayName, entryID)
-	defaultEntry := grubBootEntry{
+	defaultEntry := bootEntry{
 		Linux:       entry.Linux,
 		Initrd:      entry.Initrd,
 		DisplayName: displayName,
@@ -155,7 +159,7 @@ func (g *Grub) Install(rootPath, espDir, espLabel, entryID, kernelCmdline, recKe
 	entries = append(entries, &defaultEntry)
 
 	if recKernelCmdline != "" {
-		recoveryEntry := grubBootEntry{
+		recoveryEntry := bootEntry{
 			Linux:       entry.Linux,
 			Initrd:      entry.Initrd,
 			DisplayName: fmt.Sprintf("%s (%s)", displayName, RecoveryBootID),
@@ -170,6 +174,17 @@ func (g *Grub) Install(rootPath, espDir, espLabel, entryID, kernelCmdline, recKe
 		return fmt.Errorf("updating boot entries: %w", err)
 	}
 
+	if g.signer != nil {
+		artifacts := []string{filepath.Join(espDir, entry.Linux)}
+		for _, efiEntry := range []string{"BOOT", "ELEMENTAL"} {
+			artifacts = append(artifacts, g.grubBinaries(filepath.Join(espDir, "EFI", efiEntry))...)
+		}
+		err = sign(g.signer, artifacts...)
+		if err != nil {
+			return fmt.Errorf("signing boot artifacts: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -240,66 +255,117 @@ func (g Grub) Prune(rootPath, espDir string, keepSnapshotIDs []int) (err error)
 	return g.pruneOldKernels(rootPath, espDir, activeEntries)
 }
 
-func (g Grub) pruneOldKernels(rootPath, espDir string, activeEntries []string) error {
-	activeKernels := map[string]bool{}
+// SetDefault overwrites the default boot entry with the kernel, initrd and kernel command line
+// of the boot entry identified by the given entryID.
+func (g Grub) SetDefault(espDir, entryID string) error {
+	g.s.Logger().Info("Setting boot entry '%s' as default", entryID)
 
-	for _, entry := range activeEntries {
-		grubEnv := filepath.Join(espDir, "loader", "entries", entry)
-		vars, err := g.readGrubEnv(grubEnv)
+	entryPath := filepath.Join(espDir, "loader", "entries", entryID)
+	if ok, _ := vfs.Exists(g.s.FS(), entryPath); !ok {
+		return fmt.Errorf("boot entry '%s' not found", entryID)
+	}
+
+	vars, err := g.readGrubEnv(entryPath)
+	if err != nil {
+		return fmt.Errorf("reading boot entry '%s': %w", entryID, err)
+	}
+
+	defaultEntry := &bootEntry{
+		Linux:       vars["linux"],
+		Initrd:      vars["initrd"],
+		CmdLine:     vars["cmdline"],
+		DisplayName: strings.TrimSuffix(vars["display_name"], fmt.Sprintf(" (%s)", entryID)),
+		ID:          DefaultBootID,
+	}
+
+	err = g.writeBootEntry(espDir, defaultEntry)
+	if err != nil {
+		return fmt.Errorf("writing default boot entry: %w", err)
+	}
+	return nil
+}
+
+// SetBootCounter enables boot counting for the default boot entry. After the given number of
+// unsuccessful boots the boot entry identified by fallbackID is booted instead. Setting 0 tries
+// disables boot counting, which is how a boot is marked as successful.
+func (g Grub) SetBootCounter(espDir string, tries int, fallbackID string) error {
+	grubEnvPath := filepath.Join(espDir, grubEnvFile)
+
+	if tries == 0 {
+		g.s.Logger().Info("Disabling boot counting")
+		stdOut, err := g.s.Runner().Run("grub2-editenv", grubEnvPath, "unset", bootTriesVar, bootFallbackVar)
+		g.s.Logger().Debug("grub2-editenv stdout: %s", string(stdOut))
 		if err != nil {
-			return fmt.Errorf("failed reading grubenv '%s': %w", grubEnv, err)
+			return fmt.Errorf("failed saving %s: %w", grubEnvPath, err)
 		}
+		return nil
+	}
+
+	if err := bootcount.Validate(tries); err != nil {
+		return err
+	}
 
-		linux := vars["linux"]
-		linuxDir, _ := filepath.Split(linux)
-		version := filepath.Base(linuxDir)
+	if ok, _ := vfs.Exists(g.s.FS(), filepath.Join(espDir, "loader", "entries", fallbackID)); !ok {
+		return fmt.Errorf("boot entry '%s' not found", fallbackID)
+	}
 
-		activeKernels[version] = true
+	g.s.Logger().Info("Enabling boot counting with %d tries, falling back to boot entry '%s'", tries, fallbackID)
+	stdOut, err := g.s.Runner().Run(
+		"grub2-editenv", grubEnvPath, "set",
+		fmt.Sprintf("%s=%d", bootTriesVar, tries), fmt.Sprintf("%s=%s", bootFallbackVar, fallbackID),
+	)
+	g.s.Logger().Debug("grub2-editenv stdout: %s", string(stdOut))
+	if err != nil {
+		return fmt.Errorf("failed saving %s: %w", grubEnvPath, err)
 	}
+	return nil
+}
 
-	osVars, err := vfs.LoadEnvFile(g.s.FS(), filepath.Join(rootPath, OsReleasePath))
+// GetBootCounter returns the current boot counting state, returns nil if boot counting is disabled.
+func (g Grub) GetBootCounter(espDir string) (*BootCounter, error) {
+	grubEnv, err := g.readGrubEnv(filepath.Join(espDir, grubEnvFile))
 	if err != nil {
-		return fmt.Errorf("loading %s vars: %w", OsReleasePath, err)
+		return nil, err
 	}
 
-	var (
-		ok   bool
-		osID string
-	)
-	if osID, ok = osVars["ID"]; !ok {
-		return fmt.Errorf("%s ID not set", OsReleasePath)
+	tries := grubEnv[bootTriesVar]
+	if tries == "" {
+		return nil, nil
 	}
 
-	// look for older kernels
-	kernelDir := filepath.Join(espDir, osID)
-	kernelDirs, err := g.s.FS().ReadDir(kernelDir)
+	triesLeft, err := strconv.Atoi(tries)
 	if err != nil {
-		return fmt.Errorf("reading sub-directories: %w", err)
+		return nil, fmt.Errorf("parsing boot tries '%s': %w", tries, err)
 	}
 
-	for _, dirEntry := range kernelDirs {
-		if !dirEntry.IsDir() {
-			continue
-		}
+	return &BootCounter{TriesLeft: triesLeft, FallbackID: grubEnv[bootFallbackVar]}, nil
+}
 
-		if _, ok := activeKernels[dirEntry.Name()]; !ok {
-			path := filepath.Join(kernelDir, dirEntry.Name())
-			err := g.s.FS().RemoveAll(path)
-			if err != nil {
-				return fmt.Errorf("failed removing old kernel '%s': %w", path, err)
-			}
+func (g Grub) pruneOldKernels(rootPath, espDir string, activeEntries []string) error {
+	activeKernels := map[string]bool{}
+
+	for _, entry := range activeEntries {
+		grubEnv := filepath.Join(espDir, "loader", "entries", entry)
+		vars, err := g.readGrubEnv(grubEnv)
+		if err != nil {
+			return fmt.Errorf("failed reading grubenv '%s': %w", grubEnv, err)
 		}
+
+		activeKernels[kernelVersion(vars["linux"])] = true
 	}
 
-	return nil
+	return pruneKernels(g.s, rootPath, espDir, activeKernels)
 }
 
 func (g Grub) generateIDFile(targetDir string) (string, error) {
-	bytes := make([]byte, 4)
-	if _, err := rand.Read(bytes); err != nil {
-		return "", fmt.Errorf("failed generating random boot identifier: %w", err)
+	randomID := g.liveID
+	if randomID == "" {
+		bytes := make([]byte, 4)
+		if _, err := rand.Read(bytes); err != nil {
+			return "", fmt.Errorf("failed generating random boot identifier: %w", err)
+		}
+		randomID = hex.EncodeToString(bytes)
 	}
-	randomID := hex.EncodeToString(bytes)
 
 	idFile := filepath.Join(targetDir, randomID)
 	err := g.s.FS().WriteFile(idFile, []byte(randomID), vfs.FilePerm)
@@ -384,6 +450,16 @@ func (g *Grub) installEFIEntry(rootPath, targetDir string, grubTmpl []byte, data
 	return nil
 }
 
+// grubBinaries returns the paths of the grub EFI applications within the given EFI entry directory,
+// shim and MokManager are excluded as they are signed by the distribution vendor.
+func (g Grub) grubBinaries(efiEntryDir string) []string {
+	binaries := []string{filepath.Join(efiEntryDir, "grub.efi")}
+	if src, target := defaultEfiBootFileName(g.s.Platform()); src == "grub.efi" {
+		binaries = append(binaries, filepath.Join(efiEntryDir, target))
+	}
+	return binaries
+}
+
 func grubArch(arch string) string {
 	switch arch {
 	case platform.ArchArm64:
@@ -445,91 +521,6 @@ func (g *Grub) installGrub(rootPath, espDir string) error {
 	return nil
 }
 
-// readIDAndName parses OS ID and OS name from os-relese file. Returns error of no OS ID is found.
-func (g *Grub) readIDAndName(rootPath string) (osID string, displayName string, err error) {
-	g.s.Logger().Info("Reading OS Release")
-
-	osVars, err := vfs.LoadEnvFile(g.s.FS(), filepath.Join(rootPath, OsReleasePath))
-	if err != nil {
-		return "", "", fmt.Errorf("loading %s vars: %w", OsReleasePath, err)
-	}
-
-	var ok bool
-	if osID, ok = osVars["ID"]; !ok {
-		return "", "", fmt.Errorf("%s ID not set", OsReleasePath)
-	}
-
-	displayName, ok = osVars["PRETTY_NAME"]
-	if !ok {
-		displayName, ok = osVars["VARIANT"]
-		if !ok {
-			displayName = osVars["NAME"]
-		}
-	}
-	return osID, displayName, nil
-}
-
-// installKernelInitrd copies the kernel and initrd to the given ESP path.
-//
-// This function takes a rootPath to find and copy kernel and initrd from there. The espDir parameter
-// is the target path where artifacts will be copied to. The subfolder specifies the location under espDir
-// where artifacts will be copied (mostly used on live images to specify a "boot" folder). The snapshotID parameter
-// is an identifier of the non default generated grubBootEntry. Finally kernelCmdline provides the kernel arguments
-// for the generated grubBootEntries.
-//
-// Returns a grubBootEntry list with two items, one defined as a default entry and another one identified with the provided ID.
-func (g *Grub) installKernelInitrd(rootPath, espDir, subfolder string) (grubBootEntry, error) {
-	g.s.Logger().Info("Installing kernel/initrd")
-	entry := grubBootEntry{}
-
-	osID, displayName, err := g.readIDAndName(rootPath)
-	if err != nil {
-		return entry, fmt.Errorf("failed parsing OS release: %w", err)
-	}
-
-	kernel, kernelVersion, err := vfs.FindKernel(g.s.FS(), rootPath)
-	if err != nil {
-		return entry, fmt.Errorf("finding kernel: %w", err)
-	}
-
-	targetDir := filepath.Join(espDir, subfolder, osID, kernelVersion)
-	err = vfs.MkdirAll(g.s.FS(), targetDir, vfs.DirPerm)
-	if err != nil {
-		return entry, fmt.Errorf("creating kernel dir '%s': %w", targetDir, err)
-	}
-
-	err = vfs.CopyFile(g.s.FS(), kernel, targetDir)
-	if err != nil {
-		return entry, fmt.Errorf("copying kernel '%s': %w", kernel, err)
-	}
-
-	// Copy kernel .hmac in order to enable FIPS.
-	kernelHmac, err := vfs.FindKernelHmac(g.s.FS(), kernel)
-	if err != nil {
-		return entry, fmt.Errorf("finding kernel hmac '%s': %w", kernel, err)
-	}
-
-	err = vfs.CopyFile(g.s.FS(), kernelHmac, targetDir)
-	if err != nil {
-		return entry, fmt.Errorf("copying kernel hmac '%s': %w", kernelHmac, err)
-	}
-
-	initrdPath := filepath.Join(filepath.Dir(kernel), Initrd)
-	if exists, _ := vfs.Exists(g.s.FS(), initrdPath); !exists {
-		return entry, fmt.Errorf("initrd not found")
-	}
-
-	err = vfs.CopyFile(g.s.FS(), initrdPath, targetDir)
-	if err != nil {
-		return entry, fmt.Errorf("copying initrd '%s': %w", initrdPath, err)
-	}
-	entry.Linux = filepath.Join("/", subfolder, osID, kernelVersion, filepath.Base(kernel))
-	entry.Initrd = filepath.Join("/", subfolder, osID, kernelVersion, Initrd)
-	entry.DisplayName = displayName
-
-	return entry, nil
-}
-
 func (g *Grub) readGrubEnv(path string) (map[string]string, error) {
 	stdOut, err := g.s.Runner().Run("grub2-editenv", path, "list")
 	if err != nil {
@@ -544,7 +535,7 @@ func (g *Grub) readGrubEnv(path string) (map[string]string, error) {
 	return val, nil
 }
 
-func (g *Grub) updateBootEntries(espDir string, newEntries ...*grubBootEntry) error {
+func (g *Grub) updateBootEntries(espDir string, newEntries ...*bootEntry) error {
 	grubEnvPath := filepath.Join(espDir, grubEnvFile)
 	activeEntries := []string{}
 	hasRecovery := false
@@ -610,7 +601,7 @@ func (g *Grub) updateBootEntries(espDir string, newEntries ...*grubBootEntry) er
 	return err
 }
 
-func (g Grub) writeBootEntry(espDir string, entry *grubBootEntry) error {
+func (g Grub) writeBootEntry(espDir string, entry *bootEntry) error {
 	displayName := fmt.Sprintf("display_name=%s", entry.DisplayName)
 	linux := fmt.Sprintf("linux=%s", entry.Linux)
 	initrd := fmt.Sprintf("initrd=%s", entry.Initrd)
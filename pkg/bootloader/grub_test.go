@@ -28,6 +28,7 @@ import (
 
 	"github.com/suse/elemental/v3/pkg/bootloader"
 	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/secureboot"
 	"github.com/suse/elemental/v3/pkg/sys"
 	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
@@ -150,6 +151,52 @@ var _ = Describe("Grub tests", Label("bootloader", "grub"), func() {
 		Expect(vfs.Exists(tfs, "/iso/dir/EFI/BOOT/grub.cfg")).To(BeTrue())
 		Expect(vfs.Exists(tfs, "/iso/dir/boot/grub2/grub.cfg")).To(BeTrue())
 	})
+	It("Installs grub for LiveOS image with the given live identifier", func() {
+		b, err := bootloader.New(bootloader.BootGrub, s, bootloader.WithLiveID("0a1b2c3d"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b.InstallLive("/target/dir", "/iso/dir", "kernel cmdline")).To(Succeed())
+
+		data, err := tfs.ReadFile("/iso/dir/boot/0a1b2c3d")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("0a1b2c3d"))
+		data, err = tfs.ReadFile("/iso/dir/EFI/BOOT/grub.cfg")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("/boot/0a1b2c3d"))
+	})
+	It("Signs grub and the kernel with custom Secure Boot keys", func() {
+		signed := map[string]bool{}
+		sideEffect := runner.SideEffect
+		runner.SideEffect = func(command string, args ...string) ([]byte, error) {
+			switch command {
+			case "sbverify":
+				if !signed[args[len(args)-1]] {
+					return nil, fmt.Errorf("no signature table present")
+				}
+				return nil, nil
+			case "sbsign":
+				signed[args[len(args)-1]] = true
+				return nil, nil
+			}
+			return sideEffect(command, args...)
+		}
+
+		signer := secureboot.NewSigner(s, secureboot.Config{Key: "/keys/db.key", Cert: "/keys/db.crt"})
+		b, err := bootloader.New(bootloader.BootGrub, s, bootloader.WithSigner(signer))
+		Expect(err).ToNot(HaveOccurred())
+
+		err = b.Install("/target/dir", "/target/dir/boot", "EFI", "1", "kernel cmdline", "")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(runner.IncludesCmds([][]string{
+			{"sbsign", "--key", "/keys/db.key", "--cert", "/keys/db.crt", "--output", "/target/dir/boot/opensuse-tumbleweed/6.14.4-1-default/vmlinuz", "/target/dir/boot/opensuse-tumbleweed/6.14.4-1-default/vmlinuz"},
+			{"sbsign", "--key", "/keys/db.key", "--cert", "/keys/db.crt", "--output", "/target/dir/boot/EFI/BOOT/grub.efi", "/target/dir/boot/EFI/BOOT/grub.efi"},
+			{"sbsign", "--key", "/keys/db.key", "--cert", "/keys/db.crt", "--output", "/target/dir/boot/EFI/ELEMENTAL/grub.efi", "/target/dir/boot/EFI/ELEMENTAL/grub.efi"},
+		})).To(Succeed())
+		// shim is signed by the vendor
+		for _, cmd := range runner.GetCmds() {
+			Expect(cmd).NotTo(ContainElement(ContainSubstring("bootx64.efi")))
+		}
+	})
 	It("Fails with an error if initrd is not found", func() {
 		// Remove initrd
 		err := tfs.Remove("/target/dir/usr/lib/modules/6.14.4-1-default/initrd")
@@ -191,6 +238,67 @@ var _ = Describe("Grub tests", Label("bootloader", "grub"), func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(string(entries)).To(Equal("entries=active 2 1 recovery"))
 	})
+	It("Sets a previous snapshot entry as the 'active' entry", func() {
+		err := grub.Install("/target/dir", "/target/dir/boot", "EFI", "1", "snapshot1", "recovery cmdline")
+		Expect(err).ToNot(HaveOccurred())
+
+		err = grub.Install("/target/dir", "/target/dir/boot", "EFI", "2", "snapshot2", "recovery cmdline")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(grub.SetDefault("/target/dir/boot", "1")).To(Succeed())
+
+		// 'active' entry should point to snapshot 1
+		activeEntry, err := tfs.ReadFile("/target/dir/boot/loader/entries/active")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.SplitSeq(string(activeEntry), "\n")).To(ContainElement("cmdline=snapshot1"))
+		Expect(strings.SplitSeq(string(activeEntry), "\n")).To(ContainElement("display_name=openSUSE Tumbleweed"))
+
+		// entries list is not modified
+		entries, err := tfs.ReadFile("/target/dir/boot/grubenv")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(entries)).To(Equal("entries=active 2 1 recovery"))
+	})
+	It("Fails to set a default entry for an unknown snapshot", func() {
+		err := grub.Install("/target/dir", "/target/dir/boot", "EFI", "1", "snapshot1", "")
+		Expect(err).ToNot(HaveOccurred())
+
+		err = grub.SetDefault("/target/dir/boot", "5")
+		Expect(err).To(HaveOccurred())
+		Expect(err).To(MatchError("boot entry '5' not found"))
+	})
+	It("Enables boot counting with a fallback entry", func() {
+		err := grub.Install("/target/dir", "/target/dir/boot", "EFI", "1", "snapshot1", "")
+		Expect(err).ToNot(HaveOccurred())
+		err = grub.Install("/target/dir", "/target/dir/boot", "EFI", "2", "snapshot2", "")
+		Expect(err).ToNot(HaveOccurred())
+
+		counter, err := grub.GetBootCounter("/target/dir/boot")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(counter).To(BeNil())
+
+		Expect(grub.SetBootCounter("/target/dir/boot", 3, "1")).To(Succeed())
+
+		counter, err = grub.GetBootCounter("/target/dir/boot")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*counter).To(Equal(bootloader.BootCounter{TriesLeft: 3, FallbackID: "1"}))
+		Expect(counter.IsExhausted()).To(BeFalse())
+	})
+	It("Disables boot counting", func() {
+		Expect(grub.SetBootCounter("/target/dir/boot", 0, "")).To(Succeed())
+		Expect(runner.IncludesCmds([][]string{
+			{"grub2-editenv", "/target/dir/boot/grubenv", "unset", "boot_tries_left", "boot_fallback"},
+		})).To(Succeed())
+	})
+	It("Fails to enable boot counting with invalid parameters", func() {
+		err := grub.Install("/target/dir", "/target/dir/boot", "EFI", "1", "snapshot1", "")
+		Expect(err).ToNot(HaveOccurred())
+
+		err = grub.SetBootCounter("/target/dir/boot", 10, "1")
+		Expect(err).To(MatchError("invalid number of boot tries 10, must be between 0 and 9"))
+
+		err = grub.SetBootCounter("/target/dir/boot", 3, "5")
+		Expect(err).To(MatchError("boot entry '5' not found"))
+	})
 	It("Prunes old snapshots", func() {
 		// "Install" older (6.6.99) kernel
 		Expect(vfs.MkdirAll(tfs, "/target/dir/boot/opensuse-tumbleweed/6.6.99-1-default", vfs.DirPerm)).To(Succeed())
@@ -0,0 +1,117 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootloader
+
+import (
+	"fmt"
+
+	"github.com/suse/elemental/v3/pkg/block"
+	"github.com/suse/elemental/v3/pkg/block/lsblk"
+	"github.com/suse/elemental/v3/pkg/cleanstack"
+	"github.com/suse/elemental/v3/pkg/rsync"
+	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+// Mirrored wraps a bootloader to keep mirrored ESPs in sync with the ESP the wrapped
+// bootloader writes to. Mirrors are synced after each change of the ESP content.
+type Mirrored struct {
+	Bootloader
+	s       *sys.System
+	mirrors []string
+}
+
+// NewMirrored returns the given bootloader wrapped to sync the ESP to the given mirror directories.
+// The given bootloader is returned as is if there are no mirrors.
+func NewMirrored(s *sys.System, b Bootloader, mirrorDirs ...string) Bootloader {
+	if len(mirrorDirs) == 0 {
+		return b
+	}
+	return &Mirrored{Bootloader: b, s: s, mirrors: mirrorDirs}
+}
+
+// MountMirrors mounts the ESP mirror partitions of the given partition UUIDs and returns the given
+// bootloader wrapped to keep them in sync. Unmounting the mirrors is added to the given cleanup stack.
+func MountMirrors(s *sys.System, cleanup *cleanstack.CleanStack, b Bootloader, partUUIDs ...string) (Bootloader, error) {
+	var mirrorDirs []string
+	bDev := lsblk.NewLsDevice(s)
+	for _, uuid := range partUUIDs {
+		bPart, err := block.GetPartitionByUUID(s, bDev, uuid, 4)
+		if err != nil {
+			return nil, fmt.Errorf("finding partition '%s': %w", uuid, err)
+		}
+		mountPoint, err := vfs.TempDir(s.FS(), "", "elemental_efi")
+		if err != nil {
+			return nil, fmt.Errorf("creating temporary directory to mount ESP mirror: %w", err)
+		}
+		cleanup.PushSuccessOnly(func() error { return s.FS().RemoveAll(mountPoint) })
+		err = s.Mounter().Mount(bPart.Path, mountPoint, "", []string{"rw"})
+		if err != nil {
+			return nil, fmt.Errorf("mounting partition '%s': %w", bPart.Path, err)
+		}
+		cleanup.Push(func() error { return s.Mounter().Unmount(mountPoint) })
+		mirrorDirs = append(mirrorDirs, mountPoint)
+	}
+	return NewMirrored(s, b, mirrorDirs...), nil
+}
+
+func (m *Mirrored) Install(rootPath, espDir, espLabel, entryID, kernelCmdline, recKernelCmdline string) error {
+	err := m.Bootloader.Install(rootPath, espDir, espLabel, entryID, kernelCmdline, recKernelCmdline)
+	if err != nil {
+		return err
+	}
+	return m.sync(espDir)
+}
+
+func (m *Mirrored) Prune(rootPath, espDir string, keepEntryIDs []int) error {
+	err := m.Bootloader.Prune(rootPath, espDir, keepEntryIDs)
+	if err != nil {
+		return err
+	}
+	return m.sync(espDir)
+}
+
+func (m *Mirrored) SetDefault(espDir, entryID string) error {
+	err := m.Bootloader.SetDefault(espDir, entryID)
+	if err != nil {
+		return err
+	}
+	return m.sync(espDir)
+}
+
+func (m *Mirrored) SetBootCounter(espDir string, tries int, fallbackID string) error {
+	err := m.Bootloader.SetBootCounter(espDir, tries, fallbackID)
+	if err != nil {
+		return err
+	}
+	return m.sync(espDir)
+}
+
+// sync mirrors the given ESP directory to all the mirror directories
+func (m *Mirrored) sync(espDir string) error {
+	// Since we are copying to a vfat filesystem we have to skip symlinks.
+	r := rsync.NewRsync(m.s, rsync.WithFlags("--archive", "--recursive", "--no-links"))
+	for _, mirror := range m.mirrors {
+		m.s.Logger().Info("Syncing ESP to mirror '%s'", mirror)
+		err := r.MirrorData(espDir, mirror, nil, nil)
+		if err != nil {
+			return fmt.Errorf("syncing ESP to mirror '%s': %w", mirror, err)
+		}
+	}
+	return nil
+}
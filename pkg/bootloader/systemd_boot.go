@@ -0,0 +1,589 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootloader
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/suse/elemental/v3/pkg/bootcount"
+	"github.com/suse/elemental/v3/pkg/secureboot"
+	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/sys/platform"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+const (
+	loaderConfFile = "loader.conf"
+	entryExt       = ".conf"
+
+	// sort keys defining the boot menu order, systemd-boot boots the first entry by default
+	activeSortKey   = "elemental-0-active"
+	fallbackSortKey = "elemental-1-fallback"
+	snapshotSortKey = "elemental-2-snapshot"
+	recoverySortKey = "elemental-3-recovery"
+
+	liveBootID = "live"
+
+	ukiDir = "EFI/Linux"
+	ukiExt = ".efi"
+)
+
+// SystemdBoot installs systemd-boot and describes the boot entries following the
+// Boot Loader Specification (BLS) type #1 entries format. Entries either boot a kernel and
+// initrd pair or, in UKI mode, a Unified Kernel Image built per snapshot.
+type SystemdBoot struct {
+	s      *sys.System
+	uki    bool
+	signer *secureboot.Signer
+}
+
+func NewSystemdBoot(s *sys.System, opts ...Opt) *SystemdBoot {
+	o := &Options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &SystemdBoot{s: s, uki: o.UKI, signer: o.Signer}
+}
+
+// InstallLive installs systemd-boot, kernel and initrd and a single live boot entry to the specified target.
+func (b *SystemdBoot) InstallLive(rootPath, target, kernelCmdLine string) error {
+	b.s.Logger().Info("Preparing systemd-boot bootloader for live media")
+
+	err := b.installEFI(rootPath, filepath.Join(target, "EFI", "BOOT"))
+	if err != nil {
+		return fmt.Errorf("installing systemd-boot EFI apps: %w", err)
+	}
+
+	entry, err := b.installKernel(rootPath, target, liveBootPath, liveBootID, kernelCmdLine)
+	if err != nil {
+		return err
+	}
+
+	err = b.writeLoaderConf(target, 5)
+	if err != nil {
+		return fmt.Errorf("writing loader config: %w", err)
+	}
+
+	err = b.writeBootEntry(b.entryPath(target, liveBootID), &entry, activeSortKey)
+	if err != nil {
+		return fmt.Errorf("writing live boot entry: %w", err)
+	}
+
+	_, efiApp := defaultEfiBootFileName(b.s.Platform())
+	err = sign(b.signer, filepath.Join(target, "EFI", "BOOT", efiApp), entryBinary(target, &entry))
+	if err != nil {
+		return fmt.Errorf("signing boot artifacts: %w", err)
+	}
+
+	return nil
+}
+
+// Install installs systemd-boot, kernel and initrd and the boot entries of the given entryID to the ESP.
+func (b *SystemdBoot) Install(rootPath, espDir, _, entryID, kernelCmdline, recKernelCmdline string) error {
+	for _, efiEntry := range []string{"BOOT", "ELEMENTAL"} {
+		err := b.installEFI(rootPath, filepath.Join(espDir, "EFI", efiEntry))
+		if err != nil {
+			return fmt.Errorf("installing systemd-boot EFI apps: %w", err)
+		}
+	}
+
+	entry, err := b.installKernel(rootPath, espDir, "", entryID, kernelCmdline)
+	if err != nil {
+		return err
+	}
+
+	if ok, _ := vfs.Exists(b.s.FS(), filepath.Join(espDir, "loader", loaderConfFile)); !ok {
+		err = b.writeLoaderConf(espDir, 10)
+		if err != nil {
+			return fmt.Errorf("writing loader config: %w", err)
+		}
+	}
+
+	displayName := entry.DisplayName
+	entry.DisplayName = fmt.Sprintf("%s (%s)", displayName, entryID)
+
+	err = b.writeBootEntry(b.entryPath(espDir, entryID), &entry, snapshotSortKey)
+	if err != nil {
+		return fmt.Errorf("writing boot entry '%s': %w", entryID, err)
+	}
+
+	defaultEntry := entry
+	defaultEntry.DisplayName = displayName
+	defaultEntry.ID = DefaultBootID
+	err = b.writeDefaultEntry(espDir, &defaultEntry)
+	if err != nil {
+		return fmt.Errorf("writing default boot entry: %w", err)
+	}
+
+	_, efiApp := defaultEfiBootFileName(b.s.Platform())
+	artifacts := []string{
+		filepath.Join(espDir, "EFI", "BOOT", efiApp), filepath.Join(espDir, "EFI", "ELEMENTAL", efiApp),
+		entryBinary(espDir, &entry),
+	}
+
+	// do not update recovery entry if already exists
+	if recKernelCmdline != "" {
+		if _, err = b.findEntry(espDir, RecoveryBootID); err != nil {
+			recoveryEntry := entry
+			recoveryEntry.CmdLine = recKernelCmdline
+			recoveryEntry.ID = RecoveryBootID
+			if b.uki {
+				// the kernel command line is part of the UKI, thus recovery requires its own image
+				recoveryEntry, err = b.installKernel(rootPath, espDir, "", RecoveryBootID, recKernelCmdline)
+				if err != nil {
+					return err
+				}
+			}
+			recoveryEntry.DisplayName = fmt.Sprintf("%s (%s)", displayName, RecoveryBootID)
+			err = b.writeBootEntry(b.entryPath(espDir, RecoveryBootID), &recoveryEntry, recoverySortKey)
+			if err != nil {
+				return fmt.Errorf("writing recovery boot entry: %w", err)
+			}
+			artifacts = append(artifacts, entryBinary(espDir, &recoveryEntry))
+		}
+	}
+
+	err = sign(b.signer, artifacts...)
+	if err != nil {
+		return fmt.Errorf("signing boot artifacts: %w", err)
+	}
+
+	return nil
+}
+
+// Prune prunes old boot entries and artifacts not in the passed in keepSnapshotIDs.
+func (b SystemdBoot) Prune(rootPath, espDir string, keepSnapshotIDs []int) error {
+	b.s.Logger().Info("Pruning old boot artifacts in %s", espDir)
+
+	entries, err := b.listEntries(espDir)
+	if err != nil {
+		return fmt.Errorf("listing boot entries: %w", err)
+	}
+
+	activeKernels := map[string]bool{}
+	activeUKIs := map[string]bool{}
+	for id, path := range entries {
+		if snapshotID, err := strconv.Atoi(id); err == nil && !slices.Contains(keepSnapshotIDs, snapshotID) {
+			err = b.s.FS().Remove(path)
+			if err != nil {
+				b.s.Logger().Warn("failed removing '%s'", path)
+				return err
+			}
+			continue
+		}
+
+		vars, err := b.readBootEntry(path)
+		if err != nil {
+			return fmt.Errorf("reading boot entry '%s': %w", id, err)
+		}
+		if vars["efi"] != "" {
+			activeUKIs[filepath.Base(vars["efi"])] = true
+			continue
+		}
+		activeKernels[kernelVersion(vars["linux"])] = true
+	}
+
+	if b.uki {
+		return b.pruneUKIs(rootPath, espDir, activeUKIs)
+	}
+	return pruneKernels(b.s, rootPath, espDir, activeKernels)
+}
+
+// SetDefault overwrites the default boot entry with the kernel, initrd and kernel command line
+// of the boot entry identified by the given entryID.
+func (b SystemdBoot) SetDefault(espDir, entryID string) error {
+	b.s.Logger().Info("Setting boot entry '%s' as default", entryID)
+
+	entryPath, err := b.findEntry(espDir, entryID)
+	if err != nil {
+		return err
+	}
+
+	vars, err := b.readBootEntry(entryPath)
+	if err != nil {
+		return fmt.Errorf("reading boot entry '%s': %w", entryID, err)
+	}
+
+	defaultEntry := &bootEntry{
+		Linux:       vars["linux"],
+		Initrd:      vars["initrd"],
+		CmdLine:     vars["options"],
+		EFI:         vars["efi"],
+		DisplayName: strings.TrimSuffix(vars["title"], fmt.Sprintf(" (%s)", entryID)),
+		ID:          DefaultBootID,
+	}
+
+	err = b.writeDefaultEntry(espDir, defaultEntry)
+	if err != nil {
+		return fmt.Errorf("writing default boot entry: %w", err)
+	}
+	return nil
+}
+
+// SetBootCounter enables boot counting for the default boot entry by using the systemd-boot
+// native boot counting, the counter is part of the default entry file name. After the given number
+// of unsuccessful boots the boot entry identified by fallbackID, which is sorted right after the
+// default entry, is booted instead. Setting 0 tries disables boot counting, which is how a boot is
+// marked as successful.
+func (b SystemdBoot) SetBootCounter(espDir string, tries int, fallbackID string) error {
+	if err := bootcount.Validate(tries); err != nil {
+		return err
+	}
+
+	activePath, err := b.findEntry(espDir, DefaultBootID)
+	if err != nil {
+		return err
+	}
+
+	entries, err := b.listEntries(espDir)
+	if err != nil {
+		return fmt.Errorf("listing boot entries: %w", err)
+	}
+
+	var fallbackPath string
+	if tries > 0 {
+		var ok bool
+		if fallbackPath, ok = entries[fallbackID]; !ok {
+			return fmt.Errorf("boot entry '%s' not found", fallbackID)
+		}
+	}
+
+	// reset any former fallback entry
+	for id, path := range entries {
+		if id == DefaultBootID || id == RecoveryBootID || path == fallbackPath {
+			continue
+		}
+		err = b.setSortKey(path, snapshotSortKey)
+		if err != nil {
+			return fmt.Errorf("updating boot entry '%s': %w", id, err)
+		}
+	}
+
+	target := b.entryPath(espDir, DefaultBootID)
+	if tries == 0 {
+		b.s.Logger().Info("Disabling boot counting")
+	} else {
+		b.s.Logger().Info("Enabling boot counting with %d tries, falling back to boot entry '%s'", tries, fallbackID)
+		err = b.setSortKey(fallbackPath, fallbackSortKey)
+		if err != nil {
+			return fmt.Errorf("updating boot entry '%s': %w", fallbackID, err)
+		}
+		target = filepath.Join(filepath.Dir(target), fmt.Sprintf("%s+%d%s", DefaultBootID, tries, entryExt))
+	}
+
+	if activePath != target {
+		err = b.s.FS().Rename(activePath, target)
+		if err != nil {
+			return fmt.Errorf("renaming default boot entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetBootCounter returns the current boot counting state, returns nil if boot counting is disabled.
+func (b SystemdBoot) GetBootCounter(espDir string) (*BootCounter, error) {
+	activePath, err := b.findEntry(espDir, DefaultBootID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, counter, found := strings.Cut(strings.TrimSuffix(filepath.Base(activePath), entryExt), "+")
+	if !found {
+		return nil, nil
+	}
+
+	// the counter is in the 'left[-done]' format
+	tries, _, _ := strings.Cut(counter, "-")
+	triesLeft, err := strconv.Atoi(tries)
+	if err != nil {
+		return nil, fmt.Errorf("parsing boot tries '%s': %w", tries, err)
+	}
+
+	entries, err := b.listEntries(espDir)
+	if err != nil {
+		return nil, fmt.Errorf("listing boot entries: %w", err)
+	}
+
+	bootCounter := &BootCounter{TriesLeft: triesLeft}
+	for id, path := range entries {
+		vars, err := b.readBootEntry(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading boot entry '%s': %w", id, err)
+		}
+		if vars["sort-key"] == fallbackSortKey {
+			bootCounter.FallbackID = id
+			break
+		}
+	}
+	return bootCounter, nil
+}
+
+// installKernel installs the kernel and initrd, or a UKI in UKI mode, for the given boot entry ID
+// and kernel command line. Returns the boot entry booting them.
+func (b SystemdBoot) installKernel(rootPath, espDir, subfolder, entryID, kernelCmdline string) (bootEntry, error) {
+	if b.uki {
+		entry, err := b.installUKI(rootPath, espDir, entryID, kernelCmdline)
+		if err != nil {
+			return entry, fmt.Errorf("installing UKI: %w", err)
+		}
+		return entry, nil
+	}
+
+	entry, err := installKernelInitrd(b.s, rootPath, espDir, subfolder)
+	if err != nil {
+		return entry, fmt.Errorf("installing kernel+initrd: %w", err)
+	}
+	entry.ID = entryID
+	entry.CmdLine = kernelCmdline
+	return entry, nil
+}
+
+// installUKI builds a Unified Kernel Image including the kernel, initrd, kernel command line and
+// os-release of the given root and installs it to the EFI/Linux/<OS ID> directory of the ESP. UKIs
+// are kept in a sub-directory so systemd-boot does not list them twice as auto-discovered entries.
+func (b SystemdBoot) installUKI(rootPath, espDir, entryID, kernelCmdline string) (bootEntry, error) {
+	b.s.Logger().Info("Building UKI for boot entry '%s'", entryID)
+	entry := bootEntry{}
+
+	osID, displayName, err := readIDAndName(b.s, rootPath)
+	if err != nil {
+		return entry, fmt.Errorf("failed parsing OS release: %w", err)
+	}
+
+	kernel, kernelVersion, err := vfs.FindKernel(b.s.FS(), rootPath)
+	if err != nil {
+		return entry, fmt.Errorf("finding kernel: %w", err)
+	}
+
+	initrdPath := filepath.Join(filepath.Dir(kernel), Initrd)
+	if exists, _ := vfs.Exists(b.s.FS(), initrdPath); !exists {
+		return entry, fmt.Errorf("initrd not found")
+	}
+
+	targetDir := filepath.Join(espDir, ukiDir, osID)
+	err = vfs.MkdirAll(b.s.FS(), targetDir, vfs.DirPerm)
+	if err != nil {
+		return entry, fmt.Errorf("creating UKI dir '%s': %w", targetDir, err)
+	}
+
+	stub := filepath.Join(rootPath, "usr", "lib", "systemd", "boot", "efi", fmt.Sprintf("linux%s.efi.stub", efiArch(b.s.Platform())))
+	uki := filepath.Join(targetDir, entryID+ukiExt)
+	stdOut, err := b.s.Runner().Run(
+		"ukify", "build", "--linux="+kernel, "--initrd="+initrdPath, "--cmdline="+kernelCmdline,
+		"--os-release=@"+filepath.Join(rootPath, OsReleasePath), "--uname="+kernelVersion,
+		"--stub="+stub, "--output="+uki,
+	)
+	b.s.Logger().Debug("ukify stdout: %s", string(stdOut))
+	if err != nil {
+		return entry, fmt.Errorf("building UKI '%s': %w", uki, err)
+	}
+
+	entry.EFI = filepath.Join("/", ukiDir, osID, entryID+ukiExt)
+	entry.CmdLine = kernelCmdline
+	entry.DisplayName = displayName
+	entry.ID = entryID
+	return entry, nil
+}
+
+// pruneUKIs removes all UKIs of the installed OS from the ESP which are not included in the given active UKIs.
+func (b SystemdBoot) pruneUKIs(rootPath, espDir string, activeUKIs map[string]bool) error {
+	osID, _, err := readIDAndName(b.s, rootPath)
+	if err != nil {
+		return fmt.Errorf("failed parsing OS release: %w", err)
+	}
+
+	targetDir := filepath.Join(espDir, ukiDir, osID)
+	files, err := b.s.FS().ReadDir(targetDir)
+	if err != nil {
+		return fmt.Errorf("reading UKI directory: %w", err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ukiExt) || activeUKIs[file.Name()] {
+			continue
+		}
+
+		path := filepath.Join(targetDir, file.Name())
+		err = b.s.FS().Remove(path)
+		if err != nil {
+			return fmt.Errorf("failed removing old UKI '%s': %w", path, err)
+		}
+	}
+	return nil
+}
+
+// installEFI copies the systemd-boot EFI application as the default EFI application of the given directory
+func (b SystemdBoot) installEFI(rootPath, targetDir string) error {
+	b.s.Logger().Info("Copying EFI artifacts at %s", targetDir)
+
+	err := vfs.MkdirAll(b.s.FS(), targetDir, vfs.DirPerm)
+	if err != nil {
+		return fmt.Errorf("creating dir '%s': %w", targetDir, err)
+	}
+
+	src := filepath.Join(rootPath, "usr", "lib", "systemd", "boot", "efi", fmt.Sprintf("systemd-boot%s.efi", efiArch(b.s.Platform())))
+	_, target := defaultEfiBootFileName(b.s.Platform())
+	err = vfs.CopyFile(b.s.FS(), src, filepath.Join(targetDir, target))
+	if err != nil {
+		return fmt.Errorf("copying file '%s': %w", src, err)
+	}
+	return nil
+}
+
+func (b SystemdBoot) writeLoaderConf(espDir string, timeout int) error {
+	loaderDir := filepath.Join(espDir, "loader")
+	err := vfs.MkdirAll(b.s.FS(), loaderDir, vfs.DirPerm)
+	if err != nil {
+		return fmt.Errorf("creating loader dir: %w", err)
+	}
+
+	conf := fmt.Sprintf("timeout %d\neditor no\nauto-entries no\n", timeout)
+	return b.s.FS().WriteFile(filepath.Join(loaderDir, loaderConfFile), []byte(conf), vfs.FilePerm)
+}
+
+// writeDefaultEntry writes the default boot entry keeping its current file name, so a running boot
+// counter is preserved
+func (b SystemdBoot) writeDefaultEntry(espDir string, entry *bootEntry) error {
+	path, err := b.findEntry(espDir, DefaultBootID)
+	if err != nil {
+		path = b.entryPath(espDir, DefaultBootID)
+	}
+	return b.writeBootEntry(path, entry, activeSortKey)
+}
+
+func (b SystemdBoot) writeBootEntry(path string, entry *bootEntry, sortKey string) error {
+	err := vfs.MkdirAll(b.s.FS(), filepath.Dir(path), vfs.DirPerm)
+	if err != nil {
+		return fmt.Errorf("creating loader entries dir: %w", err)
+	}
+
+	lines := []string{
+		fmt.Sprintf("title %s", entry.DisplayName),
+		fmt.Sprintf("version %s", entry.ID),
+		fmt.Sprintf("sort-key %s", sortKey),
+	}
+	if entry.EFI != "" {
+		// the kernel command line is embedded in the UKI
+		lines = append(lines, fmt.Sprintf("efi %s", entry.EFI))
+	} else {
+		lines = append(lines,
+			fmt.Sprintf("linux %s", entry.Linux),
+			fmt.Sprintf("initrd %s", entry.Initrd),
+			fmt.Sprintf("options %s", entry.CmdLine),
+		)
+	}
+	return b.s.FS().WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), vfs.FilePerm)
+}
+
+func (b SystemdBoot) readBootEntry(path string) (map[string]string, error) {
+	data, err := b.s.FS().ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := map[string]string{}
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		vars[key] = strings.TrimSpace(value)
+	}
+	return vars, nil
+}
+
+// setSortKey updates the sort key of the given boot entry file
+func (b SystemdBoot) setSortKey(path, sortKey string) error {
+	data, err := b.s.FS().ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "sort-key ") {
+			lines[i] = fmt.Sprintf("sort-key %s", sortKey)
+		}
+	}
+	return b.s.FS().WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), vfs.FilePerm)
+}
+
+func (b SystemdBoot) entryPath(espDir, entryID string) string {
+	return filepath.Join(espDir, "loader", "entries", entryID+entryExt)
+}
+
+// listEntries returns the boot entry files found in the ESP indexed by their ID. Boot counting
+// suffixes are not part of the ID.
+func (b SystemdBoot) listEntries(espDir string) (map[string]string, error) {
+	entriesDir := filepath.Join(espDir, "loader", "entries")
+	files, err := b.s.FS().ReadDir(entriesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]string{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), entryExt) {
+			continue
+		}
+		id, _, _ := strings.Cut(strings.TrimSuffix(file.Name(), entryExt), "+")
+		entries[id] = filepath.Join(entriesDir, file.Name())
+	}
+	return entries, nil
+}
+
+// findEntry returns the path of the boot entry file of the given ID
+func (b SystemdBoot) findEntry(espDir, entryID string) (string, error) {
+	entries, err := b.listEntries(espDir)
+	if err != nil {
+		return "", fmt.Errorf("boot entry '%s' not found", entryID)
+	}
+
+	path, ok := entries[entryID]
+	if !ok {
+		return "", fmt.Errorf("boot entry '%s' not found", entryID)
+	}
+	return path, nil
+}
+
+// entryBinary returns the path of the EFI binary booted by the given entry, either the UKI or the kernel
+func entryBinary(espDir string, entry *bootEntry) string {
+	if entry.EFI != "" {
+		return filepath.Join(espDir, entry.EFI)
+	}
+	return filepath.Join(espDir, entry.Linux)
+}
+
+// efiArch returns the architecture suffix systemd uses in EFI binary names for the provided
+// platform, defaults to x86_64.
+func efiArch(p *platform.Platform) string {
+	switch p.Arch {
+	case platform.ArchAarch64, platform.ArchArm64:
+		return "aa64"
+	case platform.ArchRiscv64:
+		return "riscv64"
+	default:
+		return "x64"
+	}
+}
@@ -0,0 +1,261 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootloader_test
+
+import (
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/bootloader"
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+var _ = Describe("SystemdBoot tests", Label("bootloader", "systemd-boot"), func() {
+	var tfs vfs.FS
+	var s *sys.System
+	var cleanup func()
+	var sdboot *bootloader.SystemdBoot
+	var runner *sysmock.Runner
+	BeforeEach(func() {
+		var err error
+		tfs, cleanup, err = sysmock.TestFS(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		runner = sysmock.NewRunner()
+		s, err = sys.NewSystem(
+			sys.WithRunner(runner),
+			sys.WithFS(tfs),
+			sys.WithLogger(log.New(log.WithDiscardAll())),
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		sdboot = bootloader.NewSystemdBoot(s)
+
+		// Setup systemd-boot EFI dir
+		Expect(vfs.MkdirAll(tfs, "/target/dir/usr/lib/systemd/boot/efi", vfs.DirPerm)).To(Succeed())
+		Expect(tfs.WriteFile("/target/dir/usr/lib/systemd/boot/efi/systemd-bootx64.efi", []byte("systemd-bootx64.efi"), vfs.FilePerm)).To(Succeed())
+
+		// Setup /etc/os-release file with openSUSE tumbleweed ID
+		Expect(vfs.MkdirAll(tfs, "/target/dir/etc", vfs.DirPerm)).To(Succeed())
+		Expect(tfs.WriteFile("/target/dir/etc/os-release", []byte("ID=opensuse-tumbleweed\nNAME=openSUSE Tumbleweed"), vfs.FilePerm)).To(Succeed())
+		// Setup kernel dirs
+		Expect(vfs.MkdirAll(tfs, "/target/dir/usr/lib/modules/6.14.4-1-default", vfs.DirPerm)).To(Succeed())
+		Expect(tfs.WriteFile("/target/dir/usr/lib/modules/6.14.4-1-default/vmlinuz", []byte("6.14.4-1-default vmlinux"), vfs.FilePerm)).To(Succeed())
+		Expect(tfs.WriteFile("/target/dir/usr/lib/modules/6.14.4-1-default/.vmlinuz.hmac", []byte("6.14.4-1-default .vmlinux.hmac"), vfs.FilePerm)).To(Succeed())
+		Expect(tfs.WriteFile("/target/dir/usr/lib/modules/6.14.4-1-default/initrd", []byte("6.14.4-1-default initrd"), vfs.FilePerm)).To(Succeed())
+	})
+	AfterEach(func() {
+		cleanup()
+	})
+
+	It("Installs systemd-boot and BLS entries to ESP", func() {
+		err := sdboot.Install("/target/dir", "/target/dir/boot", "EFI", "1", "snapshot1", "recovery cmdline")
+		Expect(err).ToNot(HaveOccurred())
+
+		// systemd-boot is installed as the default EFI application
+		Expect(vfs.Exists(tfs, "/target/dir/boot/EFI/ELEMENTAL/bootx64.efi")).To(BeTrue())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/EFI/BOOT/bootx64.efi")).To(BeTrue())
+
+		// Kernel and initrd exist
+		Expect(vfs.Exists(tfs, "/target/dir/boot/opensuse-tumbleweed/6.14.4-1-default/vmlinuz")).To(BeTrue())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/opensuse-tumbleweed/6.14.4-1-default/.vmlinuz.hmac")).To(BeTrue())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/opensuse-tumbleweed/6.14.4-1-default/initrd")).To(BeTrue())
+
+		// Loader config and entries exist
+		Expect(vfs.Exists(tfs, "/target/dir/boot/loader/loader.conf")).To(BeTrue())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/loader/entries/1.conf")).To(BeTrue())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/loader/entries/active.conf")).To(BeTrue())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/loader/entries/recovery.conf")).To(BeTrue())
+
+		entry1, err := tfs.ReadFile("/target/dir/boot/loader/entries/1.conf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Split(string(entry1), "\n")).To(ContainElements(
+			"title openSUSE Tumbleweed (1)",
+			"linux /opensuse-tumbleweed/6.14.4-1-default/vmlinuz",
+			"initrd /opensuse-tumbleweed/6.14.4-1-default/initrd",
+			"options snapshot1",
+		))
+	})
+	It("Installs systemd-boot for LiveOS image", func() {
+		err := sdboot.InstallLive("/target/dir", "/iso/dir", "kernel cmdline")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(vfs.Exists(tfs, "/iso/dir/EFI/BOOT/bootx64.efi")).To(BeTrue())
+		Expect(vfs.Exists(tfs, "/iso/dir/boot/opensuse-tumbleweed/6.14.4-1-default/vmlinuz")).To(BeTrue())
+		Expect(vfs.Exists(tfs, "/iso/dir/loader/loader.conf")).To(BeTrue())
+
+		live, err := tfs.ReadFile("/iso/dir/loader/entries/live.conf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Split(string(live), "\n")).To(ContainElements(
+			"linux /boot/opensuse-tumbleweed/6.14.4-1-default/vmlinuz",
+			"options kernel cmdline",
+		))
+	})
+	It("Leaves old snapshots, overwrites 'active' entry and keeps the 'recovery' entry", func() {
+		err := sdboot.Install("/target/dir", "/target/dir/boot", "EFI", "1", "snapshot1", "recovery cmdline")
+		Expect(err).ToNot(HaveOccurred())
+
+		err = sdboot.Install("/target/dir", "/target/dir/boot", "EFI", "2", "snapshot2", "new recovery cmdline")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(vfs.Exists(tfs, "/target/dir/boot/loader/entries/1.conf")).To(BeTrue())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/loader/entries/2.conf")).To(BeTrue())
+
+		activeEntry, err := tfs.ReadFile("/target/dir/boot/loader/entries/active.conf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Split(string(activeEntry), "\n")).To(ContainElement("options snapshot2"))
+
+		recoveryEntry, err := tfs.ReadFile("/target/dir/boot/loader/entries/recovery.conf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Split(string(recoveryEntry), "\n")).To(ContainElement("options recovery cmdline"))
+	})
+	It("Sets a previous snapshot entry as the 'active' entry", func() {
+		err := sdboot.Install("/target/dir", "/target/dir/boot", "EFI", "1", "snapshot1", "")
+		Expect(err).ToNot(HaveOccurred())
+		err = sdboot.Install("/target/dir", "/target/dir/boot", "EFI", "2", "snapshot2", "")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(sdboot.SetDefault("/target/dir/boot", "1")).To(Succeed())
+
+		activeEntry, err := tfs.ReadFile("/target/dir/boot/loader/entries/active.conf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Split(string(activeEntry), "\n")).To(ContainElements("options snapshot1", "title openSUSE Tumbleweed"))
+
+		err = sdboot.SetDefault("/target/dir/boot", "5")
+		Expect(err).To(MatchError("boot entry '5' not found"))
+	})
+	It("Enables and disables boot counting with a fallback entry", func() {
+		err := sdboot.Install("/target/dir", "/target/dir/boot", "EFI", "1", "snapshot1", "")
+		Expect(err).ToNot(HaveOccurred())
+		err = sdboot.Install("/target/dir", "/target/dir/boot", "EFI", "2", "snapshot2", "")
+		Expect(err).ToNot(HaveOccurred())
+
+		counter, err := sdboot.GetBootCounter("/target/dir/boot")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(counter).To(BeNil())
+
+		Expect(sdboot.SetBootCounter("/target/dir/boot", 3, "1")).To(Succeed())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/loader/entries/active+3.conf")).To(BeTrue())
+
+		entry1, err := tfs.ReadFile("/target/dir/boot/loader/entries/1.conf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Split(string(entry1), "\n")).To(ContainElement("sort-key elemental-1-fallback"))
+
+		// simulate two failed boots
+		Expect(tfs.Rename("/target/dir/boot/loader/entries/active+3.conf", "/target/dir/boot/loader/entries/active+1-2.conf")).To(Succeed())
+
+		counter, err = sdboot.GetBootCounter("/target/dir/boot")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*counter).To(Equal(bootloader.BootCounter{TriesLeft: 1, FallbackID: "1"}))
+
+		// the running counter is kept when the default entry is updated
+		Expect(sdboot.SetDefault("/target/dir/boot", "1")).To(Succeed())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/loader/entries/active+1-2.conf")).To(BeTrue())
+
+		Expect(sdboot.SetBootCounter("/target/dir/boot", 0, "")).To(Succeed())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/loader/entries/active.conf")).To(BeTrue())
+
+		entry1, err = tfs.ReadFile("/target/dir/boot/loader/entries/1.conf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Split(string(entry1), "\n")).To(ContainElement("sort-key elemental-2-snapshot"))
+
+		counter, err = sdboot.GetBootCounter("/target/dir/boot")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(counter).To(BeNil())
+	})
+	It("Fails to enable boot counting with invalid parameters", func() {
+		err := sdboot.Install("/target/dir", "/target/dir/boot", "EFI", "1", "snapshot1", "")
+		Expect(err).ToNot(HaveOccurred())
+
+		err = sdboot.SetBootCounter("/target/dir/boot", 10, "1")
+		Expect(err).To(MatchError("invalid number of boot tries 10, must be between 0 and 9"))
+
+		err = sdboot.SetBootCounter("/target/dir/boot", 3, "5")
+		Expect(err).To(MatchError("boot entry '5' not found"))
+	})
+	It("Prunes old snapshots", func() {
+		// "Install" older (6.6.99) kernel
+		Expect(vfs.MkdirAll(tfs, "/target/dir/boot/opensuse-tumbleweed/6.6.99-1-default", vfs.DirPerm)).To(Succeed())
+		Expect(tfs.WriteFile("/target/dir/boot/opensuse-tumbleweed/6.6.99-1-default/vmlinuz", []byte("6.6.99-1-default vmlinux"), vfs.FilePerm)).To(Succeed())
+
+		err := sdboot.Install("/target/dir", "/target/dir/boot", "EFI", "1", "snapshot1", "recoverycmd")
+		Expect(err).ToNot(HaveOccurred())
+		err = sdboot.Install("/target/dir", "/target/dir/boot", "EFI", "2", "snapshot2", "recoverycmd")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(sdboot.Prune("/target/dir", "/target/dir/boot", []int{2})).To(Succeed())
+
+		Expect(vfs.Exists(tfs, "/target/dir/boot/loader/entries/1.conf")).To(BeFalse())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/loader/entries/2.conf")).To(BeTrue())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/loader/entries/active.conf")).To(BeTrue())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/loader/entries/recovery.conf")).To(BeTrue())
+
+		Expect(vfs.Exists(tfs, "/target/dir/boot/opensuse-tumbleweed/6.6.99-1-default")).To(BeFalse())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/opensuse-tumbleweed/6.14.4-1-default/vmlinuz")).To(BeTrue())
+	})
+	It("Installs and prunes per snapshot UKIs", func() {
+		runner.SideEffect = func(command string, args ...string) ([]byte, error) {
+			if filepath.Base(command) == "ukify" {
+				output := strings.TrimPrefix(args[len(args)-1], "--output=")
+				return nil, tfs.WriteFile(output, []byte(strings.Join(args, "\n")), vfs.FilePerm)
+			}
+			return nil, nil
+		}
+		sdboot = bootloader.NewSystemdBoot(s, bootloader.WithUKI(true))
+
+		err := sdboot.Install("/target/dir", "/target/dir/boot", "EFI", "1", "snapshot1", "recoverycmd")
+		Expect(err).ToNot(HaveOccurred())
+		err = sdboot.Install("/target/dir", "/target/dir/boot", "EFI", "2", "snapshot2", "recoverycmd")
+		Expect(err).ToNot(HaveOccurred())
+
+		// UKIs include the kernel command line, kernel and initrd are not copied separately
+		uki, err := tfs.ReadFile("/target/dir/boot/EFI/Linux/opensuse-tumbleweed/2.efi")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Split(string(uki), "\n")).To(ContainElements(
+			"--cmdline=snapshot2", "--uname=6.14.4-1-default",
+			"--stub=/target/dir/usr/lib/systemd/boot/efi/linuxx64.efi.stub",
+		))
+		Expect(vfs.Exists(tfs, "/target/dir/boot/EFI/Linux/opensuse-tumbleweed/recovery.efi")).To(BeTrue())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/opensuse-tumbleweed")).To(BeFalse())
+
+		activeEntry, err := tfs.ReadFile("/target/dir/boot/loader/entries/active.conf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Split(string(activeEntry), "\n")).To(ContainElement("efi /EFI/Linux/opensuse-tumbleweed/2.efi"))
+
+		Expect(sdboot.SetDefault("/target/dir/boot", "1")).To(Succeed())
+		activeEntry, err = tfs.ReadFile("/target/dir/boot/loader/entries/active.conf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Split(string(activeEntry), "\n")).To(ContainElement("efi /EFI/Linux/opensuse-tumbleweed/1.efi"))
+
+		Expect(sdboot.Prune("/target/dir", "/target/dir/boot", []int{2})).To(Succeed())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/EFI/Linux/opensuse-tumbleweed/2.efi")).To(BeTrue())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/EFI/Linux/opensuse-tumbleweed/recovery.efi")).To(BeTrue())
+		// UKI 1 is still referenced by the 'active' entry
+		Expect(vfs.Exists(tfs, "/target/dir/boot/EFI/Linux/opensuse-tumbleweed/1.efi")).To(BeTrue())
+
+		Expect(sdboot.SetDefault("/target/dir/boot", "2")).To(Succeed())
+		Expect(sdboot.Prune("/target/dir", "/target/dir/boot", []int{2})).To(Succeed())
+		Expect(vfs.Exists(tfs, "/target/dir/boot/EFI/Linux/opensuse-tumbleweed/1.efi")).To(BeFalse())
+	})
+})
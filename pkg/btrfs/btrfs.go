@@ -20,6 +20,8 @@ package btrfs
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
@@ -134,3 +136,53 @@ func SetDefaultSubvolume(s *sys.System, path string) error {
 	}
 	return nil
 }
+
+// AddRAID1Devices adds the given devices to the btrfs filesystem mounted at the given path and converts
+// its data and metadata to the RAID1 profile. Devices already part of the filesystem are skipped.
+func AddRAID1Devices(s *sys.System, path string, devices ...string) error {
+	current, err := ListDevices(s, path)
+	if err != nil {
+		return err
+	}
+
+	var added bool
+	for _, device := range devices {
+		if slices.Contains(current, device) {
+			continue
+		}
+		s.Logger().Debug("Adding device '%s' to btrfs filesystem", device)
+		cmdOut, err := s.Runner().Run("btrfs", "device", "add", "-f", device, path)
+		if err != nil {
+			return fmt.Errorf("adding device '%s' to btrfs filesystem at %s: %s: %w", device, path, string(cmdOut), err)
+		}
+		added = true
+	}
+	if !added {
+		return nil
+	}
+
+	cmdOut, err := s.Runner().Run(
+		"btrfs", "balance", "start", "--full-balance", "-dconvert=raid1", "-mconvert=raid1", path,
+	)
+	if err != nil {
+		return fmt.Errorf("converting btrfs filesystem at %s to RAID1: %s: %w", path, string(cmdOut), err)
+	}
+	return nil
+}
+
+// ListDevices returns the devices of the btrfs filesystem mounted at the given path
+func ListDevices(s *sys.System, path string) ([]string, error) {
+	cmdOut, err := s.Runner().Run("btrfs", "filesystem", "show", path)
+	if err != nil {
+		return nil, fmt.Errorf("listing devices of btrfs filesystem at %s: %s: %w", path, string(cmdOut), err)
+	}
+
+	var devices []string
+	for _, line := range strings.Split(string(cmdOut), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == "devid" && fields[len(fields)-2] == "path" {
+			devices = append(devices, fields[len(fields)-1])
+		}
+	}
+	return devices, nil
+}
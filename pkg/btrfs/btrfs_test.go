@@ -103,4 +103,31 @@ var _ = Describe("DirectoryUnpacker", Label("directory"), func() {
 			{"btrfs", "subvolume", "set-default", "/path/to/mountpoint/@"},
 		})).To(Succeed())
 	})
+	It("adds RAID1 devices to a btrfs filesystem", func() {
+		runner.SideEffect = func(cmd string, args ...string) ([]byte, error) {
+			if cmd == "btrfs" && args[0] == "filesystem" {
+				return []byte(fsShow), nil
+			}
+			return []byte{}, nil
+		}
+		Expect(btrfs.AddRAID1Devices(s, "/path/to/mountpoint", "/dev/sdb2")).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{
+			{"btrfs", "filesystem", "show", "/path/to/mountpoint"},
+			{"btrfs", "device", "add", "-f", "/dev/sdb2", "/path/to/mountpoint"},
+			{"btrfs", "balance", "start", "--full-balance", "-dconvert=raid1", "-mconvert=raid1", "/path/to/mountpoint"},
+		})).To(Succeed())
+
+		// Devices already in the filesystem are skipped
+		runner.ClearCmds()
+		Expect(btrfs.AddRAID1Devices(s, "/path/to/mountpoint", "/dev/sda2")).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{
+			{"btrfs", "filesystem", "show", "/path/to/mountpoint"},
+		})).To(Succeed())
+	})
 })
+
+const fsShow = `Label: 'SYSTEM'  uuid: 4e5e4b1c-7f3a-4e37-a7c8-5e2d1c0c5c2a
+	Total devices 1 FS bytes used 1.20GiB
+	devid    1 size 20.00GiB used 2.02GiB path /dev/sda2
+
+`
@@ -0,0 +1,437 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	containerregistry "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/match"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/suse/elemental/v3/pkg/archive"
+	"github.com/suse/elemental/v3/pkg/registry"
+	"github.com/suse/elemental/v3/pkg/signature"
+	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+const (
+	// RefAnnotation holds the reference an image was pulled from
+	RefAnnotation = "org.opencontainers.image.ref.name"
+	// TitleAnnotation holds the file name of a file stored in the bundle
+	TitleAnnotation = "org.opencontainers.image.title"
+	// KindAnnotation holds the purpose of an entry of the bundle
+	KindAnnotation = "com.suse.elemental.bundle.kind"
+	// URLAnnotation holds the URL a file stored in the bundle was downloaded from
+	URLAnnotation = "com.suse.elemental.bundle.url"
+	// IndexDigestAnnotation holds the digest of the image index an image was selected from
+	IndexDigestAnnotation = "com.suse.elemental.bundle.index-digest"
+
+	// FileMediaType is the media type of plain files stored in the bundle
+	FileMediaType types.MediaType = "application/vnd.suse.elemental.bundle.file"
+)
+
+type Kind string
+
+const (
+	ReleaseManifest Kind = "release-manifest"
+	OperatingSystem Kind = "os"
+	ISO             Kind = "iso"
+	Extension       Kind = "extension"
+	HelmChart       Kind = "helm-chart"
+	K8sManifest     Kind = "kubernetes-manifest"
+	ContainerImage  Kind = "image"
+	Signature       Kind = "signature"
+)
+
+// Bundle is an OCI image layout holding the images and files required to build,
+// customize or upgrade a system without network access
+type Bundle struct {
+	s        *sys.System
+	dir      string
+	path     layout.Path
+	platform containerregistry.Platform
+	tempDir  string
+	// registries configures the access to the registries images are pulled from
+	registries *registry.Config
+}
+
+type Option func(b *Bundle)
+
+// WithRegistries sets the credentials, mirrors and TLS settings images are pulled with
+func WithRegistries(c *registry.Config) Option {
+	return func(b *Bundle) {
+		b.registries = c
+	}
+}
+
+// Entry is an image stored in the bundle
+type Entry struct {
+	// Ref is the reference the image was pulled from
+	Ref name.Reference
+	// Digest is the digest the image is published with, either the one of the
+	// image index or the one of the image itself
+	Digest containerregistry.Hash
+
+	desc containerregistry.Descriptor
+}
+
+// New creates an empty bundle at the given directory. Images are added for the given platform.
+func New(s *sys.System, dir string, platform containerregistry.Platform, opts ...Option) (*Bundle, error) {
+	if err := vfs.MkdirAll(s.FS(), dir, vfs.DirPerm); err != nil {
+		return nil, fmt.Errorf("creating bundle directory: %w", err)
+	}
+	rawDir, err := s.FS().RawPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	p, err := layout.Write(rawDir, empty.Index)
+	if err != nil {
+		return nil, fmt.Errorf("initializing OCI layout: %w", err)
+	}
+	b := &Bundle{s: s, dir: dir, path: p, platform: platform}
+	for _, o := range opts {
+		o(b)
+	}
+	return b, nil
+}
+
+// Open opens the bundle at the given path, either an OCI layout directory or a tarball of it.
+// Tarballs are extracted to a temporary directory which is removed on Close.
+func Open(ctx context.Context, s *sys.System, path string) (b *Bundle, err error) {
+	b = &Bundle{s: s}
+
+	dir := path
+	info, err := s.FS().Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening bundle: %w", err)
+	}
+	if !info.IsDir() {
+		b.tempDir, err = vfs.TempDir(s.FS(), "", "elemental-bundle-")
+		if err != nil {
+			return nil, fmt.Errorf("creating bundle directory: %w", err)
+		}
+		defer func() {
+			if err != nil {
+				_ = b.Close()
+			}
+		}()
+		if err = archive.ExtractTarball(ctx, s, path, b.tempDir); err != nil {
+			return nil, fmt.Errorf("extracting bundle '%s': %w", path, err)
+		}
+		dir = b.tempDir
+	}
+
+	b.dir = dir
+	rawDir, err := s.FS().RawPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	b.path, err = layout.FromPath(rawDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading OCI layout of bundle '%s': %w", path, err)
+	}
+	return b, nil
+}
+
+// Close releases any temporary data of the bundle
+func (b *Bundle) Close() error {
+	if b.tempDir == "" {
+		return nil
+	}
+	err := vfs.ForceRemoveAll(b.s.FS(), b.tempDir)
+	b.tempDir = ""
+	return err
+}
+
+// Pack writes the bundle to a .tar or .tar.gz tarball
+func (b *Bundle) Pack(ctx context.Context, tarball string) error {
+	return archive.CreateTarball(ctx, b.s, b.dir, tarball)
+}
+
+// AddImage pulls the image at the given reference for the bundle platform and stores it in the
+// bundle together with its signatures, if any. References can be pinned as 'name:tag@digest'.
+func (b *Bundle) AddImage(ctx context.Context, imageRef string, kind Kind) error {
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		return fmt.Errorf("parsing image reference '%s': %w", imageRef, err)
+	}
+
+	locations, err := b.registries.Resolve(ref)
+	if err != nil {
+		return err
+	}
+	registryOpts, err := b.registries.RemoteOptions(b.s.FS())
+	if err != nil {
+		return fmt.Errorf("configuring registries: %w", err)
+	}
+	opts := append([]remote.Option{remote.WithPlatform(b.platform), remote.WithContext(ctx)}, registryOpts...)
+
+	// images are pulled from the first location providing them, mirrors are listed first
+	var desc *remote.Descriptor
+	var location name.Reference
+	var errs error
+	for _, l := range locations {
+		desc, err = remote.Get(l, opts...)
+		if err == nil {
+			location = l
+			break
+		}
+		errs = errors.Join(errs, err)
+	}
+	if location == nil {
+		return fmt.Errorf("resolving image '%s': %w", imageRef, errs)
+	}
+	img, err := desc.Image()
+	if err != nil {
+		return fmt.Errorf("resolving image '%s' for platform %s: %w", imageRef, b.platform.String(), err)
+	}
+
+	stored := storedRef(imageRef, ref)
+	annotations := map[string]string{
+		RefAnnotation:  stored,
+		KindAnnotation: string(kind),
+	}
+	layoutOpts := []layout.Option{}
+	if desc.MediaType.IsIndex() {
+		// the index manifest is stored to tie the image to the digest the index is signed and pinned with
+		err = b.path.WriteBlob(desc.Digest, io.NopCloser(bytes.NewReader(desc.Manifest)))
+		if err != nil {
+			return fmt.Errorf("writing image index of '%s' to bundle: %w", imageRef, err)
+		}
+		annotations[IndexDigestAnnotation] = desc.Digest.String()
+		layoutOpts = append(layoutOpts, layout.WithPlatform(b.platform))
+	}
+	layoutOpts = append(layoutOpts, layout.WithAnnotations(annotations))
+
+	b.s.Logger().Info("Adding %s '%s' to bundle", kind, stored)
+	err = b.path.ReplaceImage(img, match.Annotation(RefAnnotation, stored), layoutOpts...)
+	if err != nil {
+		return fmt.Errorf("writing image '%s' to bundle: %w", imageRef, err)
+	}
+
+	sigTag := signature.SignatureTag(ref, desc.Digest)
+	sigImg, err := remote.Image(signature.SignatureTag(location, desc.Digest), opts...)
+	var terr *transport.Error
+	if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+		b.s.Logger().Debug("No signatures found for '%s'", imageRef)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("fetching signatures of '%s': %w", imageRef, err)
+	}
+
+	annotations = map[string]string{
+		RefAnnotation:  sigTag.Name(),
+		KindAnnotation: string(Signature),
+	}
+	err = b.path.ReplaceImage(sigImg, match.Annotation(RefAnnotation, sigTag.Name()), layout.WithAnnotations(annotations))
+	if err != nil {
+		return fmt.Errorf("writing signatures of '%s' to bundle: %w", imageRef, err)
+	}
+	return nil
+}
+
+// AddFile stores the given file, downloaded from the given URL, in the bundle
+func (b *Bundle) AddFile(url, file string, kind Kind) error {
+	f, err := b.s.FS().Open(file)
+	if err != nil {
+		return fmt.Errorf("opening file: %w", err)
+	}
+	digest, size, err := containerregistry.SHA256(f)
+	_ = f.Close()
+	if err != nil {
+		return fmt.Errorf("computing file digest: %w", err)
+	}
+
+	f, err = b.s.FS().Open(file)
+	if err != nil {
+		return fmt.Errorf("opening file: %w", err)
+	}
+	if err = b.path.WriteBlob(digest, f); err != nil {
+		return fmt.Errorf("writing file '%s' to bundle: %w", file, err)
+	}
+
+	b.s.Logger().Info("Adding %s '%s' to bundle", kind, url)
+	if err = b.path.RemoveDescriptors(match.Annotation(URLAnnotation, url)); err != nil {
+		return fmt.Errorf("replacing file '%s' in bundle: %w", url, err)
+	}
+	return b.path.AppendDescriptor(containerregistry.Descriptor{
+		MediaType: FileMediaType,
+		Size:      size,
+		Digest:    digest,
+		Annotations: map[string]string{
+			URLAnnotation:   url,
+			TitleAnnotation: filepath.Base(file),
+			KindAnnotation:  string(kind),
+		},
+	})
+}
+
+// ExtractFile writes the file downloaded from the given URL to the given path
+func (b *Bundle) ExtractFile(url, path string) (err error) {
+	manifests, err := b.manifests()
+	if err != nil {
+		return err
+	}
+
+	for _, desc := range manifests {
+		if desc.MediaType != FileMediaType || desc.Annotations[URLAnnotation] != url {
+			continue
+		}
+
+		blob, err := verifiedReader(desc.Digest, func() (io.ReadCloser, error) { return b.path.Blob(desc.Digest) })
+		if err != nil {
+			return fmt.Errorf("reading file '%s' from bundle: %w", url, err)
+		}
+		defer blob.Close()
+
+		f, err := b.s.FS().Create(path)
+		if err != nil {
+			return fmt.Errorf("creating file: %w", err)
+		}
+		defer func() {
+			e := f.Close()
+			if err == nil && e != nil {
+				err = fmt.Errorf("closing file: %w", e)
+			}
+		}()
+
+		if _, err = io.Copy(f, blob); err != nil {
+			return fmt.Errorf("copying file '%s' from bundle: %w", url, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("file '%s' not found in bundle", url)
+}
+
+// Lookup finds the image stored for the given reference and platform, if any platform is given.
+// Images referenced by digest match any image of the same repository with that digest. References
+// pinned as 'name:tag@digest' must match both.
+func (b *Bundle) Lookup(imageRef string, platform *containerregistry.Platform) (*Entry, error) {
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("parsing image reference '%s': %w", imageRef, err)
+	}
+	stored := storedRef(imageRef, ref)
+	pinned, isDigest := ref.(name.Digest)
+
+	manifests, err := b.manifests()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, desc := range manifests {
+		annotation, ok := desc.Annotations[RefAnnotation]
+		if !ok || desc.MediaType == FileMediaType {
+			continue
+		}
+		entryRef, err := name.ParseReference(annotation)
+		if err != nil {
+			continue
+		}
+
+		entry := &Entry{Ref: entryRef, Digest: desc.Digest, desc: desc}
+		if index, ok := desc.Annotations[IndexDigestAnnotation]; ok {
+			entry.Digest, err = b.indexDigest(desc, index)
+			if err != nil {
+				return nil, fmt.Errorf("verifying image index of '%s' in bundle: %w", annotation, err)
+			}
+		}
+
+		switch {
+		case annotation == stored && isDigest && !entry.hasDigest(pinned.DigestStr()):
+			return nil, fmt.Errorf("image '%s' in bundle has digest %s, expected %s", stored, entry.Digest.String(), pinned.DigestStr())
+		case annotation == stored:
+		case isDigest && entryRef.Context().Name() == ref.Context().Name() && entry.hasDigest(pinned.DigestStr()):
+		default:
+			continue
+		}
+
+		if platform != nil && desc.Platform != nil && !desc.Platform.Satisfies(*platform) {
+			return nil, fmt.Errorf("image '%s' in bundle is for platform %s", imageRef, desc.Platform.String())
+		}
+		return entry, nil
+	}
+	return nil, fmt.Errorf("image '%s' not found in bundle", imageRef)
+}
+
+// Image returns the image of the given bundle entry. The image manifest and configuration are
+// verified against the entry digest and the layers are verified as they are read.
+func (b *Bundle) Image(e *Entry) (containerregistry.Image, error) {
+	img, err := b.path.Image(e.desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	return verifyImage(img, e.desc.Digest)
+}
+
+// Signature returns the signatures image of the given bundle entry
+func (b *Bundle) Signature(e *Entry) (containerregistry.Image, error) {
+	sig, err := b.Lookup(signature.SignatureTag(e.Ref, e.Digest).Name(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("looking up signatures of '%s': %w", e.Ref.String(), err)
+	}
+	return b.Image(sig)
+}
+
+func (b *Bundle) manifests() ([]containerregistry.Descriptor, error) {
+	index, err := b.path.ImageIndex()
+	if err != nil {
+		return nil, fmt.Errorf("reading bundle index: %w", err)
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("reading bundle index: %w", err)
+	}
+	return manifest.Manifests, nil
+}
+
+func (e *Entry) hasDigest(digest string) bool {
+	return e.Digest.String() == digest || e.desc.Digest.String() == digest
+}
+
+// storedRef returns the reference images are stored with, that is the given reference without
+// the pinned digest for references in the 'name:tag@digest' form
+func storedRef(imageRef string, ref name.Reference) string {
+	pinned, ok := ref.(name.Digest)
+	if !ok {
+		return ref.Name()
+	}
+	tagged := strings.TrimSuffix(imageRef, "@"+pinned.DigestStr())
+	if !strings.Contains(tagged[strings.LastIndex(tagged, "/")+1:], ":") {
+		return ref.Name()
+	}
+	tag, err := name.NewTag(tagged)
+	if err != nil {
+		return ref.Name()
+	}
+	return tag.Name()
+}
@@ -0,0 +1,210 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	stdlog "log"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	containerregistry "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/suse/elemental/v3/pkg/bundle"
+	"github.com/suse/elemental/v3/pkg/log"
+	elementalregistry "github.com/suse/elemental/v3/pkg/registry"
+	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+var _ = Describe("Bundle", Label("bundle"), func() {
+	var tfs vfs.FS
+	var s *sys.System
+	var cleanup func()
+	var host string
+	var amd64, arm64 containerregistry.Platform
+	var indexDigest, imageDigest containerregistry.Hash
+
+	BeforeEach(func() {
+		var err error
+		tfs, cleanup, err = sysmock.TestFS(nil)
+		Expect(err).NotTo(HaveOccurred())
+		s, err = sys.NewSystem(sys.WithFS(tfs), sys.WithLogger(log.New(log.WithDiscardAll())))
+		Expect(err).NotTo(HaveOccurred())
+
+		srv := httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+		DeferCleanup(srv.Close)
+		host = strings.TrimPrefix(srv.URL, "http://")
+
+		amd64 = containerregistry.Platform{OS: "linux", Architecture: "amd64"}
+		arm64 = containerregistry.Platform{OS: "linux", Architecture: "arm64"}
+		img, err := random.Image(64, 1)
+		Expect(err).NotTo(HaveOccurred())
+		other, err := random.Image(64, 1)
+		Expect(err).NotTo(HaveOccurred())
+		index := mutate.AppendManifests(empty.Index,
+			mutate.IndexAddendum{Add: img, Descriptor: containerregistry.Descriptor{Platform: &amd64}},
+			mutate.IndexAddendum{Add: other, Descriptor: containerregistry.Descriptor{Platform: &arm64}},
+		)
+		tag, err := name.NewTag(host + "/os/image:1.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote.WriteIndex(tag, index)).To(Succeed())
+		indexDigest, err = index.Digest()
+		Expect(err).NotTo(HaveOccurred())
+		imageDigest, err = img.Digest()
+		Expect(err).NotTo(HaveOccurred())
+
+		sig, err := random.Image(16, 1)
+		Expect(err).NotTo(HaveOccurred())
+		sigTag := tag.Context().Tag(fmt.Sprintf("%s-%s.sig", indexDigest.Algorithm, indexDigest.Hex))
+		Expect(remote.Write(sigTag, sig)).To(Succeed())
+	})
+	AfterEach(func() {
+		cleanup()
+	})
+	It("adds an image with its signatures for the bundle platform", func() {
+		b, err := bundle.New(s, "/bundle", amd64)
+		Expect(err).NotTo(HaveOccurred())
+		ref := host + "/os/image:1.0"
+		Expect(b.AddImage(context.Background(), ref+"@"+indexDigest.String(), bundle.OperatingSystem)).To(Succeed())
+
+		entry, err := b.Lookup(ref, &amd64)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.Digest).To(Equal(indexDigest))
+		img, err := b.Image(entry)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(img.Digest()).To(Equal(imageDigest))
+		_, err = b.Signature(entry)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("looking up pinned references")
+		_, err = b.Lookup(ref+"@"+imageDigest.String(), &amd64)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = b.Lookup(host+"/os/image@"+indexDigest.String(), &amd64)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = b.Lookup(ref+"@sha256:"+strings.Repeat("0", 64), &amd64)
+		Expect(err).To(MatchError(ContainSubstring("expected sha256:000")))
+
+		By("looking up missing images")
+		_, err = b.Lookup(host+"/os/image:2.0", &amd64)
+		Expect(err).To(MatchError(ContainSubstring("not found in bundle")))
+		_, err = b.Lookup(ref, &arm64)
+		Expect(err).To(MatchError(ContainSubstring("is for platform linux/amd64")))
+	})
+	It("rejects images that are not tied to the signed index", func() {
+		b, err := bundle.New(s, "/bundle", amd64)
+		Expect(err).NotTo(HaveOccurred())
+		ref := host + "/os/image:1.0"
+		Expect(b.AddImage(context.Background(), ref, bundle.OperatingSystem)).To(Succeed())
+
+		rawDir, err := tfs.RawPath("/bundle")
+		Expect(err).NotTo(HaveOccurred())
+		p, err := layout.FromPath(rawDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("looking up an image annotated with an index that does not list it")
+		other, err := random.Image(64, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.AppendImage(other, layout.WithAnnotations(map[string]string{
+			bundle.RefAnnotation:         host + "/os/other:1.0",
+			bundle.IndexDigestAnnotation: indexDigest.String(),
+		}))).To(Succeed())
+		_, err = b.Lookup(host+"/os/other:1.0", &amd64)
+		Expect(err).To(MatchError(ContainSubstring("is not listed in image index")))
+
+		By("reading a tampered image layer")
+		entry, err := b.Lookup(ref, &amd64)
+		Expect(err).NotTo(HaveOccurred())
+		img, err := b.Image(entry)
+		Expect(err).NotTo(HaveOccurred())
+		layers, err := img.Layers()
+		Expect(err).NotTo(HaveOccurred())
+		digest, err := layers[0].Digest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tfs.WriteFile(filepath.Join("/bundle/blobs", digest.Algorithm, digest.Hex), []byte("tampered"), vfs.FilePerm)).To(Succeed())
+		rc, err := layers[0].Compressed()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.ReadAll(rc)
+		Expect(err).To(MatchError(ContainSubstring("content has digest")))
+
+		By("looking up an image of a tampered index")
+		Expect(tfs.WriteFile(filepath.Join("/bundle/blobs", indexDigest.Algorithm, indexDigest.Hex), []byte("{}"), vfs.FilePerm)).To(Succeed())
+		_, err = b.Lookup(ref, &amd64)
+		Expect(err).To(MatchError(ContainSubstring("content has digest")))
+	})
+	It("adds an image pulled from a registry mirror by its original reference", func() {
+		registries := &elementalregistry.Config{Registries: []elementalregistry.Registry{{
+			Prefix:  "registry.invalid./suse",
+			Mirrors: []string{host},
+		}}}
+		b, err := bundle.New(s, "/bundle", amd64, bundle.WithRegistries(registries))
+		Expect(err).NotTo(HaveOccurred())
+		ref := "registry.invalid./suse/os/image:1.0"
+		Expect(b.AddImage(context.Background(), ref, bundle.OperatingSystem)).To(Succeed())
+
+		entry, err := b.Lookup(ref, &amd64)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.Digest).To(Equal(indexDigest))
+		_, err = b.Signature(entry)
+		Expect(err).NotTo(HaveOccurred())
+	})
+	It("stores plain files", func() {
+		b, err := bundle.New(s, "/bundle", amd64)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tfs.WriteFile("/ext.raw", []byte("extension"), vfs.FilePerm)).To(Succeed())
+		Expect(b.AddFile("https://example.com/ext.raw", "/ext.raw", bundle.Extension)).To(Succeed())
+
+		Expect(b.ExtractFile("https://example.com/ext.raw", "/extracted.raw")).To(Succeed())
+		data, err := tfs.ReadFile("/extracted.raw")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("extension"))
+		Expect(b.ExtractFile("https://example.com/other.raw", "/other.raw")).To(MatchError(ContainSubstring("not found")))
+	})
+	It("packs and opens a bundle tarball", func() {
+		b, err := bundle.New(s, "/bundle", amd64)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b.AddImage(context.Background(), host+"/os/image:1.0", bundle.OperatingSystem)).To(Succeed())
+		Expect(b.Pack(context.Background(), "/bundle.tar.gz")).To(Succeed())
+
+		opened, err := bundle.Open(context.Background(), s, "/bundle.tar.gz")
+		Expect(err).NotTo(HaveOccurred())
+		entry, err := opened.Lookup(host+"/os/image:1.0", &amd64)
+		Expect(err).NotTo(HaveOccurred())
+		img, err := opened.Image(entry)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(img.Digest()).To(Equal(imageDigest))
+		Expect(opened.Close()).To(Succeed())
+
+		_, err = bundle.Open(context.Background(), s, "/missing")
+		Expect(err).To(HaveOccurred())
+	})
+})
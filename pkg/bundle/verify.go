@@ -0,0 +1,159 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"io"
+	"slices"
+
+	containerregistry "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// indexDigest returns the digest of the image index the image with the given descriptor was selected
+// from. The annotation only locates the index manifest stored in the bundle, the manifest must hash to
+// it and list the image.
+func (b *Bundle) indexDigest(desc containerregistry.Descriptor, annotation string) (containerregistry.Hash, error) {
+	digest, err := containerregistry.NewHash(annotation)
+	if err != nil {
+		return containerregistry.Hash{}, fmt.Errorf("invalid index digest: %w", err)
+	}
+	raw, err := b.path.Bytes(digest)
+	if err != nil {
+		return containerregistry.Hash{}, fmt.Errorf("reading image index %s: %w", digest.String(), err)
+	}
+	if err = checkDigest(raw, digest); err != nil {
+		return containerregistry.Hash{}, fmt.Errorf("image index %s: %w", digest.String(), err)
+	}
+	index, err := containerregistry.ParseIndexManifest(bytes.NewReader(raw))
+	if err != nil {
+		return containerregistry.Hash{}, fmt.Errorf("parsing image index %s: %w", digest.String(), err)
+	}
+	listed := slices.ContainsFunc(index.Manifests, func(m containerregistry.Descriptor) bool {
+		return m.Digest == desc.Digest
+	})
+	if !listed {
+		return containerregistry.Hash{}, fmt.Errorf("image %s is not listed in image index %s", desc.Digest.String(), digest.String())
+	}
+	return digest, nil
+}
+
+// verifyImage checks the manifest and the configuration of the image read from the bundle match the
+// given digest and returns the image with its layers verified as they are read
+func verifyImage(img containerregistry.Image, digest containerregistry.Hash) (containerregistry.Image, error) {
+	raw, err := img.RawManifest()
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest of image %s: %w", digest.String(), err)
+	}
+	if err = checkDigest(raw, digest); err != nil {
+		return nil, fmt.Errorf("manifest of image %s: %w", digest.String(), err)
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("parsing manifest of image %s: %w", digest.String(), err)
+	}
+	config, err := img.RawConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("reading configuration of image %s: %w", digest.String(), err)
+	}
+	if err = checkDigest(config, manifest.Config.Digest); err != nil {
+		return nil, fmt.Errorf("configuration of image %s: %w", digest.String(), err)
+	}
+	return &verifiedImage{Image: img}, nil
+}
+
+func checkDigest(data []byte, digest containerregistry.Hash) error {
+	h, _, err := containerregistry.SHA256(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if h != digest {
+		return fmt.Errorf("content has digest %s, expected %s", h.String(), digest.String())
+	}
+	return nil
+}
+
+type verifiedImage struct {
+	containerregistry.Image
+}
+
+func (i *verifiedImage) Layers() ([]containerregistry.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	verified := make([]containerregistry.Layer, len(layers))
+	for n, l := range layers {
+		verified[n] = &verifiedLayer{Layer: l}
+	}
+	return verified, nil
+}
+
+// verifiedLayer is a layer whose compressed and uncompressed contents are checked against its digest
+// and diff ID respectively, reads fail at the end of the content on mismatch
+type verifiedLayer struct {
+	containerregistry.Layer
+}
+
+func (l *verifiedLayer) Compressed() (io.ReadCloser, error) {
+	digest, err := l.Digest()
+	if err != nil {
+		return nil, err
+	}
+	return verifiedReader(digest, l.Layer.Compressed)
+}
+
+func (l *verifiedLayer) Uncompressed() (io.ReadCloser, error) {
+	diffID, err := l.DiffID()
+	if err != nil {
+		return nil, err
+	}
+	return verifiedReader(diffID, l.Layer.Uncompressed)
+}
+
+func verifiedReader(expected containerregistry.Hash, open func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	hasher, err := containerregistry.Hasher(expected.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := open()
+	if err != nil {
+		return nil, err
+	}
+	return &verifyingReader{ReadCloser: rc, hasher: hasher, expected: expected}, nil
+}
+
+type verifyingReader struct {
+	io.ReadCloser
+	hasher   hash.Hash
+	expected containerregistry.Hash
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hasher.Write(p[:n])
+	if err == io.EOF {
+		got := containerregistry.Hash{Algorithm: r.expected.Algorithm, Hex: fmt.Sprintf("%x", r.hasher.Sum(nil))}
+		if got != r.expected {
+			return n, fmt.Errorf("content has digest %s, expected %s", got.String(), r.expected.String())
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,330 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+const (
+	blobsDir = "blobs/sha256"
+	tmpDir   = "tmp"
+	// DefaultMaxSize is the size the cache is pruned to after each build, in bytes
+	DefaultMaxSize int64 = 20 << 30
+	// drainLimit is the amount of unread data consumed on close to complete a blob, streams
+	// like layer tarballs are usually closed before reading their trailing padding
+	drainLimit = 1 << 20
+)
+
+// Cache is a persistent content-addressed store of OCI layers and downloaded files, blobs
+// are stored by their sha256 digest and least recently used blobs are pruned first.
+type Cache struct {
+	fs      vfs.FS
+	dir     string
+	maxSize int64
+}
+
+// Blob is a file stored in the cache
+type Blob struct {
+	Digest   string
+	Size     int64
+	LastUsed time.Time
+}
+
+type Option func(c *Cache)
+
+// WithMaxSize sets the size in bytes the cache is pruned to by Trim, zero or negative means no limit
+func WithMaxSize(size int64) Option {
+	return func(c *Cache) {
+		c.maxSize = size
+	}
+}
+
+// New returns the cache stored at the given directory
+func New(fs vfs.FS, dir string, opts ...Option) *Cache {
+	c := &Cache{fs: fs, dir: dir, maxSize: DefaultMaxSize}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// DefaultDir returns the default cache directory, the system one for root and the
+// user one otherwise
+func DefaultDir() string {
+	if os.Geteuid() == 0 {
+		return "/var/cache/elemental"
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "/var/cache/elemental"
+	}
+	return filepath.Join(dir, "elemental")
+}
+
+// Dir returns the directory of the cache
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Get copies the blob with the given digest to the given path, it returns false if the
+// blob is not cached
+func (c *Cache) Get(digest, path string) (bool, error) {
+	blob, err := c.blobPath(digest)
+	if err != nil {
+		return false, err
+	}
+	if ok, _ := vfs.Exists(c.fs, blob); !ok {
+		return false, nil
+	}
+	if err = vfs.CopyFile(c.fs, blob, path); err != nil {
+		return false, fmt.Errorf("copying cached blob %s: %w", digest, err)
+	}
+	c.touch(blob)
+	return true, nil
+}
+
+// Put stores the file at the given path as the blob with the given digest, the file
+// is not stored if it does not match the digest
+func (c *Cache) Put(digest, path string) error {
+	f, err := c.fs.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening file: %w", err)
+	}
+	w, err := c.tee(digest, f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	if _, err = io.Copy(io.Discard, w); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("caching file: %w", err)
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	if hex.EncodeToString(w.h.Sum(nil)) != w.expected {
+		return fmt.Errorf("file '%s' does not match digest %s", path, digest)
+	}
+	if !w.stored {
+		return fmt.Errorf("storing blob %s failed", digest)
+	}
+	return nil
+}
+
+// open returns a reader of the blob with the given digest, it returns false if the
+// blob is not cached
+func (c *Cache) open(digest string) (io.ReadCloser, bool, error) {
+	blob, err := c.blobPath(digest)
+	if err != nil {
+		return nil, false, err
+	}
+	f, err := c.fs.Open(blob)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("opening cached blob %s: %w", digest, err)
+	}
+	c.touch(blob)
+	return f, true, nil
+}
+
+// tee returns a reader of the given stream which stores what is read as the blob with
+// the given digest once the stream is consumed and closed, if it matches the digest. Failing
+// to write to the cache does not fail reading the stream.
+func (c *Cache) tee(digest string, rc io.ReadCloser) (*cachingReader, error) {
+	blob, err := c.blobPath(digest)
+	if err != nil {
+		return nil, err
+	}
+	r := &cachingReader{rc: rc, c: c, blob: blob, expected: strings.TrimPrefix(digest, "sha256:"), h: sha256.New()}
+
+	tmp := filepath.Join(c.dir, tmpDir)
+	if err = vfs.MkdirAll(c.fs, tmp, vfs.DirPerm); err == nil {
+		r.tmp = filepath.Join(tmp, fmt.Sprintf("%s-%d-%d", r.expected, os.Getpid(), time.Now().UnixNano()))
+		r.f, err = c.fs.OpenFile(r.tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, vfs.FilePerm)
+	}
+	if err != nil {
+		r.f = nil
+	}
+	return r, nil
+}
+
+// List returns the cached blobs, least recently used first
+func (c *Cache) List() ([]Blob, error) {
+	var entries []Blob
+	dir := filepath.Join(c.dir, blobsDir)
+	if ok, _ := vfs.Exists(c.fs, dir); !ok {
+		return nil, nil
+	}
+	files, err := c.fs.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading cache directory: %w", err)
+	}
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil {
+			return nil, fmt.Errorf("reading cached blob %s: %w", file.Name(), err)
+		}
+		entries = append(entries, Blob{Digest: "sha256:" + file.Name(), Size: info.Size(), LastUsed: info.ModTime()})
+	}
+	slices.SortStableFunc(entries, func(a, b Blob) int { return a.LastUsed.Compare(b.LastUsed) })
+	return entries, nil
+}
+
+// Size returns the total size of the cached blobs in bytes
+func (c *Cache) Size() (int64, error) {
+	entries, err := c.List()
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, e := range entries {
+		size += e.Size
+	}
+	return size, nil
+}
+
+// Prune removes the least recently used blobs until the cache is not bigger than the given
+// size in bytes and returns the removed blobs. A zero size empties the cache. Leftovers of
+// interrupted writes are removed too.
+func (c *Cache) Prune(maxSize int64) ([]Blob, error) {
+	if err := c.fs.RemoveAll(filepath.Join(c.dir, tmpDir)); err != nil {
+		return nil, fmt.Errorf("removing temporary cache files: %w", err)
+	}
+
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	var size int64
+	for _, e := range entries {
+		size += e.Size
+	}
+
+	var removed []Blob
+	for _, e := range entries {
+		if size <= maxSize {
+			break
+		}
+		blob, _ := c.blobPath(e.Digest)
+		if err = c.fs.Remove(blob); err != nil {
+			return removed, fmt.Errorf("removing cached blob %s: %w", e.Digest, err)
+		}
+		size -= e.Size
+		removed = append(removed, e)
+	}
+	return removed, nil
+}
+
+// Trim prunes the cache to the size it is limited to, if any
+func (c *Cache) Trim() ([]Blob, error) {
+	if c.maxSize <= 0 {
+		return nil, nil
+	}
+	return c.Prune(c.maxSize)
+}
+
+func (c *Cache) blobPath(digest string) (string, error) {
+	h, ok := strings.CutPrefix(digest, "sha256:")
+	if !ok || len(h) != sha256.Size*2 {
+		return "", fmt.Errorf("unsupported digest '%s'", digest)
+	}
+	if _, err := hex.DecodeString(h); err != nil {
+		return "", fmt.Errorf("invalid digest '%s': %w", digest, err)
+	}
+	return filepath.Join(c.dir, blobsDir, h), nil
+}
+
+// touch updates the modification time of the given blob, which tracks when it was last used
+func (c *Cache) touch(blob string) {
+	if raw, err := c.fs.RawPath(blob); err == nil {
+		now := time.Now()
+		_ = os.Chtimes(raw, now, now)
+	}
+}
+
+type cachingReader struct {
+	rc       io.ReadCloser
+	c        *Cache
+	f        *os.File
+	tmp      string
+	blob     string
+	expected string
+	h        hash.Hash
+	eof      bool
+	stored   bool
+}
+
+func (r *cachingReader) Read(b []byte) (int, error) {
+	n, err := r.rc.Read(b)
+	if n > 0 {
+		r.h.Write(b[:n])
+		if r.f != nil {
+			if _, wErr := r.f.Write(b[:n]); wErr != nil {
+				r.discard()
+			}
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		r.eof = true
+	}
+	return n, err
+}
+
+// Close closes the stream and stores the blob if it was entirely read and matches the expected digest
+func (r *cachingReader) Close() error {
+	if !r.eof && r.f != nil {
+		_, _ = io.CopyN(io.Discard, r, drainLimit)
+	}
+	err := r.rc.Close()
+	if r.f == nil {
+		return err
+	}
+
+	_ = r.f.Close()
+	if r.eof && hex.EncodeToString(r.h.Sum(nil)) == r.expected {
+		if mkErr := vfs.MkdirAll(r.c.fs, filepath.Dir(r.blob), vfs.DirPerm); mkErr == nil {
+			r.stored = r.c.fs.Rename(r.tmp, r.blob) == nil
+		}
+	}
+	if !r.stored {
+		_ = r.c.fs.Remove(r.tmp)
+	}
+	return err
+}
+
+// discard stops writing the blob to the cache
+func (r *cachingReader) discard() {
+	_ = r.f.Close()
+	_ = r.c.fs.Remove(r.tmp)
+	r.f = nil
+}
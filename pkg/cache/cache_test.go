@@ -0,0 +1,213 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+func TestCacheSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cache test suite")
+}
+
+func sha256Digest(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+var _ = Describe("Cache", Label("cache"), func() {
+	var fs vfs.FS
+	var c *Cache
+	BeforeEach(func() {
+		var err error
+		var cleanup func()
+		fs, cleanup, err = mock.TestFS(map[string]any{
+			"/files/a": "first file",
+			"/files/b": "second file",
+			"/files/c": "third file",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(cleanup)
+		c = New(fs, "/cache")
+	})
+	// setLastUsed sets the last use time of the blob with the given digest
+	setLastUsed := func(digest string, t time.Time) {
+		blob, err := c.blobPath(digest)
+		Expect(err).NotTo(HaveOccurred())
+		raw, err := fs.RawPath(blob)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chtimes(raw, t, t)).To(Succeed())
+	}
+	It("stores and retrieves files by digest", func() {
+		digest := sha256Digest("first file")
+		ok, err := c.Get(digest, "/out/a")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+
+		Expect(c.Put(digest, "/files/a")).To(Succeed())
+		Expect(vfs.MkdirAll(fs, "/out", vfs.DirPerm)).To(Succeed())
+		ok, err = c.Get(digest, "/out/a")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		data, err := fs.ReadFile("/out/a")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("first file"))
+	})
+	It("does not store files not matching their digest", func() {
+		digest := sha256Digest("first file")
+		Expect(c.Put(digest, "/files/b")).To(MatchError(ContainSubstring("does not match digest")))
+		entries, err := c.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+		tmp, err := fs.ReadDir(filepath.Join("/cache", tmpDir))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tmp).To(BeEmpty())
+	})
+	It("rejects unsupported digests", func() {
+		Expect(c.Put("md5:abc", "/files/a")).To(MatchError(ContainSubstring("unsupported digest")))
+		_, err := c.Get("sha256:../../etc", "/out/a")
+		Expect(err).To(MatchError(ContainSubstring("unsupported digest")))
+	})
+	It("lists and prunes the least recently used blobs first", func() {
+		now := time.Now()
+		for i, name := range []string{"a", "b", "c"} {
+			data, err := fs.ReadFile("/files/" + name)
+			Expect(err).NotTo(HaveOccurred())
+			digest := sha256Digest(string(data))
+			Expect(c.Put(digest, "/files/"+name)).To(Succeed())
+			// b is the least recently used, then c, then a
+			setLastUsed(digest, now.Add(-time.Duration((i*2)%3+1)*time.Hour))
+		}
+
+		entries, err := c.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(3))
+		Expect(entries[0].Digest).To(Equal(sha256Digest("second file")))
+		Expect(entries[1].Digest).To(Equal(sha256Digest("third file")))
+		Expect(entries[2].Digest).To(Equal(sha256Digest("first file")))
+		size, err := c.Size()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(size).To(Equal(int64(len("first file") + len("second file") + len("third file"))))
+
+		removed, err := c.Prune(int64(len("first file")))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(removed).To(HaveLen(2))
+		Expect(removed[0].Digest).To(Equal(sha256Digest("second file")))
+		Expect(removed[1].Digest).To(Equal(sha256Digest("third file")))
+		entries, err = c.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+
+		removed, err = c.Prune(0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(removed).To(HaveLen(1))
+	})
+	It("marks blobs as used when read", func() {
+		digest := sha256Digest("first file")
+		Expect(c.Put(digest, "/files/a")).To(Succeed())
+		old := time.Now().Add(-24 * time.Hour)
+		setLastUsed(digest, old)
+
+		Expect(vfs.MkdirAll(fs, "/out", vfs.DirPerm)).To(Succeed())
+		_, err := c.Get(digest, "/out/a")
+		Expect(err).NotTo(HaveOccurred())
+		entries, err := c.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries[0].LastUsed).To(BeTemporally(">", old))
+	})
+	It("only trims to the configured size", func() {
+		Expect(New(fs, "/cache", WithMaxSize(0)).Trim()).To(BeEmpty())
+		Expect(c.Put(sha256Digest("first file"), "/files/a")).To(Succeed())
+		removed, err := New(fs, "/cache", WithMaxSize(1)).Trim()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(removed).To(HaveLen(1))
+	})
+	It("caches the layers of an image as they are read", func() {
+		img, err := random.Image(1024, 2)
+		Expect(err).NotTo(HaveOccurred())
+
+		layers, err := c.Image(img).Layers()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(layers).To(HaveLen(2))
+		for _, l := range layers {
+			rc, err := l.Compressed()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = io.Copy(io.Discard, rc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rc.Close()).To(Succeed())
+		}
+
+		entries, err := c.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+
+		// layers are now read from the cache and match the original ones
+		origLayers, err := img.Layers()
+		Expect(err).NotTo(HaveOccurred())
+		for n, l := range layers {
+			digest, err := l.Digest()
+			Expect(err).NotTo(HaveOccurred())
+			blob, err := c.blobPath(digest.String())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vfs.Exists(fs, blob)).To(BeTrue())
+
+			rc, err := l.Compressed()
+			Expect(err).NotTo(HaveOccurred())
+			cached, err := io.ReadAll(rc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rc.Close()).To(Succeed())
+
+			orc, err := origLayers[n].Compressed()
+			Expect(err).NotTo(HaveOccurred())
+			orig, err := io.ReadAll(orc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(orc.Close()).To(Succeed())
+			Expect(cached).To(Equal(orig))
+		}
+	})
+	It("does not cache partially read layers", func() {
+		img, err := random.Image(4*drainLimit, 1)
+		Expect(err).NotTo(HaveOccurred())
+
+		layers, err := c.Image(img).Layers()
+		Expect(err).NotTo(HaveOccurred())
+		rc, err := layers[0].Compressed()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.CopyN(io.Discard, rc, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rc.Close()).To(Succeed())
+
+		entries, err := c.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+})
@@ -0,0 +1,93 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"io"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// Image returns the given image with its layers read from the cache, layers missing in the
+// cache are stored as they are read from the image
+func (c *Cache) Image(img v1.Image) v1.Image {
+	return &image{Image: img, c: c}
+}
+
+type image struct {
+	v1.Image
+	c *Cache
+}
+
+func (i *image) Layers() ([]v1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	cached := make([]v1.Layer, len(layers))
+	for n, l := range layers {
+		cached[n] = &layer{Layer: l, c: i.c}
+	}
+	return cached, nil
+}
+
+// layer is a layer whose compressed and uncompressed contents are cached by digest and
+// diff ID respectively
+type layer struct {
+	v1.Layer
+	c *Cache
+}
+
+func (l *layer) Compressed() (io.ReadCloser, error) {
+	digest, err := l.Digest()
+	if err != nil {
+		return nil, err
+	}
+	return l.read(digest, l.Layer.Compressed)
+}
+
+func (l *layer) Uncompressed() (io.ReadCloser, error) {
+	diffID, err := l.DiffID()
+	if err != nil {
+		return nil, err
+	}
+	return l.read(diffID, l.Layer.Uncompressed)
+}
+
+func (l *layer) read(h v1.Hash, open func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	if h.Algorithm != "sha256" {
+		return open()
+	}
+	rc, ok, err := l.c.open(h.String())
+	if err != nil {
+		return nil, err
+	} else if ok {
+		return rc, nil
+	}
+
+	rc, err = open()
+	if err != nil {
+		return nil, err
+	}
+	cr, err := l.c.tee(h.String(), rc)
+	if err != nil {
+		_ = rc.Close()
+		return nil, err
+	}
+	return cr, nil
+}
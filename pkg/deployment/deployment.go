@@ -26,14 +26,22 @@ import (
 	"runtime"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"go.yaml.in/yaml/v3"
 
+	"github.com/suse/elemental/v3/pkg/bootcount"
+	"github.com/suse/elemental/v3/pkg/bootloader"
 	"github.com/suse/elemental/v3/pkg/crypto"
 	"github.com/suse/elemental/v3/pkg/firmware"
+	"github.com/suse/elemental/v3/pkg/registry"
+	"github.com/suse/elemental/v3/pkg/secureboot"
+	"github.com/suse/elemental/v3/pkg/signature"
 	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/sys/platform"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
 )
 
@@ -64,7 +72,7 @@ const (
 	ConfigLabel = "ignition"
 	ConfigMnt   = "/run/elemental/firstboot"
 
-	deploymentFile = "/etc/elemental/deployment.yaml"
+	File = "/etc/elemental/deployment.yaml"
 
 	Unknown = "unknown"
 )
@@ -197,37 +205,155 @@ func (p *PartRole) UnmarshalYAML(data *yaml.Node) (err error) {
 	return err
 }
 
+// MergePolicy defines how conflicts are solved when merging local modifications of a
+// snapshotted RW volume with the content of a new OS image
+type MergePolicy string
+
+const (
+	// PreferLocal keeps local modifications over the image content, this is the default
+	PreferLocal MergePolicy = "preferLocal"
+	// PreferImage keeps the image content over conflicting local modifications
+	PreferImage MergePolicy = "preferImage"
+	// FailOnConflict makes the upgrade fail if there is any conflict
+	FailOnConflict MergePolicy = "failOnConflict"
+	// KeepBoth keeps local modifications and stores the conflicting image content
+	// next to them with the MergeNewSuffix
+	KeepBoth MergePolicy = "keepBoth"
+
+	MergeNewSuffix = ".elemental-new"
+)
+
+func (p MergePolicy) IsValid() bool {
+	switch p {
+	case "", PreferLocal, PreferImage, FailOnConflict, KeepBoth:
+		return true
+	default:
+		return false
+	}
+}
+
 type RWVolume struct {
-	Path          string   `yaml:"path" validate:"required,abspath"`
-	Snapshotted   bool     `yaml:"snapshotted,omitempty"`
-	NoCopyOnWrite bool     `yaml:"noCopyOnWrite,omitempty"`
-	MountOpts     []string `yaml:"mountOpts,omitempty"`
+	Path          string      `yaml:"path" validate:"required,abspath"`
+	Snapshotted   bool        `yaml:"snapshotted,omitempty"`
+	NoCopyOnWrite bool        `yaml:"noCopyOnWrite,omitempty"`
+	MountOpts     []string    `yaml:"mountOpts,omitempty"`
+	MergePolicy   MergePolicy `yaml:"mergePolicy,omitempty" validate:"merge_policy"`
+}
+
+// GetMergePolicy returns the merge policy of the volume, defaults to PreferLocal
+func (v RWVolume) GetMergePolicy() MergePolicy {
+	if v.MergePolicy == "" {
+		return PreferLocal
+	}
+	return v.MergePolicy
 }
 
 type RWVolumes []RWVolume
 
 type Partition struct {
-	Label      string     `yaml:"label,omitempty"`
-	FileSystem FileSystem `yaml:"fileSystem,omitempty"`
-	Size       MiB        `yaml:"size,omitempty"`
-	Role       PartRole   `yaml:"role"`
-	MountPoint string     `yaml:"mountPoint,omitempty" validate:"recovery_mountpoint"`
-	MountOpts  []string   `yaml:"mountOpts,omitempty"`
-	RWVolumes  RWVolumes  `yaml:"rwVolumes,omitempty" validate:"excluded_unless=FileSystem 1,dive"` // FileSystem 1 = btrfs
-	UUID       string     `yaml:"uuid,omitempty"`
-	Hidden     bool       `yaml:"hidden,omitempty"`
+	Label      string      `yaml:"label,omitempty"`
+	FileSystem FileSystem  `yaml:"fileSystem,omitempty"`
+	Size       MiB         `yaml:"size,omitempty"`
+	Role       PartRole    `yaml:"role"`
+	MountPoint string      `yaml:"mountPoint,omitempty" validate:"recovery_mountpoint"`
+	MountOpts  []string    `yaml:"mountOpts,omitempty"`
+	RWVolumes  RWVolumes   `yaml:"rwVolumes,omitempty" validate:"excluded_unless=FileSystem 1,dive"` // FileSystem 1 = btrfs
+	UUID       string      `yaml:"uuid,omitempty"`
+	Hidden     bool        `yaml:"hidden,omitempty"`
+	Encryption *Encryption `yaml:"encryption,omitempty" validate:"omitempty,encryption"`
+	// Mirror sets the partition as a mirror, on another disk, of the system or efi partition. System
+	// mirrors are added to the btrfs filesystem of the system partition as RAID1 devices, EFI mirrors
+	// are kept in sync with the efi partition by the bootloader.
+	Mirror bool `yaml:"mirror,omitempty"`
+}
+
+// Encryption defines the LUKS2 encryption of a partition and the sources of its keys
+type Encryption struct {
+	// KeyFile is the path of a key file enrolled to unlock the volume
+	KeyFile string `yaml:"keyFile,omitempty"`
+	// RecoveryPassphraseFile is the path of a file including a recovery passphrase to enroll,
+	// the volume is unlocked with the key file, if any, or the TPM2 device on enrollment
+	RecoveryPassphraseFile string `yaml:"recoveryPassphraseFile,omitempty"`
+	// TPM2 binds the volume to the TPM2 device of the host
+	TPM2 *TPM2 `yaml:"tpm2,omitempty"`
+	// UUID is the UUID of the LUKS2 header, set at partitioning time
+	UUID string `yaml:"uuid,omitempty"`
+}
+
+// TPM2 defines the PCR policy used to seal the volume key with a TPM2 device
+type TPM2 struct {
+	// PCRs is the list of PCR indexes the volume key is bound to, defaults to PCR 7
+	PCRs []int `yaml:"pcrs,omitempty"`
+}
+
+// GetPCRs returns the PCRs the volume key is bound to in the '7+11' form used by systemd tools
+func (t TPM2) GetPCRs() string {
+	pcrs := t.PCRs
+	if len(pcrs) == 0 {
+		pcrs = []int{7}
+	}
+	strPCRs := make([]string, len(pcrs))
+	for i, pcr := range pcrs {
+		strPCRs[i] = strconv.Itoa(pcr)
+	}
+	return strings.Join(strPCRs, "+")
+}
+
+// IsValid checks the encryption defines at least one key source to unlock the volume
+func (e Encryption) IsValid() bool {
+	if e.KeyFile == "" && e.TPM2 == nil {
+		return false
+	}
+	for _, pcr := range e.GetPCRs() {
+		if pcr < 0 || pcr > 23 {
+			return false
+		}
+	}
+	return true
+}
+
+// GetPCRs returns the list of configured TPM2 PCRs, nil if TPM2 is not used
+func (e Encryption) GetPCRs() []int {
+	if e.TPM2 == nil {
+		return nil
+	}
+	return e.TPM2.PCRs
+}
+
+// MapperName returns the device mapper name of the unlocked volume. It matches the
+// default name used by systemd-cryptsetup for volumes unlocked at boot.
+func (p Partition) MapperName() string {
+	if p.Encryption == nil {
+		return ""
+	}
+	return "luks-" + p.Encryption.UUID
+}
+
+// FstabDevice returns the device reference used to mount the partition in fstab
+func (p Partition) FstabDevice() string {
+	if p.Encryption != nil {
+		return filepath.Join("/dev/mapper", p.MapperName())
+	}
+	return fmt.Sprintf("PARTUUID=%s", p.UUID)
 }
 
 type Partitions []*Partition
 
 type Disk struct {
-	Device     string     `yaml:"target,omitempty" validate:"disk_device_required,disk_device_exists"`
-	Partitions Partitions `yaml:"partitions" validate:"required,min=1,dive"`
+	Device string `yaml:"target,omitempty" validate:"disk_device_required,disk_device_exists"`
+	// Selector picks the target device at install time when no device is given
+	Selector   *DiskSelector `yaml:"selector,omitempty" validate:"omitempty,disk_selector"`
+	Partitions Partitions    `yaml:"partitions" validate:"required,min=1,dive"`
 }
 
 type BootConfig struct {
 	Bootloader    string `yaml:"name"`
 	KernelCmdline string `yaml:"kernelCmdline"`
+	// BootTries enables boot counting after upgrades, the previous snapshot is booted
+	// after the given number of unsuccessful boots. Zero disables boot counting.
+	BootTries int `yaml:"bootTries,omitempty" validate:"boot_tries"`
+	// UKI enables booting a Unified Kernel Image built per snapshot, requires systemd-boot
+	UKI bool `yaml:"uki,omitempty" validate:"uki"`
 }
 
 type FirmwareConfig struct {
@@ -236,6 +362,10 @@ type FirmwareConfig struct {
 
 type SecurityConfig struct {
 	CryptoPolicy crypto.Policy `yaml:"cryptoPolicy" validate:"crypto_policy"`
+	// SignaturePolicy defines the signatures required for OCI images to be unpacked
+	SignaturePolicy *signature.Policy `yaml:"signaturePolicy,omitempty" validate:"omitempty,signature_policy"`
+	// SecureBoot defines the custom keys the bootloader, kernels and UKIs are signed with
+	SecureBoot *secureboot.Config `yaml:"secureBoot,omitempty" validate:"omitempty,secure_boot"`
 }
 
 type SnapshotterConfig struct {
@@ -248,9 +378,16 @@ type LiveInstaller struct {
 	KernelCmdline string       `yaml:"kernelCmdline,omitempty"`
 }
 
+// RollbackRecord describes the last rollback operation that made the current
+// snapshot the default one.
+type RollbackRecord struct {
+	FromSnapshot int       `yaml:"fromSnapshot"`
+	Date         time.Time `yaml:"date"`
+}
+
 type Deployment struct {
 	SourceOS    *ImageSource       `yaml:"sourceOS" validate:"required,not_empty_source"`
-	Disks       []*Disk            `yaml:"disks" validate:"required,min=1,dive,system_partition,multiple_system_partitions,efi_partition,multiple_efi_partitions,recovery_partition,last_partition_size,rw_volumes"`
+	Disks       []*Disk            `yaml:"disks" validate:"required,min=1,system_partition,multiple_system_partitions,efi_partition,multiple_efi_partitions,recovery_partition,rw_volumes,mirror_partitions,dive,last_partition_size"`
 	Firmware    *FirmwareConfig    `yaml:"firmware"`
 	BootConfig  *BootConfig        `yaml:"bootloader"`
 	Security    *SecurityConfig    `yaml:"security" validate:"required"`
@@ -258,6 +395,9 @@ type Deployment struct {
 	OverlayTree *ImageSource       `yaml:"overlayTree,omitempty"`
 	CfgScript   string             `yaml:"configScript,omitempty"`
 	Installer   LiveInstaller      `yaml:"installer,omitempty"`
+	Rollback    *RollbackRecord    `yaml:"rollback,omitempty"`
+	// Registries defines the credentials, mirrors and TLS settings used to pull OCI images
+	Registries *registry.Config `yaml:"registries,omitempty" validate:"omitempty,registries"`
 }
 
 var validate = validator.New()
@@ -271,7 +411,16 @@ func init() {
 	_ = validate.RegisterValidation("recovery_partition", validateRecoveryPartition)
 	_ = validate.RegisterValidation("last_partition_size", validateLastPartitionSize)
 	_ = validate.RegisterValidation("rw_volumes", validateRWVolumes)
+	_ = validate.RegisterValidation("mirror_partitions", validateMirrorPartitions)
 	_ = validate.RegisterValidation("crypto_policy", validateCryptoPolicy)
+	_ = validate.RegisterValidation("boot_tries", validateBootTries)
+	_ = validate.RegisterValidation("uki", validateUKI)
+	_ = validate.RegisterValidation("secure_boot", validateSecureBoot)
+	_ = validate.RegisterValidation("encryption", validateEncryption)
+	_ = validate.RegisterValidation("disk_selector", validateDiskSelector)
+	_ = validate.RegisterValidation("merge_policy", validateMergePolicy)
+	_ = validate.RegisterValidation("signature_policy", validateSignaturePolicy)
+	_ = validate.RegisterValidation("registries", validateRegistries)
 	_ = validate.RegisterValidation("abspath", validateAbsPath)
 	_ = validate.RegisterValidationCtx("disk_device_exists", validateDiskDeviceExists)
 	_ = validate.RegisterValidationCtx("disk_device_required", validateDiskDeviceRequired)
@@ -308,7 +457,7 @@ func validateSystemPartition(fl validator.FieldLevel) bool {
 			continue
 		}
 		for _, part := range disk.Partitions {
-			if part != nil && part.Role == System {
+			if part != nil && part.Role == System && !part.Mirror {
 				count++
 			}
 		}
@@ -331,7 +480,7 @@ func validateMultipleSystemPartitions(fl validator.FieldLevel) bool {
 			continue
 		}
 		for _, part := range disk.Partitions {
-			if part != nil && part.Role == System {
+			if part != nil && part.Role == System && !part.Mirror {
 				count++
 			}
 		}
@@ -354,7 +503,7 @@ func validateEFIPartition(fl validator.FieldLevel) bool {
 			continue
 		}
 		for _, part := range disk.Partitions {
-			if part != nil && part.Role == EFI {
+			if part != nil && part.Role == EFI && !part.Mirror {
 				count++
 			}
 		}
@@ -377,7 +526,7 @@ func validateMultipleEFIPartitions(fl validator.FieldLevel) bool {
 			continue
 		}
 		for _, part := range disk.Partitions {
-			if part != nil && part.Role == EFI {
+			if part != nil && part.Role == EFI && !part.Mirror {
 				count++
 			}
 		}
@@ -408,6 +557,56 @@ func validateRecoveryPartition(fl validator.FieldLevel) bool {
 	return count <= 1
 }
 
+func validateMirrorPartitions(fl validator.FieldLevel) bool {
+	disks, ok := fl.Field().Interface().([]*Disk)
+	if !ok {
+		return false
+	}
+	return checkMirrorPartitions(disks) == nil
+}
+
+// checkMirrorPartitions checks mirror partitions are only set for the system and efi roles, on disks
+// other than the disk of the mirrored partition. System mirrors require btrfs.
+func checkMirrorPartitions(disks []*Disk) error {
+	primaryDisk := map[PartRole]*Disk{}
+	encryptedSystem := false
+	for _, disk := range disks {
+		if disk == nil {
+			continue
+		}
+		for _, part := range disk.Partitions {
+			if part != nil && !part.Mirror && (part.Role == System || part.Role == EFI) {
+				primaryDisk[part.Role] = disk
+				encryptedSystem = encryptedSystem || (part.Role == System && part.Encryption != nil)
+			}
+		}
+	}
+	for _, disk := range disks {
+		if disk == nil {
+			continue
+		}
+		for _, part := range disk.Partitions {
+			if part == nil || !part.Mirror {
+				continue
+			}
+			switch {
+			case part.Role != System && part.Role != EFI:
+				return fmt.Errorf("mirrors are only supported for 'system' and 'efi' partitions, got '%s'", part.Role)
+			case primaryDisk[part.Role] == disk:
+				return fmt.Errorf("'%s' mirror partition must be on a different disk than the '%s' partition", part.Role, part.Role)
+			case part.Role == System && part.FileSystem != Btrfs:
+				return fmt.Errorf("'system' mirror partitions require btrfs, got '%s'", part.FileSystem)
+			case part.Encryption != nil:
+				return fmt.Errorf("encryption is not supported for mirror partitions")
+			case part.Role == System && encryptedSystem:
+				// the mirror would hold an unencrypted copy of the encrypted system
+				return fmt.Errorf("'system' mirror partitions are not supported with an encrypted 'system' partition")
+			}
+		}
+	}
+	return nil
+}
+
 func validateLastPartitionSize(fl validator.FieldLevel) bool {
 	disks, ok := fl.Field().Interface().([]*Disk)
 	if !ok {
@@ -479,6 +678,65 @@ func validateRWVolumes(fl validator.FieldLevel) bool {
 	return true
 }
 
+func validateBootTries(fl validator.FieldLevel) bool {
+	tries := fl.Field().Int()
+	return bootcount.Validate(int(tries)) == nil
+}
+
+func validateUKI(fl validator.FieldLevel) bool {
+	if !fl.Field().Bool() {
+		return true
+	}
+	bootConfig, ok := fl.Parent().Interface().(BootConfig)
+	if !ok {
+		bootConfigPtr, ok := fl.Parent().Interface().(*BootConfig)
+		if !ok {
+			return false
+		}
+		bootConfig = *bootConfigPtr
+	}
+	return bootConfig.Bootloader == bootloader.BootSystemdBoot
+}
+
+func validateSecureBoot(fl validator.FieldLevel) bool {
+	conf, ok := fl.Field().Interface().(secureboot.Config)
+	if !ok {
+		return false
+	}
+	return conf.IsValid()
+}
+
+func validateEncryption(fl validator.FieldLevel) bool {
+	enc, ok := fl.Field().Interface().(Encryption)
+	if !ok {
+		return false
+	}
+	part, ok := fl.Parent().Interface().(Partition)
+	if !ok {
+		partPtr, ok := fl.Parent().Interface().(*Partition)
+		if !ok {
+			return false
+		}
+		part = *partPtr
+	}
+	if part.Role == EFI || part.Role == Recovery {
+		return false
+	}
+	// the system partition is unlocked by the initrd, which does not include key files
+	if part.Role == System && enc.TPM2 == nil {
+		return false
+	}
+	return enc.IsValid()
+}
+
+func validateMergePolicy(fl validator.FieldLevel) bool {
+	policy, ok := fl.Field().Interface().(MergePolicy)
+	if !ok {
+		return false
+	}
+	return policy.IsValid()
+}
+
 func validateCryptoPolicy(fl validator.FieldLevel) bool {
 	policy, ok := fl.Field().Interface().(crypto.Policy)
 	if !ok {
@@ -487,6 +745,22 @@ func validateCryptoPolicy(fl validator.FieldLevel) bool {
 	return policy.IsValid()
 }
 
+func validateSignaturePolicy(fl validator.FieldLevel) bool {
+	policy, ok := fl.Field().Interface().(signature.Policy)
+	if !ok {
+		return false
+	}
+	return policy.Validate() == nil
+}
+
+func validateRegistries(fl validator.FieldLevel) bool {
+	config, ok := fl.Field().Interface().(registry.Config)
+	if !ok {
+		return false
+	}
+	return config.Validate() == nil
+}
+
 func validateAbsPath(fl validator.FieldLevel) bool {
 	return filepath.IsAbs(fl.Field().String())
 }
@@ -511,9 +785,20 @@ func validateDiskDeviceRequired(ctx context.Context, fl validator.FieldLevel) bo
 	if skip, ok := ctx.Value(contextKeySkipDiskDeviceExists).(bool); ok && skip {
 		return true
 	}
+	if disk, ok := fl.Parent().Interface().(Disk); ok && disk.Selector != nil {
+		return true
+	}
 	return fl.Field().String() != ""
 }
 
+func validateDiskSelector(fl validator.FieldLevel) bool {
+	sel, ok := fl.Field().Interface().(DiskSelector)
+	if !ok {
+		return false
+	}
+	return sel.IsValid()
+}
+
 func validateRecoveryMountPoint(_ context.Context, fl validator.FieldLevel) bool {
 	// This needs to be on the Partition struct level or we need to access the Role field.
 	// Since it's on the MountPoint field, we use Parent().
@@ -539,6 +824,15 @@ func LiveKernelCmdline(label string) string {
 	return fmt.Sprintf("root=live:LABEL=%s rd.live.overlay.overlayfs=1", label)
 }
 
+// UUIDs returns the UUIDs of the partitions
+func (p Partitions) UUIDs() []string {
+	uuids := make([]string, 0, len(p))
+	for _, part := range p {
+		uuids = append(uuids, part.UUID)
+	}
+	return uuids
+}
+
 // GetSnapshottedVolumes returns a list of snapshotted rw volumes defined in the
 // given partitions list.
 func (p Partitions) GetSnapshottedVolumes() RWVolumes {
@@ -587,7 +881,7 @@ func (d Deployment) GetSystemPartition() *Partition {
 			continue
 		}
 		for _, part := range disk.Partitions {
-			if part != nil && part.Role == System {
+			if part != nil && part.Role == System && !part.Mirror {
 				return part
 			}
 		}
@@ -613,7 +907,7 @@ func (d Deployment) GetSystemDisk() *Disk {
 			continue
 		}
 		for _, part := range disk.Partitions {
-			if part != nil && part.Role == System {
+			if part != nil && part.Role == System && !part.Mirror {
 				return disk
 			}
 		}
@@ -629,7 +923,7 @@ func (d Deployment) GetEfiPartition() *Partition {
 			continue
 		}
 		for _, part := range disk.Partitions {
-			if part != nil && part.Role == EFI {
+			if part != nil && part.Role == EFI && !part.Mirror {
 				return part
 			}
 		}
@@ -661,7 +955,7 @@ func (d Deployment) GetEfiDisk() *Disk {
 			continue
 		}
 		for _, part := range disk.Partitions {
-			if part != nil && part.Role == EFI {
+			if part != nil && part.Role == EFI && !part.Mirror {
 				return disk
 			}
 		}
@@ -669,9 +963,59 @@ func (d Deployment) GetEfiDisk() *Disk {
 	return nil
 }
 
+// GetMirrorPartitions returns the mirror partitions of the given role in all disks
+func (d Deployment) GetMirrorPartitions(role PartRole) Partitions {
+	var parts Partitions
+	for _, disk := range d.Disks {
+		if disk == nil {
+			continue
+		}
+		for _, part := range disk.Partitions {
+			if part != nil && part.Role == role && part.Mirror {
+				parts = append(parts, part)
+			}
+		}
+	}
+	return parts
+}
+
+// DefaultBootEntries returns the default EFI boot entries for the platform, one for the
+// efi partition and one for each of its mirrors
+func (d Deployment) DefaultBootEntries(p *platform.Platform) []*firmware.EfiBootEntry {
+	var entries []*firmware.EfiBootEntry
+	for _, disk := range d.Disks {
+		if disk == nil {
+			continue
+		}
+		for i, part := range disk.Partitions {
+			if part == nil || part.Role != EFI {
+				continue
+			}
+			entry := firmware.DefaultBootEntry(p, disk.Device)
+			if i > 0 {
+				entry.Partition = i + 1
+			}
+			if part.Mirror {
+				entry.Label = fmt.Sprintf("%s-mirror%d", entry.Label, len(entries))
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
 // BaseKernelCmdline returns the base kernel command line for the current deployment
 func (d Deployment) BaseKernelCmdline() string {
-	return fmt.Sprintf("root=LABEL=%s", d.GetSystemLabel())
+	cmdline := fmt.Sprintf("root=LABEL=%s", d.GetSystemLabel())
+	// The system partition is unlocked by the initrd, any other encrypted partition is set in crypttab
+	sysPart := d.GetSystemPartition()
+	if sysPart != nil && sysPart.Encryption != nil {
+		cmdline += fmt.Sprintf(" rd.luks.uuid=%s", sysPart.Encryption.UUID)
+		if sysPart.Encryption.TPM2 != nil {
+			cmdline += fmt.Sprintf(" rd.luks.options=%s=tpm2-device=auto", sysPart.Encryption.UUID)
+		}
+	}
+	return cmdline
 }
 
 // RecoveryKernelCmdline returns the base kernel command line for the current deployment
@@ -695,7 +1039,7 @@ func (d Deployment) GetSELinuxSupportedPartitions() Partitions {
 			continue
 		}
 		for _, part := range disk.Partitions {
-			if part.FileSystem != VFat {
+			if part.FileSystem != VFat && !part.Mirror {
 				parts = append(parts, part)
 			}
 		}
@@ -713,7 +1057,7 @@ func (d *Deployment) setDefaults(s *sys.System) {
 			if part == nil {
 				continue
 			}
-			if part.Role == System {
+			if part.Role == System && !part.Mirror {
 				if part.MountPoint != SystemMnt {
 					s.Logger().Warn("custom mountpoints for the system partition are not supported")
 					s.Logger().Info("system partition mountpoint set to default '%s'", SystemMnt)
@@ -729,7 +1073,7 @@ func (d *Deployment) setDefaults(s *sys.System) {
 					s.Logger().Info("efi partition set to be formatted with vfat")
 					part.FileSystem = VFat
 				}
-				if part.MountPoint != EfiMnt {
+				if part.MountPoint != EfiMnt && !part.Mirror {
 					s.Logger().Warn("custom mountpoints for the efi partition are not supported")
 					s.Logger().Info("efi partition mountpoint set to default '%s'", EfiMnt)
 					part.MountPoint = EfiMnt
@@ -761,6 +1105,11 @@ func (d *Deployment) setDefaults(s *sys.System) {
 					part.Label = RecoveryLabel
 				}
 			}
+			if part.Mirror {
+				// Mirrors are synced with the mirrored partition, they are not mounted on their own
+				part.MountPoint = ""
+				part.RWVolumes = nil
+			}
 			if part.FileSystem.String() == Unknown {
 				part.FileSystem = Btrfs
 			}
@@ -813,10 +1162,32 @@ func (d *Deployment) formatValidationErrors(s *sys.System, errs validator.Valida
 			return fmt.Errorf("only last partition can be defined to be as big as available size in disk")
 		case "rw_volumes":
 			return d.checkRWVolumes()
+		case "mirror_partitions":
+			return checkMirrorPartitions(d.Disks)
 		case "crypto_policy":
 			return fmt.Errorf("invalid crypto policy: %s", d.Security.CryptoPolicy)
+		case "signature_policy":
+			return fmt.Errorf("invalid signature policy: %w", d.Security.SignaturePolicy.Validate())
+		case "registries":
+			return fmt.Errorf("invalid registries configuration: %w", d.Registries.Validate())
+		case "secure_boot":
+			return fmt.Errorf("invalid Secure Boot configuration: certificate is required")
+		case "encryption":
+			return d.checkEncryption()
+		case "boot_tries":
+			return bootcount.Validate(d.BootConfig.BootTries)
+		case "uki":
+			return fmt.Errorf("UKI boot mode requires the '%s' bootloader, got '%s'", bootloader.BootSystemdBoot, d.BootConfig.Bootloader)
+		case "merge_policy":
+			return fmt.Errorf("invalid merge policy '%v', must be one of %s, %s, %s or %s", e.Value(), PreferLocal, PreferImage, FailOnConflict, KeepBoth)
 		case "not_empty_source":
 			return fmt.Errorf("no OS image defined in deployment")
+		case "disk_selector":
+			for i, disk := range d.Disks {
+				if disk.Selector != nil && !disk.Selector.IsValid() {
+					return fmt.Errorf("invalid disk selector '%s' for disk %d", disk.Selector, i)
+				}
+			}
 		case "disk_device_required":
 			for i, disk := range d.Disks {
 				if disk.Device == "" {
@@ -834,6 +1205,30 @@ func (d *Deployment) formatValidationErrors(s *sys.System, errs validator.Valida
 	return errs[0] // Fallback to the first error if no specific tag is matched
 }
 
+// checkEncryption is kept as a helper for specific error messages when validator fails
+func (d *Deployment) checkEncryption() error {
+	for _, disk := range d.Disks {
+		for _, part := range disk.Partitions {
+			if part.Encryption == nil {
+				continue
+			}
+			switch {
+			case part.Role == EFI || part.Role == Recovery:
+				return fmt.Errorf("encryption is not supported for '%s' partitions", part.Role)
+			case part.Encryption.KeyFile == "" && part.Encryption.TPM2 == nil:
+				return fmt.Errorf("encrypted partition '%s' requires a key file or TPM2", part.Label)
+			case part.Role == System && part.Encryption.TPM2 == nil:
+				return fmt.Errorf(
+					"encrypted '%s' partition is only unlocked at boot with TPM2, key files are not included in the initrd", part.Role,
+				)
+			case !part.Encryption.IsValid():
+				return fmt.Errorf("invalid TPM2 PCRs %v for encrypted partition '%s'", part.Encryption.GetPCRs(), part.Label)
+			}
+		}
+	}
+	return fmt.Errorf("invalid encryption configuration")
+}
+
 // checkRWVolumes is kept as a helper for specific error messages when validator fails
 func (d *Deployment) checkRWVolumes() error {
 	pathMap := map[string]bool{}
@@ -874,6 +1269,34 @@ func (d *Deployment) IsFipsEnabled() bool {
 	return d.Security.CryptoPolicy == crypto.FIPSPolicy
 }
 
+// GetSignaturePolicy returns the signature policy OCI images are verified against, nil if none is set.
+func (d *Deployment) GetSignaturePolicy() *signature.Policy {
+	if d.Security == nil {
+		return nil
+	}
+	return d.Security.SignaturePolicy
+}
+
+// GetSecureBoot returns the Secure Boot signing configuration, nil if none is set.
+func (d *Deployment) GetSecureBoot() *secureboot.Config {
+	if d.Security == nil {
+		return nil
+	}
+	return d.Security.SecureBoot
+}
+
+// BootloaderOpts returns the bootloader options defined in the deployment
+func (d *Deployment) BootloaderOpts(s *sys.System) []bootloader.Opt {
+	var opts []bootloader.Opt
+	if d.BootConfig != nil {
+		opts = append(opts, bootloader.WithUKI(d.BootConfig.UKI))
+	}
+	if conf := d.GetSecureBoot(); conf.CanSign() {
+		opts = append(opts, bootloader.WithSigner(secureboot.NewSigner(s, *conf)))
+	}
+	return opts
+}
+
 // DeepCopy returns deep copy of the current Deployment object. Note the deep copy
 // is based on yaml.Marshal and yaml.Unmarshal, hence it is subject to the defined
 // marshalling behavior with custom marshallers and type decorators.
@@ -896,7 +1319,7 @@ func (d Deployment) DeepCopy() (*Deployment, error) {
 // serialization it omits runtime information such as device paths, overlay and config
 // script paths.
 func (d *Deployment) WriteDeploymentFile(s *sys.System, root string) error {
-	path := filepath.Join(root, deploymentFile)
+	path := filepath.Join(root, File)
 	if ok, _ := vfs.Exists(s.FS(), path); !ok {
 		err := vfs.MkdirAll(s.FS(), filepath.Dir(path), vfs.DirPerm)
 		if err != nil {
@@ -943,7 +1366,7 @@ func (d *Deployment) WriteDeploymentFile(s *sys.System, root string) error {
 // Parse reads a deployment yaml file from the given root and returns a
 // Deployment object
 func Parse(s *sys.System, root string) (*Deployment, error) {
-	path := filepath.Join(root, deploymentFile)
+	path := filepath.Join(root, File)
 	if ok, err := vfs.Exists(s.FS(), path); !ok {
 		s.Logger().Warn("deployment file not found '%s'", path)
 		return nil, err
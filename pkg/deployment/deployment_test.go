@@ -28,8 +28,12 @@ import (
 
 	"github.com/suse/elemental/v3/pkg/deployment"
 	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/registry"
+	"github.com/suse/elemental/v3/pkg/secureboot"
+	"github.com/suse/elemental/v3/pkg/signature"
 	"github.com/suse/elemental/v3/pkg/sys"
 	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/platform"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
 )
 
@@ -104,6 +108,170 @@ var _ = Describe("Deployment", Label("deployment"), func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("multiple 'efi'"))
 		})
+		It("creates a multi-disk deployment with data disks and mirrors", func() {
+			d := deployment.DefaultDeployment()
+			d.SourceOS = deployment.NewDirSrc("/some/dir")
+			d.Disks[0].Device = "/dev/sda"
+			d.Disks = append(d.Disks, &deployment.Disk{
+				Device: "/dev/sdb",
+				Partitions: deployment.Partitions{
+					{Role: deployment.EFI, Mirror: true},
+					{Role: deployment.System, Mirror: true, MountPoint: "/data"},
+				},
+			}, &deployment.Disk{
+				Device: "/dev/sdc",
+				Partitions: deployment.Partitions{
+					{Role: deployment.Generic, Label: "DATA", MountPoint: "/var/lib/data"},
+				},
+			})
+			Expect(d.Sanitize(s, deployment.CheckDiskDevice)).To(Succeed())
+
+			Expect(d.GetSystemPartition()).To(Equal(d.Disks[0].Partitions[1]))
+			Expect(d.GetEfiPartition()).To(Equal(d.Disks[0].Partitions[0]))
+			Expect(d.GetSystemDisk().Device).To(Equal("/dev/sda"))
+			Expect(d.GetEfiDisk().Device).To(Equal("/dev/sda"))
+			Expect(d.GetMirrorPartitions(deployment.System)).To(Equal(deployment.Partitions{d.Disks[1].Partitions[1]}))
+			Expect(d.Disks[1].Partitions[0].Label).To(Equal(deployment.EfiLabel))
+			Expect(d.Disks[1].Partitions[0].MountPoint).To(BeEmpty())
+			Expect(d.Disks[1].Partitions[1].MountPoint).To(BeEmpty())
+			Expect(d.Disks[1].Partitions[1].FileSystem).To(Equal(deployment.Btrfs))
+
+			entries := d.DefaultBootEntries(&platform.Platform{Arch: platform.Archx86})
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Disk).To(Equal("/dev/sda"))
+			Expect(entries[0].Label).To(Equal("elemental-shim"))
+			Expect(entries[1].Disk).To(Equal("/dev/sdb"))
+			Expect(entries[1].Label).To(Equal("elemental-shim-mirror1"))
+		})
+		It("fails if mirror partitions are not valid", func() {
+			d := deployment.DefaultDeployment()
+			d.SourceOS = deployment.NewDirSrc("/some/dir")
+			d.Disks[0].Partitions = append(d.Disks[0].Partitions, &deployment.Partition{Role: deployment.EFI, Mirror: true})
+			err = d.Sanitize(s, deployment.CheckDiskDevice)
+			Expect(err).To(MatchError("'efi' mirror partition must be on a different disk than the 'efi' partition"))
+
+			d = deployment.DefaultDeployment()
+			d.SourceOS = deployment.NewDirSrc("/some/dir")
+			d.Disks = append(d.Disks, &deployment.Disk{Partitions: deployment.Partitions{
+				{Role: deployment.System, Mirror: true, FileSystem: deployment.Ext4},
+			}})
+			err = d.Sanitize(s, deployment.CheckDiskDevice)
+			Expect(err).To(MatchError("'system' mirror partitions require btrfs, got 'ext4'"))
+
+			d.Disks[1].Partitions[0] = &deployment.Partition{Role: deployment.Generic, Mirror: true}
+			err = d.Sanitize(s, deployment.CheckDiskDevice)
+			Expect(err).To(MatchError("mirrors are only supported for 'system' and 'efi' partitions, got 'generic'"))
+
+			d = deployment.DefaultDeployment()
+			d.SourceOS = deployment.NewDirSrc("/some/dir")
+			d.GetSystemPartition().Encryption = &deployment.Encryption{TPM2: &deployment.TPM2{}}
+			d.Disks = append(d.Disks, &deployment.Disk{Partitions: deployment.Partitions{
+				{Role: deployment.System, Mirror: true},
+			}})
+			err = d.Sanitize(s, deployment.CheckDiskDevice)
+			Expect(err).To(MatchError("'system' mirror partitions are not supported with an encrypted 'system' partition"))
+		})
+		It("fails if a rw volume merge policy is not valid", func() {
+			d := deployment.DefaultDeployment()
+			d.SourceOS = deployment.NewDirSrc("/some/dir")
+			sysPart := d.GetSystemPartition()
+			sysPart.RWVolumes[2].MergePolicy = "preferNone"
+			err = d.Sanitize(s, deployment.CheckDiskDevice)
+			Expect(err).To(MatchError("invalid merge policy 'preferNone', must be one of preferLocal, preferImage, failOnConflict or keepBoth"))
+
+			sysPart.RWVolumes[2].MergePolicy = deployment.KeepBoth
+			Expect(d.Sanitize(s, deployment.CheckDiskDevice)).To(Succeed())
+			Expect(sysPart.RWVolumes[2].GetMergePolicy()).To(Equal(deployment.KeepBoth))
+			Expect(sysPart.RWVolumes[0].GetMergePolicy()).To(Equal(deployment.PreferLocal))
+		})
+		It("fails if the signature policy is not valid", func() {
+			d := deployment.DefaultDeployment()
+			d.SourceOS = deployment.NewDirSrc("/some/dir")
+			d.Security.SignaturePolicy = &signature.Policy{Scopes: []signature.Scope{{Scope: "registry.suse.com"}}}
+			err = d.Sanitize(s, deployment.CheckDiskDevice)
+			Expect(err).To(MatchError(ContainSubstring("invalid signature policy: no public keys or keyless identity")))
+
+			d.Security.SignaturePolicy = &signature.Policy{}
+			Expect(d.Sanitize(s, deployment.CheckDiskDevice)).To(Succeed())
+			Expect(d.GetSignaturePolicy().IsEmpty()).To(BeTrue())
+		})
+		It("fails if the registries configuration is not valid", func() {
+			d := deployment.DefaultDeployment()
+			d.SourceOS = deployment.NewDirSrc("/some/dir")
+			d.Registries = &registry.Config{Registries: []registry.Registry{{Prefix: "quay.io"}, {Prefix: "quay.io/"}}}
+			err = d.Sanitize(s, deployment.CheckDiskDevice)
+			Expect(err).To(MatchError(ContainSubstring("invalid registries configuration: duplicated registry")))
+
+			d.Registries = &registry.Config{Registries: []registry.Registry{{Prefix: "quay.io", Mirrors: []string{"mirror.example.com"}}}}
+			Expect(d.Sanitize(s, deployment.CheckDiskDevice)).To(Succeed())
+		})
+		It("fails if the number of boot tries is out of range", func() {
+			d := deployment.DefaultDeployment()
+			d.BootConfig.BootTries = 12
+			err = d.Sanitize(s, deployment.CheckDiskDevice)
+			Expect(err).To(MatchError("invalid number of boot tries 12, must be between 0 and 9"))
+		})
+		It("fails if the Secure Boot configuration misses the certificate", func() {
+			d := deployment.DefaultDeployment()
+			d.Security.SecureBoot = &secureboot.Config{Key: "/keys/db.key"}
+			err = d.Sanitize(s, deployment.CheckDiskDevice)
+			Expect(err).To(MatchError("invalid Secure Boot configuration: certificate is required"))
+		})
+		It("fails if UKI boot mode is set without systemd-boot", func() {
+			d := deployment.DefaultDeployment()
+			d.BootConfig.Bootloader = "grub"
+			d.BootConfig.UKI = true
+			err = d.Sanitize(s, deployment.CheckDiskDevice)
+			Expect(err).To(MatchError("UKI boot mode requires the 'systemd-boot' bootloader, got 'grub'"))
+		})
+		It("fails if the partition encryption is not valid", func() {
+			d := deployment.DefaultDeployment()
+			d.SourceOS = deployment.NewDirSrc("/some/dir")
+			d.Disks[0].Partitions[1].Encryption = &deployment.Encryption{}
+			err = d.Sanitize(s, deployment.CheckDiskDevice)
+			Expect(err).To(MatchError("encrypted partition 'SYSTEM' requires a key file or TPM2"))
+
+			d.Disks[0].Partitions[1].Encryption = &deployment.Encryption{KeyFile: "/keys/luks.key"}
+			err = d.Sanitize(s, deployment.CheckDiskDevice)
+			Expect(err).To(MatchError(
+				"encrypted 'system' partition is only unlocked at boot with TPM2, key files are not included in the initrd",
+			))
+
+			d.Disks[0].Partitions[1].Encryption = &deployment.Encryption{TPM2: &deployment.TPM2{PCRs: []int{7, 24}}}
+			err = d.Sanitize(s, deployment.CheckDiskDevice)
+			Expect(err).To(MatchError("invalid TPM2 PCRs [7 24] for encrypted partition 'SYSTEM'"))
+		})
+		It("accepts a TPM2 encrypted system partition with a recovery passphrase and no key file", func() {
+			d := deployment.DefaultDeployment()
+			d.SourceOS = deployment.NewDirSrc("/some/dir")
+			d.Disks[0].Partitions[1].Encryption = &deployment.Encryption{
+				TPM2: &deployment.TPM2{}, RecoveryPassphraseFile: "/keys/recovery",
+			}
+			Expect(d.Sanitize(s, deployment.CheckDiskDevice)).To(Succeed())
+
+			d.Disks[0].Partitions[1].Encryption = nil
+			d.Disks[0].Partitions[0].Encryption = &deployment.Encryption{KeyFile: "/keys/luks.key"}
+			err = d.Sanitize(s, deployment.CheckDiskDevice)
+			Expect(err).To(MatchError("encryption is not supported for 'efi' partitions"))
+		})
+		It("unlocks an encrypted system partition from the kernel command line", func() {
+			d := deployment.DefaultDeployment()
+			d.SourceOS = deployment.NewDirSrc("/some/dir")
+			sysPart := d.GetSystemPartition()
+			sysPart.UUID = "ddb334a8-48a2-c4de-ddb3-849eb2443e92"
+			sysPart.Encryption = &deployment.Encryption{
+				KeyFile: "/keys/luks.key", UUID: "2f1a3c4e", TPM2: &deployment.TPM2{PCRs: []int{7, 11}},
+			}
+			Expect(d.Sanitize(s, deployment.CheckDiskDevice)).To(Succeed())
+			Expect(sysPart.FstabDevice()).To(Equal("/dev/mapper/luks-2f1a3c4e"))
+			Expect(sysPart.Encryption.TPM2.GetPCRs()).To(Equal("7+11"))
+			Expect(d.BaseKernelCmdline()).To(Equal(
+				"root=LABEL=SYSTEM rd.luks.uuid=2f1a3c4e rd.luks.options=2f1a3c4e=tpm2-device=auto",
+			))
+
+			sysPart.Encryption = nil
+			Expect(sysPart.FstabDevice()).To(Equal("PARTUUID=ddb334a8-48a2-c4de-ddb3-849eb2443e92"))
+		})
 		It("fails if multiple system partitions are set", func() {
 			d := deployment.New(
 				deployment.WithPartitions(2, &deployment.Partition{Role: deployment.System}),
@@ -0,0 +1,206 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/suse/elemental/v3/pkg/block"
+	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+const diskByIDPath = "/dev/disk/by-id"
+
+// DiskSelector describes the target disk by its hardware attributes instead of a device path.
+// All defined criteria must match. Sizes are in MiB.
+type DiskSelector struct {
+	// ID is a /dev/disk/by-id name or path
+	ID     string `yaml:"id,omitempty"`
+	WWN    string `yaml:"wwn,omitempty"`
+	Serial string `yaml:"serial,omitempty"`
+	// Model is a regular expression matched against the disk model
+	Model      string `yaml:"model,omitempty"`
+	MinSize    MiB    `yaml:"minSize,omitempty"`
+	MaxSize    MiB    `yaml:"maxSize,omitempty"`
+	Rotational *bool  `yaml:"rotational,omitempty"`
+	Removable  *bool  `yaml:"removable,omitempty"`
+	// Transport is the disk transport type as reported by lsblk (e.g. nvme, sata, usb)
+	Transport string `yaml:"transport,omitempty"`
+	// Smallest picks the smallest matching disk instead of requiring a single match
+	Smallest bool `yaml:"smallest,omitempty"`
+}
+
+// IsValid checks the selector defines at least one criterion and all criteria are consistent
+func (sel DiskSelector) IsValid() bool {
+	if sel == (DiskSelector{}) {
+		return false
+	}
+	if sel.MaxSize > 0 && sel.MinSize > sel.MaxSize {
+		return false
+	}
+	_, err := regexp.Compile(sel.Model)
+	return err == nil
+}
+
+// Match checks whether the given disk fulfills all the selector criteria, except the
+// by-id criterion which requires resolving links on the host.
+func (sel DiskSelector) Match(disk *block.Disk) bool {
+	switch {
+	case sel.WWN != "" && !strings.EqualFold(sel.WWN, disk.WWN):
+		return false
+	case sel.Serial != "" && sel.Serial != disk.Serial:
+		return false
+	case sel.Transport != "" && sel.Transport != disk.Transport:
+		return false
+	case sel.MinSize > 0 && MiB(disk.Size) < sel.MinSize:
+		return false
+	case sel.MaxSize > 0 && MiB(disk.Size) > sel.MaxSize:
+		return false
+	case sel.Rotational != nil && *sel.Rotational != disk.Rotational:
+		return false
+	case sel.Removable != nil && *sel.Removable != disk.Removable:
+		return false
+	}
+	if sel.Model != "" {
+		re, err := regexp.Compile(sel.Model)
+		if err != nil || !re.MatchString(disk.Model) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns a compact description of the selector criteria
+func (sel DiskSelector) String() string {
+	var criteria []string
+	add := func(key string, value any) {
+		criteria = append(criteria, fmt.Sprintf("%s=%v", key, value))
+	}
+	if sel.ID != "" {
+		add("id", sel.ID)
+	}
+	if sel.WWN != "" {
+		add("wwn", sel.WWN)
+	}
+	if sel.Serial != "" {
+		add("serial", sel.Serial)
+	}
+	if sel.Model != "" {
+		add("model", sel.Model)
+	}
+	if sel.MinSize > 0 {
+		add("minSize", sel.MinSize)
+	}
+	if sel.MaxSize > 0 {
+		add("maxSize", sel.MaxSize)
+	}
+	if sel.Rotational != nil {
+		add("rotational", *sel.Rotational)
+	}
+	if sel.Removable != nil {
+		add("removable", *sel.Removable)
+	}
+	if sel.Transport != "" {
+		add("transport", sel.Transport)
+	}
+	if sel.Smallest {
+		add("smallest", true)
+	}
+	return strings.Join(criteria, ",")
+}
+
+// ResolveDisks sets the device of each disk defined by a selector and without an explicit
+// device. Disks with mounted partitions and disks already assigned to another entry are
+// never selected.
+func (d *Deployment) ResolveDisks(s *sys.System, bDev block.Device) error {
+	if !slices.ContainsFunc(d.Disks, func(disk *Disk) bool { return disk.Device == "" && disk.Selector != nil }) {
+		return nil
+	}
+
+	disks, err := bDev.GetAllDisks()
+	if err != nil {
+		return fmt.Errorf("listing disks: %w", err)
+	}
+	parts, err := bDev.GetAllPartitions()
+	if err != nil {
+		return fmt.Errorf("listing partitions: %w", err)
+	}
+
+	inUse := map[string]bool{}
+	for _, part := range parts {
+		if len(part.MountPoints) > 0 && part.Disk != "" {
+			inUse[part.Disk] = true
+		}
+	}
+	for _, disk := range d.Disks {
+		if disk.Device != "" {
+			inUse[disk.Device] = true
+		}
+	}
+
+	for i, disk := range d.Disks {
+		if disk.Device != "" || disk.Selector == nil {
+			continue
+		}
+
+		byID := ""
+		if disk.Selector.ID != "" {
+			byID = disk.Selector.ID
+			if !filepath.IsAbs(byID) {
+				byID = filepath.Join(diskByIDPath, byID)
+			}
+			byID, err = vfs.ResolveLink(s.FS(), byID, "/", 4)
+			if err != nil {
+				return fmt.Errorf("resolving disk id '%s': %w", disk.Selector.ID, err)
+			}
+		}
+
+		var candidates block.DiskList
+		for _, bDisk := range disks {
+			if inUse[bDisk.Path] || (byID != "" && byID != bDisk.Path) {
+				continue
+			}
+			if disk.Selector.Match(bDisk) {
+				candidates = append(candidates, bDisk)
+			}
+		}
+
+		switch {
+		case len(candidates) == 0:
+			return fmt.Errorf("no available disk matches selector '%s' of disk %d", disk.Selector, i)
+		case len(candidates) > 1 && !disk.Selector.Smallest:
+			var paths []string
+			for _, c := range candidates {
+				paths = append(paths, c.Path)
+			}
+			return fmt.Errorf("multiple disks match selector '%s' of disk %d: %s", disk.Selector, i, strings.Join(paths, ", "))
+		}
+
+		selected := slices.MinFunc(candidates, func(a, b *block.Disk) int { return cmp.Compare(a.Size, b.Size) })
+		s.Logger().Info("Selected disk '%s' for selector '%s'", selected.Path, disk.Selector)
+		disk.Device = selected.Path
+		inUse[selected.Path] = true
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment_test
+
+import (
+	"bytes"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/block"
+	blockmock "github.com/suse/elemental/v3/pkg/block/mock"
+	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+var _ = Describe("Disk selectors", Label("deployment", "disk"), func() {
+	var s *sys.System
+	var tfs vfs.FS
+	var cleanup func()
+	var err error
+	var bDev *blockmock.Device
+	var d *deployment.Deployment
+
+	BeforeEach(func() {
+		tfs, cleanup, err = sysmock.TestFS(map[string]string{
+			"/dev/sda":     "",
+			"/dev/sdb":     "",
+			"/dev/nvme0n1": "",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vfs.MkdirAll(tfs, "/dev/disk/by-id", vfs.DirPerm)).To(Succeed())
+		Expect(tfs.Symlink("../../nvme0n1", "/dev/disk/by-id/nvme-eui.0025388b71b1a2c3")).To(Succeed())
+		s, err = sys.NewSystem(sys.WithFS(tfs), sys.WithLogger(log.New(log.WithBuffer(&bytes.Buffer{}))))
+		Expect(err).NotTo(HaveOccurred())
+
+		bDev = blockmock.NewBlockDevice()
+		bDev.SetDisks(block.DiskList{
+			{Path: "/dev/sda", Size: 953869, Model: "ST1000DM010-2EP102", Serial: "Z9A1B2C3", WWN: "0x5000c500a1b2c3d4", Transport: "sata", Rotational: true},
+			{Path: "/dev/sdb", Size: 15272, Model: "USB Flash Disk", Serial: "0123456789", Transport: "usb", Removable: true},
+			{Path: "/dev/nvme0n1", Size: 476940, Model: "Samsung SSD 980 PRO 500GB", Serial: "S5GXNF0R123456", WWN: "eui.0025388b71b1a2c3", Transport: "nvme"},
+		})
+
+		d = deployment.DefaultDeployment()
+		d.SourceOS = deployment.NewDirSrc("/some/dir")
+	})
+
+	AfterEach(func() {
+		cleanup()
+	})
+
+	It("selects the disk matching the model and transport", func() {
+		d.Disks[0].Selector = &deployment.DiskSelector{Model: "^Samsung SSD 9", Transport: "nvme"}
+		Expect(d.ResolveDisks(s, bDev)).To(Succeed())
+		Expect(d.Disks[0].Device).To(Equal("/dev/nvme0n1"))
+		Expect(d.Sanitize(s)).To(Succeed())
+	})
+
+	It("selects the disk by its id link", func() {
+		d.Disks[0].Selector = &deployment.DiskSelector{ID: "nvme-eui.0025388b71b1a2c3"}
+		Expect(d.ResolveDisks(s, bDev)).To(Succeed())
+		Expect(d.Disks[0].Device).To(Equal("/dev/nvme0n1"))
+	})
+
+	It("selects the disk by its WWN", func() {
+		d.Disks[0].Selector = &deployment.DiskSelector{WWN: "0x5000C500A1B2C3D4"}
+		Expect(d.ResolveDisks(s, bDev)).To(Succeed())
+		Expect(d.Disks[0].Device).To(Equal("/dev/sda"))
+	})
+
+	It("selects the smallest non-removable disk", func() {
+		removable := false
+		d.Disks[0].Selector = &deployment.DiskSelector{Removable: &removable, Smallest: true}
+		Expect(d.ResolveDisks(s, bDev)).To(Succeed())
+		Expect(d.Disks[0].Device).To(Equal("/dev/nvme0n1"))
+	})
+
+	It("selects the disk within the size range", func() {
+		rotational := true
+		d.Disks[0].Selector = &deployment.DiskSelector{MinSize: 500000, MaxSize: 1000000, Rotational: &rotational}
+		Expect(d.ResolveDisks(s, bDev)).To(Succeed())
+		Expect(d.Disks[0].Device).To(Equal("/dev/sda"))
+	})
+
+	It("does not change disks with an explicit device", func() {
+		d.Disks[0].Device = "/dev/sdb"
+		d.Disks[0].Selector = &deployment.DiskSelector{Transport: "nvme"}
+		Expect(d.ResolveDisks(s, bDev)).To(Succeed())
+		Expect(d.Disks[0].Device).To(Equal("/dev/sdb"))
+	})
+
+	It("skips disks with mounted partitions", func() {
+		bDev.SetPartitions(block.PartitionList{
+			{Path: "/dev/nvme0n1p1", Disk: "/dev/nvme0n1", MountPoints: []string{"/run/initramfs/live"}},
+		})
+		d.Disks[0].Selector = &deployment.DiskSelector{MinSize: 100000, Smallest: true}
+		Expect(d.ResolveDisks(s, bDev)).To(Succeed())
+		Expect(d.Disks[0].Device).To(Equal("/dev/sda"))
+	})
+
+	It("fails if multiple disks match", func() {
+		d.Disks[0].Selector = &deployment.DiskSelector{MinSize: 10000}
+		err = d.ResolveDisks(s, bDev)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("multiple disks match"))
+	})
+
+	It("fails if no disk matches", func() {
+		d.Disks[0].Selector = &deployment.DiskSelector{Serial: "unknown"}
+		err = d.ResolveDisks(s, bDev)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("no available disk matches"))
+	})
+
+	It("fails if disks can't be listed", func() {
+		bDev.SetError(fmt.Errorf("lsblk failed"))
+		d.Disks[0].Selector = &deployment.DiskSelector{Smallest: true}
+		Expect(d.ResolveDisks(s, bDev)).NotTo(Succeed())
+	})
+
+	It("accepts a deployment with a selector and no device", func() {
+		d.Disks[0].Selector = &deployment.DiskSelector{Transport: "nvme"}
+		Expect(d.Sanitize(s)).To(Succeed())
+	})
+
+	It("rejects invalid selectors", func() {
+		d.Disks[0].Selector = &deployment.DiskSelector{Model: "[invalid"}
+		err = d.Sanitize(s)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("invalid disk selector"))
+
+		d.Disks[0].Selector = &deployment.DiskSelector{MinSize: 2048, MaxSize: 1024}
+		Expect(d.Sanitize(s)).NotTo(Succeed())
+
+		d.Disks[0].Selector = &deployment.DiskSelector{}
+		Expect(d.Sanitize(s)).NotTo(Succeed())
+	})
+})
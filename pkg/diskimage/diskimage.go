@@ -0,0 +1,108 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diskimage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/suse/elemental/v3/pkg/sys"
+)
+
+type Format string
+
+const (
+	Raw   Format = "raw"
+	QCOW2 Format = "qcow2"
+	VHDX  Format = "vhdx"
+	VMDK  Format = "vmdk"
+	VHD   Format = "vhd"
+)
+
+// Formats returns all the supported disk image formats
+func Formats() []Format {
+	return []Format{Raw, QCOW2, VHDX, VMDK, VHD}
+}
+
+// ParseFormat returns the disk image format for the given name
+func ParseFormat(name string) (Format, error) {
+	f := Format(name)
+	if !slices.Contains(Formats(), f) {
+		return "", fmt.Errorf("unsupported disk image format %s: %w", name, errors.ErrUnsupported)
+	}
+	return f, nil
+}
+
+func (f Format) String() string {
+	return string(f)
+}
+
+// IsConverted returns true if images of this format are converted from a RAW image
+func (f Format) IsConverted() bool {
+	return f != "" && f != Raw
+}
+
+// SupportsCompression returns true if images of this format can be compressed
+func (f Format) SupportsCompression() bool {
+	return f == QCOW2 || f == VMDK
+}
+
+// qemuImgArgs returns the qemu-img output format options for the given format
+func (f Format) qemuImgArgs(compress bool) []string {
+	switch f {
+	case QCOW2:
+		if compress {
+			return []string{"-O", "qcow2", "-c"}
+		}
+		return []string{"-O", "qcow2"}
+	case VHDX:
+		return []string{"-O", "vhdx", "-o", "subformat=dynamic"}
+	case VMDK:
+		if compress {
+			return []string{"-O", "vmdk", "-o", "subformat=streamOptimized"}
+		}
+		return []string{"-O", "vmdk", "-o", "subformat=monolithicSparse"}
+	case VHD:
+		// Fixed size VHDs aligned to 1MiB are required by most hypervisors
+		return []string{"-O", "vpc", "-o", "subformat=fixed,force_size=on"}
+	default:
+		return nil
+	}
+}
+
+// Convert converts the given RAW image into a new image of the given format
+func Convert(ctx context.Context, s *sys.System, src, dst string, f Format, compress bool) error {
+	if !f.IsConverted() {
+		return fmt.Errorf("no conversion required for %s images", f)
+	}
+	if compress && !f.SupportsCompression() {
+		return fmt.Errorf("compression is not supported for %s images", f)
+	}
+
+	args := append([]string{"convert", "-f", "raw"}, f.qemuImgArgs(compress)...)
+	args = append(args, src, dst)
+
+	s.Logger().Info("Converting disk image to %s format", f)
+	out, err := s.Runner().RunContext(ctx, "qemu-img", args...)
+	if err != nil {
+		return fmt.Errorf("converting '%s' to %s image '%s': %s: %w", src, f, dst, string(out), err)
+	}
+	return nil
+}
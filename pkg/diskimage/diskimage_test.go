@@ -0,0 +1,85 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diskimage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/diskimage"
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+)
+
+func TestDiskImageSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Disk image test suite")
+}
+
+var _ = Describe("Disk image", Label("diskimage"), func() {
+	var s *sys.System
+	var runner *sysmock.Runner
+	var err error
+	BeforeEach(func() {
+		runner = sysmock.NewRunner()
+		s, err = sys.NewSystem(sys.WithLogger(log.New(log.WithDiscardAll())), sys.WithRunner(runner))
+		Expect(err).NotTo(HaveOccurred())
+	})
+	It("parses supported formats", func() {
+		f, err := diskimage.ParseFormat("vhdx")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f).To(Equal(diskimage.VHDX))
+		Expect(f.IsConverted()).To(BeTrue())
+		Expect(diskimage.Raw.IsConverted()).To(BeFalse())
+
+		_, err = diskimage.ParseFormat("vdi")
+		Expect(err).To(MatchError(errors.ErrUnsupported))
+	})
+	It("converts a RAW image to a compressed qcow2 image", func() {
+		Expect(diskimage.Convert(context.Background(), s, "disk.raw", "disk.qcow2", diskimage.QCOW2, true)).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{
+			{"qemu-img", "convert", "-f", "raw", "-O", "qcow2", "-c", "disk.raw", "disk.qcow2"},
+		})).To(Succeed())
+	})
+	It("converts a RAW image to a fixed VHD image", func() {
+		Expect(diskimage.Convert(context.Background(), s, "disk.raw", "disk.vhd", diskimage.VHD, false)).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{
+			{"qemu-img", "convert", "-f", "raw", "-O", "vpc", "-o", "subformat=fixed,force_size=on", "disk.raw", "disk.vhd"},
+		})).To(Succeed())
+	})
+	It("converts a RAW image to a stream optimized VMDK image when compressed", func() {
+		Expect(diskimage.Convert(context.Background(), s, "disk.raw", "disk.vmdk", diskimage.VMDK, true)).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{
+			{"qemu-img", "convert", "-f", "raw", "-O", "vmdk", "-o", "subformat=streamOptimized", "disk.raw", "disk.vmdk"},
+		})).To(Succeed())
+	})
+	It("fails to compress VHDX images", func() {
+		err = diskimage.Convert(context.Background(), s, "disk.raw", "disk.vhdx", diskimage.VHDX, true)
+		Expect(err).To(HaveOccurred())
+		Expect(runner.GetCmds()).To(BeEmpty())
+	})
+	It("fails if qemu-img fails", func() {
+		runner.ReturnError = errors.New("qemu-img failed")
+		Expect(diskimage.Convert(context.Background(), s, "disk.raw", "disk.qcow2", diskimage.QCOW2, false)).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,171 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"sync"
+	"time"
+)
+
+// Type is the kind of an event
+type Type string
+
+const (
+	PhaseStarted  Type = "phase-started"
+	PhaseFinished Type = "phase-finished"
+	Progress      Type = "progress"
+	Warning       Type = "warning"
+	Result        Type = "result"
+)
+
+// Phases reported by the commands
+const (
+	PhaseConfigure  = "configure"
+	PhasePartition  = "partition"
+	PhaseRecovery   = "recovery"
+	PhaseDeploy     = "deploy"
+	PhaseSetup      = "setup"
+	PhaseBootloader = "bootloader"
+	PhaseUnpack     = "unpack"
+	PhaseSync       = "sync"
+	PhaseInstall    = "install"
+	PhaseConvert    = "convert"
+	PhaseMedia      = "media"
+)
+
+// Event is a single entry of the event stream, it is written as one JSON object per line
+type Event struct {
+	Time    time.Time `json:"time"`
+	Type    Type      `json:"type"`
+	Phase   string    `json:"phase,omitempty"`
+	Message string    `json:"message,omitempty"`
+	// Bytes is the amount of bytes transferred so far in the phase
+	Bytes int64 `json:"bytes,omitempty"`
+	// Percent is the completion of the phase, if known
+	Percent int      `json:"percent,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	Outcome *Outcome `json:"result,omitempty"`
+}
+
+// Outcome is the final result of a command
+type Outcome struct {
+	Command  string `json:"command"`
+	Success  bool   `json:"success"`
+	Snapshot int    `json:"snapshot,omitempty"`
+	// Digests maps the images pulled or produced by the command to their digests
+	Digests map[string]string `json:"digests,omitempty"`
+}
+
+// Emitter writes events as JSON lines to a writer. A nil Emitter is valid and
+// discards all events, so callers do not need to check whether events are enabled.
+type Emitter struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	snapshot int
+	digests  map[string]string
+}
+
+// New returns an Emitter writing to the given writer
+func New(w io.Writer) *Emitter {
+	return &Emitter{enc: json.NewEncoder(w), digests: map[string]string{}}
+}
+
+// Enabled reports whether events are written anywhere
+func (e *Emitter) Enabled() bool {
+	return e != nil
+}
+
+// Start reports the given phase started
+func (e *Emitter) Start(phase string) {
+	e.emit(Event{Type: PhaseStarted, Phase: phase})
+}
+
+// Finish reports the given phase finished, with the error it failed with, if any
+func (e *Emitter) Finish(phase string, err error) {
+	ev := Event{Type: PhaseFinished, Phase: phase}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	e.emit(ev)
+}
+
+// Progress reports the bytes transferred and the completion percentage of the given phase,
+// zero values are omitted
+func (e *Emitter) Progress(phase string, bytes int64, percent int) {
+	e.emit(Event{Type: Progress, Phase: phase, Bytes: bytes, Percent: percent})
+}
+
+// Warn reports a warning message
+func (e *Emitter) Warn(msg string) {
+	e.emit(Event{Type: Warning, Message: msg})
+}
+
+// Snapshot records the snapshot created by the command, it is reported in the final result
+func (e *Emitter) Snapshot(id int) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.snapshot = id
+}
+
+// Digest records the digest of an image pulled or produced by the command, it is
+// reported in the final result
+func (e *Emitter) Digest(name, digest string) {
+	if e == nil || digest == "" {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.digests[name] = digest
+}
+
+// Done reports the final result of the given command including the recorded snapshot and digests
+func (e *Emitter) Done(command string, err error) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	outcome := &Outcome{Command: command, Success: err == nil, Snapshot: e.snapshot}
+	if len(e.digests) > 0 {
+		outcome.Digests = maps.Clone(e.digests)
+	}
+	e.mu.Unlock()
+
+	ev := Event{Type: Result, Outcome: outcome}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	e.emit(ev)
+}
+
+func (e *Emitter) emit(ev Event) {
+	if e == nil {
+		return
+	}
+	ev.Time = time.Now().UTC()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// the event stream is best effort, it never fails the operation it reports
+	_ = e.enc.Encode(ev)
+}
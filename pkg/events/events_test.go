@@ -0,0 +1,134 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/events"
+	"github.com/suse/elemental/v3/pkg/log"
+)
+
+func TestEventsSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Events test suite")
+}
+
+func readEvents(buf *bytes.Buffer) []events.Event {
+	var evs []events.Event
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var ev events.Event
+		ExpectWithOffset(1, json.Unmarshal(scanner.Bytes(), &ev)).To(Succeed())
+		evs = append(evs, ev)
+	}
+	return evs
+}
+
+var _ = Describe("Events", Label("events"), func() {
+	var buf *bytes.Buffer
+	var e *events.Emitter
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		e = events.New(buf)
+	})
+	It("writes one JSON event per line", func() {
+		e.Start(events.PhaseDeploy)
+		e.Progress(events.PhaseSync, 0, 42)
+		e.Finish(events.PhaseDeploy, errors.New("sync failed"))
+		e.Warn("careful")
+
+		evs := readEvents(buf)
+		Expect(evs).To(HaveLen(4))
+		Expect(evs[0].Type).To(Equal(events.PhaseStarted))
+		Expect(evs[0].Phase).To(Equal(events.PhaseDeploy))
+		Expect(evs[0].Time.IsZero()).To(BeFalse())
+		Expect(evs[1].Type).To(Equal(events.Progress))
+		Expect(evs[1].Percent).To(Equal(42))
+		Expect(evs[2].Type).To(Equal(events.PhaseFinished))
+		Expect(evs[2].Error).To(Equal("sync failed"))
+		Expect(evs[3].Type).To(Equal(events.Warning))
+		Expect(evs[3].Message).To(Equal("careful"))
+	})
+	It("reports the recorded snapshot and digests in the final result", func() {
+		e.Snapshot(3)
+		e.Digest("registry.example.com/os:1.0", "sha256:abc")
+		e.Digest("ignored", "")
+		e.Done("install", nil)
+
+		evs := readEvents(buf)
+		Expect(evs).To(HaveLen(1))
+		Expect(evs[0].Type).To(Equal(events.Result))
+		Expect(evs[0].Outcome).To(Equal(&events.Outcome{
+			Command: "install", Success: true, Snapshot: 3,
+			Digests: map[string]string{"registry.example.com/os:1.0": "sha256:abc"},
+		}))
+	})
+	It("reports failed commands", func() {
+		e.Done("upgrade", errors.New("no space left"))
+
+		evs := readEvents(buf)
+		Expect(evs).To(HaveLen(1))
+		Expect(evs[0].Outcome.Success).To(BeFalse())
+		Expect(evs[0].Error).To(Equal("no space left"))
+	})
+	It("discards all events of a nil emitter", func() {
+		var nilEmitter *events.Emitter
+		Expect(nilEmitter.Enabled()).To(BeFalse())
+		nilEmitter.Start(events.PhaseDeploy)
+		nilEmitter.Snapshot(1)
+		nilEmitter.Digest("os", "sha256:abc")
+		nilEmitter.Done("install", nil)
+
+		r := strings.NewReader("data")
+		Expect(nilEmitter.NewReader(r, events.PhaseUnpack)).To(BeIdenticalTo(r))
+	})
+	It("reports the bytes read as progress", func() {
+		data, err := io.ReadAll(e.NewReader(strings.NewReader("some layer data"), events.PhaseUnpack))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("some layer data"))
+
+		evs := readEvents(buf)
+		Expect(evs).NotTo(BeEmpty())
+		last := evs[len(evs)-1]
+		Expect(last.Type).To(Equal(events.Progress))
+		Expect(last.Phase).To(Equal(events.PhaseUnpack))
+		Expect(last.Bytes).To(Equal(int64(len("some layer data"))))
+	})
+	It("reports logged warnings", func() {
+		logBuf := &bytes.Buffer{}
+		logger := events.WrapLogger(log.New(log.WithBuffer(logBuf)), e)
+		logger.Warn("disk %s is small", "/dev/sda")
+		logger.Info("not an event")
+
+		Expect(logBuf.String()).To(ContainSubstring("disk /dev/sda is small"))
+		evs := readEvents(buf)
+		Expect(evs).To(HaveLen(1))
+		Expect(evs[0].Type).To(Equal(events.Warning))
+		Expect(evs[0].Message).To(Equal("disk /dev/sda is small"))
+	})
+})
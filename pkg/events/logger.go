@@ -0,0 +1,42 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"fmt"
+
+	"github.com/suse/elemental/v3/pkg/log"
+)
+
+type logger struct {
+	log.Logger
+	e *Emitter
+}
+
+// WrapLogger returns a logger which also reports the warnings logged to l as events
+func WrapLogger(l log.Logger, e *Emitter) log.Logger {
+	if e == nil {
+		return l
+	}
+	return &logger{Logger: l, e: e}
+}
+
+func (l *logger) Warn(msg string, args ...any) {
+	l.Logger.Warn(msg, args...)
+	l.e.Warn(fmt.Sprintf(msg, args...))
+}
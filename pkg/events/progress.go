@@ -0,0 +1,52 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"io"
+	"time"
+)
+
+// progressInterval is the minimum time between two progress events of a reader
+const progressInterval = time.Second
+
+type progressReader struct {
+	r     io.Reader
+	e     *Emitter
+	phase string
+	bytes int64
+	last  time.Time
+}
+
+// NewReader returns a reader reporting the bytes read from r as progress of the given phase
+func (e *Emitter) NewReader(r io.Reader, phase string) io.Reader {
+	if e == nil {
+		return r
+	}
+	return &progressReader{r: r, e: e, phase: phase}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.bytes += int64(n)
+	if now := time.Now(); err == io.EOF || now.Sub(p.last) >= progressInterval {
+		p.last = now
+		p.e.Progress(p.phase, p.bytes, 0)
+	}
+	return n, err
+}
@@ -23,6 +23,10 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/suse/elemental/v3/pkg/bundle"
+	"github.com/suse/elemental/v3/pkg/cache"
+	"github.com/suse/elemental/v3/pkg/registry"
+	"github.com/suse/elemental/v3/pkg/signature"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
 	"github.com/suse/elemental/v3/pkg/unpack"
@@ -35,11 +39,20 @@ type OCIUnpacker interface {
 }
 
 type ociUnpacker struct {
-	system *sys.System
+	system      *sys.System
+	localSource unpack.LocalSource
+	policy      *signature.Policy
+	bundle      *bundle.Bundle
+	cache       *cache.Cache
+	registries  *registry.Config
 }
 
 func (o *ociUnpacker) Unpack(ctx context.Context, uri, dest string, local bool) (digest string, err error) {
-	unpacker := unpack.NewOCIUnpacker(o.system, uri, unpack.WithLocalOCI(local))
+	unpacker := unpack.NewOCIUnpacker(
+		o.system, uri, unpack.WithLocalOCI(local), unpack.WithLocalSourceOCI(o.localSource),
+		unpack.WithSignaturePolicyOCI(o.policy), unpack.WithBundleOCI(o.bundle), unpack.WithCacheOCI(o.cache),
+		unpack.WithRegistriesOCI(o.registries),
+	)
 	return unpacker.Unpack(ctx, dest)
 }
 
@@ -59,6 +72,13 @@ type OCIFileExtractor struct {
 	fs       vfs.FS
 	ctx      context.Context
 	local    bool
+	// localSource is the store local images are read from
+	localSource unpack.LocalSource
+	policy      *signature.Policy
+	bundle      *bundle.Bundle
+	cache       *cache.Cache
+	// registries configures the access to the registries of the images
+	registries *registry.Config
 }
 
 type OCIFileExtractorOpts func(o *OCIFileExtractor)
@@ -93,6 +113,43 @@ func WithLocal(local bool) OCIFileExtractorOpts {
 	}
 }
 
+// WithLocalSource sets the store local images are read from when using the default OCI unpacker
+func WithLocalSource(source unpack.LocalSource) OCIFileExtractorOpts {
+	return func(r *OCIFileExtractor) {
+		r.localSource = source
+	}
+}
+
+// WithSignaturePolicy sets the signature policy images are verified against
+// when using the default OCI unpacker
+func WithSignaturePolicy(policy *signature.Policy) OCIFileExtractorOpts {
+	return func(r *OCIFileExtractor) {
+		r.policy = policy
+	}
+}
+
+// WithBundle sets the bundle images are read from when using the default OCI unpacker
+func WithBundle(b *bundle.Bundle) OCIFileExtractorOpts {
+	return func(r *OCIFileExtractor) {
+		r.bundle = b
+	}
+}
+
+// WithCache sets the cache image layers are read from when using the default OCI unpacker
+func WithCache(c *cache.Cache) OCIFileExtractorOpts {
+	return func(r *OCIFileExtractor) {
+		r.cache = c
+	}
+}
+
+// WithRegistries sets the credentials, mirrors and TLS settings images are pulled with
+// when using the default OCI unpacker
+func WithRegistries(c *registry.Config) OCIFileExtractorOpts {
+	return func(r *OCIFileExtractor) {
+		r.registries = c
+	}
+}
+
 func New(searchPaths []string, opts ...OCIFileExtractorOpts) (*OCIFileExtractor, error) {
 	extr := &OCIFileExtractor{
 		searchPaths: searchPaths,
@@ -124,7 +181,12 @@ func New(searchPaths []string, opts ...OCIFileExtractorOpts) (*OCIFileExtractor,
 		}
 
 		extr.unpacker = &ociUnpacker{
-			system: s,
+			system:      s,
+			localSource: extr.localSource,
+			policy:      extr.policy,
+			bundle:      extr.bundle,
+			cache:       extr.cache,
+			registries:  extr.registries,
 		}
 	}
 
@@ -80,4 +80,13 @@ var _ = Describe("Mksquashfs", Label("mksquashfs"), func() {
 			{"mksquashfs", "/some/root", "/some/rootfs.squashfs", "-b", "1024k"},
 		})).To(Succeed())
 	})
+	It("Creates a squashfs image with fixed timestamps", func() {
+		Expect(filesystem.CreateSquashFS(
+			context.Background(), s, "/some/root", "/some/rootfs.squashfs",
+			append(filesystem.DefaultSquashfsCompressionOptions(), filesystem.SquashfsReproducibleOptions("1700000000")...),
+		)).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{
+			{"mksquashfs", "/some/root", "/some/rootfs.squashfs", "-b", "1024k", "-mkfs-time", "1700000000", "-all-time", "1700000000"},
+		})).To(Succeed())
+	})
 })
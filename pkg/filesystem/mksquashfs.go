@@ -53,3 +53,9 @@ func SquashfsExcludeOptions(excludes ...string) []string {
 	opts = append(opts, "-wildcards", "-e")
 	return append(opts, excludes...)
 }
+
+// SquashfsReproducibleOptions sets the filesystem and all file timestamps to the given
+// seconds since the Unix epoch
+func SquashfsReproducibleOptions(timestamp string) []string {
+	return []string{"-mkfs-time", timestamp, "-all-time", timestamp}
+}
@@ -19,6 +19,7 @@ package firmware
 
 import (
 	"path/filepath"
+	"strconv"
 
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/platform"
@@ -44,6 +45,8 @@ type EfiBootEntry struct {
 	Label  string
 	Loader string
 	Disk   string
+	// Partition is the number of the EFI partition in the disk, efibootmgr defaults to the first one
+	Partition int
 }
 
 // NewEfiBootManager creates a new EfiBootManager.
@@ -56,7 +59,11 @@ func (b *EfiBootManager) CreateBootEntries(entries []*EfiBootEntry) error {
 	b.s.Logger().Info("Creating %d boot entries...", len(entries))
 
 	for _, entry := range entries {
-		cmdOut, err := b.s.Runner().Run("efibootmgr", "--create", "--disk", entry.Disk, "--label", entry.Label, "--loader", entry.Loader)
+		args := []string{"--create", "--disk", entry.Disk, "--label", entry.Label, "--loader", entry.Loader}
+		if entry.Partition > 0 {
+			args = append(args, "--part", strconv.Itoa(entry.Partition))
+		}
+		cmdOut, err := b.s.Runner().Run("efibootmgr", args...)
 		if err != nil {
 			b.s.Logger().Error("failed creating boot entry (%s): %s", err.Error(), string(cmdOut))
 			return err
@@ -32,7 +32,12 @@ import (
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
 )
 
-const File = "/etc/fstab"
+const (
+	File         = "/etc/fstab"
+	CrypttabFile = "/etc/crypttab"
+	// CryptKeysDir is the directory systemd-cryptsetup looks up volume key files in
+	CryptKeysDir = "/etc/cryptsetup-keys.d"
+)
 
 type Line struct {
 	Device     string
@@ -43,6 +48,48 @@ type Line struct {
 	FsckOrder  int
 }
 
+// CrypttabLine is an encrypted volume entry of a crypttab file
+type CrypttabLine struct {
+	Name    string
+	Device  string
+	KeyFile string
+	Options []string
+}
+
+// WriteCrypttab writes a crypttab file at the given location including the given crypttab lines
+func WriteCrypttab(s *sys.System, crypttabFile string, crypttabLines []CrypttabLine) (err error) {
+	crypttab, err := s.FS().Create(crypttabFile)
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+	defer func() {
+		e := crypttab.Close()
+		if err == nil && e != nil {
+			err = fmt.Errorf("closing file: %w", e)
+		}
+	}()
+
+	tw := tabwriter.NewWriter(crypttab, 1, 4, 1, ' ', 0)
+	for _, cLine := range crypttabLines {
+		keyFile := cLine.KeyFile
+		if keyFile == "" {
+			keyFile = "none"
+		}
+		_, err = fmt.Fprintf( // #nosec G705
+			tw, "%s\t%s\t%s\t%s\n", cLine.Name, cLine.Device, keyFile, strings.Join(cLine.Options, ","),
+		)
+		if err != nil {
+			return fmt.Errorf("writing content: %w", err)
+		}
+	}
+
+	err = tw.Flush()
+	if err != nil {
+		return fmt.Errorf("writing content: %w", err)
+	}
+	return nil
+}
+
 // Write writes an fstab file at the given location including the given fstab lines
 func Write(s *sys.System, fstabFile string, fstabLines []Line) (err error) {
 	fstab, err := s.FS().Create(fstabFile)
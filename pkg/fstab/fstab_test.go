@@ -46,6 +46,10 @@ UUID=uuid   /data btrfs defaults,subvol=/@/new/data     0 0
 UUID=afadf  /etc  btrfs defaults,subvol=/@/new/path/etc 0 0
 `
 
+const crypttabFile = `luks-root UUID=2f1a3c4e none               luks,tpm2-device=auto
+luks-data UUID=8b9c0d1e /etc/keys/data.key luks
+`
+
 var _ = Describe("Fstab", Label("fstab"), func() {
 	var tfs vfs.FS
 	var s *sys.System
@@ -87,6 +91,16 @@ var _ = Describe("Fstab", Label("fstab"), func() {
 		Expect(err).NotTo(HaveOccurred())
 		Expect(string(data)).To(Equal(fstabFile))
 	})
+	It("creates a crypttab file with the given lines", func() {
+		Expect(fstab.WriteCrypttab(s, fstab.CrypttabFile, []fstab.CrypttabLine{{
+			Name: "luks-root", Device: "UUID=2f1a3c4e", Options: []string{"luks", "tpm2-device=auto"},
+		}, {
+			Name: "luks-data", Device: "UUID=8b9c0d1e", KeyFile: "/etc/keys/data.key", Options: []string{"luks"},
+		}})).To(Succeed())
+		data, err := tfs.ReadFile(fstab.CrypttabFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(crypttabFile))
+	})
 	It("fails to write fstab file on a read-only filesystem", func() {
 		tfs, err := sysmock.ReadOnlyTestFS(tfs)
 		Expect(err).NotTo(HaveOccurred())
@@ -0,0 +1,66 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// RepositoryIndex is the index.yaml file of an HTTP(S) chart repository
+type RepositoryIndex struct {
+	Entries map[string][]ChartVersion `yaml:"entries"`
+}
+
+type ChartVersion struct {
+	Version string   `yaml:"version"`
+	URLs    []string `yaml:"urls"`
+}
+
+// ChartURL returns the URL of the archive of the given chart version listed in the
+// index.yaml file of the given repository. Relative URLs are resolved against the repository.
+func ChartURL(index []byte, repository, chart, version string) (string, error) {
+	var repoIndex RepositoryIndex
+	if err := yaml.Unmarshal(index, &repoIndex); err != nil {
+		return "", fmt.Errorf("parsing repository index: %w", err)
+	}
+
+	base, err := url.Parse(strings.TrimSuffix(repository, "/") + "/")
+	if err != nil {
+		return "", fmt.Errorf("parsing repository URL: %w", err)
+	}
+
+	for _, v := range repoIndex.Entries[chart] {
+		if v.Version != version {
+			continue
+		}
+		if len(v.URLs) == 0 {
+			return "", fmt.Errorf("no URLs listed for chart %s %s", chart, version)
+		}
+
+		chartURL, err := url.Parse(v.URLs[0])
+		if err != nil {
+			return "", fmt.Errorf("parsing chart URL: %w", err)
+		}
+		return base.ResolveReference(chartURL).String(), nil
+	}
+	return "", fmt.Errorf("chart %s %s not found in repository '%s'", chart, version, repository)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const repositoryIndex = `apiVersion: v1
+entries:
+  metallb:
+  - version: 0.15.2
+    urls:
+    - metallb-0.15.2.tgz
+  - version: 0.14.9
+    urls:
+    - https://charts.example.com/archive/metallb-0.14.9.tgz
+  cert-manager:
+  - version: 1.18.2
+`
+
+var _ = Describe("Repository index", func() {
+	It("Resolves relative chart URLs against the repository", func() {
+		url, err := ChartURL([]byte(repositoryIndex), "https://metallb.github.io/metallb", "metallb", "0.15.2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(url).To(Equal("https://metallb.github.io/metallb/metallb-0.15.2.tgz"))
+	})
+
+	It("Returns absolute chart URLs", func() {
+		url, err := ChartURL([]byte(repositoryIndex), "https://metallb.github.io/metallb/", "metallb", "0.14.9")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(url).To(Equal("https://charts.example.com/archive/metallb-0.14.9.tgz"))
+	})
+
+	It("Fails for missing charts", func() {
+		_, err := ChartURL([]byte(repositoryIndex), "https://metallb.github.io/metallb", "metallb", "0.13.0")
+		Expect(err).To(MatchError(ContainSubstring("chart metallb 0.13.0 not found")))
+
+		_, err = ChartURL([]byte(repositoryIndex), "https://charts.jetstack.io", "cert-manager", "1.18.2")
+		Expect(err).To(MatchError("no URLs listed for chart cert-manager 1.18.2"))
+
+		_, err = ChartURL([]byte("entries: ["), "https://charts.jetstack.io", "cert-manager", "1.18.2")
+		Expect(err).To(MatchError(ContainSubstring("parsing repository index")))
+	})
+})
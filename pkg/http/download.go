@@ -20,34 +20,256 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	backoff "github.com/cenkalti/backoff/v4"
+	"golang.org/x/net/http/httpproxy"
+
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
 )
 
-func DownloadFile(ctx context.Context, fs vfs.FS, url, path string) error {
+const (
+	// PartialSuffix is appended to the path of a file while it is downloaded, an interrupted
+	// download is resumed from it
+	PartialSuffix = ".part"
+	// partialInfoSuffix is appended to the path of a partial file to record where it is downloaded from
+	partialInfoSuffix = ".info"
+
+	defaultRetries       = 4
+	defaultRetryInterval = 2 * time.Second
+	responseTimeout      = 90 * time.Second
+)
+
+// Downloader fetches files over HTTP(S). Interrupted transfers are resumed with range requests,
+// failed ones are retried with an exponential backoff and then attempted from the configured mirrors.
+// Proxies are taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+type Downloader struct {
+	client        *http.Client
+	mirrors       []string
+	retries       uint64
+	retryInterval time.Duration
+	caBundle      []byte
+}
+
+type Option func(d *Downloader)
+
+// WithMirrors sets base URLs files are attempted from, in order, if downloading them from their
+// own URL fails. The path of the file URL is appended to the mirror URL.
+func WithMirrors(mirrors ...string) Option {
+	return func(d *Downloader) {
+		d.mirrors = mirrors
+	}
+}
+
+// WithCABundle sets PEM encoded CA certificates trusted in addition to the system ones
+func WithCABundle(pem []byte) Option {
+	return func(d *Downloader) {
+		d.caBundle = pem
+	}
+}
+
+// WithRetries sets how many times a failed download is retried from each URL
+func WithRetries(retries uint64) Option {
+	return func(d *Downloader) {
+		d.retries = retries
+	}
+}
+
+// WithRetryInterval sets the initial interval between retries, it grows exponentially
+func WithRetryInterval(interval time.Duration) Option {
+	return func(d *Downloader) {
+		d.retryInterval = interval
+	}
+}
+
+func NewDownloader(opts ...Option) (*Downloader, error) {
+	d := &Downloader{
+		retries:       defaultRetries,
+		retryInterval: defaultRetryInterval,
+	}
+	for _, o := range opts {
+		o(d)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// http.ProxyFromEnvironment reads the environment only once per process
+	proxy := httpproxy.FromEnvironment().ProxyFunc()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) { return proxy(req.URL) }
+	transport.ResponseHeaderTimeout = responseTimeout
+	if len(d.caBundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(d.caBundle) {
+			return nil, fmt.Errorf("no valid certificates found in CA bundle")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	d.client = &http.Client{Transport: transport}
+
+	return d, nil
+}
+
+// DownloadFile downloads the file at the given URL to the given path with the default downloader
+// settings, the file is verified against the given 'sha256:<hex>' digest, if any.
+func DownloadFile(ctx context.Context, fs vfs.FS, url, path, digest string) error {
+	d, err := NewDownloader()
+	if err != nil {
+		return err
+	}
+	return d.Download(ctx, fs, url, path, digest)
+}
+
+// Download downloads the file at the given URL to the given path, the file is verified
+// against the given 'sha256:<hex>' digest, if any. The file is only written to the given
+// path once it is complete and verified.
+func (d *Downloader) Download(ctx context.Context, fs vfs.FS, url, path, digest string) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return fmt.Errorf("creating request: %w", err)
 	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("unsupported protocol scheme '%s' in URL '%s'", req.URL.Scheme, url)
+	}
 
-	httpClient := &http.Client{Timeout: 90 * time.Second}
-	resp, err := httpClient.Do(req) // #nosec G704 -- url is assumed to be trusted.
+	urls, err := d.candidates(url)
 	if err != nil {
-		return fmt.Errorf("executing request: %w", err)
+		return err
+	}
+
+	var errs []error
+	for _, u := range urls {
+		b := backoff.NewExponentialBackOff(
+			backoff.WithInitialInterval(d.retryInterval), backoff.WithMaxElapsedTime(0),
+		)
+		err = backoff.Retry(func() error {
+			return d.fetch(ctx, fs, u, path, digest)
+		}, backoff.WithContext(backoff.WithMaxRetries(b, d.retries), ctx))
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("downloading '%s': %w", u, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// candidates returns the URLs the given file URL is attempted from, in order
+func (d *Downloader) candidates(fileURL string) ([]string, error) {
+	urls := []string{fileURL}
+	if len(d.mirrors) == 0 {
+		return urls, nil
+	}
+
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing URL '%s': %w", fileURL, err)
+	}
+	for _, mirror := range d.mirrors {
+		m, err := url.Parse(mirror)
+		if err != nil {
+			return nil, fmt.Errorf("parsing mirror URL '%s': %w", mirror, err)
+		}
+		m = m.JoinPath(u.Path)
+		m.RawQuery = u.RawQuery
+		urls = append(urls, m.String())
+	}
+	return urls, nil
+}
+
+// partialInfo records the source of a partial file, it is only resumed from the same URL and
+// as long as the remote file matches the validator
+type partialInfo struct {
+	URL string `json:"url"`
+	// Validator is the strong ETag or the Last-Modified date of the remote file
+	Validator string `json:"validator,omitempty"`
+}
+
+// fetch downloads the given URL to the partial file of the given path, resuming it if it already
+// exists, and moves it to the given path once verified. Errors which are not worth a retry are
+// returned as permanent.
+func (d *Downloader) fetch(ctx context.Context, fs vfs.FS, url, path, digest string) error {
+	partial := path + PartialSuffix
+
+	var offset int64
+	info := resumableInfo(fs, partial, url, digest)
+	if info == nil {
+		removePartial(fs, partial)
+	} else if fInfo, err := fs.Stat(partial); err == nil {
+		offset = fInfo.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return backoff.Permanent(fmt.Errorf("creating request: %w", err))
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if info.Validator != "" {
+			// the full file is sent instead if it changed since the partial file was started
+			req.Header.Set("If-Range", info.Validator)
+		}
+	}
+
+	resp, err := d.client.Do(req) // #nosec G704 -- url is assumed to be trusted.
+	if err != nil {
+		err = fmt.Errorf("executing request: %w", err)
+		var certErr *tls.CertificateVerificationError
+		if errors.As(err, &certErr) {
+			return backoff.Permanent(err)
+		}
+		return err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
+	flags := os.O_WRONLY | os.O_CREATE
+	switch resp.StatusCode {
+	case http.StatusOK:
+		flags |= os.O_TRUNC
+	case http.StatusPartialContent:
+		if start, ok := rangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+			removePartial(fs, partial)
+			return fmt.Errorf("unexpected content range '%s'", resp.Header.Get("Content-Range"))
+		}
+		flags |= os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the partial file is not a prefix of the remote file, start over
+		removePartial(fs, partial)
 		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	default:
+		err = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		if !retryable(resp.StatusCode) {
+			return backoff.Permanent(err)
+		}
+		return err
 	}
 
-	file, err := fs.Create(path)
+	file, err := fs.OpenFile(partial, flags, vfs.FilePerm)
 	if err != nil {
-		return fmt.Errorf("creating file: %w", err)
+		return backoff.Permanent(fmt.Errorf("creating file: %w", err))
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		err = writePartialInfo(fs, partial, partialInfo{URL: url, Validator: validator(resp.Header)})
+		if err != nil {
+			_ = file.Close()
+			return backoff.Permanent(err)
+		}
 	}
 
 	_, err = io.Copy(file, resp.Body)
@@ -60,5 +282,101 @@ func DownloadFile(ctx context.Context, fs vfs.FS, url, path string) error {
 		return fmt.Errorf("closing file: %w", err)
 	}
 
+	if err = verify(fs, partial, digest); err != nil {
+		removePartial(fs, partial)
+		return err
+	}
+
+	if err = fs.Rename(partial, path); err != nil {
+		return backoff.Permanent(fmt.Errorf("moving downloaded file: %w", err))
+	}
+	_ = fs.Remove(partial + partialInfoSuffix)
+	return nil
+}
+
+// resumableInfo returns the source of the given partial file if it can be resumed from the given URL.
+// Without a validator the partial file is only resumed if the result is verified by a digest.
+func resumableInfo(fs vfs.FS, partial, url, digest string) *partialInfo {
+	data, err := fs.ReadFile(partial + partialInfoSuffix)
+	if err != nil {
+		return nil
+	}
+	info := &partialInfo{}
+	if err = json.Unmarshal(data, info); err != nil || info.URL != url {
+		return nil
+	}
+	if info.Validator == "" && digest == "" {
+		return nil
+	}
+	return info
+}
+
+// writePartialInfo records the source of the given partial file
+func writePartialInfo(fs vfs.FS, partial string, info partialInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("marshalling partial file info: %w", err)
+	}
+	if err = fs.WriteFile(partial+partialInfoSuffix, data, vfs.FilePerm); err != nil {
+		return fmt.Errorf("writing partial file info: %w", err)
+	}
+	return nil
+}
+
+// removePartial removes the given partial file and its source info
+func removePartial(fs vfs.FS, partial string) {
+	_ = fs.Remove(partial)
+	_ = fs.Remove(partial + partialInfoSuffix)
+}
+
+// validator returns the value to resume a download of the response with the given headers in an
+// If-Range request. Weak ETags can't be used for range requests.
+func validator(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// verify checks the file at the given path matches the given 'sha256:<hex>' digest, nothing
+// is checked if the digest is empty
+func verify(fs vfs.FS, path, digest string) error {
+	if digest == "" {
+		return nil
+	}
+	if !strings.HasPrefix(digest, "sha256:") {
+		return backoff.Permanent(fmt.Errorf("unsupported digest '%s'", digest))
+	}
+
+	got, err := vfs.FileDigest(fs, path)
+	if err != nil {
+		return err
+	}
+	if got != digest {
+		return fmt.Errorf("digest mismatch: expected %s, got %s", digest, got)
+	}
 	return nil
 }
+
+// rangeStart returns the first byte of a 'bytes <start>-<end>/<size>' content range
+func rangeStart(contentRange string) (int64, bool) {
+	r, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(r, "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	return n, err == nil
+}
+
+// retryable reports whether a request failing with the given status code is worth retrying
+func retryable(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return status >= http.StatusInternalServerError
+}
@@ -19,12 +19,22 @@ package http
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
 )
 
 func TestDownloadSuite(t *testing.T) {
@@ -32,24 +42,217 @@ func TestDownloadSuite(t *testing.T) {
 	RunSpecs(t, "Download test suite")
 }
 
+const content = "systemd extension image content"
+
+func sha256Digest(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// server serves the content at /ext.raw, the first request is cut after the given amount of
+// bytes and the first failures requests fail with an internal server error
+type server struct {
+	mu       sync.Mutex
+	cutAt    int
+	failures int
+	etag     string
+	ranges   []string
+	ifRanges []string
+	requests int
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+	s.ifRanges = append(s.ifRanges, r.Header.Get("If-Range"))
+	if s.etag != "" {
+		w.Header().Set("ETag", s.etag)
+	}
+
+	if r.URL.Path != "/ext.raw" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if s.failures > 0 {
+		s.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if s.cutAt > 0 {
+		// announce the full size but close the connection before sending it all
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(content)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(content[:s.cutAt]))
+		w.(http.Flusher).Flush()
+		s.cutAt = 0
+		panic(http.ErrAbortHandler)
+	}
+	http.ServeContent(w, r, "ext.raw", time.Time{}, strings.NewReader(content))
+}
+
+var _ = Describe("Downloader", Label("download"), func() {
+	var fs vfs.FS
+	var srv *server
+	var ts *httptest.Server
+	var d *Downloader
+	BeforeEach(func() {
+		var err error
+		var cleanup func()
+		fs, cleanup, err = mock.TestFS(map[string]any{"/downloads/empty": ""})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(cleanup)
+
+		srv = &server{}
+		ts = httptest.NewServer(srv)
+		DeferCleanup(ts.Close)
+
+		d, err = NewDownloader(WithRetryInterval(time.Millisecond))
+		Expect(err).NotTo(HaveOccurred())
+	})
+	It("downloads and verifies a file", func() {
+		Expect(d.Download(context.Background(), fs, ts.URL+"/ext.raw", "/downloads/ext.raw", sha256Digest(content))).To(Succeed())
+		data, err := fs.ReadFile("/downloads/ext.raw")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(content))
+		Expect(vfs.Exists(fs, "/downloads/ext.raw"+PartialSuffix)).To(BeFalse())
+	})
+	It("resumes an interrupted download", func() {
+		srv.cutAt = 10
+		Expect(d.Download(context.Background(), fs, ts.URL+"/ext.raw", "/downloads/ext.raw", sha256Digest(content))).To(Succeed())
+		data, err := fs.ReadFile("/downloads/ext.raw")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(content))
+		Expect(srv.ranges).To(Equal([]string{"", "bytes=10-"}))
+	})
+	It("resumes an interrupted download only if the remote file did not change", func() {
+		srv.cutAt = 10
+		srv.etag = `"v1"`
+		Expect(d.Download(context.Background(), fs, ts.URL+"/ext.raw", "/downloads/ext.raw", "")).To(Succeed())
+		data, err := fs.ReadFile("/downloads/ext.raw")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(content))
+		Expect(srv.ranges).To(Equal([]string{"", "bytes=10-"}))
+		Expect(srv.ifRanges).To(Equal([]string{"", `"v1"`}))
+		Expect(vfs.Exists(fs, "/downloads/ext.raw"+PartialSuffix+partialInfoSuffix)).To(BeFalse())
+	})
+	It("resumes from a partial file of a previous run", func() {
+		srv.etag = `"v1"`
+		Expect(fs.WriteFile("/downloads/ext.raw"+PartialSuffix, []byte(content[:5]), vfs.FilePerm)).To(Succeed())
+		Expect(writePartialInfo(fs, "/downloads/ext.raw"+PartialSuffix, partialInfo{
+			URL: ts.URL + "/ext.raw", Validator: `"v1"`,
+		})).To(Succeed())
+		Expect(d.Download(context.Background(), fs, ts.URL+"/ext.raw", "/downloads/ext.raw", "")).To(Succeed())
+		data, err := fs.ReadFile("/downloads/ext.raw")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(content))
+		Expect(srv.ranges).To(Equal([]string{"bytes=5-"}))
+	})
+	It("downloads the whole file if it changed since the partial file was started", func() {
+		srv.etag = `"v2"`
+		Expect(fs.WriteFile("/downloads/ext.raw"+PartialSuffix, []byte("stale"), vfs.FilePerm)).To(Succeed())
+		Expect(writePartialInfo(fs, "/downloads/ext.raw"+PartialSuffix, partialInfo{
+			URL: ts.URL + "/ext.raw", Validator: `"v1"`,
+		})).To(Succeed())
+		Expect(d.Download(context.Background(), fs, ts.URL+"/ext.raw", "/downloads/ext.raw", "")).To(Succeed())
+		data, err := fs.ReadFile("/downloads/ext.raw")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(content))
+		Expect(srv.ifRanges).To(Equal([]string{`"v1"`}))
+	})
+	It("discards partial files of another URL or without validator nor digest", func() {
+		for _, info := range []partialInfo{
+			{URL: "http://mirror.example.com/ext.raw", Validator: `"v1"`},
+			{URL: ts.URL + "/ext.raw"},
+		} {
+			srv.ranges = nil
+			Expect(fs.WriteFile("/downloads/ext.raw"+PartialSuffix, []byte("stale"), vfs.FilePerm)).To(Succeed())
+			Expect(writePartialInfo(fs, "/downloads/ext.raw"+PartialSuffix, info)).To(Succeed())
+			Expect(d.Download(context.Background(), fs, ts.URL+"/ext.raw", "/downloads/ext.raw", "")).To(Succeed())
+			data, err := fs.ReadFile("/downloads/ext.raw")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal(content))
+			Expect(srv.ranges).To(Equal([]string{""}))
+		}
+
+		srv.ranges = nil
+		Expect(fs.WriteFile("/downloads/ext.raw"+PartialSuffix, []byte(content[:5]), vfs.FilePerm)).To(Succeed())
+		Expect(d.Download(context.Background(), fs, ts.URL+"/ext.raw", "/downloads/ext.raw", sha256Digest(content))).To(Succeed())
+		Expect(srv.ranges).To(Equal([]string{""}))
+	})
+	It("retries on server errors", func() {
+		srv.failures = 2
+		Expect(d.Download(context.Background(), fs, ts.URL+"/ext.raw", "/downloads/ext.raw", "")).To(Succeed())
+		Expect(srv.requests).To(Equal(3))
+	})
+	It("does not retry on client errors", func() {
+		err := d.Download(context.Background(), fs, ts.URL+"/missing.raw", "/downloads/ext.raw", "")
+		Expect(err).To(MatchError(ContainSubstring("unexpected status code: 404")))
+		Expect(srv.requests).To(Equal(1))
+	})
+	It("fails and discards the file on digest mismatch", func() {
+		d, err := NewDownloader(WithRetryInterval(time.Millisecond), WithRetries(1))
+		Expect(err).NotTo(HaveOccurred())
+		err = d.Download(context.Background(), fs, ts.URL+"/ext.raw", "/downloads/ext.raw", sha256Digest("other"))
+		Expect(err).To(MatchError(ContainSubstring("digest mismatch")))
+		Expect(srv.requests).To(Equal(2))
+		Expect(vfs.Exists(fs, "/downloads/ext.raw")).To(BeFalse())
+		Expect(vfs.Exists(fs, "/downloads/ext.raw"+PartialSuffix)).To(BeFalse())
+	})
+	It("falls back to mirrors", func() {
+		broken := httptest.NewServer(http.NotFoundHandler())
+		DeferCleanup(broken.Close)
+
+		d, err := NewDownloader(WithRetryInterval(time.Millisecond), WithMirrors(ts.URL+"/"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Download(context.Background(), fs, broken.URL+"/ext.raw", "/downloads/ext.raw", "")).To(Succeed())
+		Expect(srv.requests).To(Equal(1))
+	})
+	It("trusts the given CA bundle", func() {
+		tlsServer := httptest.NewTLSServer(srv)
+		DeferCleanup(tlsServer.Close)
+
+		err := d.Download(context.Background(), fs, tlsServer.URL+"/ext.raw", "/downloads/ext.raw", "")
+		Expect(err).To(MatchError(ContainSubstring("certificate")))
+
+		ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})
+		d, err := NewDownloader(WithRetryInterval(time.Millisecond), WithCABundle(ca))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Download(context.Background(), fs, tlsServer.URL+"/ext.raw", "/downloads/ext.raw", "")).To(Succeed())
+	})
+	It("fails on an invalid CA bundle", func() {
+		_, err := NewDownloader(WithCABundle([]byte("invalid")))
+		Expect(err).To(MatchError("no valid certificates found in CA bundle"))
+	})
+	It("downloads through the proxy set in the environment", func() {
+		var proxied string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied = r.URL.String()
+			_, _ = w.Write([]byte(content))
+		}))
+		DeferCleanup(proxy.Close)
+		// the proxy is taken from the environment when the transport is created
+		GinkgoT().Setenv("HTTP_PROXY", proxy.URL)
+
+		d, err := NewDownloader()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Download(context.Background(), fs, "http://example.com/ext.raw", "/downloads/ext.raw", "")).To(Succeed())
+		Expect(proxied).To(Equal("http://example.com/ext.raw"))
+	})
+})
+
 var _ = Describe("Invalid download attempts", func() {
 	It("Fails to create a request for nil context", func() {
-		err := DownloadFile(nil, nil, "", "")
+		err := DownloadFile(nil, nil, "", "", "")
 		Expect(err).To(HaveOccurred())
 		Expect(err).To(MatchError("creating request: net/http: nil Context"))
 	})
 
-	It("Fails to execute a request for invalid URL", func() {
-		err := DownloadFile(context.Background(), nil, "invalid-url", "")
+	It("Fails to download an invalid URL", func() {
+		err := DownloadFile(context.Background(), nil, "invalid-url", "", "")
 		Expect(err).To(HaveOccurred())
-		Expect(err).To(MatchError("executing request: Get \"invalid-url\": unsupported protocol scheme \"\""))
-	})
-
-	It("Fails to download a request due to unexpected status code", func() {
-		url := "https://github.com/suse/elemental3"
-		err := DownloadFile(context.Background(), nil, url, "")
-		Expect(err).To(HaveOccurred())
-		Expect(err).To(MatchError("unexpected status code: 404"))
+		Expect(err).To(MatchError("unsupported protocol scheme '' in URL 'invalid-url'"))
 	})
 
 	It("Fails to create output file", func() {
@@ -57,9 +260,11 @@ var _ = Describe("Invalid download attempts", func() {
 		Expect(err).NotTo(HaveOccurred())
 		DeferCleanup(cleanup)
 
-		url := "https://github.com/suse/elemental"
-		err = DownloadFile(context.Background(), fs, url, "downloads/abc")
+		ts := httptest.NewServer(&server{})
+		DeferCleanup(ts.Close)
+
+		err = DownloadFile(context.Background(), fs, ts.URL+"/ext.raw", "downloads/abc", "")
 		Expect(err).To(HaveOccurred())
-		Expect(err).To(MatchError("creating file: Create downloads/abc: operation not permitted"))
+		Expect(err).To(MatchError(ContainSubstring("creating file: OpenFile downloads/abc.part")))
 	})
 })
@@ -0,0 +1,291 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package install
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/suse/elemental/v3/pkg/cleanstack"
+	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/events"
+	"github.com/suse/elemental/v3/pkg/installer"
+	"github.com/suse/elemental/v3/pkg/repart"
+	"github.com/suse/elemental/v3/pkg/rsync"
+	"github.com/suse/elemental/v3/pkg/selinux"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+	"github.com/suse/elemental/v3/pkg/transaction"
+	"github.com/suse/elemental/v3/pkg/unpack"
+)
+
+// InstallImage installs the given deployment into a new disk image file of the given size, a zero size
+// fits the image to its content. Partitions are populated from directory trees and the disk is composed
+// with systemd-repart in offline mode, hence no loop devices, mounts or chrooted commands are involved and
+// root privileges are not required. SELinux labels are set on first boot. Encryption, system mirrors,
+// FIPS mode and configuration scripts require a live system and are not supported.
+func (i Installer) InstallImage(d *deployment.Deployment, image string, size deployment.MiB) (err error) {
+	cleanup := cleanstack.NewCleanStack()
+	defer func() { err = cleanup.Cleanup(err) }()
+
+	err = checkImageDeployment(d)
+	if err != nil {
+		return fmt.Errorf("unsupported deployment for unprivileged installation: %w", err)
+	}
+
+	workDir, err := vfs.TempDir(i.s.FS(), "", "elemental_image")
+	if err != nil {
+		return fmt.Errorf("creating a temporary directory to build the image: %w", err)
+	}
+	cleanup.Push(func() error { return vfs.ForceRemoveAll(i.s.FS(), workDir) })
+
+	// fstab refers to partition UUIDs, hence they are set before the partitions are created
+	for _, part := range d.GetSystemDisk().Partitions {
+		if part.UUID != "" {
+			continue
+		}
+		if i.repro != nil {
+			part.UUID = i.repro.UUID(part.Label)
+		} else {
+			part.UUID = uuid.NewString()
+		}
+	}
+
+	var recDir string
+	if recPart := d.GetRecoveryPartition(); recPart != nil {
+		i.s.Logger().Info("Preparing recovery system")
+		recDir = filepath.Join(workDir, recPart.Role.String())
+		mediaOpts := []installer.Option{installer.WithUnpackOpts(i.imageUnpackOpts(d)...)}
+		if i.repro != nil {
+			mediaOpts = append(mediaOpts, installer.WithReproducible(i.repro))
+		}
+		media := installer.NewMedia(i.ctx, i.s, installer.Disk, mediaOpts...)
+		i.s.Events().Start(events.PhaseRecovery)
+		err = media.PrepareInstallerFS(recDir, filepath.Join(workDir, "recovery-root"), d)
+		i.s.Events().Finish(events.PhaseRecovery, err)
+		if err != nil {
+			return fmt.Errorf("failed preparing recovery partition root: %w", err)
+		}
+		d.SourceOS = deployment.NewRawSrc(filepath.Join(recDir, installer.SquashfsRelPath))
+	}
+
+	sysDir := filepath.Join(workDir, deployment.System.String())
+	tree := transaction.NewSnapperTree(i.ctx, i.s, sysDir, *d)
+	trans, err := i.prepareSystemTree(d, tree)
+	if err != nil {
+		return err
+	}
+
+	parts, err := imagePartitions(d, tree, trans, sysDir, recDir)
+	if err != nil {
+		return err
+	}
+
+	flags := []string{"--offline=yes"}
+	if i.repro != nil {
+		for _, dir := range []string{sysDir, recDir} {
+			if dir == "" {
+				continue
+			}
+			err = i.repro.ClampMTimes(i.ctx, i.s, dir)
+			if err != nil {
+				return fmt.Errorf("normalizing timestamps: %w", err)
+			}
+		}
+		flags = append(flags, fmt.Sprintf("--seed=%s", i.repro.Seed))
+	}
+	i.s.Events().Start(events.PhasePartition)
+	err = repart.CreateDiskImage(i.s, image, size, parts, flags...)
+	i.s.Events().Finish(events.PhasePartition, err)
+	if err != nil {
+		return fmt.Errorf("creating disk image: %w", err)
+	}
+	return nil
+}
+
+// prepareSystemTree populates the given snapper tree with the OS, overlays and bootloader of the given deployment
+func (i Installer) prepareSystemTree(d *deployment.Deployment, tree *transaction.SnapperTree) (*transaction.Transaction, error) {
+	esp := d.GetEfiPartition()
+	if esp == nil {
+		return nil, fmt.Errorf("no %s partition defined in deployment", deployment.EfiLabel)
+	}
+
+	trans, err := tree.Start()
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+
+	i.s.Events().Start(events.PhaseDeploy)
+	err = tree.SyncImageContent(d.SourceOS, trans, i.imageUnpackOpts(d)...)
+	i.s.Events().Finish(events.PhaseDeploy, err)
+	if err != nil {
+		return nil, fmt.Errorf("syncing OS image content: %w", err)
+	}
+
+	if i.sbom != nil {
+		err = i.sbom.AddRPMs(i.ctx, i.s, trans.Path)
+		if err != nil {
+			return nil, fmt.Errorf("listing OS packages: %w", err)
+		}
+	}
+
+	// The overlay tree is unpacked before merging, so its content under non snapshotted RW volumes
+	// is moved to their subvolumes instead of being hidden below their mountpoints
+	if d.OverlayTree != nil && !d.OverlayTree.IsEmpty() {
+		unpacker, err := unpack.NewUnpacker(
+			i.s, d.OverlayTree, unpack.WithRsyncFlags(rsync.OverlayTreeSyncFlags()...),
+			unpack.WithSignaturePolicy(d.GetSignaturePolicy()), unpack.WithRegistries(d.Registries),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("initializing unpacker: %w", err)
+		}
+		_, err = unpacker.Unpack(i.ctx, trans.Path)
+		if err != nil {
+			return nil, fmt.Errorf("unpacking overlay tree: %w", err)
+		}
+	}
+
+	err = tree.Merge(trans)
+	if err != nil {
+		return nil, fmt.Errorf("merging RW volumes: %w", err)
+	}
+
+	err = tree.UpdateFstab(trans)
+	if err != nil {
+		return nil, fmt.Errorf("updating fstab: %w", err)
+	}
+
+	err = d.WriteDeploymentFile(i.s, trans.Path)
+	if err != nil {
+		return nil, fmt.Errorf("writing deployment file: %w", err)
+	}
+
+	err = tree.Lock(trans)
+	if err != nil {
+		return nil, fmt.Errorf("locking transaction '%d': %w", trans.ID, err)
+	}
+
+	err = selinux.RequestRelabel(i.s, trans.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	cmdline := ""
+	if d.BootConfig != nil {
+		cmdline = d.BootConfig.KernelCmdline
+	}
+	kernelCmdline := strings.TrimSpace(fmt.Sprintf("%s %s %s", d.BaseKernelCmdline(), tree.GenerateKernelCmdline(trans), cmdline))
+	recKernelCmdline := ""
+	if d.GetRecoveryPartition() != nil {
+		recKernelCmdline = strings.TrimSpace(fmt.Sprintf("%s %s", d.RecoveryKernelCmdline(), d.Installer.KernelCmdline))
+	}
+
+	espDir := filepath.Join(trans.Path, esp.MountPoint)
+	err = vfs.MkdirAll(i.s.FS(), espDir, vfs.DirPerm)
+	if err != nil {
+		return nil, fmt.Errorf("creating ESP directory: %w", err)
+	}
+	i.s.Events().Start(events.PhaseBootloader)
+	err = i.b.Install(trans.Path, espDir, esp.Label, strconv.Itoa(trans.ID), kernelCmdline, recKernelCmdline)
+	i.s.Events().Finish(events.PhaseBootloader, err)
+	if err != nil {
+		return nil, fmt.Errorf("installing bootloader: %w", err)
+	}
+
+	err = tree.Commit(trans)
+	if err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+	i.s.Events().Snapshot(trans.ID)
+	return trans, nil
+}
+
+func (i Installer) imageUnpackOpts(d *deployment.Deployment) []unpack.Opt {
+	return append(
+		slices.Clone(i.unpackOpts), unpack.WithSignaturePolicy(d.GetSignaturePolicy()), unpack.WithRegistries(d.Registries),
+	)
+}
+
+// imagePartitions returns the systemd-repart partitions of the disk image. The system partition is copied
+// from the snapper tree, except for the content of other partitions mountpoints, which is copied into
+// their own partitions.
+func imagePartitions(
+	d *deployment.Deployment, tree *transaction.SnapperTree, trans *transaction.Transaction, sysDir, recDir string,
+) ([]repart.Partition, error) {
+	var excludes []string
+	for _, part := range d.GetSystemDisk().Partitions {
+		if part.Role != deployment.System && part.MountPoint != "" {
+			// Trailing slash excludes the directory content but keeps the directory as mountpoint
+			excludes = append(excludes, filepath.Join(trans.Path, part.MountPoint)+"/")
+		}
+	}
+
+	var parts []repart.Partition
+	for _, part := range d.GetSystemDisk().Partitions {
+		p := repart.Partition{Partition: part}
+		switch {
+		case part.Role == deployment.System:
+			p.CopyFiles = []string{sysDir + ":/"}
+			p.Excludes = excludes
+			p.Subvolumes = tree.Subvolumes(trans)
+			p.DefaultSubvolume = tree.DefaultSubvolume(trans)
+		case part.Role == deployment.Recovery:
+			if recDir == "" {
+				return nil, fmt.Errorf("recovery partition content not prepared")
+			}
+			p.CopyFiles = []string{recDir + ":/"}
+		case part.MountPoint != "":
+			p.CopyFiles = []string{filepath.Join(trans.Path, part.MountPoint) + ":/"}
+		}
+		parts = append(parts, p)
+	}
+	return parts, nil
+}
+
+// checkImageDeployment checks the given deployment can be installed into a disk image without privileges
+func checkImageDeployment(d *deployment.Deployment) error {
+	if len(d.Disks) != 1 {
+		return fmt.Errorf("multiple disks are not supported")
+	}
+	if d.Snapshotter == nil || d.Snapshotter.Name != "snapper" {
+		return fmt.Errorf("only the snapper snapshotter is supported")
+	}
+	if d.IsFipsEnabled() {
+		return fmt.Errorf("FIPS mode is not supported")
+	}
+	if d.CfgScript != "" {
+		return fmt.Errorf("configuration scripts are not supported")
+	}
+	for _, part := range d.GetSystemDisk().Partitions {
+		switch {
+		case part.Encryption != nil:
+			return fmt.Errorf("encrypted partition '%s' is not supported", part.Label)
+		case part.Mirror:
+			return fmt.Errorf("mirrored partition '%s' is not supported", part.Label)
+		case part.Role == deployment.System && part.FileSystem != deployment.Btrfs:
+			return fmt.Errorf("system partition must be btrfs")
+		case part.Role != deployment.System && len(part.RWVolumes) > 0:
+			return fmt.Errorf("RW volumes of partition '%s' are not supported, only system partition volumes are", part.Label)
+		}
+	}
+	return nil
+}
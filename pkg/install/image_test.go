@@ -0,0 +1,183 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package install_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/install"
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/reproducible"
+	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+var _ = Describe("InstallImage", Label("install", "image"), func() {
+	var runner *sysmock.Runner
+	var mounter *sysmock.Mounter
+	var fs vfs.FS
+	var cleanup func()
+	var s *sys.System
+	var d *deployment.Deployment
+	var i *install.Installer
+	var systemConf string
+	var repartArgs []string
+	BeforeEach(func() {
+		var err error
+		runner = sysmock.NewRunner()
+		mounter = sysmock.NewMounter()
+		systemConf = ""
+
+		fs, cleanup, err = sysmock.TestFS(nil)
+		Expect(err).ToNot(HaveOccurred())
+		s, err = sys.NewSystem(
+			sys.WithMounter(mounter), sys.WithRunner(runner),
+			sys.WithFS(fs), sys.WithLogger(log.New(log.WithDiscardAll())),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		d = deployment.DefaultDeployment()
+		d.Disks[0].Device = "/some/image.raw"
+		d.SourceOS = deployment.NewDirSrc("/some/dir")
+		Expect(d.Sanitize(s, deployment.CheckDiskDevice)).To(Succeed())
+		i = install.New(context.Background(), s)
+
+		runner.SideEffect = func(cmd string, args ...string) ([]byte, error) {
+			switch cmd {
+			case "rsync":
+				// Populate the root snapshot with the snapper files of the OS image, rsync
+				// target is given as a host path
+				target := args[len(args)-1]
+				if strings.HasSuffix(target, ".snapshots/1/snapshot/") {
+					template := filepath.Join(target, "/usr/share/snapper/config-templates/default")
+					Expect(os.MkdirAll(filepath.Dir(template), vfs.DirPerm)).To(Succeed())
+					Expect(os.MkdirAll(filepath.Join(target, "/etc/snapper/configs"), vfs.DirPerm)).To(Succeed())
+					Expect(os.MkdirAll(filepath.Join(target, "/etc/sysconfig"), vfs.DirPerm)).To(Succeed())
+					Expect(os.WriteFile(template, []byte{}, vfs.FilePerm)).To(Succeed())
+				}
+			case "systemd-repart":
+				var defs string
+				for _, arg := range args {
+					if after, ok := strings.CutPrefix(arg, "--definitions="); ok {
+						defs = after
+					}
+				}
+				data, err := fs.ReadFile(filepath.Join(defs, "1-system.conf"))
+				Expect(err).NotTo(HaveOccurred())
+				systemConf = string(data)
+				repartArgs = args
+				return []byte(fmt.Sprintf(`[
+					{"uuid" : "%s", "file" : "%s/0-efi.conf"},
+					{"uuid" : "%s", "file" : "%s/1-system.conf"}
+				]`, d.Disks[0].Partitions[0].UUID, defs, d.Disks[0].Partitions[1].UUID, defs)), nil
+			}
+			return runner.ReturnValue, runner.ReturnError
+		}
+	})
+	AfterEach(func() {
+		cleanup()
+	})
+	It("composes a disk image without mounts or chrooted commands", func() {
+		Expect(i.InstallImage(d, "/some/image.raw", 4096)).To(Succeed())
+		Expect(runner.MatchMilestones([][]string{
+			{"rsync"},
+			{"systemd-repart", "--json=pretty"},
+		})).To(Succeed())
+		Expect(repartArgs).To(ContainElements("--empty=create", "--size=4096M", "--offline=yes", "/some/image.raw"))
+		Expect(runner.IncludesCmds([][]string{{"snapper"}})).NotTo(Succeed())
+		Expect(runner.IncludesCmds([][]string{{"btrfs"}})).NotTo(Succeed())
+		Expect(runner.IncludesCmds([][]string{{"losetup"}})).NotTo(Succeed())
+		Expect(mounter.List()).To(BeEmpty())
+
+		Expect(d.Disks[0].Partitions[0].UUID).NotTo(BeEmpty())
+		Expect(systemConf).To(MatchRegexp(`CopyFiles=/tmp/elemental_image[0-9]*/system:/`))
+		Expect(systemConf).To(MatchRegexp(`ExcludeFiles=/tmp/elemental_image[0-9]*/system/@/.snapshots/1/snapshot/boot/`))
+		Expect(systemConf).To(ContainSubstring("Subvolumes=/@/.snapshots/1/snapshot:ro"))
+		Expect(systemConf).To(ContainSubstring("Subvolumes=/@/var:nodatacow"))
+		Expect(systemConf).To(ContainSubstring("DefaultSubvolume=/@/.snapshots/1/snapshot"))
+	})
+	It("composes a reproducible disk image", func() {
+		repro, err := reproducible.New("registry.example.com/os:1.0")
+		Expect(err).NotTo(HaveOccurred())
+		i = install.New(context.Background(), s, install.WithReproducible(repro))
+
+		Expect(i.InstallImage(d, "/some/image.raw", 4096)).To(Succeed())
+		Expect(d.Disks[0].Partitions[0].UUID).To(Equal(repro.UUID(d.Disks[0].Partitions[0].Label)))
+		Expect(d.Disks[0].Partitions[1].UUID).To(Equal(repro.UUID(d.Disks[0].Partitions[1].Label)))
+		Expect(runner.IncludesCmds([][]string{{"find"}})).To(Succeed())
+		Expect(repartArgs).To(ContainElements("--offline=yes", "--seed="+repro.Seed.String()))
+	})
+	It("moves overlay content under non snapshotted volumes to their subvolumes", func() {
+		d.OverlayTree = deployment.NewDirSrc("/some/overlay")
+		var volumeFile, snapshotFile bool
+		sideEffect := runner.SideEffect
+		runner.SideEffect = func(cmd string, args ...string) ([]byte, error) {
+			switch {
+			case cmd == "rsync" && strings.Contains(args[len(args)-2], "/some/overlay"):
+				file := filepath.Join(args[len(args)-1], "/var/lib/overlay.conf")
+				Expect(os.MkdirAll(filepath.Dir(file), vfs.DirPerm)).To(Succeed())
+				Expect(os.WriteFile(file, []byte("overlay"), vfs.FilePerm)).To(Succeed())
+				return []byte{}, nil
+			case cmd == "systemd-repart":
+				out, err := sideEffect(cmd, args...)
+				root := regexp.MustCompile(`CopyFiles=(\S+):/`).FindStringSubmatch(systemConf)[1]
+				volumeFile, _ = vfs.Exists(fs, filepath.Join(root, "/@/var/lib/overlay.conf"))
+				snapshotFile, _ = vfs.Exists(fs, filepath.Join(root, "/@/.snapshots/1/snapshot/var/lib/overlay.conf"))
+				return out, err
+			}
+			return sideEffect(cmd, args...)
+		}
+		Expect(i.InstallImage(d, "/some/image.raw", 4096)).To(Succeed())
+		Expect(volumeFile).To(BeTrue())
+		Expect(snapshotFile).To(BeFalse())
+	})
+	It("fails for deployments with encrypted partitions", func() {
+		d.Disks[0].Partitions[1].Encryption = &deployment.Encryption{KeyFile: "/some/key"}
+		err := i.InstallImage(d, "/some/image.raw", 4096)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("encrypted partition 'SYSTEM' is not supported"))
+		Expect(runner.GetCmds()).To(BeEmpty())
+	})
+	It("fails for deployments with multiple disks", func() {
+		d.Disks = append(d.Disks, &deployment.Disk{Device: "/dev/other"})
+		err := i.InstallImage(d, "/some/image.raw", 4096)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("multiple disks are not supported"))
+	})
+	It("fails if systemd-repart fails", func() {
+		sideEffect := runner.SideEffect
+		runner.SideEffect = func(cmd string, args ...string) ([]byte, error) {
+			if cmd == "systemd-repart" {
+				return []byte{}, fmt.Errorf("repart failed")
+			}
+			return sideEffect(cmd, args...)
+		}
+		err := i.InstallImage(d, "/some/image.raw", 4096)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("creating disk image"))
+	})
+})
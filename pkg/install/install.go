@@ -21,6 +21,7 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/suse/elemental/v3/pkg/block"
@@ -29,8 +30,13 @@ import (
 	"github.com/suse/elemental/v3/pkg/btrfs"
 	"github.com/suse/elemental/v3/pkg/cleanstack"
 	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/events"
 	"github.com/suse/elemental/v3/pkg/installer"
+	"github.com/suse/elemental/v3/pkg/luks"
 	"github.com/suse/elemental/v3/pkg/repart"
+	"github.com/suse/elemental/v3/pkg/reproducible"
+	"github.com/suse/elemental/v3/pkg/sbom"
+	"github.com/suse/elemental/v3/pkg/secureboot"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
 	"github.com/suse/elemental/v3/pkg/unpack"
@@ -45,6 +51,8 @@ type Installer struct {
 	u          upgrade.Interface
 	unpackOpts []unpack.Opt
 	b          bootloader.Bootloader
+	repro      *reproducible.Build
+	sbom       *sbom.SBOM
 }
 
 func WithUnpackOpts(opts ...unpack.Opt) Option {
@@ -65,6 +73,21 @@ func WithBootloader(b bootloader.Bootloader) Option {
 	}
 }
 
+// WithReproducible installs disk images reproducibly, timestamps are set to the build epoch
+// and partition and filesystem UUIDs are derived from the build seed
+func WithReproducible(b *reproducible.Build) Option {
+	return func(i *Installer) {
+		i.repro = b
+	}
+}
+
+// WithSBOM adds the RPM packages of OS disk images to the given SBOM
+func WithSBOM(sb *sbom.SBOM) Option {
+	return func(i *Installer) {
+		i.sbom = sb
+	}
+}
+
 func New(ctx context.Context, s *sys.System, opts ...Option) *Installer {
 	installer := &Installer{
 		s:   s,
@@ -115,21 +138,16 @@ func (i Installer) Install(d *deployment.Deployment) (err error) {
 		return err
 	}
 
-	for _, disk := range d.Disks {
-		err = repart.PartitionAndFormatDevice(i.s, disk)
-		if err != nil {
-			return fmt.Errorf("partitioning disk '%s': %w", disk.Device, err)
-		}
-		for _, part := range disk.Partitions {
-			i.s.Logger().Debug("creating partition volumes: %+v", part.RWVolumes)
-			err = createPartitionVolumes(i.s, cleanup, part)
-			if err != nil {
-				return fmt.Errorf("creating partition volumes: %w", err)
-			}
-		}
+	i.s.Events().Start(events.PhasePartition)
+	err = prepareDisks(i.s, cleanup, d, repart.PartitionAndFormatDevice)
+	i.s.Events().Finish(events.PhasePartition, err)
+	if err != nil {
+		return err
 	}
 
+	i.s.Events().Start(events.PhaseRecovery)
 	err = i.installRecoveryPartition(cleanup, d)
+	i.s.Events().Finish(events.PhaseRecovery, err)
 	if err != nil {
 		return fmt.Errorf("installing recovery system: %w", err)
 	}
@@ -139,25 +157,34 @@ func (i Installer) Install(d *deployment.Deployment) (err error) {
 		return fmt.Errorf("executing transaction: %w", err)
 	}
 
+	err = i.enrollMOK(d)
+	if err != nil {
+		return fmt.Errorf("enrolling Secure Boot certificate: %w", err)
+	}
+
 	return nil
 }
 
+// enrollMOK requests the enrollment of the Secure Boot certificate as Machine Owner Key if
+// required by the deployment. Only done when EFI boot entries are created, as both change the
+// EFI variables of the host.
+func (i Installer) enrollMOK(d *deployment.Deployment) error {
+	conf := d.GetSecureBoot()
+	if conf == nil || !conf.EnrollMOK || d.Firmware == nil || len(d.Firmware.BootEntries) == 0 {
+		return nil
+	}
+	return secureboot.NewSigner(i.s, *conf).EnrollMOK()
+}
+
 func (i Installer) Reset(d *deployment.Deployment) (err error) {
 	cleanup := cleanstack.NewCleanStack()
 	defer func() { err = cleanup.Cleanup(err) }()
 
-	for _, disk := range d.Disks {
-		err = repart.ReconcileDevicePartitions(i.s, disk)
-		if err != nil {
-			return fmt.Errorf("partitioning disk '%s': %w", disk.Device, err)
-		}
-		for _, part := range disk.Partitions {
-			i.s.Logger().Debug("creating partition volumes: %+v", part.RWVolumes)
-			err = createPartitionVolumes(i.s, cleanup, part)
-			if err != nil {
-				return fmt.Errorf("creating partition volumes: %w", err)
-			}
-		}
+	i.s.Events().Start(events.PhasePartition)
+	err = prepareDisks(i.s, cleanup, d, repart.ReconcileDevicePartitions)
+	i.s.Events().Finish(events.PhasePartition, err)
+	if err != nil {
+		return err
 	}
 
 	err = i.u.Upgrade(d)
@@ -170,7 +197,14 @@ func (i Installer) Reset(d *deployment.Deployment) (err error) {
 
 func (i Installer) checkTargetDisks(d *deployment.Deployment) error {
 	bDev := lsblk.NewLsDevice(i.s)
+	err := d.ResolveDisks(i.s, bDev)
+	if err != nil {
+		return fmt.Errorf("resolving target disks: %w", err)
+	}
 	for _, disk := range d.Disks {
+		if disk.Device == "" {
+			return fmt.Errorf("no target device defined for disk")
+		}
 		parts, err := bDev.GetDevicePartitions(disk.Device)
 		if err != nil {
 			return fmt.Errorf("failed to list target device partitions: %w", err)
@@ -215,7 +249,11 @@ func (i Installer) installRecoveryPartition(cleanup *cleanstack.CleanStack, d *d
 	}
 	cleanup.Push(func() error { return i.s.Mounter().Unmount(mountPoint) })
 
-	media := installer.NewMedia(i.ctx, i.s, installer.Disk, installer.WithUnpackOpts(i.unpackOpts...))
+	media := installer.NewMedia(i.ctx, i.s, installer.Disk, installer.WithUnpackOpts(
+		append(
+			slices.Clone(i.unpackOpts), unpack.WithSignaturePolicy(d.GetSignaturePolicy()), unpack.WithRegistries(d.Registries),
+		)...,
+	))
 	err = media.PrepareInstallerFS(mountPoint, workDir, d)
 	if err != nil {
 		return fmt.Errorf("failed preparing recovery partition root: %w", err)
@@ -224,6 +262,114 @@ func (i Installer) installRecoveryPartition(cleanup *cleanstack.CleanStack, d *d
 	return nil
 }
 
+// prepareDisks partitions all the disks of the given deployment with the given partitioning function and
+// prepares their partitions. All disks are partitioned first, as system mirrors are in a different disk.
+func prepareDisks(
+	s *sys.System, cleanup *cleanstack.CleanStack, d *deployment.Deployment,
+	partition func(*sys.System, *deployment.Disk) error,
+) error {
+	for _, disk := range d.Disks {
+		err := partition(s, disk)
+		if err != nil {
+			return fmt.Errorf("partitioning disk '%s': %w", disk.Device, err)
+		}
+	}
+
+	for _, disk := range d.Disks {
+		for _, part := range disk.Partitions {
+			err := openEncryptedPartition(s, cleanup, part)
+			if err != nil {
+				return fmt.Errorf("setting up encrypted partition: %w", err)
+			}
+		}
+		for _, part := range disk.Partitions {
+			if part.Mirror {
+				continue
+			}
+			s.Logger().Debug("creating partition volumes: %+v", part.RWVolumes)
+			err := createPartitionVolumes(s, cleanup, part)
+			if err != nil {
+				return fmt.Errorf("creating partition volumes: %w", err)
+			}
+		}
+	}
+
+	err := addSystemMirrors(s, cleanup, d)
+	if err != nil {
+		return fmt.Errorf("adding system mirrors: %w", err)
+	}
+	return nil
+}
+
+// addSystemMirrors adds the system mirror partitions to the btrfs filesystem of the system partition
+// as RAID1 devices
+func addSystemMirrors(s *sys.System, cleanStack *cleanstack.CleanStack, d *deployment.Deployment) error {
+	mirrors := d.GetMirrorPartitions(deployment.System)
+	sysPart := d.GetSystemPartition()
+	if len(mirrors) == 0 || sysPart == nil {
+		return nil
+	}
+
+	bDev := lsblk.NewLsDevice(s)
+	var devices []string
+	for _, mirror := range mirrors {
+		bPart, err := block.GetPartitionByUUID(s, bDev, mirror.UUID, 4)
+		if err != nil {
+			return fmt.Errorf("finding partition '%s': %w", mirror.UUID, err)
+		}
+		devices = append(devices, bPart.Path)
+	}
+
+	mountPoint, err := vfs.TempDir(s.FS(), "", "elemental_"+sysPart.Role.String())
+	if err != nil {
+		return fmt.Errorf("creating temporary directory to mount system partition: %w", err)
+	}
+	cleanStack.PushSuccessOnly(func() error { return s.FS().RemoveAll(mountPoint) })
+
+	bPart, err := block.GetPartitionByUUID(s, bDev, sysPart.UUID, 4)
+	if err != nil {
+		return fmt.Errorf("finding partition '%s': %w", sysPart.UUID, err)
+	}
+	device, err := luks.DevicePath(s, sysPart, bPart.Path)
+	if err != nil {
+		return err
+	}
+	err = s.Mounter().Mount(device, mountPoint, "", []string{})
+	if err != nil {
+		return fmt.Errorf("mounting partition '%s': %w", device, err)
+	}
+	cleanStack.Push(func() error { return s.Mounter().Unmount(mountPoint) })
+
+	return btrfs.AddRAID1Devices(s, mountPoint, devices...)
+}
+
+// openEncryptedPartition records the LUKS UUID of the given encrypted partition, enrolls its recovery
+// passphrase and unlocks it. Locking the partition again is added to the given cleanup stack.
+func openEncryptedPartition(s *sys.System, cleanStack *cleanstack.CleanStack, part *deployment.Partition) error {
+	if part.Encryption == nil {
+		return nil
+	}
+
+	bPart, err := block.GetPartitionByUUID(s, lsblk.NewLsDevice(s), part.UUID, 4)
+	if err != nil {
+		return fmt.Errorf("finding partition '%s': %w", part.UUID, err)
+	}
+	part.Encryption.UUID, err = luks.ReadUUID(s, bPart.Path)
+	if err != nil {
+		return err
+	}
+	err = luks.EnrollRecoveryPassphrase(s, part, bPart.Path)
+	if err != nil {
+		return err
+	}
+	_, err = luks.Open(s, part, bPart.Path)
+	if err != nil {
+		return err
+	}
+	cleanStack.Push(func() error { return luks.Close(s, part) })
+	return nil
+}
+
 func createPartitionVolumes(s *sys.System, cleanStack *cleanstack.CleanStack, part *deployment.Partition) (err error) {
 	var mountPoint string
 
@@ -239,9 +385,13 @@ func createPartitionVolumes(s *sys.System, cleanStack *cleanstack.CleanStack, pa
 		if err != nil {
 			return fmt.Errorf("finding partition '%s': %w", part.UUID, err)
 		}
-		err = s.Mounter().Mount(bPart.Path, mountPoint, "", []string{})
+		device, err := luks.DevicePath(s, part, bPart.Path)
+		if err != nil {
+			return err
+		}
+		err = s.Mounter().Mount(device, mountPoint, "", []string{})
 		if err != nil {
-			return fmt.Errorf("mounting partition '%s': %w", bPart.Path, err)
+			return fmt.Errorf("mounting partition '%s': %w", device, err)
 		}
 		cleanStack.Push(func() error { return s.Mounter().Unmount(mountPoint) })
 
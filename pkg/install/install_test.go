@@ -19,16 +19,29 @@ package install_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"math/big"
+	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/suse/elemental/v3/pkg/bootloader"
 	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/firmware"
 	"github.com/suse/elemental/v3/pkg/install"
+	"github.com/suse/elemental/v3/pkg/installer"
 	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/secureboot"
 	"github.com/suse/elemental/v3/pkg/sys"
 	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
@@ -167,6 +180,52 @@ var _ = Describe("Install", Label("install"), func() {
 			{"mksquashfs"},
 		}))
 	})
+	It("installs the given deployment with the system partition mirrored in a second disk", func() {
+		deployment.WithRecoveryPartition(0)(d)
+		d.Disks = append(d.Disks, &deployment.Disk{
+			Device: "/dev/mirror",
+			Partitions: deployment.Partitions{
+				{Role: deployment.System, Mirror: true, FileSystem: deployment.Btrfs},
+			},
+		})
+		sideEffects["systemd-repart"] = func(args ...string) ([]byte, error) {
+			if args[len(args)-1] == "/dev/mirror" {
+				return []byte(`[{"uuid" : "5c1f9a2e-0b7d-4f3a-9e6c-1d2b3a4c5d6e", "file" : "/tmp/elemental-repart.d/0-system.conf"}]`), nil
+			}
+			return []byte(systemdRepartJson), nil
+		}
+		sideEffects["lsblk"] = func(args ...string) ([]byte, error) {
+			if slices.Contains(args, "NAME,PHY-SEC") {
+				return []byte(sectorSizeJson), nil
+			}
+			if slices.Contains(args, "/dev/device") || slices.Contains(args, "/dev/mirror") {
+				return []byte(`{"blockdevices": []}`), nil
+			}
+			return []byte(strings.Replace(lsblkJson, "\n\t]\n", `,{
+				"partuuid": "5c1f9a2e-0b7d-4f3a-9e6c-1d2b3a4c5d6e",
+				"path": "/dev/mirror1",
+				"pkname": "/dev/mirror",
+				"type": "part"
+			}]
+`, 1)), nil
+		}
+		sideEffects["btrfs"] = func(args ...string) ([]byte, error) {
+			if slices.Contains(args, "show") {
+				return []byte("Label: 'SYSTEM'\n\tdevid    1 size 20.00GiB used 2.02GiB path /dev/device3\n"), nil
+			}
+			return []byte{}, nil
+		}
+		Expect(i.Install(d)).To(Succeed())
+		Expect(d.Disks[1].Partitions[0].UUID).To(Equal("5c1f9a2e-0b7d-4f3a-9e6c-1d2b3a4c5d6e"))
+		Expect(runner.MatchMilestones([][]string{
+			{"systemd-repart"},
+			{"systemd-repart"},
+			{"btrfs", "subvolume", "create"},
+			{"btrfs", "device", "add", "-f", "/dev/mirror1"},
+			{"btrfs", "balance", "start", "--full-balance", "-dconvert=raid1", "-mconvert=raid1"},
+			{"mksquashfs"},
+		})).To(Succeed())
+	})
 	It("fails if lsblk can't get target device data", func() {
 		sideEffects["lsblk"] = func(args ...string) ([]byte, error) {
 			return nil, fmt.Errorf("lsblk failed")
@@ -212,6 +271,30 @@ var _ = Describe("Install", Label("install"), func() {
 			{"mksquashfs"},
 		}))
 	})
+	It("installs from media built with Secure Boot keys", func() {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vfs.MkdirAll(fs, filepath.Dir(installer.SecureBootCert), vfs.DirPerm)).To(Succeed())
+		Expect(fs.WriteFile(installer.SecureBootCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), vfs.FilePerm)).To(Succeed())
+
+		deployment.WithRecoveryPartition(0)(d)
+		// the install description of the media only references the embedded certificate
+		d.Security.SecureBoot = &secureboot.Config{Cert: installer.SecureBootCert, EnrollMOK: true}
+		d.Firmware = &deployment.FirmwareConfig{BootEntries: []*firmware.EfiBootEntry{{Label: "elemental-shim"}}}
+		Expect(d.Sanitize(s)).To(Succeed())
+
+		bl, err := bootloader.New(bootloader.BootGrub, s, d.BootloaderOpts(s)...)
+		Expect(err).NotTo(HaveOccurred())
+		i = install.New(context.Background(), s, install.WithUpgrader(upgrader), install.WithBootloader(bl))
+		Expect(i.Install(d)).To(Succeed())
+		Expect(runner.IncludesCmds([][]string{{"mokutil", "--import"}})).To(Succeed())
+		for _, cmd := range runner.GetCmds() {
+			Expect(cmd[0]).NotTo(BeElementOf("sbsign", "sbverify"))
+		}
+	})
 	It("resets the given deployment", func() {
 		deployment.WithRecoveryPartition(0)(d)
 		Expect(i.Reset(d)).To(Succeed())
@@ -23,16 +23,24 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"path/filepath"
+	"slices"
 
 	"go.yaml.in/yaml/v3"
 
 	"github.com/suse/elemental/v3/pkg/bootloader"
 	"github.com/suse/elemental/v3/pkg/cleanstack"
 	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/diskimage"
+	"github.com/suse/elemental/v3/pkg/events"
 	"github.com/suse/elemental/v3/pkg/filesystem"
+	"github.com/suse/elemental/v3/pkg/registry"
 	"github.com/suse/elemental/v3/pkg/repart"
+	"github.com/suse/elemental/v3/pkg/reproducible"
 	"github.com/suse/elemental/v3/pkg/rsync"
+	"github.com/suse/elemental/v3/pkg/sbom"
+	"github.com/suse/elemental/v3/pkg/secureboot"
 	"github.com/suse/elemental/v3/pkg/selinux"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
@@ -47,6 +55,7 @@ const (
 	installCfg     = "install.yaml"
 	isoBootCatalog = "boot.catalog"
 	cfgScript      = "setup.sh"
+	secureBootCert = "secureboot.crt"
 	xorriso        = "xorriso"
 
 	LiveMountPoint  = "/run/initramfs/live"
@@ -54,6 +63,9 @@ const (
 	SquashfsPath    = LiveMountPoint + "/" + SquashfsRelPath
 	InstallDesc     = LiveMountPoint + "/" + installDir + "/" + installCfg
 	InstallScript   = LiveMountPoint + "/" + installDir + "/" + cfgScript
+	// SecureBootCert is the certificate of the keys the media is signed with, it is enrolled
+	// as Machine Owner Key on installation if requested
+	SecureBootCert = LiveMountPoint + "/" + installDir + "/" + secureBootCert
 )
 
 type MediaType int
@@ -74,13 +86,15 @@ func (m MediaType) String() string {
 	}
 }
 
+// StringToMediaType parses the given media type, any disk image format is a Disk media type
 func StringToMediaType(mType string) (MediaType, error) {
 	switch mType {
-	case "raw":
-		return Disk, nil
 	case "iso":
 		return ISO, nil
 	default:
+		if _, err := diskimage.ParseFormat(mType); err == nil {
+			return Disk, nil
+		}
 		return 0, fmt.Errorf("unsupported media type %s: %w", mType, errors.ErrUnsupported)
 	}
 }
@@ -100,6 +114,10 @@ type Media struct {
 	bl          bootloader.Bootloader
 	outputFile  string
 	rawDiskSize deployment.MiB
+	diskFormat  diskimage.Format
+	compress    bool
+	repro       *reproducible.Build
+	sbom        *sbom.SBOM
 }
 
 // WithBootloader allows to create an ISO object with the given bootloader interface instance
@@ -122,6 +140,31 @@ func WithRawDiskSize(size deployment.MiB) Option {
 	}
 }
 
+// WithDiskFormat sets the image format of Disk media, the image is optionally compressed
+// for formats supporting it
+func WithDiskFormat(format diskimage.Format, compress bool) Option {
+	return func(i *Media) {
+		i.diskFormat = format
+		i.compress = compress
+	}
+}
+
+// WithReproducible builds the media reproducibly, timestamps are set to the build epoch and
+// identifiers are derived from the build seed
+func WithReproducible(b *reproducible.Build) Option {
+	return func(i *Media) {
+		i.repro = b
+	}
+}
+
+// WithSBOM adds the RPM packages of the media OS to the given SBOM and writes it next to the
+// media image
+func WithSBOM(sb *sbom.SBOM) Option {
+	return func(i *Media) {
+		i.sbom = sb
+	}
+}
+
 func WithOutputFile(outputFile string) Option {
 	return func(i *Media) {
 		i.outputFile = outputFile
@@ -136,12 +179,17 @@ func NewMedia(ctx context.Context, s *sys.System, mType MediaType, opts ...Optio
 		ctx:        ctx,
 		unpackOpts: []unpack.Opt{},
 		mType:      mType,
+		diskFormat: diskimage.Raw,
 	}
 	for _, o := range opts {
 		o(media)
 	}
 	if media.bl == nil {
-		media.bl, _ = bootloader.New(bootloader.BootGrub, media.s)
+		var blOpts []bootloader.Opt
+		if media.repro != nil {
+			blOpts = append(blOpts, bootloader.WithLiveID(LiveID(media.repro)))
+		}
+		media.bl, _ = bootloader.New(bootloader.BootGrub, media.s, blOpts...)
 	}
 	if media.mType == ISO {
 		media.Label = "LIVE"
@@ -149,6 +197,11 @@ func NewMedia(ctx context.Context, s *sys.System, mType MediaType, opts ...Optio
 	return media
 }
 
+// LiveID returns the live media identifier of the given reproducible build
+func LiveID(b *reproducible.Build) string {
+	return b.ID(liveDir)
+}
+
 // extractISO extracts the given source path (relative to iso root) to the destination path
 func extractISO(s *sys.System, iso, srcPath, destPath string) error {
 	args := []string{
@@ -214,25 +267,41 @@ func (i Media) Build(d *deployment.Deployment) (err error) {
 		return fmt.Errorf("failed creating ISO directory: %w", err)
 	}
 
+	i.s.Events().Start(events.PhaseDeploy)
 	err = i.PrepareInstallerFS(liveRoot, osRoot, d)
+	i.s.Events().Finish(events.PhaseDeploy, err)
 	if err != nil {
 		return fmt.Errorf("failed to populate ISO directory tree: %w", err)
 	}
 
+	err = i.clampMTimes(liveRoot)
+	if err != nil {
+		return err
+	}
+
+	i.s.Events().Start(events.PhaseMedia)
 	switch i.mType {
 	case ISO:
 		cmdline := fmt.Sprintf("%s %s", deployment.LiveKernelCmdline(i.Label), d.Installer.KernelCmdline)
 		err = i.buildISO(tempDir, liveRoot, osRoot, cmdline)
 	case Disk:
 		err = i.buildDisk(tempDir, liveRoot, osRoot, d)
+		if err == nil {
+			err = i.convertDisk(tempDir)
+		}
 	default:
-		return fmt.Errorf("unknown media type: %w", errors.ErrUnsupported)
+		err = fmt.Errorf("unknown media type: %w", errors.ErrUnsupported)
 	}
+	i.s.Events().Finish(events.PhaseMedia, err)
 	if err != nil {
 		return err
 	}
 
-	return i.writeChecksum()
+	err = i.writeChecksum()
+	if err != nil {
+		return err
+	}
+	return i.writeSBOM()
 }
 
 // PrepareInstallerFS prepares the directory tree of the installer image, rootDir is the path
@@ -254,12 +323,31 @@ func (i *Media) PrepareInstallerFS(rootDir, workDir string, d *deployment.Deploy
 		if err != nil {
 			return fmt.Errorf("failed copying OS image to installer root tree: %w", err)
 		}
+		if i.sbom != nil {
+			err = i.sbom.AddSquashfsRPMs(i.ctx, i.s, squashImg, workDir)
+			if err != nil {
+				return fmt.Errorf("failed listing OS packages: %w", err)
+			}
+		}
 	default:
-		err = i.prepareOSRoot(d.SourceOS, workDir)
+		err = i.prepareOSRoot(d.SourceOS, workDir, d.Registries)
 		if err != nil {
 			return fmt.Errorf("preparing unpack: %w", err)
 		}
-		err = filesystem.CreateSquashFS(i.ctx, i.s, workDir, squashImg, filesystem.DefaultSquashfsCompressionOptions())
+		if i.sbom != nil {
+			if d.SourceOS.IsOCI() {
+				i.sbom.AddImage(sbom.OSImage, d.SourceOS.URI(), d.SourceOS.GetDigest())
+			}
+			err = i.sbom.AddRPMs(i.ctx, i.s, workDir)
+			if err != nil {
+				return fmt.Errorf("failed listing OS packages: %w", err)
+			}
+		}
+		options := filesystem.DefaultSquashfsCompressionOptions()
+		if i.repro != nil {
+			options = append(options, filesystem.SquashfsReproducibleOptions(i.repro.Timestamp())...)
+		}
+		err = filesystem.CreateSquashFS(i.ctx, i.s, workDir, squashImg, options)
 		if err != nil {
 			return fmt.Errorf("failed creating image (%s) for live ISO: %w", squashImg, err)
 		}
@@ -275,7 +363,7 @@ func (i *Media) PrepareInstallerFS(rootDir, workDir string, d *deployment.Deploy
 	if d.Installer.OverlayTree != nil {
 		unpacker, err := unpack.NewUnpacker(
 			i.s, d.Installer.OverlayTree,
-			append(i.unpackOpts, unpack.WithRsyncFlags(rsync.OverlayTreeSyncFlags()...))...,
+			append(i.unpackOpts, unpack.WithRsyncFlags(rsync.OverlayTreeSyncFlags()...), unpack.WithRegistries(d.Registries))...,
 		)
 		if err != nil {
 			return fmt.Errorf("could not initiate overlay unpacker: %w", err)
@@ -331,6 +419,22 @@ func (i *Media) Customize(d *deployment.Deployment) (err error) {
 		return fmt.Errorf("failed extracting install description from '%s': %w", i.InputFile, err)
 	}
 
+	if i.sbom != nil {
+		squashImg := filepath.Join(tempDir, squashfsImg)
+		err = extractISO(i.s, i.InputFile, SquashfsRelPath, squashImg)
+		if err != nil {
+			return fmt.Errorf("failed extracting OS image from '%s': %w", i.InputFile, err)
+		}
+		err = i.sbom.AddSquashfsRPMs(i.ctx, i.s, squashImg, tempDir)
+		if err != nil {
+			return fmt.Errorf("failed listing OS packages: %w", err)
+		}
+		err = i.s.FS().RemoveAll(squashImg)
+		if err != nil {
+			return fmt.Errorf("failed removing extracted OS image: %w", err)
+		}
+	}
+
 	m := map[string]string{}
 
 	grubEnvPath := filepath.Join(tempDir, "grubenv")
@@ -356,7 +460,7 @@ func (i *Media) Customize(d *deployment.Deployment) (err error) {
 			}
 			unpacker, err := unpack.NewUnpacker(
 				i.s, d.Installer.OverlayTree,
-				append(i.unpackOpts, unpack.WithRsyncFlags(rsync.OverlayTreeSyncFlags()...))...,
+				append(i.unpackOpts, unpack.WithRsyncFlags(rsync.OverlayTreeSyncFlags()...), unpack.WithRegistries(d.Registries))...,
 			)
 			if err != nil {
 				return fmt.Errorf("could not initiate overlay unpacker: %w", err)
@@ -396,19 +500,40 @@ func (i *Media) Customize(d *deployment.Deployment) (err error) {
 		return err
 	}
 
+	i.s.Events().Start(events.PhaseMedia)
 	switch i.mType {
 	case ISO:
 		err = i.customizeISO(i.InputFile, i.outputFile, m)
 	case Disk:
 		err = i.customizeDisk(tempDir, installDesc, m)
+		if err == nil {
+			err = i.convertDisk(tempDir)
+		}
 	default:
 		err = fmt.Errorf("unknown media type: %w", errors.ErrUnsupported)
 	}
+	i.s.Events().Finish(events.PhaseMedia, err)
 	if err != nil {
 		return err
 	}
 
-	return i.writeChecksum()
+	err = i.writeChecksum()
+	if err != nil {
+		return err
+	}
+	return i.writeSBOM()
+}
+
+// writeSBOM writes the SBOM next to the media output file, if any
+func (i Media) writeSBOM() error {
+	if i.sbom == nil {
+		return nil
+	}
+	err := i.sbom.Write(i.s.FS(), i.outputFile)
+	if err != nil {
+		return fmt.Errorf("failed writing SBOM: %w", err)
+	}
+	return nil
 }
 
 // writeChecksum computes the checksum for the current media output file and writes
@@ -424,6 +549,7 @@ func (i Media) writeChecksum() error {
 	if err != nil {
 		return fmt.Errorf("failed writing image checksum file %s: %w", checksumFile, err)
 	}
+	i.s.Events().Digest(filepath.Base(i.outputFile), "sha256:"+checksum)
 	return nil
 }
 
@@ -501,8 +627,23 @@ func (i *Media) sanitize() error {
 		}
 	}
 
+	if i.diskFormat == "" {
+		i.diskFormat = diskimage.Raw
+	}
+
+	ext := i.mType.String()
+	if i.mType == Disk {
+		ext = i.diskFormat.String()
+	} else if i.diskFormat.IsConverted() || i.compress {
+		return fmt.Errorf("disk image format options are only supported for disk media")
+	}
+
+	if i.compress && !i.diskFormat.SupportsCompression() {
+		return fmt.Errorf("compression is not supported for %s disk images", i.diskFormat)
+	}
+
 	if i.outputFile == "" {
-		i.outputFile = filepath.Join(i.OutputDir, fmt.Sprintf("%s.%s", i.Name, i.mType.String()))
+		i.outputFile = filepath.Join(i.OutputDir, fmt.Sprintf("%s.%s", i.Name, ext))
 		if ok, _ := vfs.Exists(i.s.FS(), i.outputFile); ok {
 			return fmt.Errorf("target output file %s is an already existing file", i.outputFile)
 		}
@@ -514,8 +655,11 @@ func (i *Media) sanitize() error {
 func (i Media) customizeISO(inputFile, outputFile string, fileMap map[string]string) error {
 	args := []string{"-indev", inputFile, "-outdev", outputFile, "-boot_image", "any", "replay"}
 
-	for f, m := range fileMap {
-		args = append(args, "-map", f, m)
+	for _, f := range slices.Sorted(maps.Keys(fileMap)) {
+		args = append(args, "-map", f, fileMap[f])
+	}
+	if i.repro != nil {
+		args = append(args, xorrisoReproducibleArgs(i.repro)...)
 	}
 
 	_, err := i.s.Runner().RunContext(i.ctx, xorriso, args...)
@@ -544,11 +688,12 @@ func (i Media) writeGrubEnv(file string, vars map[string]string) error {
 }
 
 // prepareOSRoot arranges the root directory tree that will be used to build the ISO's
-// squashfs image. It essentially extracts OS OCI images to the given location.
-func (i Media) prepareOSRoot(sourceOS *deployment.ImageSource, rootDir string) error {
+// squashfs image. It essentially extracts OS OCI images to the given location, pulling them with
+// the given registries configuration.
+func (i Media) prepareOSRoot(sourceOS *deployment.ImageSource, rootDir string, registries *registry.Config) error {
 	i.s.Logger().Info("Extracting OS %s", sourceOS.String())
 
-	unpacker, err := unpack.NewUnpacker(i.s, sourceOS, i.unpackOpts...)
+	unpacker, err := unpack.NewUnpacker(i.s, sourceOS, append(slices.Clone(i.unpackOpts), unpack.WithRegistries(registries))...)
 	if err != nil {
 		return fmt.Errorf("could not initiate OS unpacker: %w", err)
 	}
@@ -607,6 +752,14 @@ func (i Media) addInstallationAssets(root string, d *deployment.Deployment) erro
 		}
 	}
 
+	// only the certificate is embedded, the private key never leaves the build host
+	if conf := d.GetSecureBoot(); conf.IsValid() && conf.Cert != SecureBootCert {
+		err = vfs.CopyFile(i.s.FS(), conf.Cert, filepath.Join(installPath, secureBootCert))
+		if err != nil {
+			return fmt.Errorf("failed copying %s to install directory: %w", conf.Cert, err)
+		}
+	}
+
 	if d.OverlayTree != nil {
 		overlayPath := filepath.Join(installPath, overlayDir)
 		err = vfs.MkdirAll(i.s.FS(), overlayPath, vfs.DirPerm)
@@ -672,6 +825,12 @@ func (i Media) writeInstallDescription(installPath string, d *deployment.Deploym
 		d.Installer.CfgScript = filepath.Join(LiveMountPoint, liveDir, cfgScript)
 	}
 
+	// boot artifacts of the media are signed at build time, the installation can only
+	// enroll the embedded certificate
+	if conf := d.GetSecureBoot(); conf != nil {
+		d.Security.SecureBoot = &secureboot.Config{Cert: SecureBootCert, EnrollMOK: conf.EnrollMOK}
+	}
+
 	d.SourceOS = deployment.NewRawSrc(SquashfsPath)
 	d.Installer.OverlayTree = deployment.NewDirSrc(LiveMountPoint)
 
@@ -747,7 +906,53 @@ func (i Media) customizeDisk(tempDir string, d *deployment.Deployment, mappedFil
 			Excludes:  []string{filepath.Join(isoDir, "boot"), filepath.Join(isoDir, "EFI")},
 		},
 	}
-	return repart.CreateDiskImage(i.s, i.outputFile, i.rawDiskSize, parts)
+	err = i.clampMTimes(isoDir)
+	if err != nil {
+		return err
+	}
+	return repart.CreateDiskImage(i.s, i.rawDiskFile(tempDir), i.rawDiskSize, parts, i.repartFlags()...)
+}
+
+// repartFlags returns the systemd-repart flags required by the media settings
+func (i Media) repartFlags() []string {
+	if i.repro == nil {
+		return nil
+	}
+	return []string{fmt.Sprintf("--seed=%s", i.repro.Seed)}
+}
+
+// clampMTimes clamps the modification times of the given tree to the build epoch of reproducible media
+func (i Media) clampMTimes(root string) error {
+	if i.repro == nil {
+		return nil
+	}
+	err := i.repro.ClampMTimes(i.ctx, i.s, root)
+	if err != nil {
+		return fmt.Errorf("failed normalizing timestamps: %w", err)
+	}
+	return nil
+}
+
+// rawDiskFile returns the path of the RAW disk image, a temporary file within the given
+// directory if the image is converted to another format afterwards
+func (i Media) rawDiskFile(tempDir string) string {
+	if i.diskFormat.IsConverted() {
+		return filepath.Join(tempDir, "disk.raw")
+	}
+	return i.outputFile
+}
+
+// convertDisk converts the RAW disk image to the output file if another format was requested
+func (i Media) convertDisk(tempDir string) error {
+	if !i.diskFormat.IsConverted() {
+		return nil
+	}
+	rawFile := i.rawDiskFile(tempDir)
+	err := diskimage.Convert(i.ctx, i.s, rawFile, i.outputFile, i.diskFormat, i.compress)
+	if err != nil {
+		return fmt.Errorf("failed converting disk image: %w", err)
+	}
+	return i.s.FS().RemoveAll(rawFile)
 }
 
 // buildDisk creates an installer disk image from the prepared root
@@ -780,7 +985,11 @@ func (i Media) buildDisk(tempDir, liveRoot, osRoot string, d *deployment.Deploym
 			CopyFiles: []string{fmt.Sprintf("%s:/", liveRoot)},
 		},
 	}
-	err = repart.CreateDiskImage(i.s, i.outputFile, 0, parts)
+	err = i.clampMTimes(espDir)
+	if err != nil {
+		return err
+	}
+	err = repart.CreateDiskImage(i.s, i.rawDiskFile(tempDir), 0, parts, i.repartFlags()...)
 	if err != nil {
 		return fmt.Errorf("failed creating disk image: %w", err)
 	}
@@ -816,6 +1025,9 @@ func (i Media) buildISO(tempDir, isoDir, osRoot, kernelCmdline string) error {
 		"-outdev", i.outputFile, "-map", isoDir, "/", "-chmod", "0755", "--",
 	}
 	args = append(args, xorrisoBootloaderArgs(efiImg)...)
+	if i.repro != nil {
+		args = append(args, xorrisoReproducibleArgs(i.repro)...)
+	}
 
 	_, err = i.s.Runner().RunContext(i.ctx, xorriso, args...)
 	if err != nil {
@@ -839,6 +1051,18 @@ func xorrisoBootloaderArgs(efiImg string) []string {
 	return args
 }
 
+// xorrisoReproducibleArgs returns a slice of flags for xorriso to set all volume and file
+// timestamps to the build epoch
+func xorrisoReproducibleArgs(b *reproducible.Build) []string {
+	epoch := "=" + b.Timestamp()
+	return []string{
+		"-volume_date", "c", epoch,
+		"-volume_date", "m", epoch,
+		"-volume_date", "uuid", b.Epoch.Format("20060102150405") + "00",
+		"-volume_date", "all_file_dates", epoch,
+	}
+}
+
 // calcFileChecksum opens the given file and returns the sha256 checksum of it.
 func calcFileChecksum(fs vfs.FS, fileName string) (string, error) {
 	f, err := fs.Open(fileName)
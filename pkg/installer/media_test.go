@@ -20,18 +20,26 @@ package installer_test
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"testing"
 
+	"go.yaml.in/yaml/v3"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"github.com/suse/elemental/v3/pkg/bootloader"
 	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/diskimage"
 	"github.com/suse/elemental/v3/pkg/installer"
 	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/reproducible"
+	"github.com/suse/elemental/v3/pkg/sbom"
+	"github.com/suse/elemental/v3/pkg/secureboot"
 	"github.com/suse/elemental/v3/pkg/sys"
 	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
@@ -108,6 +116,106 @@ var _ = Describe("InstallerMedia", Label("installermedia"), func() {
 			{"xorriso", "-volid", "LIVE", "-padding", "0", "-outdev", "/some/dir/build/installer.iso"},
 		}))
 	})
+	It("Creates an installation ISO embedding only the Secure Boot certificate", func() {
+		isoDir := "/some/dir/build/elemental-installer/liveroot"
+		var cert, desc []byte
+		sideEffects["xorriso"] = func(args ...string) ([]byte, error) {
+			var err error
+			cert, err = fs.ReadFile(filepath.Join(isoDir, "Install/secureboot.crt"))
+			Expect(err).NotTo(HaveOccurred())
+			desc, err = fs.ReadFile(filepath.Join(isoDir, "Install/install.yaml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fs.WriteFile("/some/dir/build/installer.iso", []byte("data"), vfs.FilePerm)).To(Succeed())
+			return []byte{}, nil
+		}
+
+		d.SourceOS = deployment.NewDirSrc("/some/root")
+		d.Security.SecureBoot = &secureboot.Config{Key: "/keys/db.key", Cert: "/keys/db.crt", EnrollMOK: true}
+		Expect(vfs.MkdirAll(fs, "/keys", vfs.DirPerm)).To(Succeed())
+		Expect(fs.WriteFile("/keys/db.key", []byte("key"), vfs.FilePerm)).To(Succeed())
+		Expect(fs.WriteFile("/keys/db.crt", []byte("cert"), vfs.FilePerm)).To(Succeed())
+
+		iso := installer.NewMedia(context.Background(), s, installer.ISO, installer.WithBootloader(bootloader.NewNone(s)))
+		iso.OutputDir = "/some/dir/build"
+		Expect(iso.Build(d)).To(Succeed())
+
+		Expect(string(cert)).To(Equal("cert"))
+		installDesc := &deployment.Deployment{}
+		Expect(yaml.Unmarshal(desc, installDesc)).To(Succeed())
+		Expect(installDesc.GetSecureBoot()).To(Equal(&secureboot.Config{Cert: installer.SecureBootCert, EnrollMOK: true}))
+		Expect(string(desc)).NotTo(ContainSubstring("/keys/"))
+	})
+	It("Creates a reproducible installation ISO", func() {
+		var squashfsArgs, findArgs, xorrisoArgs []string
+		sideEffects["mksquashfs"] = func(args ...string) ([]byte, error) {
+			squashfsArgs = args
+			return []byte{}, nil
+		}
+		sideEffects["find"] = func(args ...string) ([]byte, error) {
+			findArgs = args
+			return []byte{}, nil
+		}
+		sideEffects["xorriso"] = func(args ...string) ([]byte, error) {
+			xorrisoArgs = args
+			Expect(fs.WriteFile("/some/dir/build/installer.iso", []byte("data"), vfs.FilePerm)).To(Succeed())
+			return []byte{}, nil
+		}
+
+		d.SourceOS = deployment.NewDirSrc("/some/root")
+		repro := &reproducible.Build{Epoch: time.Unix(1700000000, 0).UTC()}
+		iso := installer.NewMedia(
+			context.Background(), s, installer.ISO, installer.WithBootloader(bootloader.NewNone(s)),
+			installer.WithReproducible(repro),
+		)
+		iso.OutputDir = "/some/dir/build"
+
+		Expect(iso.Build(d)).To(Succeed())
+		Expect(squashfsArgs).To(ContainElements("-mkfs-time", "1700000000", "-all-time"))
+		Expect(findArgs).To(ContainElements("-newermt", "@1700000000", "--date=@1700000000"))
+		Expect(xorrisoArgs).To(ContainElements(
+			"-volume_date", "uuid", "2023111422132000", "all_file_dates", "=1700000000",
+		))
+	})
+	It("Creates a compressed qcow2 installation disk", func() {
+		sideEffects["systemd-repart"] = func(args ...string) ([]byte, error) {
+			return []byte(`[
+				{"uuid" : "c60d1845-7b04-4fc4-8639-8c49eb7277d5", "file" : "/tmp/elemental-repart.d/0-efi.conf"},
+				{"uuid" : "ddb334a8-48a2-c4de-ddb3-849eb2443e92", "file" : "/tmp/elemental-repart.d/1-recovery.conf"}
+			]`), nil
+		}
+		sideEffects["qemu-img"] = func(args ...string) ([]byte, error) {
+			Expect(fs.WriteFile(args[len(args)-1], []byte("data"), vfs.FilePerm)).To(Succeed())
+			return []byte{}, nil
+		}
+
+		d = deployment.New(deployment.WithRecoveryPartition(0))
+		d.SourceOS = deployment.NewDirSrc("/some/root")
+		disk := installer.NewMedia(
+			context.Background(), s, installer.Disk, installer.WithBootloader(bootloader.NewNone(s)),
+			installer.WithDiskFormat(diskimage.QCOW2, true), installer.WithSBOM(sbom.New()),
+		)
+		disk.OutputDir = "/some/dir/build"
+
+		Expect(disk.Build(d)).To(Succeed())
+		Expect(runner.MatchMilestones([][]string{
+			{
+				"qemu-img", "convert", "-f", "raw", "-O", "qcow2", "-c",
+				"/some/dir/build/elemental-installer/disk.raw", "/some/dir/build/installer.qcow2",
+			},
+		})).To(Succeed())
+		Expect(vfs.Exists(fs, "/some/dir/build/installer.qcow2.sha256")).To(BeTrue())
+		Expect(vfs.Exists(fs, "/some/dir/build/installer.qcow2.spdx.json")).To(BeTrue())
+	})
+	It("fails to create a compressed VHD installation disk", func() {
+		d.SourceOS = deployment.NewDirSrc("/some/root")
+		disk := installer.NewMedia(
+			context.Background(), s, installer.Disk, installer.WithBootloader(bootloader.NewNone(s)),
+			installer.WithDiskFormat(diskimage.VHD, true),
+		)
+		disk.OutputDir = "/some/dir/build"
+
+		Expect(disk.Build(d)).To(MatchError(ContainSubstring("compression is not supported")))
+	})
 	It("fails to create an ISO without an output directory defined", func() {
 		d.SourceOS = deployment.NewDirSrc("/some/root")
 		iso := installer.NewMedia(context.Background(), s, installer.ISO, installer.WithBootloader(bootloader.NewNone(s)))
@@ -0,0 +1,118 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package luks
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+const MapperDir = "/dev/mapper"
+
+// ReadUUID returns the UUID of the LUKS2 header of the given device
+func ReadUUID(s *sys.System, device string) (string, error) {
+	out, err := s.Runner().Run("cryptsetup", "luksUUID", device)
+	if err != nil {
+		return "", fmt.Errorf("reading LUKS UUID of '%s': %w", device, err)
+	}
+	uuid := strings.TrimSpace(string(out))
+	if uuid == "" {
+		return "", fmt.Errorf("empty LUKS UUID for '%s'", device)
+	}
+	return uuid, nil
+}
+
+// EnrollRecoveryPassphrase adds the recovery passphrase of the partition encryption, if any, as
+// a new key slot of the given device. The volume is unlocked with the key file, if any, otherwise
+// with the TPM2 token enrolled at partitioning time.
+func EnrollRecoveryPassphrase(s *sys.System, part *deployment.Partition, device string) error {
+	enc := part.Encryption
+	if enc == nil || enc.RecoveryPassphraseFile == "" {
+		return nil
+	}
+	args := []string{"luksAddKey", "--batch-mode", "--type", "luks2"}
+	if enc.KeyFile != "" {
+		args = append(args, "--key-file", enc.KeyFile)
+	} else {
+		args = append(args, "--token-only", "--token-type", "systemd-tpm2")
+	}
+	_, err := s.Runner().Run("cryptsetup", append(args, device, enc.RecoveryPassphraseFile)...)
+	if err != nil {
+		return fmt.Errorf("enrolling recovery passphrase for '%s': %w", device, err)
+	}
+	return nil
+}
+
+// Open unlocks the encrypted volume of the given partition device and returns the path of the
+// unlocked device. Nothing is done if the volume is already unlocked. The key file is preferred
+// over the TPM2 device to unlock the volume.
+func Open(s *sys.System, part *deployment.Partition, device string) (string, error) {
+	enc := part.Encryption
+	if enc == nil {
+		return "", fmt.Errorf("partition '%s' is not encrypted", part.Label)
+	}
+	if enc.UUID == "" {
+		uuid, err := ReadUUID(s, device)
+		if err != nil {
+			return "", err
+		}
+		enc.UUID = uuid
+	}
+
+	name := part.MapperName()
+	mapper := filepath.Join(MapperDir, name)
+	if ok, _ := vfs.Exists(s.FS(), mapper); ok {
+		return mapper, nil
+	}
+
+	var err error
+	if enc.KeyFile != "" {
+		_, err = s.Runner().Run("cryptsetup", "open", "--type", "luks2", "--key-file", enc.KeyFile, device, name)
+	} else {
+		_, err = s.Runner().Run(
+			"/usr/lib/systemd/systemd-cryptsetup", "attach", name, device, "-", "tpm2-device=auto",
+		)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unlocking encrypted device '%s': %w", device, err)
+	}
+	return mapper, nil
+}
+
+// Close locks the unlocked volume of the given partition
+func Close(s *sys.System, part *deployment.Partition) error {
+	_, err := s.Runner().Run("cryptsetup", "close", part.MapperName())
+	if err != nil {
+		return fmt.Errorf("closing encrypted device '%s': %w", part.MapperName(), err)
+	}
+	return nil
+}
+
+// DevicePath returns the device path to mount the given partition. For encrypted partitions the volume
+// is unlocked, if not already, and the unlocked device is returned. Otherwise the given device is returned.
+func DevicePath(s *sys.System, part *deployment.Partition, device string) (string, error) {
+	if part.Encryption == nil {
+		return device, nil
+	}
+	return Open(s, part, device)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package luks_test
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/luks"
+	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+const luksUUID = "2f1a3c4e-5b6d-4e7f-8a9b-0c1d2e3f4a5b"
+
+var _ = Describe("LUKS", Label("luks"), func() {
+	var runner *sysmock.Runner
+	var fs vfs.FS
+	var cleanup func()
+	var s *sys.System
+	var part *deployment.Partition
+
+	BeforeEach(func() {
+		var err error
+		runner = sysmock.NewRunner()
+		fs, cleanup, err = sysmock.TestFS(map[string]string{
+			"/etc/keys/luks.key": "secret",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		s, err = sys.NewSystem(
+			sys.WithRunner(runner), sys.WithFS(fs),
+			sys.WithLogger(log.New(log.WithDiscardAll())),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		runner.SideEffect = func(cmd string, args ...string) ([]byte, error) {
+			if cmd == "cryptsetup" && args[0] == "luksUUID" {
+				return []byte(luksUUID + "\n"), nil
+			}
+			return []byte{}, nil
+		}
+		part = &deployment.Partition{
+			Label:      "SYSTEM",
+			Role:       deployment.System,
+			Encryption: &deployment.Encryption{KeyFile: "/etc/keys/luks.key"},
+		}
+	})
+
+	AfterEach(func() {
+		cleanup()
+	})
+
+	It("unlocks an encrypted partition with a key file", func() {
+		mapper, err := luks.Open(s, part, "/dev/loop0p2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mapper).To(Equal("/dev/mapper/luks-" + luksUUID))
+		Expect(part.Encryption.UUID).To(Equal(luksUUID))
+		Expect(runner.CmdsMatch([][]string{
+			{"cryptsetup", "luksUUID", "/dev/loop0p2"},
+			{"cryptsetup", "open", "--type", "luks2", "--key-file", "/etc/keys/luks.key", "/dev/loop0p2", "luks-" + luksUUID},
+		})).To(Succeed())
+
+		runner.ClearCmds()
+		Expect(luks.Close(s, part)).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{{"cryptsetup", "close", "luks-" + luksUUID}})).To(Succeed())
+	})
+
+	It("unlocks an encrypted partition with the TPM2 device", func() {
+		part.Encryption = &deployment.Encryption{TPM2: &deployment.TPM2{}, UUID: luksUUID}
+		mapper, err := luks.Open(s, part, "/dev/loop0p2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mapper).To(Equal("/dev/mapper/luks-" + luksUUID))
+		Expect(runner.CmdsMatch([][]string{{
+			"/usr/lib/systemd/systemd-cryptsetup", "attach", "luks-" + luksUUID, "/dev/loop0p2", "-", "tpm2-device=auto",
+		}})).To(Succeed())
+	})
+
+	It("does nothing if the partition is already unlocked", func() {
+		part.Encryption.UUID = luksUUID
+		Expect(vfs.MkdirAll(fs, luks.MapperDir, vfs.DirPerm)).To(Succeed())
+		Expect(fs.WriteFile("/dev/mapper/luks-"+luksUUID, []byte{}, vfs.FilePerm)).To(Succeed())
+
+		mapper, err := luks.DevicePath(s, part, "/dev/loop0p2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mapper).To(Equal("/dev/mapper/luks-" + luksUUID))
+		Expect(runner.GetCmds()).To(BeEmpty())
+	})
+
+	It("returns the given device for plain partitions", func() {
+		part.Encryption = nil
+		device, err := luks.DevicePath(s, part, "/dev/loop0p2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(device).To(Equal("/dev/loop0p2"))
+		Expect(runner.GetCmds()).To(BeEmpty())
+	})
+
+	It("enrolls the recovery passphrase unlocking the volume with the key file", func() {
+		Expect(luks.EnrollRecoveryPassphrase(s, part, "/dev/loop0p2")).To(Succeed())
+		Expect(runner.GetCmds()).To(BeEmpty())
+
+		part.Encryption.RecoveryPassphraseFile = "/etc/keys/recovery"
+		Expect(luks.EnrollRecoveryPassphrase(s, part, "/dev/loop0p2")).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{{
+			"cryptsetup", "luksAddKey", "--batch-mode", "--type", "luks2",
+			"--key-file", "/etc/keys/luks.key", "/dev/loop0p2", "/etc/keys/recovery",
+		}})).To(Succeed())
+	})
+
+	It("enrolls the recovery passphrase unlocking the volume with the TPM2 token", func() {
+		part.Encryption.KeyFile = ""
+		part.Encryption.TPM2 = &deployment.TPM2{}
+		part.Encryption.RecoveryPassphraseFile = "/etc/keys/recovery"
+		Expect(luks.EnrollRecoveryPassphrase(s, part, "/dev/loop0p2")).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{{
+			"cryptsetup", "luksAddKey", "--batch-mode", "--type", "luks2",
+			"--token-only", "--token-type", "systemd-tpm2", "/dev/loop0p2", "/etc/keys/recovery",
+		}})).To(Succeed())
+	})
+
+	It("fails to unlock the partition if the LUKS UUID can't be read", func() {
+		runner.SideEffect = func(cmd string, args ...string) ([]byte, error) {
+			return []byte{}, fmt.Errorf("not a LUKS device")
+		}
+		_, err := luks.Open(s, part, "/dev/loop0p2")
+		Expect(err).To(MatchError(ContainSubstring("reading LUKS UUID of '/dev/loop0p2'")))
+	})
+})
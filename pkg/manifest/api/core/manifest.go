@@ -44,8 +44,20 @@ type OperatingSystem struct {
 }
 
 type Image struct {
-	Base string `yaml:"base" validate:"required"`
-	ISO  string `yaml:"iso" validate:"required"`
+	Base       string `yaml:"base" validate:"required"`
+	BaseDigest string `yaml:"baseDigest,omitempty" validate:"omitempty,oci_digest"`
+	ISO        string `yaml:"iso" validate:"required"`
+	ISODigest  string `yaml:"isoDigest,omitempty" validate:"omitempty,oci_digest"`
+}
+
+// PinnedBase returns the base OS image reference including its digest, if any
+func (i Image) PinnedBase() string {
+	return api.PinnedImage(i.Base, i.BaseDigest)
+}
+
+// PinnedISO returns the ISO image reference including its digest, if any
+func (i Image) PinnedISO() string {
+	return api.PinnedImage(i.ISO, i.ISODigest)
 }
 
 func Parse(data []byte) (*ReleaseManifest, error) {
@@ -48,6 +48,7 @@ components:
   operatingSystem:
     image:
       base: foo.bar:latest
+      baseDigest: sha256:1234
   systemd:
     extensions:
     - name: "missing_img"
@@ -83,6 +84,8 @@ var _ = Describe("ReleaseManifest", Label("release-manifest"), func() {
 		Expect(rm.Components.OperatingSystem).ToNot(BeNil())
 		Expect(rm.Components.OperatingSystem.Image.Base).To(Equal("registry.com/foo/bar/os-base:6.2"))
 		Expect(rm.Components.OperatingSystem.Image.ISO).To(Equal("registry.com/foo/bar/installer-iso:6.2"))
+		Expect(rm.Components.OperatingSystem.Image.PinnedBase()).To(Equal("registry.com/foo/bar/os-base:6.2@sha256:4c1b5d3e2a6f7089b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5d6e"))
+		Expect(rm.Components.OperatingSystem.Image.PinnedISO()).To(Equal("registry.com/foo/bar/installer-iso:6.2"))
 
 		Expect(rm.Components.Systemd.Extensions).To(HaveLen(2))
 		Expect(rm.Components.Systemd.Extensions[0].Name).To(Equal("elemental3ctl"))
@@ -122,6 +125,7 @@ var _ = Describe("ReleaseManifest", Label("release-manifest"), func() {
 	It("fails when manifest is broken", func() {
 		expErrors := []string{
 			"field \"ReleaseManifest.components.operatingSystem.image.iso\" is required",
+			"field \"ReleaseManifest.components.operatingSystem.image.baseDigest\" must be a 'sha256:<hex>' digest, but got \"sha256:1234\"",
 			"field \"ReleaseManifest.components.systemd.extensions[0].image\" is required",
 			"field \"ReleaseManifest.components.helm.charts[0].dependsOn[0].type\" must be one of [sysext helm], but got \"broken\"",
 		}
@@ -35,7 +35,13 @@ type ReleaseManifest struct {
 }
 
 type CorePlatform struct {
-	Image string `yaml:"image" validate:"required"`
+	Image  string `yaml:"image" validate:"required"`
+	Digest string `yaml:"digest,omitempty" validate:"omitempty,oci_digest"`
+}
+
+// PinnedImage returns the core platform release manifest image reference including its digest, if any
+func (c CorePlatform) PinnedImage() string {
+	return api.PinnedImage(c.Image, c.Digest)
 }
 
 type Components struct {
@@ -99,8 +99,24 @@ type Systemd struct {
 }
 
 type SystemdExtension struct {
-	Name          string   `yaml:"name" validate:"required"`
-	Image         string   `yaml:"image" validate:"required"`
+	Name  string `yaml:"name" validate:"required"`
+	Image string `yaml:"image" validate:"required"`
+	// Digest pins the extension image, for remote URLs it is the digest of the downloaded file
+	Digest        string   `yaml:"digest,omitempty" validate:"omitempty,oci_digest"`
 	Required      bool     `yaml:"required,omitempty"`
 	KernelModules []string `yaml:"kernelModules,omitempty"`
 }
+
+// PinnedImage returns the extension image reference including its digest, if any
+func (e SystemdExtension) PinnedImage() string {
+	return PinnedImage(e.Image, e.Digest)
+}
+
+// PinnedImage returns the given image reference pinned to the given digest in the
+// 'name:tag@digest' form. The image reference is returned unchanged if the digest is empty.
+func PinnedImage(image, digest string) string {
+	if digest == "" {
+		return image
+	}
+	return image + "@" + digest
+}
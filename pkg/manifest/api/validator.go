@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/go-playground/validator/v10"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 )
 
 type ValidatorOpts func(*validator.Validate)
@@ -40,6 +41,7 @@ func WithYAMLFieldNames() ValidatorOpts {
 
 func NewValidator(opts ...ValidatorOpts) *validator.Validate {
 	validate := validator.New(validator.WithRequiredStructEnabled())
+	_ = validate.RegisterValidation("oci_digest", validateDigest)
 	for _, opt := range opts {
 		opt(validate)
 	}
@@ -56,9 +58,16 @@ func FormatErrors(errs validator.ValidationErrors) error {
 			messages = append(messages, fmt.Sprintf("field %q must be one of [%s], but got %q", err.Namespace(), err.Param(), err.Value()))
 		case "url":
 			messages = append(messages, fmt.Sprintf("field %q must be a valid URL, but got %q", err.Namespace(), err.Value()))
+		case "oci_digest":
+			messages = append(messages, fmt.Sprintf("field %q must be a 'sha256:<hex>' digest, but got %q", err.Namespace(), err.Value()))
 		default:
 			messages = append(messages, fmt.Sprintf("field %q failed validation on tag %q", err.Namespace(), err.Tag()))
 		}
 	}
 	return errors.New(strings.Join(messages, "; "))
 }
+
+func validateDigest(fl validator.FieldLevel) bool {
+	h, err := v1.NewHash(fl.Field().String())
+	return err == nil && h.Algorithm == "sha256"
+}
@@ -87,6 +87,6 @@ func (r *Resolver) resolveRecursive(uri string, rm *ResolvedManifest) error {
 	}
 	rm.ProductExtension = productManifest
 
-	coreReleaseManifestOCI := fmt.Sprintf("%s://%s", source.OCI, rm.ProductExtension.CorePlatform.Image)
+	coreReleaseManifestOCI := fmt.Sprintf("%s://%s", source.OCI, rm.ProductExtension.CorePlatform.PinnedImage())
 	return r.resolveRecursive(coreReleaseManifestOCI, rm)
 }
@@ -32,7 +32,8 @@ import (
 const (
 	corePlatformRef              = "foo.example.com/bar/release-manifest"
 	corePlatformVersion          = "1.0"
-	expectedCorePlatformImage    = corePlatformRef + ":" + corePlatformVersion
+	corePlatformDigest           = "sha256:4c1b5d3e2a6f7089b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5d6e"
+	expectedCorePlatformImage    = corePlatformRef + ":" + corePlatformVersion + "@" + corePlatformDigest
 	expectedProductManifestImage = "prod.example.com/bar/release-manifest:0.0.1"
 )
 
@@ -190,6 +191,7 @@ func validateResolvedManifest(rm *resolver.ResolvedManifest, coreOnly bool) {
 
 		Expect(rm.ProductExtension.CorePlatform).ToNot(BeNil())
 		Expect(rm.ProductExtension.CorePlatform.Image).To(Equal("foo.example.com/bar/release-manifest:1.0"))
+		Expect(rm.ProductExtension.CorePlatform.Digest).To(Equal(corePlatformDigest))
 
 		Expect(rm.ProductExtension.Components.Systemd.Extensions).To(HaveLen(1))
 		Expect(rm.ProductExtension.Components.Systemd.Extensions[0].Name).To(Equal("foo-ext"))
@@ -0,0 +1,132 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+// authFile is the subset of a Docker or Podman auth file holding the credentials
+type authFile struct {
+	Auths map[string]authn.AuthConfig `json:"auths"`
+}
+
+// Keychain returns the keychain resolving the credentials of the registries: the ones configured
+// for the registry first, then the ones in the auth file and finally the ones in the default
+// Docker and Podman auth files
+func (c *Config) Keychain(fs vfs.FS) authn.Keychain {
+	if c == nil {
+		return authn.DefaultKeychain
+	}
+	return authn.NewMultiKeychain(&keychain{fs: fs, c: c}, authn.DefaultKeychain)
+}
+
+type keychain struct {
+	fs vfs.FS
+	c  *Config
+}
+
+func (k *keychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	repository := target.String()
+
+	if r := k.c.matchLocation(repository); r != nil && r.Credentials != nil {
+		auth, err := r.Credentials.resolve(k.fs)
+		if err != nil {
+			return nil, fmt.Errorf("resolving credentials of registry '%s': %w", r.Prefix, err)
+		}
+		return authn.FromConfig(auth), nil
+	}
+
+	if k.c.AuthFile == "" {
+		return authn.Anonymous, nil
+	}
+	data, err := k.fs.ReadFile(k.c.AuthFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading auth file: %w", err)
+	}
+	f := &authFile{}
+	if err = json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("parsing auth file '%s': %w", k.c.AuthFile, err)
+	}
+	type auth struct {
+		key    string
+		config authn.AuthConfig
+	}
+	var auths []auth
+	for key, config := range f.Auths {
+		auths = append(auths, auth{key: authFileKey(key), config: config})
+	}
+	if a := match(auths, repository, func(a auth) string { return a.key }); a != nil {
+		return authn.FromConfig(a.config), nil
+	}
+	return authn.Anonymous, nil
+}
+
+// resolve reads the secret of the credentials
+func (cr *Credentials) resolve(fs vfs.FS) (authn.AuthConfig, error) {
+	var secret string
+	var err error
+	switch {
+	case cr.PasswordEnv != "":
+		secret, err = readEnv(cr.PasswordEnv)
+	case cr.PasswordFile != "":
+		secret, err = readSecretFile(fs, cr.PasswordFile)
+	case cr.TokenEnv != "":
+		secret, err = readEnv(cr.TokenEnv)
+	case cr.TokenFile != "":
+		secret, err = readSecretFile(fs, cr.TokenFile)
+	}
+	if err != nil {
+		return authn.AuthConfig{}, err
+	}
+
+	if cr.TokenEnv != "" || cr.TokenFile != "" {
+		return authn.AuthConfig{Username: cr.Username, RegistryToken: secret}, nil
+	}
+	return authn.AuthConfig{Username: cr.Username, Password: secret}, nil
+}
+
+func readEnv(env string) (string, error) {
+	value := os.Getenv(env)
+	if value == "" {
+		return "", fmt.Errorf("environment variable %s is not set", env)
+	}
+	return value, nil
+}
+
+func readSecretFile(fs vfs.FS, path string) (string, error) {
+	data, err := fs.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading secret: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// authFileKey returns the repository prefix of the given auth file key, keys are either
+// registries, repositories or legacy URLs such as 'https://index.docker.io/v1/'
+func authFileKey(key string) string {
+	key = strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	return normalize(strings.TrimSuffix(strings.TrimSuffix(key, "/"), "/v1"))
+}
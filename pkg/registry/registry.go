@@ -0,0 +1,282 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+const dockerHubAlias = "docker.io"
+
+// Config defines how OCI registries are accessed: the credentials, mirrors and TLS settings
+// used to pull images. Images of registries not configured are pulled as referenced with
+// the credentials of the default Docker and Podman auth files.
+type Config struct {
+	// AuthFile is a Docker or Podman auth file with the credentials of the registries, it
+	// takes precedence over the default auth files
+	AuthFile string `yaml:"authFile,omitempty" json:"authFile,omitempty"`
+	// Registries configures the access to registries or repositories
+	Registries []Registry `yaml:"registries,omitempty" json:"registries,omitempty"`
+}
+
+// Registry configures the access to the images within a registry or repository
+type Registry struct {
+	// Prefix is a registry host or a repository prefix, such as 'registry.suse.com' or
+	// 'registry.suse.com/suse'
+	Prefix string `yaml:"prefix" json:"prefix"`
+	// Location replaces the prefix of the images pulled from the registry, e.g. to pull
+	// them from an internal registry. Defaults to the prefix itself.
+	Location string `yaml:"location,omitempty" json:"location,omitempty"`
+	// Mirrors replace the prefix of the images pulled from the registry in the given order,
+	// images are pulled from the location only if no mirror provides them
+	Mirrors []string `yaml:"mirrors,omitempty" json:"mirrors,omitempty"`
+	// Insecure allows plain HTTP and unverified TLS connections to the location
+	Insecure bool `yaml:"insecure,omitempty" json:"insecure,omitempty"`
+	// Credentials used to authenticate to the location
+	Credentials *Credentials `yaml:"credentials,omitempty" json:"credentials,omitempty"`
+	// TLS configures the connections to the host of the location
+	TLS *TLS `yaml:"tls,omitempty" json:"tls,omitempty"`
+}
+
+// Credentials of a registry, secrets are read from environment variables or files
+// when the registry is accessed and never stored in the configuration
+type Credentials struct {
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+	// PasswordEnv is the environment variable holding the password
+	PasswordEnv string `yaml:"passwordEnv,omitempty" json:"passwordEnv,omitempty"`
+	// PasswordFile is the file holding the password
+	PasswordFile string `yaml:"passwordFile,omitempty" json:"passwordFile,omitempty"`
+	// TokenEnv is the environment variable holding a bearer token sent instead of a password
+	TokenEnv string `yaml:"tokenEnv,omitempty" json:"tokenEnv,omitempty"`
+	// TokenFile is the file holding a bearer token sent instead of a password
+	TokenFile string `yaml:"tokenFile,omitempty" json:"tokenFile,omitempty"`
+}
+
+// TLS defines the certificates used to connect to a registry
+type TLS struct {
+	// CAFile is a PEM file of CA certificates trusted in addition to the system ones
+	CAFile string `yaml:"caFile,omitempty" json:"caFile,omitempty"`
+	// CertFile and KeyFile are the PEM encoded client certificate and key
+	CertFile string `yaml:"certFile,omitempty" json:"certFile,omitempty"`
+	KeyFile  string `yaml:"keyFile,omitempty" json:"keyFile,omitempty"`
+}
+
+// Load reads a registries configuration from the given YAML file
+func Load(fs vfs.FS, path string) (*Config, error) {
+	data, err := fs.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading registries configuration: %w", err)
+	}
+	c := &Config{}
+	err = yaml.Unmarshal(data, c)
+	if err != nil {
+		return nil, fmt.Errorf("parsing registries configuration: %w", err)
+	}
+	return c, c.Validate()
+}
+
+// Validate checks all registries define a valid prefix, locations and credentials
+func (c *Config) Validate() error {
+	if c == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, r := range c.Registries {
+		prefix := normalize(r.Prefix)
+		if prefix == "" {
+			return fmt.Errorf("registry prefix is required")
+		}
+		if seen[prefix] {
+			return fmt.Errorf("duplicated registry '%s'", r.Prefix)
+		}
+		seen[prefix] = true
+
+		for _, loc := range append([]string{r.Prefix, r.Location}, r.Mirrors...) {
+			if loc == "" {
+				continue
+			}
+			if _, err := registryHost(normalize(loc)); err != nil {
+				return fmt.Errorf("invalid location '%s' of registry '%s': %w", loc, r.Prefix, err)
+			}
+		}
+		if err := r.Credentials.validate(); err != nil {
+			return fmt.Errorf("invalid credentials of registry '%s': %w", r.Prefix, err)
+		}
+		if r.TLS != nil && (r.TLS.CertFile == "") != (r.TLS.KeyFile == "") {
+			return fmt.Errorf("invalid TLS configuration of registry '%s': both certificate and key are required", r.Prefix)
+		}
+	}
+	return nil
+}
+
+// Match returns the most specific registry the given repository belongs to, nil if none matches.
+// Repositories are fully qualified, e.g. 'registry.suse.com/suse/sl-micro'.
+func (c *Config) Match(repository string) *Registry {
+	if c == nil {
+		return nil
+	}
+	return match(c.Registries, repository, func(r Registry) string { return r.Prefix })
+}
+
+// Resolve returns the references the given image is pulled from in order of preference, the
+// mirrors first and then the location of the registry it belongs to. Images of registries not
+// configured are pulled from the given reference only. The given options are applied to parse
+// the resolved references.
+func (c *Config) Resolve(ref name.Reference, opts ...name.Option) ([]name.Reference, error) {
+	r := c.Match(ref.Context().Name())
+	if r == nil {
+		return []name.Reference{ref}, nil
+	}
+
+	var refs []name.Reference
+	for _, loc := range append(slices.Clone(r.Mirrors), r.location()) {
+		resolved, err := c.replacePrefix(ref, normalize(r.Prefix), normalize(loc), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("resolving '%s' at '%s': %w", ref.String(), loc, err)
+		}
+		refs = append(refs, resolved)
+	}
+	return refs, nil
+}
+
+// RemoteOptions returns the options to access the registries with
+func (c *Config) RemoteOptions(fs vfs.FS) ([]remote.Option, error) {
+	t, err := c.Transport(fs)
+	if err != nil {
+		return nil, err
+	}
+	return []remote.Option{remote.WithTransport(t), remote.WithAuthFromKeychain(c.Keychain(fs))}, nil
+}
+
+// NameOptions returns the options to parse references to the given repository with
+func (c *Config) NameOptions(repository string) []name.Option {
+	if r := c.matchLocation(repository); r != nil && r.Insecure {
+		return []name.Option{name.Insecure}
+	}
+	return nil
+}
+
+// replacePrefix returns the given reference with the prefix of its repository replaced by the
+// given location, keeping its tag and digest
+func (c *Config) replacePrefix(ref name.Reference, prefix, location string, opts ...name.Option) (name.Reference, error) {
+	repo := location + strings.TrimPrefix(ref.Context().Name(), prefix)
+	return name.ParseReference(repo+identifier(ref), append(c.NameOptions(repo), opts...)...)
+}
+
+// matchLocation returns the most specific registry whose location the given repository
+// belongs to, nil if none matches
+func (c *Config) matchLocation(repository string) *Registry {
+	if c == nil {
+		return nil
+	}
+	return match(c.Registries, repository, func(r Registry) string { return r.location() })
+}
+
+func (r Registry) location() string {
+	if r.Location != "" {
+		return r.Location
+	}
+	return r.Prefix
+}
+
+func (cr *Credentials) validate() error {
+	if cr == nil {
+		return nil
+	}
+	secrets := 0
+	for _, s := range []string{cr.PasswordEnv, cr.PasswordFile, cr.TokenEnv, cr.TokenFile} {
+		if s != "" {
+			secrets++
+		}
+	}
+	switch {
+	case secrets != 1:
+		return fmt.Errorf("exactly one of passwordEnv, passwordFile, tokenEnv or tokenFile is required")
+	case cr.Username == "" && (cr.PasswordEnv != "" || cr.PasswordFile != ""):
+		return fmt.Errorf("a username is required to authenticate with a password")
+	}
+	return nil
+}
+
+// registryHost checks the given registry host or repository prefix is valid and returns its
+// registry host
+func registryHost(location string) (string, error) {
+	host, path, _ := strings.Cut(location, "/")
+	reg, err := name.NewRegistry(host)
+	if err != nil {
+		return "", err
+	}
+	if path != "" {
+		if _, err = name.NewRepository(location); err != nil {
+			return "", err
+		}
+	}
+	return reg.RegistryStr(), nil
+}
+
+// identifier returns the tag and digest suffix of the given reference as it was written,
+// e.g. ':6.2@sha256:...'
+func identifier(ref name.Reference) string {
+	s := ref.String()
+	suffix := ""
+	if i := strings.Index(s, "@"); i >= 0 {
+		suffix = s[i:]
+		s = s[:i]
+	}
+	if i := strings.LastIndex(s, ":"); i > strings.LastIndex(s, "/") {
+		suffix = s[i:] + suffix
+	}
+	if suffix == "" {
+		suffix = ":" + ref.Identifier()
+	}
+	return suffix
+}
+
+// match returns the item with the most specific prefix the given repository belongs to
+func match[T any](items []T, repository string, prefix func(T) string) *T {
+	var found *T
+	foundLen := -1
+	for i := range items {
+		p := normalize(prefix(items[i]))
+		if p == "" || (repository != p && !strings.HasPrefix(repository, p+"/")) {
+			continue
+		}
+		if len(p) > foundLen {
+			found, foundLen = &items[i], len(p)
+		}
+	}
+	return found
+}
+
+// normalize removes trailing slashes and expands the Docker Hub registry alias
+// to the registry name used in parsed references
+func normalize(prefix string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == dockerHubAlias || strings.HasPrefix(prefix, dockerHubAlias+"/") {
+		prefix = name.DefaultRegistry + strings.TrimPrefix(prefix, dockerHubAlias)
+	}
+	return prefix
+}
@@ -0,0 +1,178 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry_test
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/registry"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+const registriesYAML = `authFile: /etc/elemental/auth.json
+registries:
+- prefix: registry.suse.com
+  mirrors:
+  - mirror.example.com/suse
+  credentials:
+    username: ci
+    passwordFile: /run/secrets/password
+- prefix: docker.io/library
+  location: registry.example.com/hub
+  credentials:
+    tokenEnv: HUB_TOKEN
+- prefix: registry.example.com:5000
+  insecure: true
+`
+
+func resolve(c *registry.Config, ref string) []string {
+	r, err := name.ParseReference(ref)
+	Expect(err).NotTo(HaveOccurred())
+	refs, err := c.Resolve(r)
+	Expect(err).NotTo(HaveOccurred())
+	var names []string
+	for _, r := range refs {
+		names = append(names, r.String())
+	}
+	return names
+}
+
+func authConfig(k authn.Keychain, repository string) *authn.AuthConfig {
+	repo, err := name.NewRepository(repository)
+	Expect(err).NotTo(HaveOccurred())
+	auth, err := k.Resolve(repo)
+	Expect(err).NotTo(HaveOccurred())
+	conf, err := auth.Authorization()
+	Expect(err).NotTo(HaveOccurred())
+	return conf
+}
+
+var _ = Describe("Config", Label("registry"), func() {
+	var fs vfs.FS
+	var c *registry.Config
+	BeforeEach(func() {
+		var err error
+		var cleanup func()
+		fs, cleanup, err = sysmock.TestFS(map[string]any{
+			"/etc/elemental/registries.yaml": registriesYAML,
+			"/etc/elemental/auth.json":       `{"auths": {"quay.io/org": {"auth": "dXNlcjpzZWNyZXQ="}}}`,
+			"/run/secrets/password":          "s3cr3t\n",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(cleanup)
+
+		c, err = registry.Load(fs, "/etc/elemental/registries.yaml")
+		Expect(err).NotTo(HaveOccurred())
+	})
+	It("resolves images to their mirrors and rewritten locations", func() {
+		digest := "sha256:" + strings.Repeat("a", 64)
+		Expect(resolve(c, "registry.suse.com/suse/sl-micro:6.2")).To(Equal([]string{
+			"mirror.example.com/suse/suse/sl-micro:6.2",
+			"registry.suse.com/suse/sl-micro:6.2",
+		}))
+		Expect(resolve(c, "registry.suse.com/suse/sl-micro:6.2@"+digest)).To(Equal([]string{
+			"mirror.example.com/suse/suse/sl-micro:6.2@" + digest,
+			"registry.suse.com/suse/sl-micro:6.2@" + digest,
+		}))
+		Expect(resolve(c, "alpine")).To(Equal([]string{"registry.example.com/hub/alpine:latest"}))
+		Expect(resolve(c, "quay.io/org/image@"+digest)).To(Equal([]string{"quay.io/org/image@" + digest}))
+		Expect(resolve(nil, "quay.io/org/image:1.0")).To(Equal([]string{"quay.io/org/image:1.0"}))
+	})
+	It("parses references to insecure registries as such", func() {
+		refs, err := c.Resolve(name.MustParseReference("registry.example.com:5000/os/image:1.0"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refs).To(HaveLen(1))
+		Expect(refs[0].Context().Scheme()).To(Equal("http"))
+	})
+	It("resolves the credentials of each registry", func() {
+		GinkgoT().Setenv("HUB_TOKEN", "token")
+		k := c.Keychain(fs)
+
+		conf := authConfig(k, "registry.suse.com/suse/sl-micro")
+		Expect(conf.Username).To(Equal("ci"))
+		Expect(conf.Password).To(Equal("s3cr3t"))
+
+		conf = authConfig(k, "registry.example.com/hub/alpine")
+		Expect(conf.RegistryToken).To(Equal("token"))
+
+		conf = authConfig(k, "quay.io/org/image")
+		Expect(conf.Username).To(Equal("user"))
+		Expect(conf.Password).To(Equal("secret"))
+
+		By("failing if the secret is not available")
+		GinkgoT().Setenv("HUB_TOKEN", "")
+		repo, err := name.NewRepository("registry.example.com/hub/alpine")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = k.Resolve(repo)
+		Expect(err).To(MatchError(ContainSubstring("environment variable HUB_TOKEN is not set")))
+	})
+	It("rejects invalid configurations", func() {
+		invalid := map[string]*registry.Config{
+			"registry prefix is required": {Registries: []registry.Registry{{Location: "registry.example.com"}}},
+			"duplicated registry":         {Registries: []registry.Registry{{Prefix: "docker.io"}, {Prefix: "index.docker.io/"}}},
+			"invalid location":            {Registries: []registry.Registry{{Prefix: "quay.io", Mirrors: []string{"Mirror/UPPER"}}}},
+			"exactly one of":              {Registries: []registry.Registry{{Prefix: "quay.io", Credentials: &registry.Credentials{Username: "user"}}}},
+			"a username is required": {Registries: []registry.Registry{{
+				Prefix: "quay.io", Credentials: &registry.Credentials{PasswordEnv: "PASSWORD"},
+			}}},
+			"both certificate and key": {Registries: []registry.Registry{{
+				Prefix: "quay.io", TLS: &registry.TLS{CertFile: "/etc/cert.pem"},
+			}}},
+		}
+		for msg, conf := range invalid {
+			Expect(conf.Validate()).To(MatchError(ContainSubstring(msg)))
+		}
+		Expect((*registry.Config)(nil).Validate()).To(Succeed())
+	})
+	It("connects to registries signed by a custom CA", func() {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		DeferCleanup(srv.Close)
+		ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+		Expect(fs.WriteFile("/etc/elemental/ca.pem", ca, vfs.FilePerm)).To(Succeed())
+		host := strings.TrimPrefix(srv.URL, "https://")
+
+		t, err := (*registry.Config)(nil).Transport(fs)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = (&http.Client{Transport: t}).Get(srv.URL + "/v2/")
+		Expect(err).To(HaveOccurred())
+
+		c = &registry.Config{Registries: []registry.Registry{{Prefix: host, TLS: &registry.TLS{CAFile: "/etc/elemental/ca.pem"}}}}
+		t, err = c.Transport(fs)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := (&http.Client{Transport: t}).Get(srv.URL + "/v2/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		By("failing on invalid CA files")
+		c.Registries[0].TLS.CAFile = "/run/secrets/password"
+		_, err = c.Transport(fs)
+		Expect(err).To(MatchError(ContainSubstring("no valid certificates found")))
+	})
+})
@@ -0,0 +1,112 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+// Transport returns the HTTP transport connecting to the registries with their TLS settings.
+// TLS settings apply to the whole host of the registry location.
+func (c *Config) Transport(fs vfs.FS) (http.RoundTripper, error) {
+	if c == nil {
+		return http.DefaultTransport, nil
+	}
+
+	t := &transport{hosts: map[string]http.RoundTripper{}, fallback: http.DefaultTransport}
+	for _, r := range c.Registries {
+		if r.TLS == nil && !r.Insecure {
+			continue
+		}
+		host, err := registryHost(normalize(r.location()))
+		if err != nil {
+			return nil, fmt.Errorf("invalid location of registry '%s': %w", r.Prefix, err)
+		}
+		if _, ok := t.hosts[host]; ok {
+			continue
+		}
+		tlsConfig, err := r.tlsConfig(fs)
+		if err != nil {
+			return nil, fmt.Errorf("configuring TLS of registry '%s': %w", r.Prefix, err)
+		}
+		hostTransport := http.DefaultTransport.(*http.Transport).Clone()
+		hostTransport.TLSClientConfig = tlsConfig
+		t.hosts[host] = hostTransport
+	}
+	if len(t.hosts) == 0 {
+		return http.DefaultTransport, nil
+	}
+	return t, nil
+}
+
+// transport routes the requests to each registry host through its own transport
+type transport struct {
+	hosts    map[string]http.RoundTripper
+	fallback http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt, ok := t.hosts[req.URL.Host]; ok {
+		return rt.RoundTrip(req)
+	}
+	return t.fallback.RoundTrip(req)
+}
+
+func (r Registry) tlsConfig(fs vfs.FS) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: r.Insecure} //nolint:gosec // insecure registries are explicitly configured
+	if r.TLS == nil {
+		return config, nil
+	}
+
+	if r.TLS.CAFile != "" {
+		pem, err := fs.ReadFile(r.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in '%s'", r.TLS.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if r.TLS.CertFile != "" {
+		certPEM, err := fs.ReadFile(r.TLS.CertFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading client certificate: %w", err)
+		}
+		keyPEM, err := fs.ReadFile(r.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading client key: %w", err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
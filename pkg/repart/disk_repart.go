@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -29,6 +30,7 @@ import (
 
 	"github.com/suse/elemental/v3/pkg/block/lsblk"
 	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/reproducible"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
 )
@@ -56,6 +58,11 @@ type Partition struct {
 	// Excludes is a list of paths to exclude from the host to be copied into the partition, uses
 	// ExcludeFiles syntax as defined in repart.d(5) man pages
 	Excludes []string
+	// Subvolumes is a list of btrfs subvolumes to create within the copied files, uses Subvolumes
+	// syntax as defined in repart.d(5) man pages
+	Subvolumes []string
+	// DefaultSubvolume is the btrfs subvolume set as the default one of the filesystem
+	DefaultSubvolume string
 }
 
 // PartitionAndFormatDevice creates a new empty partition table on target disk
@@ -84,8 +91,9 @@ func ReconcileDevicePartitions(s *sys.System, d *deployment.Disk) error {
 	return nil
 }
 
-// CreateDiskImage creates a disk image file with the given size and partitions
-func CreateDiskImage(s *sys.System, filename string, size deployment.MiB, partitions []Partition) error {
+// CreateDiskImage creates a disk image file with the given size and partitions, additional systemd-repart
+// flags can be given
+func CreateDiskImage(s *sys.System, filename string, size deployment.MiB, partitions []Partition, flags ...string) error {
 	s.Logger().Info("Partitioning image '%s'", filename)
 
 	var sizeFlag string
@@ -94,7 +102,7 @@ func CreateDiskImage(s *sys.System, filename string, size deployment.MiB, partit
 	} else {
 		sizeFlag = fmt.Sprintf("--size=%dM", size)
 	}
-	flags := []string{"--empty=create", sizeFlag}
+	flags = append([]string{"--empty=create", sizeFlag}, flags...)
 	return runSystemdRepart(s, filename, partitions, flags...)
 }
 
@@ -122,6 +130,13 @@ func CreatePartitionConf(s *sys.System, wr io.Writer, p Partition) error {
 		return fmt.Errorf("invalid partition role: %s", p.Partition.Role.String())
 	}
 
+	format := fileSystemToFormat(p.Partition.FileSystem)
+	if p.Partition.Mirror && p.Partition.Role == deployment.System {
+		// System mirrors are left unformatted, they are added as devices to the system btrfs filesystem
+		pType = genericType
+		format = ""
+	}
+
 	for _, copy := range p.CopyFiles {
 		path := strings.Split(copy, ":")[0]
 		if path != "" && !filepath.IsAbs(path) {
@@ -130,23 +145,29 @@ func CreatePartitionConf(s *sys.System, wr io.Writer, p Partition) error {
 	}
 
 	values := struct {
-		Type      string
-		Format    string
-		Size      deployment.MiB
-		Label     string
-		UUID      string
-		CopyFiles []string
-		Excludes  []string
-		ReadOnly  string
+		Type             string
+		Format           string
+		Size             deployment.MiB
+		Label            string
+		UUID             string
+		CopyFiles        []string
+		Excludes         []string
+		Subvolumes       []string
+		DefaultSubvolume string
+		ReadOnly         string
+		Encrypt          string
 	}{
-		Type:      pType,
-		Format:    fileSystemToFormat(p.Partition.FileSystem),
-		Size:      p.Partition.Size,
-		Label:     p.Partition.Label,
-		UUID:      p.Partition.UUID,
-		CopyFiles: p.CopyFiles,
-		Excludes:  p.Excludes,
-		ReadOnly:  readOnlyPart(p.Partition),
+		Type:             pType,
+		Format:           format,
+		Size:             p.Partition.Size,
+		Label:            p.Partition.Label,
+		UUID:             p.Partition.UUID,
+		CopyFiles:        p.CopyFiles,
+		Excludes:         p.Excludes,
+		Subvolumes:       p.Subvolumes,
+		DefaultSubvolume: p.DefaultSubvolume,
+		ReadOnly:         readOnlyPart(p.Partition),
+		Encrypt:          encryptMode(p.Partition),
 	}
 
 	partCfg := template.New("partition")
@@ -183,6 +204,11 @@ func repartDisk(s *sys.System, d *deployment.Disk, empty string) (err error) {
 	flags := []string{
 		fmt.Sprintf("--empty=%s", empty), fmt.Sprintf("--sector-size=%d", sSize),
 	}
+	encFlags, err := encryptionFlags(d.Partitions)
+	if err != nil {
+		return err
+	}
+	flags = append(flags, encFlags...)
 	return runSystemdRepart(s, d.Device, parts, flags...)
 }
 
@@ -225,7 +251,12 @@ func runSystemdRepart(s *sys.System, target string, parts []Partition, flags ...
 	}
 	args = append(args, target)
 
-	out, err := s.Runner().RunEnv("systemd-repart", []string{"PATH=/sbin:/usr/sbin:/usr/bin:/bin"}, args...)
+	env := []string{"PATH=/sbin:/usr/sbin:/usr/bin:/bin"}
+	if epoch := os.Getenv(reproducible.EpochEnv); epoch != "" {
+		// Forwarded so filesystem timestamps are reproducible
+		env = append(env, fmt.Sprintf("%s=%s", reproducible.EpochEnv, epoch))
+	}
+	out, err := s.Runner().RunEnv("systemd-repart", env, args...)
 	if err != nil {
 		return fmt.Errorf("failed partitioning disk '%s' with systemd-repart: %w", target, err)
 	}
@@ -281,6 +312,55 @@ func fileSystemToFormat(f deployment.FileSystem) string {
 	}
 }
 
+// encryptMode returns the systemd-repart Encrypt value for the given partition, empty
+// for plain partitions
+func encryptMode(part *deployment.Partition) string {
+	enc := part.Encryption
+	switch {
+	case enc == nil:
+		return ""
+	case enc.KeyFile != "" && enc.TPM2 != nil:
+		return "key-file+tpm2"
+	case enc.TPM2 != nil:
+		return "tpm2"
+	default:
+		return "key-file"
+	}
+}
+
+// encryptionFlags returns the systemd-repart flags setting the key sources of the encrypted partitions.
+// These are global flags, hence all encrypted partitions in a disk must share the same key sources.
+func encryptionFlags(parts deployment.Partitions) ([]string, error) {
+	var keyFile, pcrs string
+	for _, part := range parts {
+		enc := part.Encryption
+		if enc == nil {
+			continue
+		}
+		if enc.KeyFile != "" {
+			if keyFile != "" && keyFile != enc.KeyFile {
+				return nil, fmt.Errorf("encrypted partitions in a disk must share the same key file")
+			}
+			keyFile = enc.KeyFile
+		}
+		if enc.TPM2 != nil {
+			if pcrs != "" && pcrs != enc.TPM2.GetPCRs() {
+				return nil, fmt.Errorf("encrypted partitions in a disk must share the same TPM2 PCRs")
+			}
+			pcrs = enc.TPM2.GetPCRs()
+		}
+	}
+
+	var flags []string
+	if keyFile != "" {
+		flags = append(flags, fmt.Sprintf("--key-file=%s", keyFile))
+	}
+	if pcrs != "" {
+		flags = append(flags, "--tpm2-device=auto", fmt.Sprintf("--tpm2-pcrs=%s", pcrs))
+	}
+	return flags, nil
+}
+
 func readOnlyPart(part *deployment.Partition) string {
 	for _, opt := range part.MountOpts {
 		if strings.HasPrefix(opt, "ro") {
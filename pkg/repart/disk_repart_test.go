@@ -19,6 +19,7 @@ package repart_test
 
 import (
 	"bytes"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/suse/elemental/v3/pkg/deployment"
 	"github.com/suse/elemental/v3/pkg/log"
 	"github.com/suse/elemental/v3/pkg/repart"
+	"github.com/suse/elemental/v3/pkg/reproducible"
 	"github.com/suse/elemental/v3/pkg/sys"
 	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
@@ -101,6 +103,7 @@ var _ = Describe("Systemd-repart tests", Label("systemd-repart"), func() {
 		Expect(buffer.String()).ToNot(ContainSubstring("Format"))
 		Expect(buffer.String()).ToNot(ContainSubstring("CopyFiles"))
 		Expect(buffer.String()).ToNot(ContainSubstring("ExcludeFiles"))
+		Expect(buffer.String()).ToNot(ContainSubstring("Subvolume"))
 		Expect(buffer.String()).ToNot(ContainSubstring("SizeMinBytes"))
 		Expect(buffer.String()).ToNot(ContainSubstring("UUID"))
 		Expect(buffer.String()).ToNot(ContainSubstring("ReadOnly"))
@@ -116,6 +119,10 @@ var _ = Describe("Systemd-repart tests", Label("systemd-repart"), func() {
 				Partition: part,
 				CopyFiles: []string{"/some/root:/", "/some/other/root"},
 				Excludes:  []string{"/some/root/excludeme"},
+				Subvolumes: []string{
+					"/@", "/@/.snapshots", "/@/.snapshots/1/snapshot:ro",
+				},
+				DefaultSubvolume: "/@/.snapshots/1/snapshot",
 			},
 		)).To(Succeed())
 
@@ -126,10 +133,35 @@ var _ = Describe("Systemd-repart tests", Label("systemd-repart"), func() {
 		Expect(buffer.String()).To(ContainSubstring("CopyFiles=/some/root:/"))
 		Expect(buffer.String()).To(ContainSubstring("CopyFiles=/some/other/root"))
 		Expect(buffer.String()).To(ContainSubstring("ExcludeFiles=/some/root/excludeme"))
+		Expect(buffer.String()).To(ContainSubstring("Subvolumes=/@\n"))
+		Expect(buffer.String()).To(ContainSubstring("Subvolumes=/@/.snapshots/1/snapshot:ro"))
+		Expect(buffer.String()).To(ContainSubstring("DefaultSubvolume=/@/.snapshots/1/snapshot"))
 		Expect(buffer.String()).To(ContainSubstring("ReadOnly=on"))
 		Expect(buffer.String()).ToNot(ContainSubstring("UUID"))
 	})
 
+	It("creates an encrypted partition configuration", func() {
+		var buffer bytes.Buffer
+		part := &deployment.Partition{
+			Label:      "SYSTEM",
+			Role:       deployment.System,
+			Encryption: &deployment.Encryption{KeyFile: "/etc/keys/luks.key"},
+		}
+
+		Expect(repart.CreatePartitionConf(s, &buffer, repart.Partition{Partition: part})).To(Succeed())
+		Expect(buffer.String()).To(ContainSubstring("Encrypt=key-file\n"))
+
+		buffer.Reset()
+		part.Encryption.TPM2 = &deployment.TPM2{}
+		Expect(repart.CreatePartitionConf(s, &buffer, repart.Partition{Partition: part})).To(Succeed())
+		Expect(buffer.String()).To(ContainSubstring("Encrypt=key-file+tpm2"))
+
+		buffer.Reset()
+		part.Encryption.KeyFile = ""
+		Expect(repart.CreatePartitionConf(s, &buffer, repart.Partition{Partition: part})).To(Succeed())
+		Expect(buffer.String()).To(ContainSubstring("Encrypt=tpm2"))
+	})
+
 	It("creates a partition configuration file", func() {
 		part := &deployment.Partition{
 			Label: "SYSTEM",
@@ -173,6 +205,27 @@ var _ = Describe("Systemd-repart tests", Label("systemd-repart"), func() {
 		}}))
 	})
 
+	It("forwards SOURCE_DATE_EPOCH to systemd-repart", func() {
+		Expect(os.Setenv(reproducible.EpochEnv, "1700000000")).To(Succeed())
+		DeferCleanup(os.Unsetenv, reproducible.EpochEnv)
+
+		parts := []repart.Partition{{
+			Partition: &deployment.Partition{Label: "EFI", Role: deployment.EFI},
+			CopyFiles: []string{"/efi/path/in/host:/"},
+		}, {
+			Partition: &deployment.Partition{Label: "SYSTEM", Role: deployment.System},
+			CopyFiles: []string{"/system/path/in/host:/"},
+		}}
+		Expect(repart.CreateDiskImage(s, filepath.Join(tempDir, "image.raw"), 0, parts, "--seed=5a3b8f0e-6c1d-4f2a-9e7b-3d5c8a1f0b64")).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{{
+			"systemd-repart", "--json=pretty", "--definitions=/tmp/elemental-repart.d", "--dry-run=no",
+			"--empty=create", "--size=auto", "--seed=5a3b8f0e-6c1d-4f2a-9e7b-3d5c8a1f0b64",
+		}})).To(Succeed())
+		Expect(runner.EnvsMatch([][]string{{
+			"systemd-repart", "PATH=/sbin:/usr/sbin:/usr/bin:/bin", "SOURCE_DATE_EPOCH=1700000000",
+		}})).To(Succeed())
+	})
+
 	It("reparts a disk with force flag and feeds partition UUIDs", func() {
 		d := deployment.DefaultDeployment()
 		Expect(len(d.Disks)).To(Equal(1))
@@ -187,6 +240,34 @@ var _ = Describe("Systemd-repart tests", Label("systemd-repart"), func() {
 		}}))
 	})
 
+	It("reparts a disk with encrypted partitions", func() {
+		d := deployment.DefaultDeployment()
+		d.Disks[0].Device = "/dev/device"
+		d.Disks[0].Partitions[1].Encryption = &deployment.Encryption{
+			KeyFile: "/etc/keys/luks.key",
+			TPM2:    &deployment.TPM2{PCRs: []int{7, 11}},
+		}
+		Expect(repart.PartitionAndFormatDevice(s, d.Disks[0])).To(Succeed())
+		Expect(runner.MatchMilestones([][]string{{
+			"systemd-repart", "--json=pretty", "--definitions=/tmp/elemental-repart.d",
+			"--dry-run=no", "--empty=force", "--sector-size=512", "--key-file=/etc/keys/luks.key",
+			"--tpm2-device=auto", "--tpm2-pcrs=7+11", "/dev/device",
+		}})).To(Succeed())
+	})
+
+	It("fails to repart a disk with encrypted partitions using different key files", func() {
+		d := deployment.DefaultDeployment()
+		d.Disks[0].Device = "/dev/device"
+		d.Disks[0].Partitions = append(d.Disks[0].Partitions, &deployment.Partition{
+			Role:       deployment.Generic,
+			Encryption: &deployment.Encryption{KeyFile: "/etc/keys/data.key"},
+		})
+		d.Disks[0].Partitions[1].Encryption = &deployment.Encryption{KeyFile: "/etc/keys/luks.key"}
+		Expect(repart.PartitionAndFormatDevice(s, d.Disks[0])).To(
+			MatchError(ContainSubstring("must share the same key file")),
+		)
+	})
+
 	It("fails if systemd-repart reports partitions not matching the deployment", func() {
 		d := deployment.DefaultDeployment()
 		deployment.WithConfigPartition(0)(d)
@@ -19,6 +19,15 @@ CopyFiles={{ $cpy }}
 {{- range $excl := .Excludes }}
 ExcludeFiles={{ $excl }}
 {{- end }}
+{{- range $subvol := .Subvolumes }}
+Subvolumes={{ $subvol }}
+{{- end }}
+{{- if .DefaultSubvolume }}
+DefaultSubvolume={{ .DefaultSubvolume }}
+{{- end }}
+{{- if .Encrypt }}
+Encrypt={{ .Encrypt }}
+{{- end }}
 {{- if .ReadOnly }}
 ReadOnly={{ .ReadOnly }}
 {{- end }}
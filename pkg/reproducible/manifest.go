@@ -0,0 +1,76 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reproducible
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+	"github.com/suse/elemental/v3/pkg/version"
+)
+
+// ManifestSuffix is the suffix appended to the image file name to write the build manifest
+const ManifestSuffix = ".manifest.json"
+
+// Manifest records the settings and inputs of a reproducible build together with the digest
+// of the resulting image, so a build can be repeated and verified
+type Manifest struct {
+	Version         string     `json:"version"`
+	SourceDateEpoch int64      `json:"sourceDateEpoch"`
+	Seed            string     `json:"seed"`
+	Inputs          []Artifact `json:"inputs"`
+	Output          Artifact   `json:"output"`
+}
+
+// Artifact is a build input or output and its digest
+type Artifact struct {
+	Name      string `json:"name"`
+	Reference string `json:"reference"`
+	Digest    string `json:"digest,omitempty"`
+}
+
+// NewManifest returns a build manifest for the given build and inputs
+func NewManifest(b *Build, inputs ...Artifact) *Manifest {
+	return &Manifest{
+		Version:         version.String(),
+		SourceDateEpoch: b.Epoch.Unix(),
+		Seed:            b.Seed.String(),
+		Inputs:          inputs,
+	}
+}
+
+// Write digests the given image file and writes the manifest next to it
+func (m *Manifest) Write(fs vfs.FS, image string) error {
+	digest, err := vfs.FileDigest(fs, image)
+	if err != nil {
+		return err
+	}
+	m.Output = Artifact{Name: "image", Reference: image, Digest: digest}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling build manifest: %w", err)
+	}
+	manifest := image + ManifestSuffix
+	err = fs.WriteFile(manifest, append(data, '\n'), vfs.FilePerm)
+	if err != nil {
+		return fmt.Errorf("writing build manifest '%s': %w", manifest, err)
+	}
+	return nil
+}
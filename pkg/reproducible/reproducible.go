@@ -0,0 +1,173 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reproducible
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+// EpochEnv is the environment variable defining the timestamp of reproducible builds,
+// see https://reproducible-builds.org/specs/source-date-epoch/
+const EpochEnv = "SOURCE_DATE_EPOCH"
+
+// seedNamespace is the UUID namespace reproducible build seeds are derived in
+var seedNamespace = uuid.MustParse("5a3b8f0e-6c1d-4f2a-9e7b-3d5c8a1f0b64")
+
+// Build defines the settings of a reproducible build. Timestamps are set to the epoch and
+// identifiers such as UUIDs are derived from the seed, hence builds of the same inputs with
+// the same epoch produce identical images.
+type Build struct {
+	Epoch time.Time
+	Seed  uuid.UUID
+}
+
+// New returns the settings of a reproducible build of the given inputs. The epoch is read from
+// SOURCE_DATE_EPOCH and defaults to the Unix epoch, the seed is derived from the inputs.
+func New(inputs ...string) (*Build, error) {
+	epoch, ok, err := Epoch()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		epoch = time.Unix(0, 0).UTC()
+	}
+	return &Build{
+		Epoch: epoch,
+		Seed:  uuid.NewSHA1(seedNamespace, []byte(strings.Join(inputs, "\n"))),
+	}, nil
+}
+
+// Epoch returns the time defined in SOURCE_DATE_EPOCH, false is returned if it is not set
+func Epoch() (time.Time, bool, error) {
+	value := os.Getenv(EpochEnv)
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	secs, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("parsing %s value '%s': %w", EpochEnv, value, err)
+	}
+	return time.Unix(secs, 0).UTC(), true, nil
+}
+
+// Now returns the time defined in SOURCE_DATE_EPOCH if set, the current time otherwise
+func Now() time.Time {
+	if epoch, ok, err := Epoch(); ok && err == nil {
+		return epoch
+	}
+	return time.Now().UTC()
+}
+
+// Export sets SOURCE_DATE_EPOCH to the build epoch, so the build tools run afterwards and
+// Now use it
+func (b Build) Export() error {
+	err := os.Setenv(EpochEnv, b.Timestamp())
+	if err != nil {
+		return fmt.Errorf("setting %s: %w", EpochEnv, err)
+	}
+	return nil
+}
+
+// Timestamp returns the epoch as seconds since the Unix epoch
+func (b Build) Timestamp() string {
+	return strconv.FormatInt(b.Epoch.Unix(), 10)
+}
+
+// UUID returns a UUID derived from the build seed for the given name
+func (b Build) UUID(name string) string {
+	return uuid.NewSHA1(b.Seed, []byte(name)).String()
+}
+
+// ID returns a short hexadecimal identifier derived from the build seed for the given name
+func (b Build) ID(name string) string {
+	sum := sha256.Sum256([]byte(b.Seed.String() + name))
+	return hex.EncodeToString(sum[:4])
+}
+
+// ClampMTimes sets the modification time of all files within the given root which are newer
+// than the epoch to the epoch, symbolic links are not followed
+func (b Build) ClampMTimes(ctx context.Context, s *sys.System, root string) error {
+	stamp := "@" + b.Timestamp()
+	out, err := s.Runner().RunContext(
+		ctx, "find", root, "-newermt", stamp, "-exec", "touch", "--no-dereference", "--date="+stamp, "{}", "+",
+	)
+	if err != nil {
+		s.Logger().Error("Error clamping modification times, stdout and stderr output: %s", out)
+		return fmt.Errorf("clamping modification times of '%s': %w", root, err)
+	}
+	return nil
+}
+
+// DirDigest returns the sha256 digest of the given directory tree. Relative paths, file modes,
+// symbolic link targets and file contents are digested, modification times are not.
+func DirDigest(vfsys vfs.FS, root string) (string, error) {
+	h := sha256.New()
+	err := vfs.WalkDirFs(vfsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(h, "%s\x00%s\x00", rel, info.Mode().String())
+
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := vfs.ReadLink(vfsys, path)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(h, "%s\x00", target)
+		case info.Mode().IsRegular():
+			f, err := vfsys.Open(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(h, f)
+			_ = f.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("computing digest of '%s': %w", root, err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reproducible_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/reproducible"
+	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+func TestReproducibleSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Reproducible builds test suite")
+}
+
+var _ = Describe("Reproducible builds", Label("reproducible"), func() {
+	var s *sys.System
+	var fs vfs.FS
+	var runner *sysmock.Runner
+	var cleanup func()
+	BeforeEach(func() {
+		var err error
+		runner = sysmock.NewRunner()
+		fs, cleanup, err = sysmock.TestFS(map[string]any{
+			"/overlays/etc/hostname": "host",
+			"/overlays/etc/motd":     "welcome",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		s, err = sys.NewSystem(
+			sys.WithFS(fs), sys.WithRunner(runner),
+			sys.WithLogger(log.New(log.WithDiscardAll())),
+		)
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		cleanup()
+	})
+	It("derives identifiers from the inputs", func() {
+		b1, err := reproducible.New("os:1.0", "manifest:1.0")
+		Expect(err).NotTo(HaveOccurred())
+		b2, err := reproducible.New("os:1.0", "manifest:1.0")
+		Expect(err).NotTo(HaveOccurred())
+		b3, err := reproducible.New("os:1.1", "manifest:1.0")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(b1.Epoch).To(Equal(time.Unix(0, 0).UTC()))
+		Expect(b1.Seed).To(Equal(b2.Seed))
+		Expect(b1.Seed).NotTo(Equal(b3.Seed))
+		Expect(b1.UUID("EFI")).To(Equal(b2.UUID("EFI")))
+		Expect(b1.UUID("EFI")).NotTo(Equal(b1.UUID("SYSTEM")))
+		Expect(b1.ID("live")).To(HaveLen(8))
+		Expect(b1.ID("live")).To(Equal(b2.ID("live")))
+	})
+	It("honours SOURCE_DATE_EPOCH", func() {
+		Expect(os.Setenv(reproducible.EpochEnv, "1700000000")).To(Succeed())
+		DeferCleanup(os.Unsetenv, reproducible.EpochEnv)
+
+		b, err := reproducible.New("os:1.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b.Timestamp()).To(Equal("1700000000"))
+		Expect(reproducible.Now()).To(Equal(time.Unix(1700000000, 0).UTC()))
+
+		Expect(os.Setenv(reproducible.EpochEnv, "yesterday")).To(Succeed())
+		_, err = reproducible.New("os:1.0")
+		Expect(err).To(MatchError(ContainSubstring("parsing SOURCE_DATE_EPOCH")))
+	})
+	It("clamps modification times to the epoch", func() {
+		b := reproducible.Build{Epoch: time.Unix(1700000000, 0)}
+		Expect(b.ClampMTimes(context.Background(), s, "/overlays")).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{{
+			"find", "/overlays", "-newermt", "@1700000000", "-exec",
+			"touch", "--no-dereference", "--date=@1700000000", "{}", "+",
+		}})).To(Succeed())
+	})
+	It("computes directory digests ignoring modification times", func() {
+		digest, err := reproducible.DirDigest(fs, "/overlays")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(digest).To(HavePrefix("sha256:"))
+
+		motd, err := fs.RawPath("/overlays/etc/motd")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chtimes(motd, time.Unix(0, 0), time.Unix(0, 0))).To(Succeed())
+		Expect(reproducible.DirDigest(fs, "/overlays")).To(Equal(digest))
+
+		Expect(fs.WriteFile("/overlays/etc/motd", []byte("goodbye"), vfs.FilePerm)).To(Succeed())
+		Expect(reproducible.DirDigest(fs, "/overlays")).NotTo(Equal(digest))
+	})
+	It("writes a build manifest next to the image", func() {
+		Expect(fs.WriteFile("/image.raw", []byte("data"), vfs.FilePerm)).To(Succeed())
+		b, err := reproducible.New("os:1.0")
+		Expect(err).NotTo(HaveOccurred())
+
+		m := reproducible.NewManifest(b, reproducible.Artifact{Name: "os", Reference: "os:1.0", Digest: "sha256:abc"})
+		Expect(m.Write(fs, "/image.raw")).To(Succeed())
+
+		data, err := fs.ReadFile("/image.raw" + reproducible.ManifestSuffix)
+		Expect(err).NotTo(HaveOccurred())
+		loaded := &reproducible.Manifest{}
+		Expect(json.Unmarshal(data, loaded)).To(Succeed())
+		Expect(loaded.Seed).To(Equal(b.Seed.String()))
+		Expect(loaded.Inputs).To(HaveLen(1))
+		Expect(loaded.Output.Digest).To(Equal("sha256:3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7"))
+	})
+})
@@ -0,0 +1,186 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollback
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/suse/elemental/v3/pkg/bootloader"
+	"github.com/suse/elemental/v3/pkg/cleanstack"
+	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/snapper"
+	"github.com/suse/elemental/v3/pkg/sys"
+)
+
+const (
+	rootConfig     = "root"
+	updateProgress = "update-in-progress"
+	stagedKey      = "staged"
+)
+
+type Option func(*Rollbacker)
+
+type Rollbacker struct {
+	s    *sys.System
+	b    bootloader.Bootloader
+	snap *snapper.Snapper
+	root string
+}
+
+func WithBootloader(b bootloader.Bootloader) Option {
+	return func(r *Rollbacker) {
+		r.b = b
+	}
+}
+
+// WithRoot sets the root path of the system to rollback, defaults to '/'
+func WithRoot(root string) Option {
+	return func(r *Rollbacker) {
+		r.root = root
+	}
+}
+
+func New(s *sys.System, opts ...Option) *Rollbacker {
+	r := &Rollbacker{
+		s:    s,
+		snap: snapper.New(s),
+		root: "/",
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	if r.b == nil {
+		r.b = bootloader.NewNone(s)
+	}
+	return r
+}
+
+// Rollback sets the given snapshot ID as the default one and points the default boot entry to it.
+// If the given ID is 0 the snapshot preceding the current default one is used. Returns the ID of the
+// new default snapshot.
+func (r Rollbacker) Rollback(d *deployment.Deployment, id int) (target int, err error) {
+	cleanup := cleanstack.NewCleanStack()
+	defer func() { err = cleanup.Cleanup(err) }()
+
+	if d.Snapshotter != nil && d.Snapshotter.Name != "snapper" {
+		return 0, fmt.Errorf("rollback is not supported for snapshotter '%s'", d.Snapshotter.Name)
+	}
+
+	esp := d.GetEfiPartition()
+	if esp == nil {
+		return 0, fmt.Errorf("no %s partition defined in deployment", deployment.EfiLabel)
+	}
+
+	snaps, err := r.snap.ListSnapshots(r.root, rootConfig)
+	if err != nil {
+		return 0, fmt.Errorf("listing snapshots: %w", err)
+	}
+
+	current := snaps.GetDefault()
+	target, err = rollbackTarget(snaps, current, id)
+	if err != nil {
+		return 0, err
+	}
+
+	r.s.Logger().Info("Rolling back from snapshot %d to snapshot %d", current, target)
+
+	err = r.recordRollback(cleanup, current, target)
+	if err != nil {
+		return 0, fmt.Errorf("recording rollback in deployment file: %w", err)
+	}
+
+	err = r.snap.SetDefault(r.root, target, nil)
+	if err != nil {
+		return 0, fmt.Errorf("setting default snapshot: %w", err)
+	}
+	cleanup.PushErrorOnly(func() error { return r.snap.SetDefault(r.root, current, nil) })
+
+	err = r.b.SetDefault(filepath.Join(r.root, esp.MountPoint), strconv.Itoa(target))
+	if err != nil {
+		return 0, fmt.Errorf("setting default boot entry: %w", err)
+	}
+
+	return target, nil
+}
+
+// recordRollback writes the rollback details into the deployment file of the target snapshot. Committed
+// snapshots are read-only, hence the target snapshot is made writable while recording the rollback. The
+// previous deployment file is restored if the rollback fails later on.
+func (r Rollbacker) recordRollback(cleanup *cleanstack.CleanStack, from, target int) error {
+	snapshotRoot := snapper.SnapshotPath(r.root, target)
+	d, err := deployment.Parse(r.s, snapshotRoot)
+	if err != nil {
+		return fmt.Errorf("parsing deployment of snapshot %d: %w", target, err)
+	} else if d == nil {
+		return fmt.Errorf("deployment of snapshot %d not found", target)
+	}
+
+	err = r.snap.SetPermissions(r.root, target, true)
+	if err != nil {
+		return fmt.Errorf("setting snapshot %d writable: %w", target, err)
+	}
+	cleanup.Push(func() error { return r.snap.SetPermissions(r.root, target, false) })
+
+	previous := d.Rollback
+	d.Rollback = &deployment.RollbackRecord{
+		FromSnapshot: from,
+		Date:         time.Now().UTC(),
+	}
+	err = d.WriteDeploymentFile(r.s, snapshotRoot)
+	if err != nil {
+		return err
+	}
+	cleanup.PushErrorOnly(func() error {
+		d.Rollback = previous
+		return d.WriteDeploymentFile(r.s, snapshotRoot)
+	})
+	return nil
+}
+
+// rollbackTarget validates the given snapshot ID is a suitable rollback target. If the
+// given ID is 0 it returns the most recent complete snapshot older than the current one.
+// Incomplete and staged snapshots are not considered.
+func rollbackTarget(snaps snapper.Snapshots, current, id int) (int, error) {
+	if id == current && id != 0 {
+		return 0, fmt.Errorf("snapshot %d is already the default snapshot", id)
+	}
+
+	target := 0
+	for _, snap := range snaps {
+		if snap.UserData[updateProgress] == "yes" || snap.UserData[stagedKey] == "yes" {
+			continue
+		}
+		if id != 0 && snap.Number == id {
+			return id, nil
+		}
+		if id == 0 && snap.Number < current && snap.Number > target {
+			target = snap.Number
+		}
+	}
+
+	if id != 0 {
+		return 0, fmt.Errorf("snapshot %d not found", id)
+	}
+	if target == 0 {
+		return 0, fmt.Errorf("no previous snapshot found to rollback to")
+	}
+	return target, nil
+}
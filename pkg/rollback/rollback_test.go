@@ -0,0 +1,182 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollback_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/bootloader"
+	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/rollback"
+	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+func TestRollbackSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Rollback test suite")
+}
+
+const snapperList = `{
+  "root": [
+    {"number": 0, "default": false, "active": false, "userdata": null},
+    {"number": 1, "default": false, "active": false, "userdata": null},
+    {"number": 2, "default": false, "active": false, "userdata": {"update-in-progress": "yes"}},
+    {"number": 3, "default": false, "active": false, "userdata": null},
+    {"number": 4, "default": true, "active": true, "userdata": null}
+  ]
+}`
+
+var _ = Describe("Rollback", Label("rollback"), func() {
+	var runner *sysmock.Runner
+	var fs vfs.FS
+	var cleanup func()
+	var s *sys.System
+	var d *deployment.Deployment
+	var r *rollback.Rollbacker
+	var snapperCalls [][]string
+
+	BeforeEach(func() {
+		var err error
+		snapperCalls = [][]string{}
+		runner = sysmock.NewRunner()
+		fs, cleanup, err = sysmock.TestFS(map[string]any{
+			"/boot/loader/entries/1": "display_name=OS (1)\nlinux=/os/6.14/vmlinuz\ninitrd=/os/6.14/initrd\ncmdline=snapshot1",
+			"/boot/loader/entries/3": "display_name=OS (3)\nlinux=/os/6.15/vmlinuz\ninitrd=/os/6.15/initrd\ncmdline=snapshot3",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		s, err = sys.NewSystem(
+			sys.WithRunner(runner), sys.WithFS(fs),
+			sys.WithLogger(log.New(log.WithDiscardAll())),
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		runner.SideEffect = func(command string, args ...string) ([]byte, error) {
+			switch command {
+			case "snapper":
+				snapperCalls = append(snapperCalls, args)
+				if strings.Contains(strings.Join(args, " "), "list") {
+					return []byte(snapperList), nil
+				}
+				return []byte{}, nil
+			case "grub2-editenv":
+				switch args[1] {
+				case "set":
+					return nil, fs.WriteFile(args[0], []byte(strings.Join(args[2:], "\n")), vfs.FilePerm)
+				case "list":
+					return fs.ReadFile(args[0])
+				}
+			}
+			return []byte{}, nil
+		}
+
+		d = deployment.DefaultDeployment()
+		d.SourceOS = deployment.NewOCISrc("registry.org/my/os:v2")
+		for _, id := range []int{1, 3} {
+			root := filepath.Join("/.snapshots", fmt.Sprint(id), "snapshot")
+			Expect(d.WriteDeploymentFile(s, root)).To(Succeed())
+		}
+
+		r = rollback.New(s, rollback.WithBootloader(bootloader.NewGrub(s)))
+	})
+	AfterEach(func() {
+		cleanup()
+	})
+	It("rolls back to the previous complete snapshot", func() {
+		id, err := r.Rollback(d, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal(3))
+		Expect(snapperCalls[1:]).To(Equal([][]string{
+			{"--no-dbus", "modify", "--read-write", "3"},
+			{"--no-dbus", "modify", "--default", "3"},
+			{"--no-dbus", "modify", "--read-only", "3"},
+		}))
+
+		activeEntry, err := fs.ReadFile("/boot/loader/entries/active")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Split(string(activeEntry), "\n")).To(ContainElements("display_name=OS", "cmdline=snapshot3"))
+
+		dep, err := deployment.Parse(s, "/.snapshots/3/snapshot")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Rollback).NotTo(BeNil())
+		Expect(dep.Rollback.FromSnapshot).To(Equal(4))
+	})
+	It("rolls back to the given snapshot", func() {
+		id, err := r.Rollback(d, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal(1))
+
+		activeEntry, err := fs.ReadFile("/boot/loader/entries/active")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Split(string(activeEntry), "\n")).To(ContainElement("cmdline=snapshot1"))
+	})
+	It("fails to rollback to the current default snapshot", func() {
+		_, err := r.Rollback(d, 4)
+		Expect(err).To(MatchError("snapshot 4 is already the default snapshot"))
+	})
+	It("fails to rollback to an incomplete snapshot", func() {
+		_, err := r.Rollback(d, 2)
+		Expect(err).To(MatchError("snapshot 2 not found"))
+	})
+	It("fails to rollback with a non snapper snapshotter", func() {
+		d.Snapshotter.Name = "overwrite"
+		_, err := r.Rollback(d, 0)
+		Expect(err).To(MatchError("rollback is not supported for snapshotter 'overwrite'"))
+	})
+	It("restores the default snapshot if the boot entry can't be updated", func() {
+		Expect(fs.Remove("/boot/loader/entries/3")).To(Succeed())
+		_, err := r.Rollback(d, 3)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("boot entry '3' not found"))
+		Expect(snapperCalls[len(snapperCalls)-2:]).To(Equal([][]string{
+			{"--no-dbus", "modify", "--default", "4"},
+			{"--no-dbus", "modify", "--read-only", "3"},
+		}))
+	})
+	It("reverts the deployment file and keeps the snapshot read-only if the default snapshot can't be set", func() {
+		runner.SideEffect = func(command string, args ...string) ([]byte, error) {
+			snapperCalls = append(snapperCalls, args)
+			if strings.Contains(strings.Join(args, " "), "list") {
+				return []byte(snapperList), nil
+			}
+			if strings.Contains(strings.Join(args, " "), "--default") {
+				return nil, fmt.Errorf("snapper failed")
+			}
+			return []byte{}, nil
+		}
+		_, err := r.Rollback(d, 3)
+		Expect(err).To(MatchError(ContainSubstring("setting default snapshot: snapper failed")))
+		Expect(snapperCalls[1:]).To(Equal([][]string{
+			{"--no-dbus", "modify", "--read-write", "3"},
+			{"--no-dbus", "modify", "--default", "3"},
+			{"--no-dbus", "modify", "--read-only", "3"},
+		}))
+
+		dep, err := deployment.Parse(s, "/.snapshots/3/snapshot")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Rollback).To(BeNil())
+	})
+})
@@ -25,6 +25,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/suse/elemental/v3/pkg/events"
 	"github.com/suse/elemental/v3/pkg/log"
 	"github.com/suse/elemental/v3/pkg/sys"
 )
@@ -116,7 +117,7 @@ func (r Rsync) rsyncWrapper(source string, target string, flags []string) error
 	args = append(args, source, target)
 
 	if r.ctx != nil {
-		err = r.s.Runner().RunContextParseOutput(r.ctx, parseProgress(log), func(msg string) {
+		err = r.s.Runner().RunContextParseOutput(r.ctx, parseProgress(log, r.s.Events()), func(msg string) {
 			log.Debug("rsync stderr: %s", msg)
 		}, "rsync", args...)
 	} else {
@@ -152,7 +153,7 @@ func OverlayTreeSyncFlags() []string {
 	}
 }
 
-func parseProgress(log log.Logger) func(string) {
+func parseProgress(log log.Logger, ev *events.Emitter) func(string) {
 	var progress int
 	re := regexp.MustCompile(`.* (\d+(.\d+)?)% .*`)
 	return func(line string) {
@@ -161,6 +162,7 @@ func parseProgress(log log.Logger) func(string) {
 			i, _ := strconv.Atoi(match[1])
 			if i != progress {
 				log.Debug("synchronizing: %s", line)
+				ev.Progress(events.PhaseSync, 0, i)
 				progress = i
 			}
 		}
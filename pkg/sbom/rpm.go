@@ -0,0 +1,141 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+const rpmQueryFormat = "%{NAME}\\t%{EPOCHNUM}\\t%{VERSION}\\t%{RELEASE}\\t%{ARCH}\\t%{LICENSE}\\n"
+
+// rpmDBPaths are the RPM database locations within an OS root, in order of preference
+var rpmDBPaths = []string{"/usr/lib/sysimage/rpm", "/var/lib/rpm"}
+
+// osReleasePaths are the os-release file locations within an OS root, in order of preference
+var osReleasePaths = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+// AddRPMs adds the RPM packages installed in the given OS root, the root is not modified
+func (s *SBOM) AddRPMs(ctx context.Context, system *sys.System, root string) error {
+	var dbPath string
+	for _, path := range rpmDBPaths {
+		if ok, _ := vfs.Exists(system.FS(), filepath.Join(root, path)); ok {
+			dbPath = filepath.Join(root, path)
+			break
+		}
+	}
+	if dbPath == "" {
+		system.Logger().Warn("No RPM database found in '%s', skipping package list", root)
+		return nil
+	}
+
+	out, err := system.Runner().RunContext(ctx, "rpm", "--dbpath", dbPath, "-qa", "--queryformat", rpmQueryFormat)
+	if err != nil {
+		return fmt.Errorf("listing RPM packages of '%s': %w: %s", root, err, string(out))
+	}
+
+	packages, err := parseRPMs(out, osID(system.FS(), root))
+	if err != nil {
+		return err
+	}
+	s.Add(packages...)
+	return nil
+}
+
+// AddSquashfsRPMs adds the RPM packages installed in the OS root of the given squashfs image,
+// only the RPM database and os-release files are extracted to the given working directory
+func (s *SBOM) AddSquashfsRPMs(ctx context.Context, system *sys.System, image, workDir string) error {
+	root := filepath.Join(workDir, "rpmdb-root")
+	args := []string{"-no-xattrs", "-d", root, image}
+	for _, path := range append(slices.Clone(rpmDBPaths), osReleasePaths...) {
+		args = append(args, strings.TrimPrefix(path, "/"))
+	}
+	out, err := system.Runner().RunContext(ctx, "unsquashfs", args...)
+	if err != nil {
+		return fmt.Errorf("extracting RPM database from '%s': %w: %s", image, err, string(out))
+	}
+	defer func() { _ = system.FS().RemoveAll(root) }()
+
+	return s.AddRPMs(ctx, system, root)
+}
+
+// parseRPMs parses the output of the RPM query, the given distribution is the package URL namespace
+func parseRPMs(out []byte, distro string) ([]Component, error) {
+	var packages []Component
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("unexpected RPM query output '%s'", line)
+		}
+		name, epoch, ver, release, arch, license := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5]
+		if name == "gpg-pubkey" {
+			// Imported signing keys are listed as packages
+			continue
+		}
+
+		evr := fmt.Sprintf("%s-%s", ver, release)
+		qualifiers := url.Values{"arch": []string{arch}}
+		if epoch != "" && epoch != "0" {
+			evr = epoch + ":" + evr
+			qualifiers.Set("epoch", epoch)
+		}
+		namespace := ""
+		if distro != "" {
+			namespace = distro + "/"
+		}
+		packages = append(packages, Component{
+			Kind:    RPM,
+			Name:    name,
+			Version: evr,
+			License: license,
+			PURL:    fmt.Sprintf("pkg:rpm/%s%s@%s-%s?%s", namespace, name, ver, release, qualifiers.Encode()),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading RPM query output: %w", err)
+	}
+	slices.SortFunc(packages, func(a, b Component) int {
+		return strings.Compare(a.Name+" "+a.Version, b.Name+" "+b.Version)
+	})
+	return packages, nil
+}
+
+// osID returns the distribution ID of the given OS root, empty if unknown
+func osID(fs vfs.FS, root string) string {
+	for _, path := range osReleasePaths {
+		vars, err := vfs.LoadEnvFile(fs, filepath.Join(root, path))
+		if err == nil && vars["ID"] != "" {
+			return vars["ID"]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,313 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/suse/elemental/v3/pkg/manifest/api"
+	"github.com/suse/elemental/v3/pkg/manifest/resolver"
+	"github.com/suse/elemental/v3/pkg/reproducible"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+	"github.com/suse/elemental/v3/pkg/version"
+)
+
+// Suffix is appended to the image file name to write its SBOM
+const Suffix = ".spdx.json"
+
+const (
+	spdxVersion   = "SPDX-2.3"
+	dataLicense   = "CC0-1.0"
+	documentID    = "SPDXRef-DOCUMENT"
+	imageID       = "SPDXRef-Image"
+	noAssertion   = "NOASSERTION"
+	namespaceBase = "https://github.com/suse/elemental/spdx/"
+)
+
+// Kind is the kind of a component shipped in an image
+type Kind string
+
+const (
+	ReleaseManifest Kind = "release-manifest"
+	OSImage         Kind = "os-image"
+	ISOImage        Kind = "iso-image"
+	Extension       Kind = "systemd-extension"
+	HelmChart       Kind = "helm-chart"
+	RPM             Kind = "rpm"
+)
+
+// Component is a software component shipped in an image
+type Component struct {
+	Kind    Kind
+	Name    string
+	Version string
+	// Reference is the location the component is fetched from, if any
+	Reference string
+	// Digest is the digest of the component in the '<algorithm>:<hex>' form, if known
+	Digest  string
+	License string
+	// PURL is the package URL of the component, if any
+	PURL string
+}
+
+// SBOM collects the components of an image to write a Software Bill of Materials in SPDX format
+type SBOM struct {
+	mu         sync.Mutex
+	components []Component
+}
+
+// New returns an empty SBOM
+func New() *SBOM {
+	return &SBOM{}
+}
+
+// Add adds the given components to the SBOM
+func (s *SBOM) Add(components ...Component) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.components = append(s.components, components...)
+}
+
+// Components returns the components added to the SBOM
+func (s *SBOM) Components() []Component {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.components)
+}
+
+// AddReleaseManifest adds the release manifests read from the given URI and the OS and ISO
+// images they refer to
+func (s *SBOM) AddReleaseManifest(uri string, rm *resolver.ResolvedManifest) {
+	if rm.ProductExtension != nil {
+		s.Add(manifestComponent(rm.ProductExtension.Metadata, uri, ""))
+		core := rm.ProductExtension.CorePlatform
+		s.Add(manifestComponent(rm.CorePlatform.Metadata, core.Image, core.Digest))
+	} else {
+		s.Add(manifestComponent(rm.CorePlatform.Metadata, uri, ""))
+	}
+
+	image := rm.CorePlatform.Components.OperatingSystem.Image
+	s.AddImage(OSImage, image.Base, image.BaseDigest)
+	s.AddImage(ISOImage, image.ISO, image.ISODigest)
+}
+
+// AddImage adds the given OCI image, its name and version are the image repository and tag
+func (s *SBOM) AddImage(kind Kind, ref, digest string) {
+	s.Add(imageComponent(kind, ref, digest))
+}
+
+// AddExtensions adds the given systemd extensions, their version is the image tag
+func (s *SBOM) AddExtensions(extensions ...api.SystemdExtension) {
+	for _, ext := range extensions {
+		c := imageComponent(Extension, ext.Image, ext.Digest)
+		c.Name = ext.Name
+		s.Add(c)
+	}
+}
+
+// AddHelmCharts adds the given helm charts, repositories maps repository names to their URLs
+func (s *SBOM) AddHelmCharts(charts []*api.HelmChart, repositories map[string]string) {
+	for _, chart := range charts {
+		ref := repositories[chart.Repository]
+		s.Add(Component{
+			Kind:      HelmChart,
+			Name:      chart.Chart,
+			Version:   chart.Version,
+			Reference: ref,
+			PURL:      fmt.Sprintf("pkg:helm/%s@%s", chart.Chart, chart.Version),
+		})
+	}
+}
+
+// Write writes the SBOM of the given image file in SPDX JSON format next to it
+func (s *SBOM) Write(fs vfs.FS, image string) error {
+	doc := s.document(filepath.Base(image), reproducible.Now())
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling SBOM: %w", err)
+	}
+	file := image + Suffix
+	err = fs.WriteFile(file, append(data, '\n'), vfs.FilePerm)
+	if err != nil {
+		return fmt.Errorf("writing SBOM '%s': %w", file, err)
+	}
+	return nil
+}
+
+type document struct {
+	SPDXVersion       string         `json:"spdxVersion"`
+	DataLicense       string         `json:"dataLicense"`
+	SPDXID            string         `json:"SPDXID"`
+	Name              string         `json:"name"`
+	DocumentNamespace string         `json:"documentNamespace"`
+	CreationInfo      creationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage  `json:"packages"`
+	Relationships     []relationship `json:"relationships"`
+}
+
+type creationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID                string        `json:"SPDXID"`
+	Name                  string        `json:"name"`
+	VersionInfo           string        `json:"versionInfo,omitempty"`
+	DownloadLocation      string        `json:"downloadLocation"`
+	FilesAnalyzed         bool          `json:"filesAnalyzed"`
+	LicenseConcluded      string        `json:"licenseConcluded"`
+	LicenseDeclared       string        `json:"licenseDeclared"`
+	CopyrightText         string        `json:"copyrightText"`
+	PrimaryPackagePurpose string        `json:"primaryPackagePurpose,omitempty"`
+	Checksums             []checksum    `json:"checksums,omitempty"`
+	ExternalRefs          []externalRef `json:"externalRefs,omitempty"`
+	Comment               string        `json:"comment,omitempty"`
+}
+
+type checksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type externalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type relationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+var invalidIDChars = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// document returns the SPDX document of the given image, the document namespace is derived
+// from the content so identical images get identical documents
+func (s *SBOM) document(name string, created time.Time) document {
+	doc := document{
+		SPDXVersion: spdxVersion,
+		DataLicense: dataLicense,
+		SPDXID:      documentID,
+		Name:        name,
+		CreationInfo: creationInfo{
+			Created:  created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: elemental-" + version.Get()},
+		},
+		Packages: []spdxPackage{{
+			SPDXID:                imageID,
+			Name:                  name,
+			DownloadLocation:      noAssertion,
+			LicenseConcluded:      noAssertion,
+			LicenseDeclared:       noAssertion,
+			CopyrightText:         noAssertion,
+			PrimaryPackagePurpose: "OPERATING-SYSTEM",
+		}},
+		Relationships: []relationship{{
+			SPDXElementID: documentID, RelationshipType: "DESCRIBES", RelatedSPDXElement: imageID,
+		}},
+	}
+
+	ids := map[string]int{}
+	for _, c := range s.Components() {
+		id := "SPDXRef-" + invalidIDChars.ReplaceAllString(fmt.Sprintf("%s-%s-%s", c.Kind, c.Name, c.Version), "-")
+		if n := ids[id]; n > 0 {
+			ids[id]++
+			id = fmt.Sprintf("%s-%d", id, n)
+		} else {
+			ids[id] = 1
+		}
+		doc.Packages = append(doc.Packages, spdxPackageFor(id, c))
+		doc.Relationships = append(doc.Relationships, relationship{
+			SPDXElementID: imageID, RelationshipType: "CONTAINS", RelatedSPDXElement: id,
+		})
+	}
+
+	data, _ := json.Marshal(doc.Packages)
+	doc.DocumentNamespace = fmt.Sprintf("%s%s-%s", namespaceBase, name, uuid.NewSHA1(uuid.NameSpaceURL, data))
+	return doc
+}
+
+func spdxPackageFor(id string, c Component) spdxPackage {
+	p := spdxPackage{
+		SPDXID:           id,
+		Name:             c.Name,
+		VersionInfo:      c.Version,
+		DownloadLocation: noAssertion,
+		LicenseConcluded: noAssertion,
+		LicenseDeclared:  noAssertion,
+		CopyrightText:    noAssertion,
+		Comment:          string(c.Kind),
+	}
+	if c.Reference != "" {
+		p.DownloadLocation = c.Reference
+	}
+	if c.License != "" {
+		p.LicenseDeclared = c.License
+	}
+	switch c.Kind {
+	case OSImage, ISOImage, Extension:
+		p.PrimaryPackagePurpose = "CONTAINER"
+	case ReleaseManifest:
+		p.PrimaryPackagePurpose = "FILE"
+	default:
+		p.PrimaryPackagePurpose = "APPLICATION"
+	}
+	if algorithm, value, ok := strings.Cut(c.Digest, ":"); ok {
+		p.Checksums = []checksum{{Algorithm: strings.ToUpper(algorithm), ChecksumValue: value}}
+	}
+	if c.PURL != "" {
+		p.ExternalRefs = []externalRef{{
+			ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: c.PURL,
+		}}
+	}
+	return p
+}
+
+func manifestComponent(metadata *api.Metadata, ref, digest string) Component {
+	c := imageComponent(ReleaseManifest, ref, digest)
+	if metadata != nil {
+		c.Name = metadata.Name
+		c.Version = metadata.Version
+	}
+	return c
+}
+
+// imageComponent returns the component of the given OCI image reference, its name and version
+// are the image repository and tag
+func imageComponent(kind Kind, ref, digest string) Component {
+	c := Component{Kind: kind, Name: ref, Reference: ref, Digest: digest}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		c.Name, c.Version = ref[:i], ref[i+1:]
+	}
+	if digest != "" {
+		c.Reference = api.PinnedImage(ref, digest)
+	}
+	return c
+}
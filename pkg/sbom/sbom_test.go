@@ -0,0 +1,132 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/sbom"
+	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+const rpmOutput = `kernel-default	0	6.12.0	160000.1	x86_64	GPL-2.0-only
+gpg-pubkey	0	29b700a4	62b07e22	(none)	pubkey
+bash	0	5.2.37	160000.2	x86_64	GPL-3.0-or-later
+shadow	2	4.17.4	160000.1	x86_64	BSD-3-Clause AND GPL-2.0-or-later
+`
+
+func TestSBOMSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SBOM test suite")
+}
+
+var _ = Describe("SBOM", Label("sbom"), func() {
+	var s *sys.System
+	var fs vfs.FS
+	var runner *sysmock.Runner
+	var cleanup func()
+	BeforeEach(func() {
+		var err error
+		runner = sysmock.NewRunner()
+		fs, cleanup, err = sysmock.TestFS(map[string]any{
+			"/root/usr/lib/sysimage/rpm/rpmdb.sqlite": []byte{},
+			"/root/etc/os-release":                    "ID=sl-micro\n",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		s, err = sys.NewSystem(
+			sys.WithFS(fs), sys.WithRunner(runner),
+			sys.WithLogger(log.New(log.WithDiscardAll())),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		runner.SideEffect = func(cmd string, args ...string) ([]byte, error) {
+			if cmd == "rpm" {
+				return []byte(rpmOutput), nil
+			}
+			return []byte{}, nil
+		}
+	})
+	AfterEach(func() {
+		cleanup()
+	})
+	It("lists the RPM packages of an OS root", func() {
+		sb := sbom.New()
+		Expect(sb.AddRPMs(context.Background(), s, "/root")).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{{"rpm", "--dbpath", "/root/usr/lib/sysimage/rpm", "-qa"}})).To(Succeed())
+
+		packages := sb.Components()
+		Expect(packages).To(HaveLen(3))
+		Expect(packages[0]).To(Equal(sbom.Component{
+			Kind: sbom.RPM, Name: "bash", Version: "5.2.37-160000.2", License: "GPL-3.0-or-later",
+			PURL: "pkg:rpm/sl-micro/bash@5.2.37-160000.2?arch=x86_64",
+		}))
+		Expect(packages[2].Version).To(Equal("2:4.17.4-160000.1"))
+		Expect(packages[2].PURL).To(Equal("pkg:rpm/sl-micro/shadow@4.17.4-160000.1?arch=x86_64&epoch=2"))
+	})
+	It("skips OS roots without RPM database", func() {
+		sb := sbom.New()
+		Expect(sb.AddRPMs(context.Background(), s, "/other")).To(Succeed())
+		Expect(sb.Components()).To(BeEmpty())
+		Expect(runner.GetCmds()).To(BeEmpty())
+	})
+	It("lists the RPM packages of a squashfs image", func() {
+		sb := sbom.New()
+		Expect(sb.AddSquashfsRPMs(context.Background(), s, "/squashfs.img", "/work")).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{{
+			"unsquashfs", "-no-xattrs", "-d", "/work/rpmdb-root", "/squashfs.img",
+			"usr/lib/sysimage/rpm", "var/lib/rpm", "etc/os-release", "usr/lib/os-release",
+		}})).To(Succeed())
+	})
+	It("writes an SPDX document next to the image", func() {
+		sb := sbom.New()
+		sb.AddImage(sbom.OSImage, "registry.example.com/os:6.2", "sha256:abcd")
+		sb.AddImage(sbom.OSImage, "registry.example.com/os:6.2", "sha256:abcd")
+		Expect(sb.AddRPMs(context.Background(), s, "/root")).To(Succeed())
+		Expect(sb.Write(fs, "/image.raw")).To(Succeed())
+
+		data, err := fs.ReadFile("/image.raw" + sbom.Suffix)
+		Expect(err).NotTo(HaveOccurred())
+		doc := map[string]any{}
+		Expect(json.Unmarshal(data, &doc)).To(Succeed())
+		Expect(doc["spdxVersion"]).To(Equal("SPDX-2.3"))
+		Expect(doc["name"]).To(Equal("image.raw"))
+		Expect(doc["packages"]).To(HaveLen(6))
+		Expect(doc["relationships"]).To(HaveLen(6))
+
+		packages := doc["packages"].([]any)
+		osImage := packages[1].(map[string]any)
+		Expect(osImage["SPDXID"]).To(Equal("SPDXRef-os-image-registry.example.com-os-6.2"))
+		Expect(osImage["versionInfo"]).To(Equal("6.2"))
+		Expect(osImage["checksums"]).To(ConsistOf(map[string]any{"algorithm": "SHA256", "checksumValue": "abcd"}))
+		Expect(packages[2].(map[string]any)["SPDXID"]).To(Equal("SPDXRef-os-image-registry.example.com-os-6.2-1"))
+
+		// Documents of the same content are identical
+		Expect(vfs.MkdirAll(fs, "/other", vfs.DirPerm)).To(Succeed())
+		Expect(sb.Write(fs, "/other/image.raw")).To(Succeed())
+		other, err := fs.ReadFile("/other/image.raw" + sbom.Suffix)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other).To(Equal(data))
+	})
+})
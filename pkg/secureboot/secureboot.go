@@ -0,0 +1,144 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secureboot
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"path/filepath"
+
+	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+// Config defines the custom Secure Boot keys EFI binaries are signed with
+type Config struct {
+	// Key is the path of the PEM encoded private key used for signing, without a key nothing
+	// is signed and the certificate is only enrolled
+	Key string `yaml:"key,omitempty"`
+	// Cert is the path of the PEM encoded X.509 certificate of the key
+	Cert string `yaml:"cert"`
+	// EnrollMOK requests the enrollment of the certificate as Machine Owner Key (MOK)
+	// on installation, it is confirmed in the MokManager at the next boot
+	EnrollMOK bool `yaml:"enrollMOK,omitempty"`
+}
+
+// IsValid returns true if the certificate is set
+func (c *Config) IsValid() bool {
+	return c != nil && c.Cert != ""
+}
+
+// CanSign returns true if both the key and the certificate are set
+func (c *Config) CanSign() bool {
+	return c.IsValid() && c.Key != ""
+}
+
+// Signer signs and verifies EFI binaries with sbsign compatible Authenticode signatures
+type Signer struct {
+	s    *sys.System
+	conf Config
+}
+
+func NewSigner(s *sys.System, conf Config) *Signer {
+	return &Signer{s: s, conf: conf}
+}
+
+// CheckKeyPair verifies the key and certificate can be loaded and match each other
+func (sg Signer) CheckKeyPair() error {
+	certPEM, err := sg.s.FS().ReadFile(sg.conf.Cert)
+	if err != nil {
+		return fmt.Errorf("reading certificate: %w", err)
+	}
+	keyPEM, err := sg.s.FS().ReadFile(sg.conf.Key)
+	if err != nil {
+		return fmt.Errorf("reading key: %w", err)
+	}
+	_, err = tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("loading key pair: %w", err)
+	}
+	return nil
+}
+
+// Sign signs the given EFI binary in place and verifies the resulting signature. Binaries
+// already carrying a valid signature of the certificate are not signed again.
+func (sg Signer) Sign(path string) error {
+	if sg.Verify(path) == nil {
+		sg.s.Logger().Debug("'%s' is already signed", path)
+		return nil
+	}
+
+	sg.s.Logger().Info("Signing '%s'", path)
+	stdOut, err := sg.s.Runner().Run("sbsign", "--key", sg.conf.Key, "--cert", sg.conf.Cert, "--output", path, path)
+	sg.s.Logger().Debug("sbsign stdout: %s", string(stdOut))
+	if err != nil {
+		return fmt.Errorf("signing '%s': %w", path, err)
+	}
+
+	return sg.Verify(path)
+}
+
+// Verify checks the given EFI binary is signed by the certificate
+func (sg Signer) Verify(path string) error {
+	stdOut, err := sg.s.Runner().Run("sbverify", "--cert", sg.conf.Cert, path)
+	sg.s.Logger().Debug("sbverify stdout: %s", string(stdOut))
+	if err != nil {
+		return fmt.Errorf("verifying signature of '%s': %w", path, err)
+	}
+	return nil
+}
+
+// EnrollMOK requests the enrollment of the certificate as Machine Owner Key. The request is
+// protected with the root password and has to be confirmed in the MokManager at the next boot.
+func (sg Signer) EnrollMOK() error {
+	data, err := sg.s.FS().ReadFile(sg.conf.Cert)
+	if err != nil {
+		return fmt.Errorf("reading certificate: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return fmt.Errorf("no PEM encoded certificate found in '%s'", sg.conf.Cert)
+	}
+	if _, err = x509.ParseCertificate(block.Bytes); err != nil {
+		return fmt.Errorf("parsing certificate: %w", err)
+	}
+
+	tempDir, err := vfs.TempDir(sg.s.FS(), "", "elemental-mok")
+	if err != nil {
+		return fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer func() { _ = sg.s.FS().RemoveAll(tempDir) }()
+
+	// mokutil only accepts DER encoded certificates
+	derCert := filepath.Join(tempDir, "mok.der")
+	err = sg.s.FS().WriteFile(derCert, block.Bytes, vfs.FilePerm)
+	if err != nil {
+		return fmt.Errorf("writing DER certificate: %w", err)
+	}
+
+	sg.s.Logger().Info("Requesting MOK enrollment of '%s'", sg.conf.Cert)
+	stdOut, err := sg.s.Runner().Run("mokutil", "--import", derCert, "--root-pw")
+	sg.s.Logger().Debug("mokutil stdout: %s", string(stdOut))
+	if err != nil {
+		return fmt.Errorf("requesting MOK enrollment: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secureboot_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/secureboot"
+	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+// throwawayKeyPair returns a PEM encoded self signed certificate and its private key
+func throwawayKeyPair() ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Elemental test signing key"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+var _ = Describe("Signer", Label("secureboot"), func() {
+	var tfs vfs.FS
+	var s *sys.System
+	var runner *sysmock.Runner
+	var cleanup func()
+	var signer *secureboot.Signer
+	var signed map[string]bool
+	var certPEM []byte
+
+	BeforeEach(func() {
+		var err error
+		var keyPEM []byte
+		certPEM, keyPEM = throwawayKeyPair()
+
+		tfs, cleanup, err = sysmock.TestFS(map[string]any{
+			"/keys/db.crt":  certPEM,
+			"/keys/db.key":  keyPEM,
+			"/esp/grub.efi": "grub",
+			"/esp/vmlinuz":  "kernel",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		signed = map[string]bool{}
+		runner = sysmock.NewRunner()
+		runner.SideEffect = func(command string, args ...string) ([]byte, error) {
+			switch command {
+			case "sbverify":
+				if !signed[args[len(args)-1]] {
+					return nil, fmt.Errorf("no signature table present")
+				}
+			case "sbsign":
+				signed[args[len(args)-1]] = true
+			case "mokutil":
+				_, err := tfs.Stat(args[1])
+				return nil, err
+			}
+			return nil, nil
+		}
+
+		s, err = sys.NewSystem(
+			sys.WithFS(tfs), sys.WithRunner(runner),
+			sys.WithLogger(log.New(log.WithDiscardAll())),
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		signer = secureboot.NewSigner(s, secureboot.Config{Key: "/keys/db.key", Cert: "/keys/db.crt"})
+	})
+	AfterEach(func() {
+		cleanup()
+	})
+	It("checks the key pair", func() {
+		Expect(signer.CheckKeyPair()).To(Succeed())
+
+		otherCert, _ := throwawayKeyPair()
+		Expect(tfs.WriteFile("/keys/other.crt", otherCert, vfs.FilePerm)).To(Succeed())
+		other := secureboot.NewSigner(s, secureboot.Config{Key: "/keys/db.key", Cert: "/keys/other.crt"})
+		Expect(other.CheckKeyPair()).To(MatchError(ContainSubstring("loading key pair")))
+	})
+	It("signs and verifies EFI binaries", func() {
+		Expect(signer.Sign("/esp/grub.efi")).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{
+			{"sbverify", "--cert", "/keys/db.crt", "/esp/grub.efi"},
+			{"sbsign", "--key", "/keys/db.key", "--cert", "/keys/db.crt", "--output", "/esp/grub.efi", "/esp/grub.efi"},
+			{"sbverify", "--cert", "/keys/db.crt", "/esp/grub.efi"},
+		})).To(Succeed())
+
+		// already signed binaries are not signed again
+		runner.ClearCmds()
+		Expect(signer.Sign("/esp/grub.efi")).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{
+			{"sbverify", "--cert", "/keys/db.crt", "/esp/grub.efi"},
+		})).To(Succeed())
+	})
+	It("fails if the signature can't be verified after signing", func() {
+		runner.SideEffect = func(command string, _ ...string) ([]byte, error) {
+			if command == "sbverify" {
+				return nil, errors.New("signature verification failed")
+			}
+			return nil, nil
+		}
+		Expect(signer.Sign("/esp/vmlinuz")).To(MatchError(ContainSubstring("verifying signature of '/esp/vmlinuz'")))
+	})
+	It("requests the MOK enrollment of the DER encoded certificate", func() {
+		Expect(signer.EnrollMOK()).To(Succeed())
+
+		cmds := runner.GetCmds()
+		Expect(cmds).To(HaveLen(1))
+		Expect(cmds[0][0]).To(Equal("mokutil"))
+		Expect(filepath.Base(cmds[0][2])).To(Equal("mok.der"))
+		Expect(cmds[0][3]).To(Equal("--root-pw"))
+	})
+	It("fails to enroll a MOK which is not a PEM certificate", func() {
+		invalid := secureboot.NewSigner(s, secureboot.Config{Key: "/keys/db.key", Cert: "/keys/db.key"})
+		Expect(invalid.EnrollMOK()).To(MatchError("no PEM encoded certificate found in '/keys/db.key'"))
+		Expect(runner.GetCmds()).To(BeEmpty())
+	})
+})
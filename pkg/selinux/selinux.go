@@ -32,6 +32,8 @@ import (
 
 const (
 	SelinuxTargetedContextFile = selinuxTargetedPath + "/contexts/files/file_contexts"
+	// AutorelabelFile flags the system to be relabelled at boot
+	AutorelabelFile = "/etc/selinux/.autorelabel"
 
 	selinuxTargetedPath = "/etc/selinux/targeted"
 	debugLines          = 10
@@ -65,6 +67,21 @@ func Relabel(ctx context.Context, s *sys.System, rootDir string, extraPaths ...s
 	return nil
 }
 
+// RequestRelabel flags the system in the given root to be relabelled at next boot if it finds the context.
+// It is meant for trees where labels can't be set, as setting them requires privileges.
+func RequestRelabel(s *sys.System, rootDir string) error {
+	contextFile := filepath.Join(rootDir, SelinuxTargetedContextFile)
+	if ok, _ := vfs.Exists(s.FS(), contextFile); !ok {
+		s.Logger().Debug("Not requesting SELinux relabel, no context found")
+		return nil
+	}
+	err := s.FS().WriteFile(filepath.Join(rootDir, AutorelabelFile), []byte{}, vfs.FilePerm)
+	if err != nil {
+		return fmt.Errorf("requesting SELinux relabel: %w", err)
+	}
+	return nil
+}
+
 // ChrootedRelabel relables with setfiles the given root in a chroot env. Additionally after the first
 // chrooted call it runs a non chrooted call to relabel any mountpoint used within the chroot.
 func ChrootedRelabel(ctx context.Context, s *sys.System, rootDir string, bind map[string]string, additionalPaths ...string) (err error) {
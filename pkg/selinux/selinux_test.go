@@ -98,6 +98,14 @@ var _ = Describe("Selinux", Label("selinux"), func() {
 		Expect(fs.WriteFile(contextFile, []byte{}, vfs.FilePerm)).To(Succeed())
 		Expect(selinux.Relabel(context.Background(), s, root)).NotTo(Succeed())
 	})
+	It("requests a relabel at boot for the targeted context", func() {
+		Expect(selinux.RequestRelabel(s, root)).To(Succeed())
+		Expect(vfs.Exists(fs, filepath.Join(root, selinux.AutorelabelFile))).To(BeFalse())
+		Expect(fs.WriteFile(contextFile, []byte{}, vfs.FilePerm)).To(Succeed())
+		Expect(selinux.RequestRelabel(s, root)).To(Succeed())
+		Expect(vfs.Exists(fs, filepath.Join(root, selinux.AutorelabelFile))).To(BeTrue())
+		Expect(runner.GetCmds()).To(BeEmpty())
+	})
 	It("relabels the given paths for the targeted context in a chroot env", func() {
 		Expect(fs.WriteFile(selinux.SelinuxTargetedContextFile, []byte{}, vfs.FilePerm)).To(Succeed())
 		Expect(fs.WriteFile(contextFile, []byte{}, vfs.FilePerm)).To(Succeed())
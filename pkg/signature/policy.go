@@ -0,0 +1,181 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+const dockerHubAlias = "docker.io"
+
+// Policy defines the signatures OCI images must have to be trusted. Each scope applies to
+// a registry or repository, images not matching any scope are not verified.
+type Policy struct {
+	Scopes []Scope `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+}
+
+// Scope sets the trusted signers of the images within a registry or repository
+type Scope struct {
+	// Scope is a registry host or a repository prefix, such as 'registry.suse.com' or
+	// 'registry.suse.com/suse'. An empty scope applies to any image.
+	Scope string `yaml:"scope,omitempty" json:"scope,omitempty"`
+	// PublicKeys is a list of PEM encoded public keys, a signature from any of them is trusted
+	PublicKeys []string `yaml:"publicKeys,omitempty" json:"publicKeys,omitempty"`
+	// Keyless trusts short lived certificates issued by Fulcio to the given identity
+	Keyless *Keyless `yaml:"keyless,omitempty" json:"keyless,omitempty"`
+}
+
+// Keyless defines the trusted identity of keyless signatures
+type Keyless struct {
+	// Identity is the email or URI the signing certificate is issued to
+	Identity string `yaml:"identity" json:"identity"`
+	// Issuer is the OIDC issuer which authenticated the identity
+	Issuer string `yaml:"issuer" json:"issuer"`
+	// FulcioRoots is the PEM encoded certificate chain of the Fulcio instance
+	FulcioRoots string `yaml:"fulcioRoots" json:"fulcioRoots"`
+	// RekorPublicKey is the PEM encoded public key of the Rekor transparency log
+	RekorPublicKey string `yaml:"rekorPublicKey" json:"rekorPublicKey"`
+}
+
+// LoadPolicy reads a signature policy from the given YAML file
+func LoadPolicy(fs vfs.FS, path string) (*Policy, error) {
+	data, err := fs.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading signature policy: %w", err)
+	}
+	p := &Policy{}
+	err = yaml.Unmarshal(data, p)
+	if err != nil {
+		return nil, fmt.Errorf("parsing signature policy: %w", err)
+	}
+	return p, p.Validate()
+}
+
+// IsEmpty returns true if the policy does not enforce any signature
+func (p *Policy) IsEmpty() bool {
+	return p == nil || len(p.Scopes) == 0
+}
+
+// Validate checks all scopes define a parseable set of trusted signers
+func (p *Policy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, s := range p.Scopes {
+		if seen[s.Scope] {
+			return fmt.Errorf("duplicated signature scope '%s'", s.Scope)
+		}
+		seen[s.Scope] = true
+
+		if len(s.PublicKeys) == 0 && s.Keyless == nil {
+			return fmt.Errorf("no public keys or keyless identity defined for signature scope '%s'", s.Scope)
+		}
+		for _, key := range s.PublicKeys {
+			if _, err := parsePublicKey(key); err != nil {
+				return fmt.Errorf("invalid public key for signature scope '%s': %w", s.Scope, err)
+			}
+		}
+		if s.Keyless != nil {
+			if err := s.Keyless.validate(); err != nil {
+				return fmt.Errorf("invalid keyless identity for signature scope '%s': %w", s.Scope, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Match returns the most specific scope the given repository belongs to, nil if none matches.
+// Repositories are fully qualified, e.g. 'registry.suse.com/suse/sl-micro'.
+func (p *Policy) Match(repository string) *Scope {
+	if p == nil {
+		return nil
+	}
+	var match *Scope
+	for i, s := range p.Scopes {
+		scope := normalizeScope(s.Scope)
+		if scope != "" && repository != scope && !strings.HasPrefix(repository, scope+"/") {
+			continue
+		}
+		if match == nil || len(scope) > len(normalizeScope(match.Scope)) {
+			match = &p.Scopes[i]
+		}
+	}
+	return match
+}
+
+// normalizeScope removes trailing slashes and expands the Docker Hub registry alias
+// to the registry name used in parsed references
+func normalizeScope(scope string) string {
+	scope = strings.TrimSuffix(scope, "/")
+	if scope == dockerHubAlias || strings.HasPrefix(scope, dockerHubAlias+"/") {
+		scope = name.DefaultRegistry + strings.TrimPrefix(scope, dockerHubAlias)
+	}
+	return scope
+}
+
+func (k Keyless) validate() error {
+	if k.Identity == "" || k.Issuer == "" {
+		return fmt.Errorf("identity and issuer are required")
+	}
+	if _, err := parseCertificates(k.FulcioRoots); err != nil {
+		return fmt.Errorf("parsing Fulcio roots: %w", err)
+	}
+	if _, err := parsePublicKey(k.RekorPublicKey); err != nil {
+		return fmt.Errorf("parsing Rekor public key: %w", err)
+	}
+	return nil
+}
+
+func parsePublicKey(data string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func parseCertificates(data string) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM certificates found")
+	}
+	return certs, nil
+}
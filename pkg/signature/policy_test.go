@@ -0,0 +1,98 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/signature"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+var _ = Describe("Policy", Label("signature"), func() {
+	var key string
+	BeforeEach(func() {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		key = publicKeyPEM(&priv.PublicKey)
+	})
+	It("matches the most specific scope", func() {
+		p := &signature.Policy{Scopes: []signature.Scope{
+			{Scope: "", PublicKeys: []string{key}},
+			{Scope: "registry.suse.com", PublicKeys: []string{key}},
+			{Scope: "registry.suse.com/suse/", PublicKeys: []string{key}},
+		}}
+		Expect(p.Match("registry.suse.com/suse/sl-micro").Scope).To(Equal("registry.suse.com/suse/"))
+		Expect(p.Match("registry.suse.com/other/image").Scope).To(Equal("registry.suse.com"))
+		Expect(p.Match("registry.suse.community/image").Scope).To(Equal(""))
+
+		p.Scopes = p.Scopes[1:]
+		Expect(p.Match("registry.opensuse.org/image")).To(BeNil())
+		Expect((*signature.Policy)(nil).Match("registry.suse.com/image")).To(BeNil())
+	})
+	It("matches Docker Hub scopes", func() {
+		p := &signature.Policy{Scopes: []signature.Scope{
+			{Scope: "docker.io/library", PublicKeys: []string{key}},
+		}}
+		Expect(p.Match("index.docker.io/library/alpine").Scope).To(Equal("docker.io/library"))
+		Expect(p.Match("index.docker.io/other/alpine")).To(BeNil())
+	})
+	It("validates the policy", func() {
+		p := &signature.Policy{Scopes: []signature.Scope{{Scope: "registry.suse.com"}}}
+		Expect(p.Validate()).To(MatchError("no public keys or keyless identity defined for signature scope 'registry.suse.com'"))
+
+		p.Scopes[0].PublicKeys = []string{"not a key"}
+		Expect(p.Validate()).To(MatchError("invalid public key for signature scope 'registry.suse.com': no PEM data found"))
+
+		p.Scopes[0].PublicKeys = []string{key}
+		Expect(p.Validate()).To(Succeed())
+
+		p.Scopes = append(p.Scopes, signature.Scope{Scope: "registry.suse.com", PublicKeys: []string{key}})
+		Expect(p.Validate()).To(MatchError("duplicated signature scope 'registry.suse.com'"))
+
+		p.Scopes[1] = signature.Scope{Scope: "quay.io", Keyless: &signature.Keyless{Identity: "me@example.com"}}
+		Expect(p.Validate()).To(MatchError(
+			"invalid keyless identity for signature scope 'quay.io': identity and issuer are required",
+		))
+	})
+	It("loads a policy from a file", func() {
+		fs, cleanup, err := sysmock.TestFS(nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer cleanup()
+
+		indented := "      " + strings.ReplaceAll(strings.TrimSpace(key), "\n", "\n      ")
+		data := fmt.Sprintf("scopes:\n- scope: registry.suse.com\n  publicKeys:\n  - |\n%s\n", indented)
+		Expect(fs.WriteFile("/policy.yaml", []byte(data), vfs.FilePerm)).To(Succeed())
+
+		p, err := signature.LoadPolicy(fs, "/policy.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.Scopes).To(HaveLen(1))
+		Expect(strings.TrimSpace(p.Scopes[0].PublicKeys[0])).To(Equal(strings.TrimSpace(key)))
+
+		_, err = signature.LoadPolicy(fs, "/missing.yaml")
+		Expect(err).To(HaveOccurred())
+	})
+})
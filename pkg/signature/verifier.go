@@ -0,0 +1,406 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	containerregistry "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+const (
+	// Annotations of the cosign signature layers
+	SignatureAnnotation   = "dev.cosignproject.cosign/signature"
+	CertificateAnnotation = "dev.sigstore.cosign/certificate"
+	ChainAnnotation       = "dev.sigstore.cosign/chain"
+	BundleAnnotation      = "dev.sigstore.cosign/bundle"
+
+	// PayloadType is the type of cosign simple signing payloads
+	PayloadType = "cosign container image signature"
+)
+
+var (
+	// Fulcio certificate extensions holding the OIDC issuer
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Payload is the cosign simple signing payload
+type Payload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]any `json:"optional,omitempty"`
+}
+
+// Bundle is the Rekor inclusion promise attached to keyless signatures
+type Bundle struct {
+	SignedEntryTimestamp []byte        `json:"SignedEntryTimestamp"`
+	Payload              BundlePayload `json:"Payload"`
+}
+
+type BundlePayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogIndex       int64  `json:"logIndex"`
+	LogID          string `json:"logID"`
+}
+
+// hashedRekord is the subset of the Rekor entry used to match it with the signature
+type hashedRekord struct {
+	Spec struct {
+		Signature struct {
+			Content string `json:"content"`
+		} `json:"signature"`
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+	} `json:"spec"`
+}
+
+type Verifier struct {
+	policy     *Policy
+	remoteOpts []remote.Option
+}
+
+// NewVerifier returns a verifier for the given policy. The remote options are used to
+// query the registry, they should match the ones used to pull the image.
+func NewVerifier(policy *Policy, opts ...remote.Option) *Verifier {
+	return &Verifier{policy: policy, remoteOpts: opts}
+}
+
+// Verify checks the given image reference is signed as required by the policy scope it belongs to.
+// It returns the reference by digest of the verified image, images should be pulled by this
+// reference to prevent the tag from being moved after verification. References not matching any
+// scope are returned unmodified.
+func (v Verifier) Verify(ctx context.Context, ref name.Reference) (name.Reference, error) {
+	return v.VerifyAt(ctx, ref, ref)
+}
+
+// VerifyAt checks the given image reference is signed as required by the policy scope it belongs to,
+// reading the image and its signatures from the given location, such as a mirror of the image. It
+// returns the reference by digest of the verified image at the location.
+func (v Verifier) VerifyAt(ctx context.Context, ref, location name.Reference) (name.Reference, error) {
+	scope := v.policy.Match(ref.Context().Name())
+	if scope == nil {
+		return location, nil
+	}
+
+	opts := append([]remote.Option{remote.WithContext(ctx)}, v.remoteOpts...)
+	desc, err := remote.Head(location, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("resolving digest of '%s': %w", location.String(), err)
+	}
+
+	sigImg, err := remote.Image(SignatureTag(location, desc.Digest), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("fetching signatures of '%s': %w", location.String(), err)
+	}
+
+	err = v.CheckSignatures(ref, desc.Digest, sigImg)
+	if err != nil {
+		return nil, err
+	}
+	return location.Context().Digest(desc.Digest.String()), nil
+}
+
+// CheckSignatures checks the given signature image holds a trusted signature for the image with the
+// given reference and digest. This is meant for images and signatures stored out of the registry.
+func (v Verifier) CheckSignatures(ref name.Reference, digest containerregistry.Hash, sigImg containerregistry.Image) error {
+	scope := v.policy.Match(ref.Context().Name())
+	if scope == nil {
+		return nil
+	}
+
+	manifest, err := sigImg.Manifest()
+	if err != nil {
+		return fmt.Errorf("reading signatures manifest: %w", err)
+	}
+
+	var errs error
+	for _, layerDesc := range manifest.Layers {
+		err = v.verifyLayer(sigImg, layerDesc, scope, digest)
+		if err == nil {
+			return nil
+		}
+		errs = errors.Join(errs, err)
+	}
+	return fmt.Errorf("no valid signature found for '%s' in scope '%s': %w", ref.String(), scope.Scope, errs)
+}
+
+// SignatureTag returns the tag cosign stores the signatures of the image with the given digest at
+func SignatureTag(ref name.Reference, digest containerregistry.Hash) name.Tag {
+	return ref.Context().Tag(fmt.Sprintf("%s-%s.sig", digest.Algorithm, digest.Hex))
+}
+
+// verifyLayer checks the given signature layer is a trusted signature of the given digest
+func (v Verifier) verifyLayer(img containerregistry.Image, desc containerregistry.Descriptor, scope *Scope, digest containerregistry.Hash) error {
+	sig, err := base64.StdEncoding.DecodeString(desc.Annotations[SignatureAnnotation])
+	if err != nil || len(sig) == 0 {
+		return fmt.Errorf("invalid signature annotation in layer %s", desc.Digest)
+	}
+
+	layer, err := img.LayerByDigest(desc.Digest)
+	if err != nil {
+		return fmt.Errorf("fetching signature layer %s: %w", desc.Digest, err)
+	}
+	rc, err := layer.Compressed()
+	if err != nil {
+		return fmt.Errorf("reading signature layer %s: %w", desc.Digest, err)
+	}
+	defer rc.Close()
+	payload, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("reading signature layer %s: %w", desc.Digest, err)
+	}
+
+	err = verifySigner(scope, payload, sig, desc.Annotations)
+	if err != nil {
+		return err
+	}
+
+	p := &Payload{}
+	err = json.Unmarshal(payload, p)
+	if err != nil {
+		return fmt.Errorf("parsing signature payload: %w", err)
+	}
+	if p.Critical.Type != PayloadType {
+		return fmt.Errorf("unexpected signature payload type '%s'", p.Critical.Type)
+	}
+	if p.Critical.Image.DockerManifestDigest != digest.String() {
+		return fmt.Errorf("signature is for digest '%s', not '%s'", p.Critical.Image.DockerManifestDigest, digest.String())
+	}
+	return nil
+}
+
+// verifySigner checks the payload signature was made by any of the trusted signers of the scope
+func verifySigner(scope *Scope, payload, sig []byte, annotations map[string]string) error {
+	var errs error
+	for _, key := range scope.PublicKeys {
+		pub, err := parsePublicKey(key)
+		if err != nil {
+			return err
+		}
+		err = verifySignature(pub, payload, sig)
+		if err == nil {
+			return nil
+		}
+		errs = errors.Join(errs, err)
+	}
+
+	if scope.Keyless != nil {
+		if _, ok := annotations[CertificateAnnotation]; ok {
+			err := verifyKeyless(scope.Keyless, payload, sig, annotations)
+			if err == nil {
+				return nil
+			}
+			errs = errors.Join(errs, err)
+		}
+	}
+
+	if errs == nil {
+		return fmt.Errorf("no trusted signer found")
+	}
+	return errs
+}
+
+// verifyKeyless checks the signing certificate was issued by Fulcio to the trusted identity
+// and that the signature was recorded in the Rekor transparency log while the certificate was valid
+func verifyKeyless(k *Keyless, payload, sig []byte, annotations map[string]string) error {
+	certs, err := parseCertificates(annotations[CertificateAnnotation])
+	if err != nil {
+		return fmt.Errorf("parsing signing certificate: %w", err)
+	}
+	cert := certs[0]
+
+	bundle := &Bundle{}
+	err = json.Unmarshal([]byte(annotations[BundleAnnotation]), bundle)
+	if err != nil {
+		return fmt.Errorf("parsing Rekor bundle: %w", err)
+	}
+	err = verifyBundle(k.RekorPublicKey, bundle, payload, sig)
+	if err != nil {
+		return err
+	}
+
+	roots, err := parseCertificates(k.FulcioRoots)
+	if err != nil {
+		return fmt.Errorf("parsing Fulcio roots: %w", err)
+	}
+	verifyOpts := x509.VerifyOptions{
+		Roots:         x509.NewCertPool(),
+		Intermediates: x509.NewCertPool(),
+		CurrentTime:   time.Unix(bundle.Payload.IntegratedTime, 0),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}
+	for _, root := range roots {
+		if bytes.Equal(root.RawIssuer, root.RawSubject) {
+			verifyOpts.Roots.AddCert(root)
+		} else {
+			verifyOpts.Intermediates.AddCert(root)
+		}
+	}
+	if chain, ok := annotations[ChainAnnotation]; ok {
+		intermediates, err := parseCertificates(chain)
+		if err != nil {
+			return fmt.Errorf("parsing certificate chain: %w", err)
+		}
+		for _, c := range intermediates {
+			verifyOpts.Intermediates.AddCert(c)
+		}
+	}
+	_, err = cert.Verify(verifyOpts)
+	if err != nil {
+		return fmt.Errorf("verifying signing certificate: %w", err)
+	}
+
+	identities := append([]string{}, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	if !slices.Contains(identities, k.Identity) {
+		return fmt.Errorf("signing certificate identities %v do not include '%s'", identities, k.Identity)
+	}
+	if issuer := certificateIssuer(cert); issuer != k.Issuer {
+		return fmt.Errorf("signing certificate issuer '%s' is not '%s'", issuer, k.Issuer)
+	}
+
+	return verifySignature(cert.PublicKey, payload, sig)
+}
+
+// verifyBundle checks the Rekor signed entry timestamp and that the log entry matches the signature
+func verifyBundle(rekorKey string, bundle *Bundle, payload, sig []byte) error {
+	pub, err := parsePublicKey(rekorKey)
+	if err != nil {
+		return fmt.Errorf("parsing Rekor public key: %w", err)
+	}
+
+	// The signed entry timestamp is computed over the canonical JSON of the payload
+	// which has sorted keys and no insignificant whitespace.
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	err = enc.Encode(map[string]any{
+		"body":           bundle.Payload.Body,
+		"integratedTime": bundle.Payload.IntegratedTime,
+		"logIndex":       bundle.Payload.LogIndex,
+		"logID":          bundle.Payload.LogID,
+	})
+	if err != nil {
+		return err
+	}
+	err = verifySignature(pub, bytes.TrimSuffix(buf.Bytes(), []byte("\n")), bundle.SignedEntryTimestamp)
+	if err != nil {
+		return fmt.Errorf("verifying Rekor signed entry timestamp: %w", err)
+	}
+
+	body, err := base64.StdEncoding.DecodeString(bundle.Payload.Body)
+	if err != nil {
+		return fmt.Errorf("decoding Rekor entry: %w", err)
+	}
+	entry := &hashedRekord{}
+	err = json.Unmarshal(body, entry)
+	if err != nil {
+		return fmt.Errorf("parsing Rekor entry: %w", err)
+	}
+	hash := sha256.Sum256(payload)
+	if entry.Spec.Data.Hash.Value != hex.EncodeToString(hash[:]) {
+		return fmt.Errorf("rekor entry does not match the signed payload")
+	}
+	if entry.Spec.Signature.Content != base64.StdEncoding.EncodeToString(sig) {
+		return fmt.Errorf("rekor entry does not match the signature")
+	}
+	return nil
+}
+
+// certificateIssuer returns the OIDC issuer recorded in a Fulcio certificate
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err == nil {
+				return issuer
+			}
+		case ext.Id.Equal(oidIssuerV1):
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+func verifySignature(pub crypto.PublicKey, payload, sig []byte) error {
+	hash := sha256.Sum256(payload)
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, hash[:], sig) {
+			return fmt.Errorf("invalid ECDSA signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig); err != nil {
+			return fmt.Errorf("invalid RSA signature: %w", err)
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, payload, sig) {
+			return fmt.Errorf("invalid ED25519 signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", pub)
+	}
+	return nil
+}
+
+// String returns a short description of the trusted signers of the scope
+func (s Scope) String() string {
+	signers := []string{}
+	if len(s.PublicKeys) > 0 {
+		signers = append(signers, fmt.Sprintf("%d public key(s)", len(s.PublicKeys)))
+	}
+	if s.Keyless != nil {
+		signers = append(signers, fmt.Sprintf("keyless identity '%s'", s.Keyless.Identity))
+	}
+	return strings.Join(signers, ", ")
+}
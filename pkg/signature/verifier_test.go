@@ -0,0 +1,320 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	containerregistry "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/signature"
+)
+
+var _ = Describe("Verifier", Label("signature"), func() {
+	var server *httptest.Server
+	var host string
+	var ref name.Reference
+	var digest containerregistry.Hash
+	var key *ecdsa.PrivateKey
+	var policy *signature.Policy
+
+	BeforeEach(func() {
+		server = httptest.NewServer(registry.New())
+		u, err := url.Parse(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		host = u.Host
+
+		ref, err = name.ParseReference(host+"/elemental/os:latest", name.Insecure)
+		Expect(err).NotTo(HaveOccurred())
+		img, err := random.Image(64, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote.Write(ref, img)).To(Succeed())
+		digest, err = img.Digest()
+		Expect(err).NotTo(HaveOccurred())
+
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		policy = &signature.Policy{Scopes: []signature.Scope{{
+			Scope:      host + "/elemental",
+			PublicKeys: []string{publicKeyPEM(&key.PublicKey)},
+		}}}
+		Expect(policy.Validate()).To(Succeed())
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+	It("verifies an image signed with a trusted key", func() {
+		payload := simpleSigningPayload(digest.String())
+		pushSignature(ref, digest, payload, map[string]string{
+			signature.SignatureAnnotation: sign(key, payload),
+		})
+
+		verified, err := signature.NewVerifier(policy).Verify(context.Background(), ref)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verified.String()).To(Equal(host + "/elemental/os@" + digest.String()))
+	})
+	It("checks signatures stored out of the registry", func() {
+		payload := simpleSigningPayload(digest.String())
+		sigImg, err := mutate.Append(empty.Image, mutate.Addendum{
+			Layer:       static.NewLayer(payload, types.MediaType("application/vnd.dev.cosign.simplesigning.v1+json")),
+			Annotations: map[string]string{signature.SignatureAnnotation: sign(key, payload)},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		server.Close()
+
+		verifier := signature.NewVerifier(policy)
+		Expect(verifier.CheckSignatures(ref, digest, sigImg)).To(Succeed())
+		Expect(signature.SignatureTag(ref, digest).TagStr()).To(Equal("sha256-" + digest.Hex + ".sig"))
+
+		other, err := random.Image(64, 1)
+		Expect(err).NotTo(HaveOccurred())
+		otherDigest, err := other.Digest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verifier.CheckSignatures(ref, otherDigest, sigImg)).To(MatchError(ContainSubstring("no valid signature found")))
+	})
+	It("does not verify images out of any scope", func() {
+		policy.Scopes[0].Scope = host + "/other"
+		verified, err := signature.NewVerifier(policy).Verify(context.Background(), ref)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verified).To(Equal(ref))
+	})
+	It("fails to verify an unsigned image", func() {
+		_, err := signature.NewVerifier(policy).Verify(context.Background(), ref)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fetching signatures"))
+	})
+	It("fails to verify an image signed with an untrusted key", func() {
+		other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		payload := simpleSigningPayload(digest.String())
+		pushSignature(ref, digest, payload, map[string]string{
+			signature.SignatureAnnotation: sign(other, payload),
+		})
+
+		_, err = signature.NewVerifier(policy).Verify(context.Background(), ref)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("invalid ECDSA signature"))
+	})
+	It("fails to verify a signature of a different digest", func() {
+		payload := simpleSigningPayload("sha256:" + strings.Repeat("0", 64))
+		pushSignature(ref, digest, payload, map[string]string{
+			signature.SignatureAnnotation: sign(key, payload),
+		})
+
+		_, err := signature.NewVerifier(policy).Verify(context.Background(), ref)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("signature is for digest"))
+	})
+	Describe("keyless signatures", func() {
+		var rootPEM, leafPEM string
+		var leafKey, rekorKey *ecdsa.PrivateKey
+		var notBefore time.Time
+
+		BeforeEach(func() {
+			var err error
+			notBefore = time.Now().Add(-time.Hour)
+			rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			rootTmpl := &x509.Certificate{
+				SerialNumber:          big.NewInt(1),
+				Subject:               pkix.Name{CommonName: "fulcio"},
+				NotBefore:             notBefore.Add(-time.Hour),
+				NotAfter:              notBefore.Add(24 * time.Hour),
+				IsCA:                  true,
+				BasicConstraintsValid: true,
+				KeyUsage:              x509.KeyUsageCertSign,
+			}
+			rootDER, err := x509.CreateCertificate(rand.Reader, rootTmpl, rootTmpl, &rootKey.PublicKey, rootKey)
+			Expect(err).NotTo(HaveOccurred())
+			rootCert, err := x509.ParseCertificate(rootDER)
+			Expect(err).NotTo(HaveOccurred())
+			rootPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDER}))
+
+			issuer, err := asn1.Marshal("https://oidc.example.com")
+			Expect(err).NotTo(HaveOccurred())
+			leafKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			leafTmpl := &x509.Certificate{
+				SerialNumber:   big.NewInt(2),
+				NotBefore:      notBefore,
+				NotAfter:       notBefore.Add(10 * time.Minute),
+				KeyUsage:       x509.KeyUsageDigitalSignature,
+				ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+				EmailAddresses: []string{"release@example.com"},
+				ExtraExtensions: []pkix.Extension{{
+					Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}, Value: issuer,
+				}},
+			}
+			leafDER, err := x509.CreateCertificate(rand.Reader, leafTmpl, rootCert, &leafKey.PublicKey, rootKey)
+			Expect(err).NotTo(HaveOccurred())
+			leafPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}))
+
+			rekorKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+
+			policy.Scopes[0].PublicKeys = nil
+			policy.Scopes[0].Keyless = &signature.Keyless{
+				Identity:       "release@example.com",
+				Issuer:         "https://oidc.example.com",
+				FulcioRoots:    rootPEM,
+				RekorPublicKey: publicKeyPEM(&rekorKey.PublicKey),
+			}
+			Expect(policy.Validate()).To(Succeed())
+		})
+		It("verifies an image signed by the trusted identity", func() {
+			payload := simpleSigningPayload(digest.String())
+			sig := sign(leafKey, payload)
+			pushSignature(ref, digest, payload, map[string]string{
+				signature.SignatureAnnotation:   sig,
+				signature.CertificateAnnotation: leafPEM,
+				signature.BundleAnnotation:      rekorBundle(rekorKey, payload, sig, notBefore.Add(time.Minute)),
+			})
+
+			verified, err := signature.NewVerifier(policy).Verify(context.Background(), ref)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(verified.String()).To(Equal(host + "/elemental/os@" + digest.String()))
+		})
+		It("fails to verify a signature logged after the certificate expired", func() {
+			payload := simpleSigningPayload(digest.String())
+			sig := sign(leafKey, payload)
+			pushSignature(ref, digest, payload, map[string]string{
+				signature.SignatureAnnotation:   sig,
+				signature.CertificateAnnotation: leafPEM,
+				signature.BundleAnnotation:      rekorBundle(rekorKey, payload, sig, notBefore.Add(time.Hour)),
+			})
+
+			_, err := signature.NewVerifier(policy).Verify(context.Background(), ref)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("verifying signing certificate"))
+		})
+		It("fails to verify a signature from another identity", func() {
+			policy.Scopes[0].Keyless.Identity = "someone@example.com"
+			payload := simpleSigningPayload(digest.String())
+			sig := sign(leafKey, payload)
+			pushSignature(ref, digest, payload, map[string]string{
+				signature.SignatureAnnotation:   sig,
+				signature.CertificateAnnotation: leafPEM,
+				signature.BundleAnnotation:      rekorBundle(rekorKey, payload, sig, notBefore.Add(time.Minute)),
+			})
+
+			_, err := signature.NewVerifier(policy).Verify(context.Background(), ref)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("do not include 'someone@example.com'"))
+		})
+		It("fails to verify a signature without a valid Rekor bundle", func() {
+			payload := simpleSigningPayload(digest.String())
+			sig := sign(leafKey, payload)
+			other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			pushSignature(ref, digest, payload, map[string]string{
+				signature.SignatureAnnotation:   sig,
+				signature.CertificateAnnotation: leafPEM,
+				signature.BundleAnnotation:      rekorBundle(other, payload, sig, notBefore.Add(time.Minute)),
+			})
+
+			_, err = signature.NewVerifier(policy).Verify(context.Background(), ref)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("verifying Rekor signed entry timestamp"))
+		})
+	})
+})
+
+func publicKeyPEM(pub crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	Expect(err).NotTo(HaveOccurred())
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func simpleSigningPayload(digest string) []byte {
+	return []byte(fmt.Sprintf(
+		`{"critical":{"identity":{"docker-reference":"elemental/os"},"image":{"docker-manifest-digest":"%s"},"type":"%s"},"optional":null}`,
+		digest, signature.PayloadType,
+	))
+}
+
+func sign(key *ecdsa.PrivateKey, payload []byte) string {
+	hash := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	Expect(err).NotTo(HaveOccurred())
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func pushSignature(ref name.Reference, digest containerregistry.Hash, payload []byte, annotations map[string]string) {
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(payload, types.MediaType("application/vnd.dev.cosign.simplesigning.v1+json")),
+		Annotations: annotations,
+	})
+	Expect(err).NotTo(HaveOccurred())
+	tag := ref.Context().Tag(fmt.Sprintf("%s-%s.sig", digest.Algorithm, digest.Hex))
+	Expect(remote.Write(tag, img)).To(Succeed())
+}
+
+func rekorBundle(key *ecdsa.PrivateKey, payload []byte, sig string, integrated time.Time) string {
+	hash := sha256.Sum256(payload)
+	body, err := json.Marshal(map[string]any{
+		"kind": "hashedrekord",
+		"spec": map[string]any{
+			"signature": map[string]any{"content": sig},
+			"data":      map[string]any{"hash": map[string]any{"algorithm": "sha256", "value": hex.EncodeToString(hash[:])}},
+		},
+	})
+	Expect(err).NotTo(HaveOccurred())
+	bundlePayload := signature.BundlePayload{
+		Body:           base64.StdEncoding.EncodeToString(body),
+		IntegratedTime: integrated.Unix(),
+		LogIndex:       42,
+		LogID:          "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+	}
+	canonical := fmt.Sprintf(
+		`{"body":"%s","integratedTime":%d,"logID":"%s","logIndex":%d}`,
+		bundlePayload.Body, bundlePayload.IntegratedTime, bundlePayload.LogID, bundlePayload.LogIndex,
+	)
+	set, err := base64.StdEncoding.DecodeString(sign(key, []byte(canonical)))
+	Expect(err).NotTo(HaveOccurred())
+	bundle, err := json.Marshal(signature.Bundle{SignedEntryTimestamp: set, Payload: bundlePayload})
+	Expect(err).NotTo(HaveOccurred())
+	return string(bundle)
+}
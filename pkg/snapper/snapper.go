@@ -19,12 +19,17 @@ package snapper
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"maps"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/suse/elemental/v3/pkg/btrfs"
+	"github.com/suse/elemental/v3/pkg/reproducible"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/env"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
@@ -36,25 +41,101 @@ const (
 
 	snapperDefaultConfig = "/etc/default/snapper"
 	snapperSysconfig     = "/etc/sysconfig/snapper"
-	snapperRootConfig    = "/etc/snapper/configs/" + rootConfig
+	snapperConfigsDir    = "/etc/snapper/configs"
+	snapperRootConfig    = snapperConfigsDir + "/" + rootConfig
 	rootConfig           = "root"
 )
 
+// Snapper userdata keys used to record the provenance of a snapshot
+const (
+	ImageKey   = "image"
+	DigestKey  = "image-digest"
+	OverlayKey = "overlay"
+	VersionKey = "elemental-version"
+	DateKey    = "timestamp"
+)
+
 type Snapper struct {
 	s *sys.System
 }
 
 type Snapshot struct {
-	Number   int      `json:"number"`
-	Default  bool     `json:"default"`
-	Active   bool     `json:"active"`
-	UserData Metadata `json:"userdata,omitempty"`
+	Number      int      `json:"number"`
+	Default     bool     `json:"default"`
+	Active      bool     `json:"active"`
+	Date        string   `json:"date,omitempty"`
+	Description string   `json:"description,omitempty"`
+	UserData    Metadata `json:"userdata,omitempty"`
 }
 
 type Metadata map[string]string
 
+// Snapper splits userdata on ',' and '=' with no escaping support, hence those characters, and the
+// escape character itself, are percent encoded within userdata values.
+var (
+	metadataEscaper   = strings.NewReplacer("%", "%25", ",", "%2C", "=", "%3D")
+	metadataUnescaper = strings.NewReplacer("%25", "%", "%2C", ",", "%3D", "=")
+)
+
+// snapshotInfo is the snapper metadata file stored next to each snapshot
+type snapshotInfo struct {
+	XMLName     xml.Name       `xml:"snapshot"`
+	Type        string         `xml:"type"`
+	Number      int            `xml:"num"`
+	Date        string         `xml:"date"`
+	Description string         `xml:"description,omitempty"`
+	UserData    []infoUserData `xml:"userdata"`
+}
+
+type infoUserData struct {
+	Key   string `xml:"key"`
+	Value string `xml:"value"`
+}
+
 type Snapshots []*Snapshot
 
+// Provenance describes the sources a snapshot was created from
+type Provenance struct {
+	Image   string `json:"image,omitempty"`
+	Digest  string `json:"digest,omitempty"`
+	Overlay string `json:"overlay,omitempty"`
+	Version string `json:"version,omitempty"`
+	Date    string `json:"timestamp,omitempty"`
+}
+
+// Metadata returns the snapper userdata representing the provenance, empty fields are omitted
+func (p Provenance) Metadata() Metadata {
+	m := Metadata{}
+	for k, v := range map[string]string{
+		ImageKey:   p.Image,
+		DigestKey:  p.Digest,
+		OverlayKey: p.Overlay,
+		VersionKey: p.Version,
+		DateKey:    p.Date,
+	} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// IsEmpty returns true if no provenance data is set
+func (p Provenance) IsEmpty() bool {
+	return p == Provenance{}
+}
+
+// Provenance returns the provenance recorded in the snapshot userdata
+func (s Snapshot) Provenance() Provenance {
+	return Provenance{
+		Image:   s.UserData[ImageKey],
+		Digest:  s.UserData[DigestKey],
+		Overlay: s.UserData[OverlayKey],
+		Version: s.UserData[VersionKey],
+		Date:    s.UserData[DateKey],
+	}
+}
+
 func (s Snapshots) GetDefault() int {
 	for _, snap := range s {
 		if snap.Default {
@@ -73,6 +154,16 @@ func (s Snapshots) GetActive() int {
 	return 0
 }
 
+// Get returns the snapshot with the given ID, returns nil if not found
+func (s Snapshots) Get(id int) *Snapshot {
+	for _, snap := range s {
+		if snap.Number == id {
+			return snap
+		}
+	}
+	return nil
+}
+
 func (s Snapshots) GetWithUserdata(key, value string) []int {
 	ids := []int{}
 	for _, snap := range s {
@@ -83,14 +174,29 @@ func (s Snapshots) GetWithUserdata(key, value string) []int {
 	return ids
 }
 
+// String returns the metadata in the snapper userdata format, values are escaped
 func (m Metadata) String() string {
 	var str string
-	for k, v := range m {
-		str += fmt.Sprintf("%s=%s,", k, v)
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		str += fmt.Sprintf("%s=%s,", k, metadataEscaper.Replace(m[k]))
 	}
 	return strings.TrimSuffix(str, ",")
 }
 
+// UnmarshalJSON unmarshals the snapper userdata unescaping its values
+func (m *Metadata) UnmarshalJSON(data []byte) error {
+	var userdata map[string]string
+	err := json.Unmarshal(data, &userdata)
+	if err != nil || userdata == nil {
+		return err
+	}
+	*m = Metadata{}
+	for k, v := range userdata {
+		(*m)[k] = metadataUnescaper.Replace(v)
+	}
+	return nil
+}
+
 func configTemplatesPaths() []string {
 	return []string{
 		"/etc/snapper/config-templates/default",
@@ -105,6 +211,14 @@ func ConfigName(path string) string {
 	return strings.ReplaceAll(strings.Trim(path, "/"), "/", "_")
 }
 
+// SnapshotPath returns the path of the given root snapshot ID under the given root
+func SnapshotPath(root string, id int) string {
+	if root == "" {
+		root = "/"
+	}
+	return filepath.Join(root, SnapshotsPath, strconv.Itoa(id), "snapshot")
+}
+
 func New(s *sys.System) *Snapper {
 	return &Snapper{s: s}
 }
@@ -125,7 +239,7 @@ func (sn Snapper) ListSnapshots(root string, config string) (Snapshots, error) {
 	if config == "" {
 		config = root
 	}
-	args = append(args, "-c", config, "--jsonout", "list", "--columns", "number,default,active,userdata")
+	args = append(args, "-c", config, "--jsonout", "list", "--columns", "number,default,active,userdata,date,description")
 	cmdOut, err := sn.s.Runner().Run("snapper", args...)
 	if err != nil {
 		return nil, fmt.Errorf("collecting snapshots: %s: %w", string(cmdOut), err)
@@ -223,6 +337,19 @@ func (sn Snapper) SetPermissions(root string, id int, rw bool) error {
 	return err
 }
 
+// SetMetadata sets the given userdata to the given snapshot ID
+func (sn Snapper) SetMetadata(root string, id int, metadata Metadata) error {
+	args := []string{"--no-dbus"}
+
+	if root != "" && root != "/" {
+		args = append(args, "--root", root)
+	}
+	args = append(args, "modify", "--userdata", metadata.String(), strconv.Itoa(id))
+	sn.s.Logger().Info("Setting snapshot userdata")
+	_, err := sn.s.Runner().Run("snapper", args...)
+	return err
+}
+
 func (sn Snapper) SetDefault(root string, id int, metadata Metadata) error {
 	args := []string{"--no-dbus"}
 
@@ -239,6 +366,20 @@ func (sn Snapper) SetDefault(root string, id int, metadata Metadata) error {
 	return err
 }
 
+// GetProvenance returns the provenance recorded in the userdata of the given root snapshot ID
+func (sn Snapper) GetProvenance(root string, id int) (*Provenance, error) {
+	snaps, err := sn.ListSnapshots(root, rootConfig)
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshots: %w", err)
+	}
+	snap := snaps.Get(id)
+	if snap == nil {
+		return nil, fmt.Errorf("snapshot %d not found", id)
+	}
+	p := snap.Provenance()
+	return &p, nil
+}
+
 func (sn Snapper) Cleanup(root string, maxSnaps int) error {
 	// TODO instead of relying on manual cleanup we could provide a snapper plugin
 	// to handle cleanup and rely on 'snapper cleanup' command
@@ -250,7 +391,7 @@ func (sn Snapper) Cleanup(root string, maxSnaps int) error {
 	i := 0
 	for deletes > 0 {
 		if !snaps[i].Active && !snaps[i].Default {
-			path := filepath.Join(root, SnapshotsPath, strconv.Itoa(snaps[i].Number), "snapshot")
+			path := SnapshotPath(root, snaps[i].Number)
 			err = sn.DeleteByPath(path)
 			if err != nil {
 				return fmt.Errorf("cleaning up snapshot '%s': %w", path, err)
@@ -262,6 +403,32 @@ func (sn Snapper) Cleanup(root string, maxSnaps int) error {
 	return nil
 }
 
+// Delete removes the given snapshot of the root configuration. The default and the
+// active snapshots can't be deleted.
+func (sn Snapper) Delete(root string, id int) error {
+	snaps, err := sn.ListSnapshots(root, rootConfig)
+	if err != nil {
+		return fmt.Errorf("listing snapshots: %w", err)
+	}
+	snap := snaps.Get(id)
+	switch {
+	case snap == nil:
+		return fmt.Errorf("snapshot %d not found", id)
+	case snap.Default:
+		return fmt.Errorf("snapshot %d is the default snapshot", id)
+	case snap.Active:
+		return fmt.Errorf("snapshot %d is the active snapshot", id)
+	}
+
+	sn.s.Logger().Info("Deleting snapshot %d", id)
+	path := SnapshotPath(root, id)
+	err = sn.DeleteByPath(path)
+	if err != nil {
+		return fmt.Errorf("deleting snapshot '%s': %w", path, err)
+	}
+	return nil
+}
+
 // DeleteByPath removes the given snapshot path including any nested RO subvolume
 func (sn Snapper) DeleteByPath(path string) error {
 	// TODO instead of relying on manual btrfs calls we could provide a snapper plugin
@@ -284,17 +451,10 @@ func (sn Snapper) ConfigureRoot(snapshotPath string, maxSnapshots int) error {
 		return fmt.Errorf("finding default snapper configuration template: %w", err)
 	}
 
-	sysconfigData := map[string]string{}
-	sysconfig := filepath.Join(snapshotPath, snapperDefaultConfig)
-	if ok, _ := vfs.Exists(sn.s.FS(), sysconfig); !ok {
-		sysconfig = filepath.Join(snapshotPath, snapperSysconfig)
-	}
-
-	if ok, _ := vfs.Exists(sn.s.FS(), sysconfig); ok {
-		sysconfigData, err = vfs.LoadEnvFile(sn.s.FS(), sysconfig)
-		if err != nil {
-			return fmt.Errorf("loading global snapper sysconfig: %w", err)
-		}
+	sysconfig := sn.sysconfigFile(snapshotPath)
+	sysconfigData, err := sn.loadSysconfig(sysconfig)
+	if err != nil {
+		return err
 	}
 	sysconfigData["SNAPPER_CONFIGS"] = rootConfig
 
@@ -323,6 +483,98 @@ func (sn Snapper) ConfigureRoot(snapshotPath string, maxSnapshots int) error {
 	return nil
 }
 
+// ConfigureVolume sets the snapper configuration of the given snapshotted volume path within the given
+// root. It is the offline equivalent of 'snapper create-config', only configuration files are written, the
+// snapshots directory of the volume is not created.
+func (sn Snapper) ConfigureVolume(root, volumePath string) error {
+	defaultTmpl, err := vfs.FindFile(sn.s.FS(), root, configTemplatesPaths()...)
+	if err != nil {
+		return fmt.Errorf("finding default snapper configuration template: %w", err)
+	}
+
+	snapCfg, err := vfs.LoadEnvFile(sn.s.FS(), defaultTmpl)
+	if err != nil {
+		return fmt.Errorf("loading default snapper configuration template: %w", err)
+	}
+	snapCfg["SUBVOLUME"] = volumePath
+	snapCfg["FSTYPE"] = "btrfs"
+
+	config := ConfigName(volumePath)
+	configFile := filepath.Join(root, snapperConfigsDir, config)
+	err = vfs.MkdirAll(sn.s.FS(), filepath.Dir(configFile), vfs.DirPerm)
+	if err != nil {
+		return fmt.Errorf("creating snapper configurations directory: %w", err)
+	}
+	sn.s.Logger().Debug("Creating '%s' snapper configuration at '%s'", config, configFile)
+	err = vfs.WriteEnvFile(sn.s.FS(), snapCfg, configFile)
+	if err != nil {
+		return fmt.Errorf("writing snapper '%s' configuration: %w", config, err)
+	}
+
+	sysconfig := sn.sysconfigFile(root)
+	sysconfigData, err := sn.loadSysconfig(sysconfig)
+	if err != nil {
+		return err
+	}
+	configs := strings.Fields(sysconfigData["SNAPPER_CONFIGS"])
+	if !slices.Contains(configs, config) {
+		sysconfigData["SNAPPER_CONFIGS"] = strings.Join(append(configs, config), " ")
+	}
+	err = vfs.WriteEnvFile(sn.s.FS(), sysconfigData, sysconfig)
+	if err != nil {
+		return fmt.Errorf("writing global snapper configuration: %w", err)
+	}
+	return nil
+}
+
+// UpdateConfig sets the given values to the snapper configuration of the given name within the given root
+func (sn Snapper) UpdateConfig(root, config string, values map[string]string) error {
+	configFile := filepath.Join(root, snapperConfigsDir, config)
+	snapCfg, err := vfs.LoadEnvFile(sn.s.FS(), configFile)
+	if err != nil {
+		return fmt.Errorf("loading snapper '%s' configuration: %w", config, err)
+	}
+	maps.Copy(snapCfg, values)
+	err = vfs.WriteEnvFile(sn.s.FS(), snapCfg, configFile)
+	if err != nil {
+		return fmt.Errorf("writing snapper '%s' configuration: %w", config, err)
+	}
+	return nil
+}
+
+// WriteSnapshotInfo writes the snapper metadata file of the given snapshot ID for the volume at the given path.
+// It registers an already existing snapshot without calling snapper, metadata keys with an empty value are omitted.
+func (sn Snapper) WriteSnapshotInfo(volumePath string, id int, description string, metadata Metadata) error {
+	info := snapshotInfo{
+		Type:        "single",
+		Number:      id,
+		Date:        reproducible.Now().Format(time.DateTime),
+		Description: description,
+	}
+	for _, k := range slices.Sorted(maps.Keys(metadata)) {
+		if metadata[k] != "" {
+			info.UserData = append(info.UserData, infoUserData{Key: k, Value: metadataEscaper.Replace(metadata[k])})
+		}
+	}
+
+	data, err := xml.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling snapshot %d metadata: %w", id, err)
+	}
+	data = append([]byte(xml.Header), append(data, '\n')...)
+
+	infoFile := filepath.Join(volumePath, SnapshotsPath, strconv.Itoa(id), "info.xml")
+	err = vfs.MkdirAll(sn.s.FS(), filepath.Dir(infoFile), vfs.DirPerm)
+	if err != nil {
+		return fmt.Errorf("creating snapshot %d directory: %w", id, err)
+	}
+	err = sn.s.FS().WriteFile(infoFile, data, vfs.FilePerm)
+	if err != nil {
+		return fmt.Errorf("writing snapshot %d metadata: %w", id, err)
+	}
+	return nil
+}
+
 func (sn Snapper) Status(root, config, output string, num1, num2 int) error {
 	args := []string{"--no-dbus"}
 
@@ -340,6 +592,27 @@ func (sn Snapper) Status(root, config, output string, num1, num2 int) error {
 	return nil
 }
 
+// sysconfigFile returns the path of the global snapper configuration within the given root
+func (sn Snapper) sysconfigFile(root string) string {
+	sysconfig := filepath.Join(root, snapperDefaultConfig)
+	if ok, _ := vfs.Exists(sn.s.FS(), sysconfig); !ok {
+		sysconfig = filepath.Join(root, snapperSysconfig)
+	}
+	return sysconfig
+}
+
+// loadSysconfig loads the given global snapper configuration, returns an empty configuration if it does not exist
+func (sn Snapper) loadSysconfig(sysconfig string) (map[string]string, error) {
+	if ok, _ := vfs.Exists(sn.s.FS(), sysconfig); !ok {
+		return map[string]string{}, nil
+	}
+	sysconfigData, err := vfs.LoadEnvFile(sn.s.FS(), sysconfig)
+	if err != nil {
+		return nil, fmt.Errorf("loading global snapper sysconfig: %w", err)
+	}
+	return sysconfigData, nil
+}
+
 func unmarshalSnapperList(snapperOut []byte, config string) (Snapshots, error) {
 	var objmap map[string]*json.RawMessage
 	err := json.Unmarshal(snapperOut, &objmap)
@@ -59,7 +59,10 @@ const snapperList = `{
       "default": false,
       "active": false,
       "userdata": {
-        "important": "no"
+        "important": "no",
+        "image": "oci://registry.org/my/os:v1",
+        "image-digest": "sha256:abcdef",
+        "elemental-version": "v1.0.0"
       }
     },
     {
@@ -194,6 +197,18 @@ var _ = Describe("Snapper", Label("snapper"), func() {
 		Expect(err).To(HaveOccurred())
 		Expect(err).To(MatchError("snapper modify failed"))
 	})
+	It("sets snapshot userdata", func() {
+		Expect(snap.SetMetadata("/some/root", 3, map[string]string{"staged": "yes", "key": ""})).To(Succeed())
+		Expect(runner.CmdsMatch([][]string{{
+			"snapper", "--no-dbus", "--root", "/some/root", "modify",
+			"--userdata", "key=,staged=yes", "3",
+		}})).To(Succeed())
+
+		runner.ReturnError = fmt.Errorf("snapper modify failed")
+		err := snap.SetMetadata("/some/root", 3, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err).To(MatchError("snapper modify failed"))
+	})
 	It("sets snapshot permissions", func() {
 		Expect(snap.SetPermissions("/some/root", 3, true)).To(Succeed())
 		Expect(runner.CmdsMatch([][]string{{
@@ -239,6 +254,8 @@ var _ = Describe("Snapper", Label("snapper"), func() {
 			Expect(snaps.GetActive()).To(Equal(192))
 			Expect(snaps.GetDefault()).To(Equal(192))
 			Expect(snaps.GetWithUserdata("important", "no")).To(Equal([]int{336}))
+			Expect(snaps.Get(337).UserData["important"]).To(Equal("yes"))
+			Expect(snaps.Get(5)).To(BeNil())
 		})
 		It("fails to list snapshots for a wrong configuration", func() {
 			runner.SideEffect = func(_ string, _ ...string) ([]byte, error) {
@@ -273,7 +290,7 @@ var _ = Describe("Snapper", Label("snapper"), func() {
 			Expect(snap.Cleanup("/some/root", 4)).To(Succeed())
 			Expect(runner.CmdsMatch([][]string{{
 				"snapper", "--no-dbus", "--root", "/some/root", "-c", "root",
-				"--jsonout", "list", "--columns", "number,default,active,userdata",
+				"--jsonout", "list", "--columns", "number,default,active,userdata,date,description",
 			}})).To(Succeed())
 		})
 		It("clears old snapshots until snapshots count is not higher than maximum", func() {
@@ -284,7 +301,7 @@ var _ = Describe("Snapper", Label("snapper"), func() {
 			Expect(runner.CmdsMatch([][]string{
 				{
 					"snapper", "--no-dbus", "--root", "/some/root", "-c", "root",
-					"--jsonout", "list", "--columns", "number,default,active,userdata",
+					"--jsonout", "list", "--columns", "number,default,active,userdata,date,description",
 				}, {"btrfs", "property"}, {"btrfs", "subvolume"}, {"btrfs", "property"}, {"btrfs", "subvolume"},
 			})).To(Succeed())
 		})
@@ -297,7 +314,7 @@ var _ = Describe("Snapper", Label("snapper"), func() {
 			Expect(err).To(MatchError("listing snapshots: collecting snapshots: <list-output>: listing failed"))
 			Expect(runner.CmdsMatch([][]string{{
 				"snapper", "--no-dbus", "--root", "/some/root", "-c", "root",
-				"--jsonout", "list", "--columns", "number,default,active,userdata",
+				"--jsonout", "list", "--columns", "number,default,active,userdata,date,description",
 			}})).To(Succeed())
 		})
 		It("fails to delete specific snapshot", func() {
@@ -316,13 +333,80 @@ var _ = Describe("Snapper", Label("snapper"), func() {
 			Expect(runner.CmdsMatch([][]string{
 				{
 					"snapper", "--no-dbus", "--root", "/some/root", "-c", "root",
-					"--jsonout", "list", "--columns", "number,default,active,userdata",
+					"--jsonout", "list", "--columns", "number,default,active,userdata,date,description",
 				},
 				{"btrfs", "property"},
 				{"btrfs", "subvolume", "delete"},
 			})).To(Succeed())
 		})
 	})
+	Describe("Delete", func() {
+		BeforeEach(func() {
+			runner.SideEffect = func(_ string, _ ...string) ([]byte, error) {
+				return []byte(snapperList), nil
+			}
+		})
+		It("deletes the given snapshot", func() {
+			Expect(snap.Delete("/some/root", 336)).To(Succeed())
+			Expect(runner.CmdsMatch([][]string{
+				{
+					"snapper", "--no-dbus", "--root", "/some/root", "-c", "root",
+					"--jsonout", "list", "--columns", "number,default,active,userdata,date,description",
+				},
+				{"btrfs", "property", "set", "-ts", "/some/root/.snapshots/336/snapshot", "ro", "false"},
+				{"btrfs", "subvolume", "delete", "-c", "-R", "/some/root/.snapshots/336/snapshot"},
+			})).To(Succeed())
+		})
+		It("refuses to delete the default snapshot", func() {
+			err := snap.Delete("/some/root", 192)
+			Expect(err).To(MatchError("snapshot 192 is the default snapshot"))
+		})
+		It("fails to delete a non existing snapshot", func() {
+			err := snap.Delete("/some/root", 5)
+			Expect(err).To(MatchError("snapshot 5 not found"))
+		})
+	})
+	Describe("GetProvenance", func() {
+		BeforeEach(func() {
+			runner.SideEffect = func(_ string, _ ...string) ([]byte, error) {
+				return []byte(snapperList), nil
+			}
+		})
+		It("returns the provenance recorded in the snapshot userdata", func() {
+			p, err := snap.GetProvenance("/some/root", 336)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*p).To(Equal(snapper.Provenance{
+				Image: "oci://registry.org/my/os:v1", Digest: "sha256:abcdef", Version: "v1.0.0",
+			}))
+		})
+		It("returns an empty provenance for snapshots without userdata", func() {
+			p, err := snap.GetProvenance("/some/root", 192)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.IsEmpty()).To(BeTrue())
+		})
+		It("fails for a non existing snapshot", func() {
+			_, err := snap.GetProvenance("/some/root", 5)
+			Expect(err).To(MatchError("snapshot 5 not found"))
+		})
+		It("omits empty fields from the userdata", func() {
+			p := snapper.Provenance{Image: "oci://registry.org/my/os:v1", Date: "2025-01-01T00:00:00Z"}
+			Expect(p.Metadata().String()).To(Equal("image=oci://registry.org/my/os:v1,timestamp=2025-01-01T00:00:00Z"))
+		})
+		It("escapes userdata separators within values", func() {
+			p := snapper.Provenance{Image: "oci://registry.org/my/os:v1?a=1,b=2%", Version: "v1.0.0"}
+			userdata := p.Metadata().String()
+			Expect(userdata).To(Equal("elemental-version=v1.0.0,image=oci://registry.org/my/os:v1?a%3D1%2Cb%3D2%25"))
+
+			runner.SideEffect = func(_ string, _ ...string) ([]byte, error) {
+				return []byte(`{"root": [{"number": 1, "default": true, "active": true, "userdata": {
+					"elemental-version": "v1.0.0", "image": "oci://registry.org/my/os:v1?a%3D1%2Cb%3D2%25"
+				}}]}`), nil
+			}
+			r, err := snap.GetProvenance("/some/root", 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*r).To(Equal(p))
+		})
+	})
 	Describe("ConfigureRoot", func() {
 		It("creates a root configuration", func() {
 			rootDir := "/some/root"
@@ -344,4 +428,48 @@ var _ = Describe("Snapper", Label("snapper"), func() {
 			Expect(envMap["NUMBER_LIMIT"]).To(Equal("1-4"))
 		})
 	})
+	Describe("ConfigureVolume", func() {
+		It("creates a volume configuration and registers it", func() {
+			rootDir := "/some/root"
+			sysconfig := filepath.Join(rootDir, "/etc/sysconfig/snapper")
+			template := filepath.Join(rootDir, "/usr/share/snapper/config-templates/default")
+			Expect(vfs.MkdirAll(fs, filepath.Dir(sysconfig), vfs.DirPerm)).To(Succeed())
+			Expect(fs.WriteFile(sysconfig, []byte("SNAPPER_CONFIGS=\"root\"\n"), vfs.FilePerm)).To(Succeed())
+			Expect(vfs.MkdirAll(fs, filepath.Dir(template), vfs.DirPerm)).To(Succeed())
+			Expect(fs.WriteFile(template, []byte("QGROUP=\"\"\n"), vfs.FilePerm)).To(Succeed())
+			Expect(snap.ConfigureVolume(rootDir, "/etc")).To(Succeed())
+			Expect(snap.ConfigureVolume(rootDir, "/etc")).To(Succeed())
+
+			envMap, err := vfs.LoadEnvFile(fs, filepath.Join(rootDir, "/etc/snapper/configs/etc"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envMap["SUBVOLUME"]).To(Equal("/etc"))
+			Expect(envMap["FSTYPE"]).To(Equal("btrfs"))
+			envMap, err = vfs.LoadEnvFile(fs, sysconfig)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envMap["SNAPPER_CONFIGS"]).To(Equal("root etc"))
+
+			Expect(snap.UpdateConfig(rootDir, "etc", map[string]string{"QGROUP": "1/0"})).To(Succeed())
+			envMap, err = vfs.LoadEnvFile(fs, filepath.Join(rootDir, "/etc/snapper/configs/etc"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envMap["QGROUP"]).To(Equal("1/0"))
+			Expect(envMap["SUBVOLUME"]).To(Equal("/etc"))
+		})
+		It("fails if there is no configuration template", func() {
+			Expect(snap.ConfigureVolume("/some/root", "/etc")).NotTo(Succeed())
+		})
+	})
+	Describe("WriteSnapshotInfo", func() {
+		It("writes the snapshot metadata file", func() {
+			Expect(snap.WriteSnapshotInfo("/some/volume", 2, "stock contents", snapper.Metadata{
+				"stock": "true", "empty": "",
+			})).To(Succeed())
+			data, err := fs.ReadFile("/some/volume/.snapshots/2/info.xml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring("<num>2</num>"))
+			Expect(string(data)).To(ContainSubstring("<description>stock contents</description>"))
+			Expect(string(data)).To(ContainSubstring("<key>stock</key>"))
+			Expect(string(data)).To(ContainSubstring("<value>true</value>"))
+			Expect(string(data)).NotTo(ContainSubstring("empty"))
+		})
+	})
 })
@@ -22,6 +22,7 @@ import (
 	"os/exec"
 	"runtime"
 
+	"github.com/suse/elemental/v3/pkg/events"
 	"github.com/suse/elemental/v3/pkg/log"
 	"github.com/suse/elemental/v3/pkg/sys/mounter"
 	"github.com/suse/elemental/v3/pkg/sys/platform"
@@ -49,6 +50,7 @@ type System struct {
 	runner   Runner
 	syscall  Syscall
 	platform *platform.Platform
+	events   *events.Emitter
 }
 
 type SystemOpts func(a *System) error
@@ -99,6 +101,14 @@ func WithPlatform(pf string) SystemOpts {
 	}
 }
 
+// WithEvents sets the emitter reporting the progress of the operations
+func WithEvents(e *events.Emitter) SystemOpts {
+	return func(s *System) error {
+		s.events = e
+		return nil
+	}
+}
+
 func NewSystem(opts ...SystemOpts) (*System, error) {
 	logger := log.New()
 	sysObj := &System{
@@ -154,6 +164,12 @@ func (s System) Logger() log.Logger {
 	return s.logger
 }
 
+// Events returns the emitter of the system, it is nil, which discards all events, unless
+// it was set with WithEvents
+func (s System) Events() *events.Emitter {
+	return s.events
+}
+
 // CommandExists
 func CommandExists(command string) bool {
 	_, err := exec.LookPath(command)
@@ -18,6 +18,8 @@ limitations under the License.
 package vfs
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -566,6 +568,21 @@ func CopyFile(fs FS, source string, target string) error {
 	return ConcatFiles(fs, []string{source}, target)
 }
 
+// FileDigest returns the 'sha256:<hex>' digest of the given file
+func FileDigest(fs FS, file string) (string, error) {
+	f, err := fs.Open(file)
+	if err != nil {
+		return "", fmt.Errorf("opening '%s': %w", file, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("computing digest of '%s': %w", file, err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // ConcatFiles copies source files to a target file using the FS interface.
 // Source files are concatenated into the target file in the given order.
 // If the target is a directory, the source is copied into that directory using
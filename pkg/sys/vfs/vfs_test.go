@@ -273,6 +273,18 @@ var _ = Describe("FS", Label("fs"), func() {
 			Expect(foundPaths).To(Equal(currentPaths))
 		})
 	})
+	Describe("FileDigest", func() {
+		It("Returns the sha256 digest of the given file", func() {
+			Expect(tfs.WriteFile("/file", []byte("content"), vfs.FilePerm)).To(Succeed())
+			digest, err := vfs.FileDigest(tfs, "/file")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(digest).To(Equal("sha256:ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"))
+		})
+		It("Fails for a non existing file", func() {
+			_, err := vfs.FileDigest(tfs, "/nonexisting")
+			Expect(err).To(MatchError(ContainSubstring("opening '/nonexisting'")))
+		})
+	})
 	Describe("CopyFile", func() {
 		It("Copies source file to target file", func() {
 			err := vfs.MkdirAll(tfs, "/some", vfs.DirPerm)
@@ -0,0 +1,272 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transaction
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/snapper"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+type ChangeType string
+
+const (
+	// ChangeAdded is a new file coming from the OS image
+	ChangeAdded ChangeType = "added"
+	// ChangeOverwritten is a file updated by the OS image without local modifications
+	ChangeOverwritten ChangeType = "overwritten"
+	// ChangeRemoved is a file removed by the OS image without local modifications
+	ChangeRemoved ChangeType = "removed"
+	// ChangeKept is a local modification kept as is, the OS image did not change the file
+	ChangeKept ChangeType = "kept"
+	// ChangeConflict is a file modified both locally and by the OS image, it is solved
+	// according to the merge policy of the volume
+	ChangeConflict ChangeType = "conflict"
+)
+
+// FileChange is the merge outcome for a single file
+type FileChange struct {
+	Path   string     `json:"path"`
+	Change ChangeType `json:"change"`
+}
+
+// VolumeReport lists the merge outcome of all changed files within a snapshotted RW volume
+type VolumeReport struct {
+	Path        string                 `json:"path"`
+	MergePolicy deployment.MergePolicy `json:"mergePolicy"`
+	Changes     []FileChange           `json:"changes"`
+}
+
+// MergeReport describes what an upgrade changes in the snapshotted RW volumes
+type MergeReport struct {
+	Volumes []VolumeReport `json:"volumes"`
+}
+
+// IsEmpty returns true if the report does not include any change
+func (r MergeReport) IsEmpty() bool {
+	for _, vol := range r.Volumes {
+		if len(vol.Changes) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MergeReport computes the outcome of the three way merge of snapshotted RW volumes without applying
+// any change. It compares local modifications with the changes introduced by the new OS image, hence
+// it must be called after syncing the image content and before merging.
+func (sc snapperContext) MergeReport(trans *Transaction) (report *MergeReport, err error) {
+	defer func() { err = sc.checkCancelled(err) }()
+	if trans.status != started {
+		return nil, fmt.Errorf("transaction '%d' is not started", trans.ID)
+	}
+
+	tmpDir, err := vfs.TempDir(sc.s.FS(), "", "snapStatus")
+	if err != nil {
+		return nil, fmt.Errorf("failed creating temporary directory to store snapper output: %w", err)
+	}
+	defer func() {
+		e := sc.s.FS().RemoveAll(tmpDir)
+		if err == nil {
+			err = e
+		}
+	}()
+
+	report = &MergeReport{}
+	for _, rwVol := range sc.partitions.GetSnapshottedVolumes() {
+		m := trans.Merges[rwVol.Path]
+		if m == nil {
+			continue
+		}
+
+		status := filepath.Join(tmpDir, fmt.Sprintf("snap_status_%s", snapper.ConfigName(rwVol.Path)))
+		err = sc.customChangesStatus(rwVol.Path, m, status)
+		if err != nil {
+			return nil, err
+		}
+
+		changes, err := sc.parseStatus(status, rwVol.Path)
+		if err != nil {
+			return nil, fmt.Errorf("reading custom changes of '%s': %w", rwVol.Path, err)
+		}
+		local := map[string]bool{}
+		for _, change := range changes {
+			local[change.path] = true
+		}
+
+		image, err := imageChanges(sc.s.FS(), m.Old, filepath.Join(trans.Path, rwVol.Path))
+		if err != nil {
+			return nil, fmt.Errorf("comparing image content of '%s': %w", rwVol.Path, err)
+		}
+
+		report.Volumes = append(report.Volumes, VolumeReport{
+			Path:        rwVol.Path,
+			MergePolicy: rwVol.GetMergePolicy(),
+			Changes:     mergeChanges(rwVol.Path, local, image),
+		})
+	}
+	return report, nil
+}
+
+// imageChanges compares the old stock tree with the new image tree and returns the changed
+// paths, relative to the given trees, with their change type.
+func imageChanges(fsys vfs.FS, oldRoot, newRoot string) (map[string]ChangeType, error) {
+	changes := map[string]ChangeType{}
+
+	walk := func(root string, fn func(rel, path string) error) error {
+		return walkFiles(fsys, root, func(path string) error {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			return fn(filepath.Join("/", rel), path)
+		})
+	}
+
+	err := walk(newRoot, func(rel, path string) error {
+		same, err := sameFile(fsys, filepath.Join(oldRoot, rel), path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			changes[rel] = ChangeAdded
+		case err != nil:
+			return err
+		case !same:
+			changes[rel] = ChangeOverwritten
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = walk(oldRoot, func(rel, _ string) error {
+		_, err := fsys.Lstat(filepath.Join(newRoot, rel))
+		if errors.Is(err, fs.ErrNotExist) {
+			changes[rel] = ChangeRemoved
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// imageChanged checks if the given path, relative to the given trees, differs between the old stock
+// tree and the new image tree
+func imageChanged(fsys vfs.FS, oldRoot, newRoot, path string) (bool, error) {
+	same, err := sameFile(fsys, filepath.Join(oldRoot, path), filepath.Join(newRoot, path))
+	if errors.Is(err, fs.ErrNotExist) {
+		oldExists, _ := vfs.Exists(fsys, filepath.Join(oldRoot, path))
+		newExists, _ := vfs.Exists(fsys, filepath.Join(newRoot, path))
+		return oldExists != newExists, nil
+	} else if err != nil {
+		return false, err
+	}
+	return !same, nil
+}
+
+// walkFiles calls fn for every non directory entry within root, nested snapshots are skipped
+func walkFiles(fsys vfs.FS, root string, fn func(path string) error) error {
+	return vfs.WalkDirFs(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == snapper.SnapshotsPath {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+// sameFile checks if both paths have the same type, permissions and content. Returns an error
+// if the first path does not exist.
+func sameFile(fsys vfs.FS, a, b string) (bool, error) {
+	aInfo, err := fsys.Lstat(a)
+	if err != nil {
+		return false, err
+	}
+	bInfo, err := fsys.Lstat(b)
+	if err != nil {
+		return false, err
+	}
+	if aInfo.Mode() != bInfo.Mode() {
+		return false, nil
+	}
+
+	if aInfo.Mode()&fs.ModeSymlink != 0 {
+		aLink, err := fsys.Readlink(a)
+		if err != nil {
+			return false, err
+		}
+		bLink, err := fsys.Readlink(b)
+		if err != nil {
+			return false, err
+		}
+		return aLink == bLink, nil
+	}
+
+	if !aInfo.Mode().IsRegular() {
+		return true, nil
+	}
+	if aInfo.Size() != bInfo.Size() {
+		return false, nil
+	}
+	aData, err := fsys.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+	bData, err := fsys.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(aData, bData), nil
+}
+
+// mergeChanges combines local and image changes into the merge outcome. Paths changed on both
+// sides are reported as conflicts.
+func mergeChanges(rwVolPath string, local map[string]bool, image map[string]ChangeType) []FileChange {
+	changes := []FileChange{}
+	for path := range local {
+		change := ChangeKept
+		if _, ok := image[path]; ok {
+			change = ChangeConflict
+		}
+		changes = append(changes, FileChange{Path: filepath.Join(rwVolPath, path), Change: change})
+	}
+	for path, change := range image {
+		if local[path] {
+			continue
+		}
+		changes = append(changes, FileChange{Path: filepath.Join(rwVolPath, path), Change: change})
+	}
+	slices.SortFunc(changes, func(a, b FileChange) int { return strings.Compare(a.Path, b.Path) })
+	return changes
+}
@@ -28,16 +28,20 @@ type Transactioner struct {
 	StartErr          error
 	CommitErr         error
 	RollbackErr       error
+	StageErr          error
+	DiscardErr        error
+	Staged            *transaction.Transaction
 	Trans             *transaction.Transaction
 	UpgradeHelper     UpgradeHelper
 	SrcDigest         string
+	ActiveSnapshotIDs []int
 	rollbackCalled    bool
-	activeSnapshotIDs []int
 }
 
 type UpgradeHelper struct {
 	SyncError     error
 	MergeError    error
+	Report        *transaction.MergeReport
 	FstabError    error
 	LockError     error
 	srcDigest     string
@@ -53,6 +57,13 @@ func (u UpgradeHelper) Merge(_ *transaction.Transaction) error {
 	return u.MergeError
 }
 
+func (u UpgradeHelper) MergeReport(_ *transaction.Transaction) (*transaction.MergeReport, error) {
+	if u.Report == nil {
+		return &transaction.MergeReport{}, u.MergeError
+	}
+	return u.Report, u.MergeError
+}
+
 func (u UpgradeHelper) UpdateFstab(_ *transaction.Transaction) error {
 	return u.FstabError
 }
@@ -87,10 +98,22 @@ func (t *Transactioner) Rollback(_ *transaction.Transaction, _ error) error {
 	return t.RollbackErr
 }
 
+func (t Transactioner) Stage(_ *transaction.Transaction, _ func() error) error {
+	return t.StageErr
+}
+
+func (t Transactioner) GetStaged() (*transaction.Transaction, error) {
+	return t.Staged, nil
+}
+
+func (t Transactioner) Discard(_ *transaction.Transaction) error {
+	return t.DiscardErr
+}
+
 func (t Transactioner) RollbackCalled() bool {
 	return t.rollbackCalled
 }
 
 func (t Transactioner) GetActiveSnapshotIDs() ([]int, error) {
-	return t.activeSnapshotIDs, nil
+	return t.ActiveSnapshotIDs, nil
 }
@@ -26,6 +26,7 @@ import (
 	"github.com/suse/elemental/v3/pkg/cleanstack"
 	"github.com/suse/elemental/v3/pkg/deployment"
 	"github.com/suse/elemental/v3/pkg/fstab"
+	"github.com/suse/elemental/v3/pkg/luks"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
 	"github.com/suse/elemental/v3/pkg/unpack"
@@ -113,7 +114,12 @@ func (n Overwrite) MountPartition(root string, hwParts block.PartitionList, p *d
 		return fmt.Errorf("failed creating mountpoint %s: %w", target, err)
 	}
 
-	err := n.s.Mounter().Mount(dev.Path, target, p.FileSystem.String(), p.MountOpts)
+	device, err := luks.DevicePath(n.s, p, dev.Path)
+	if err != nil {
+		return err
+	}
+
+	err = n.s.Mounter().Mount(device, target, p.FileSystem.String(), p.MountOpts)
 	if err != nil {
 		return fmt.Errorf("failed mounting partition '%s': %w", p.Label, err)
 	}
@@ -130,6 +136,18 @@ func (n Overwrite) Rollback(*Transaction, error) error {
 	return fmt.Errorf("cannot rollback transactions using 'overwrite' snapshotter")
 }
 
+func (n Overwrite) Stage(*Transaction, func() error) error {
+	return fmt.Errorf("cannot stage transactions using 'overwrite' snapshotter")
+}
+
+func (n Overwrite) GetStaged() (*Transaction, error) {
+	return nil, nil
+}
+
+func (n Overwrite) Discard(*Transaction) error {
+	return fmt.Errorf("cannot discard transactions using 'overwrite' snapshotter")
+}
+
 func (n Overwrite) GetActiveSnapshotIDs() ([]int, error) {
 	return []int{0}, nil
 }
@@ -158,6 +176,10 @@ func (n Overwrite) Merge(*Transaction) error {
 	return nil
 }
 
+func (n Overwrite) MergeReport(*Transaction) (*MergeReport, error) {
+	return &MergeReport{}, nil
+}
+
 func (n Overwrite) UpdateFstab(trans *Transaction) error {
 	lines := []fstab.Line{}
 	sysDisk := n.d.GetSystemDisk()
@@ -167,7 +189,7 @@ func (n Overwrite) UpdateFstab(trans *Transaction) error {
 
 	for _, part := range sysDisk.Partitions {
 		lines = append(lines, fstab.Line{
-			Device:     fmt.Sprintf("PARTUUID=%s", part.UUID),
+			Device:     part.FstabDevice(),
 			MountPoint: part.MountPoint,
 			Options:    part.MountOpts,
 			FileSystem: part.FileSystem.String(),
@@ -175,7 +197,11 @@ func (n Overwrite) UpdateFstab(trans *Transaction) error {
 
 	}
 	fstabFile := filepath.Join(trans.Path, fstab.File)
-	return fstab.Write(n.s, fstabFile, lines)
+	err := fstab.Write(n.s, fstabFile, lines)
+	if err != nil {
+		return err
+	}
+	return writeCrypttab(n.s, trans.Path, sysDisk.Partitions)
 }
 
 func (n Overwrite) Lock(*Transaction) error {
@@ -23,20 +23,27 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/suse/elemental/v3/pkg/block"
 	"github.com/suse/elemental/v3/pkg/block/lsblk"
 	"github.com/suse/elemental/v3/pkg/btrfs"
 	"github.com/suse/elemental/v3/pkg/cleanstack"
 	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/luks"
+	"github.com/suse/elemental/v3/pkg/reproducible"
 	"github.com/suse/elemental/v3/pkg/snapper"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
+	"github.com/suse/elemental/v3/pkg/version"
 )
 
 const (
 	snapshotPathTmpl = ".snapshots/%d/snapshot"
 	updateProgress   = "update-in-progress"
+	stagedKey        = "staged"
 	maxSnapshots     = 8
 )
 
@@ -128,19 +135,30 @@ func (sn snapperT) Start() (trans *Transaction, err error) {
 func (sn snapperT) Commit(trans *Transaction, cleanup func() error) (err error) {
 	defer func() { err = sn.checkCancelled(err) }()
 
-	if trans.status != started {
+	if trans.status != started && trans.status != staged {
 		return fmt.Errorf("transaction '%d' is not started", trans.ID)
 	}
 	sn.s.Logger().Info("Committing transaction")
 
-	sn.s.Logger().Info("Creating post-transaction snapshots")
-	err = sn.createPostSnapshots(trans.Path)
-	if err != nil {
-		return fmt.Errorf("creating post transaction snapshots: %w", err)
+	// Staged transactions are no longer mounted and already include post-transaction snapshots
+	root := sn.rootDir
+	if trans.status == staged {
+		sn.warnChangesSinceStaged()
+	} else {
+		root = trans.Path
+		sn.s.Logger().Info("Creating post-transaction snapshots")
+		err = sn.createPostSnapshots(trans.Path)
+		if err != nil {
+			return fmt.Errorf("creating post transaction snapshots: %w", err)
+		}
 	}
 
+	metadata := sn.provenance(trans).Metadata()
+	metadata[updateProgress] = ""
+	metadata[stagedKey] = ""
+
 	sn.s.Logger().Info("Setting new default snapshot")
-	err = sn.snap.SetDefault(trans.Path, trans.ID, map[string]string{updateProgress: ""})
+	err = sn.snap.SetDefault(root, trans.ID, metadata)
 	if err != nil {
 		return fmt.Errorf("setting new default snapshot: %w", err)
 	}
@@ -161,6 +179,166 @@ func (sn snapperT) Commit(trans *Transaction, cleanup func() error) (err error)
 	return err
 }
 
+// Stage closes the current transaction without setting it as the default snapshot. The
+// snapshot is flagged as staged so it can be committed or discarded later on.
+func (sn snapperT) Stage(trans *Transaction, cleanup func() error) (err error) {
+	defer func() { err = sn.checkCancelled(err) }()
+
+	if trans.status != started {
+		return fmt.Errorf("transaction '%d' is not started", trans.ID)
+	}
+	sn.s.Logger().Info("Staging transaction")
+
+	sn.s.Logger().Info("Creating post-transaction snapshots")
+	err = sn.createPostSnapshots(trans.Path)
+	if err != nil {
+		return fmt.Errorf("creating post transaction snapshots: %w", err)
+	}
+
+	err = sn.snap.SetMetadata(trans.Path, trans.ID, map[string]string{updateProgress: "", stagedKey: "yes"})
+	if err != nil {
+		return fmt.Errorf("flagging snapshot as staged: %w", err)
+	}
+	trans.status = staged
+	if cleanup != nil {
+		sn.cleanStack.Push(cleanup)
+	}
+
+	err = sn.cleanStack.Cleanup(err)
+	if err != nil {
+		sn.s.Logger().Error("transaction cleanup procedure failed after staging")
+	}
+	sn.s.Logger().Info("Transaction staged")
+
+	return err
+}
+
+// warnChangesSinceStaged warns about changes done in snapshotted RW volumes of the system partition since
+// the transaction was staged. Those changes are not merged into the staged snapshot, hence they are lost
+// once it is applied.
+func (sn snapperT) warnChangesSinceStaged() {
+	tmpDir, err := vfs.TempDir(sn.s.FS(), "", "snapStatus")
+	if err != nil {
+		sn.s.Logger().Warn("Could not check changes since the transaction was staged: %v", err)
+		return
+	}
+	defer func() { _ = sn.s.FS().RemoveAll(tmpDir) }()
+
+	root := filepath.Join(sn.rootDir, fmt.Sprintf(snapshotPathTmpl, sn.defaultID))
+	for _, part := range sn.partitions {
+		if part.Role != deployment.System {
+			continue
+		}
+		for _, rwVol := range part.RWVolumes {
+			if !rwVol.Snapshotted {
+				continue
+			}
+			changes, err := sn.changesSincePreTransaction(root, tmpDir, rwVol.Path)
+			if err != nil {
+				sn.s.Logger().Warn("Could not check changes of '%s' since the transaction was staged: %v", rwVol.Path, err)
+				continue
+			}
+			var paths []string
+			for _, change := range changes {
+				paths = append(paths, filepath.Join(rwVol.Path, change.path))
+			}
+			slices.Sort(paths)
+			if len(paths) > 0 {
+				sn.s.Logger().Warn(
+					"Changes done in '%s' after staging the transaction are not included and will be lost: %s",
+					rwVol.Path, strings.Join(paths, ", "),
+				)
+			}
+		}
+	}
+}
+
+// changesSincePreTransaction returns the changes of the given snapshotted RW volume between its latest
+// pre-transaction snapshot and its current content.
+func (sn snapperT) changesSincePreTransaction(root, tmpDir, volPath string) ([]customChange, error) {
+	config := snapper.ConfigName(volPath)
+	snaps, err := sn.snap.ListSnapshots(root, config)
+	if err != nil {
+		return nil, err
+	}
+	ids := snaps.GetWithUserdata("pre-transaction", "true")
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no pre-transaction snapshot found")
+	}
+	status := filepath.Join(tmpDir, fmt.Sprintf("snap_status_%s", config))
+	err = sn.snap.Status(root, config, status, slices.Max(ids), 0)
+	if err != nil {
+		return nil, err
+	}
+	return sn.parseStatus(status, volPath)
+}
+
+// GetStaged returns the staged transaction, if any.
+func (sn snapperT) GetStaged() (*Transaction, error) {
+	if sn.defaultID == 0 {
+		// Nothing can be staged before the first snapshot is committed
+		return nil, nil
+	}
+	snaps, err := sn.snap.ListSnapshots(sn.rootDir, "root")
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshots: %w", err)
+	}
+
+	ids := snaps.GetWithUserdata(stagedKey, "yes")
+	switch len(ids) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &Transaction{
+			ID:     ids[0],
+			Path:   filepath.Join(sn.rootDir, fmt.Sprintf(snapshotPathTmpl, ids[0])),
+			status: staged,
+		}, nil
+	default:
+		return nil, fmt.Errorf("inconsistent number of staged snapshots: %d", len(ids))
+	}
+}
+
+// Discard deletes the given staged transaction.
+func (sn snapperT) Discard(trans *Transaction) error {
+	if trans.status != staged {
+		return fmt.Errorf("transaction '%d' is not staged", trans.ID)
+	}
+	sn.s.Logger().Info("Discarding staged transaction %d", trans.ID)
+	err := sn.snap.DeleteByPath(trans.Path)
+	if err != nil {
+		return fmt.Errorf("deleting staged snapshot: %w", err)
+	}
+	trans.status = failed
+	return nil
+}
+
+// provenance collects the image sources of the given transaction from the deployment file stored
+// in the new snapshot. The image digest is only known once the image content is synced.
+func (sc snapperContext) provenance(trans *Transaction) snapper.Provenance {
+	p := snapper.Provenance{
+		Version: version.Get(),
+		Date:    reproducible.Now().Format(time.RFC3339),
+	}
+
+	d, err := deployment.Parse(sc.s, trans.Path)
+	if err != nil {
+		sc.s.Logger().Warn("Could not parse deployment of transaction %d: %v", trans.ID, err)
+		return p
+	} else if d == nil {
+		return p
+	}
+
+	if d.SourceOS != nil {
+		p.Image = d.SourceOS.String()
+		p.Digest = d.SourceOS.GetDigest()
+	}
+	if d.OverlayTree != nil && !d.OverlayTree.IsEmpty() {
+		p.Overlay = d.OverlayTree.String()
+	}
+	return p
+}
+
 // Rollback closes the given in progress transaction by deleting the
 // associated resources. This is a cleanup method in case occurs during a transaction.
 func (sn snapperT) Rollback(trans *Transaction, e error) (err error) {
@@ -168,7 +346,11 @@ func (sn snapperT) Rollback(trans *Transaction, e error) (err error) {
 		sn.s.Logger().Warn("cannot rollback a committed transaction")
 		return e
 	}
-	sn.s.Logger().Error("Closing transaction due to a failure: %v", e)
+	if e != nil {
+		sn.s.Logger().Error("Closing transaction due to a failure: %v", e)
+	} else {
+		sn.s.Logger().Info("Discarding transaction")
+	}
 	err = sn.cleanStack.Cleanup(e)
 	err = errors.Join(err, sn.snap.DeleteByPath(trans.Path))
 	trans.status = failed
@@ -207,11 +389,16 @@ func (sn *snapperT) probe(d deployment.Deployment) (err error) {
 		return fmt.Errorf("system partition not found: %+v", sysPart)
 	}
 
-	mountPoints, err := sn.s.Mounter().GetMountPoints(part.Path)
+	device, err := luks.DevicePath(sn.s, sysPart, part.Path)
+	if err != nil {
+		return err
+	}
+
+	mountPoints, err := sn.s.Mounter().GetMountPoints(device)
 	if err != nil {
 		return fmt.Errorf("getting mount points: %w", err)
 	} else if len(mountPoints) == 0 {
-		return fmt.Errorf("no mountpoints found for device '%s'", part.Path)
+		return fmt.Errorf("no mountpoints found for device '%s'", device)
 	}
 
 	r := regexp.MustCompile(fmt.Sprintf(`%s/.snapshots/\d+/snapshot$`, btrfs.TopSubVol))
@@ -265,7 +452,11 @@ func (sn snapperT) mountPartition(part *deployment.Partition, mountPoint string)
 	if bPart == nil {
 		return fmt.Errorf("partition '%s' not found", part.UUID)
 	}
-	err = sn.s.Mounter().Mount(bPart.Path, mountPoint, "", []string{"rw"})
+	device, err := luks.DevicePath(sn.s, part, bPart.Path)
+	if err != nil {
+		return err
+	}
+	err = sn.s.Mounter().Mount(device, mountPoint, "", []string{"rw"})
 	if err != nil {
 		return fmt.Errorf("mounting partition at '%s': %w", mountPoint, err)
 	}
@@ -297,12 +488,16 @@ func (sn snapperT) mountVol(part *deployment.Partition, volumePath, mountPoint s
 	if bPart == nil {
 		return fmt.Errorf("partition '%s' not found", part.UUID)
 	}
-	err := vfs.MkdirAll(sn.s.FS(), mountPoint, vfs.DirPerm)
+	device, err := luks.DevicePath(sn.s, part, bPart.Path)
+	if err != nil {
+		return err
+	}
+	err = vfs.MkdirAll(sn.s.FS(), mountPoint, vfs.DirPerm)
 	if err != nil {
 		return fmt.Errorf("creating mountpoint at '%s': %w", mountPoint, err)
 	}
 	err = sn.s.Mounter().Mount(
-		bPart.Path, mountPoint, "",
+		device, mountPoint, "",
 		[]string{"rw", fmt.Sprintf("subvol=%s", filepath.Join(btrfs.TopSubVol, volumePath))},
 	)
 	if err != nil {
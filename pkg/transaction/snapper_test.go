@@ -18,12 +18,16 @@ limitations under the License.
 package transaction_test
 
 import (
+	"bytes"
 	"fmt"
 	"slices"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
 	"github.com/suse/elemental/v3/pkg/transaction"
 )
 
@@ -60,6 +64,33 @@ var _ = Describe("SnapperTransaction", Label("transaction"), func() {
 					{"snapper", "--no-dbus", "--root", "/some/root/@/.snapshots/1/snapshot", "modify", "--default"},
 				})).To(Succeed())
 			})
+			It("records the image provenance as snapshot userdata", func() {
+				sideEffects["snapper"] = func(args ...string) ([]byte, error) {
+					if slices.Contains(args, "create") {
+						return []byte("2\n"), nil
+					}
+					if slices.Contains(args, "list") {
+						return []byte(installSnapList), nil
+					}
+					return runner.ReturnValue, runner.ReturnError
+				}
+				imgsrc.SetDigest("sha256:abcdef")
+				d.SourceOS = imgsrc
+				Expect(d.WriteDeploymentFile(s, trans.Path)).To(Succeed())
+				Expect(sn.Commit(trans, nil)).To(Succeed())
+
+				var userdata string
+				for _, cmd := range runner.GetCmds() {
+					if slices.Contains(cmd, "modify") {
+						userdata = cmd[slices.Index(cmd, "--userdata")+1]
+					}
+				}
+				Expect(userdata).To(ContainSubstring(fmt.Sprintf("image=%s", imgsrc.String())))
+				Expect(userdata).To(ContainSubstring("image-digest=sha256:abcdef"))
+				Expect(userdata).To(ContainSubstring("elemental-version="))
+				Expect(userdata).To(ContainSubstring("timestamp="))
+				Expect(userdata).To(ContainSubstring("update-in-progress="))
+			})
 			It("commit fails if the callback returns error", func() {
 				commitCallback := func() error {
 					return fmt.Errorf("commit callback failed")
@@ -178,6 +209,98 @@ var _ = Describe("SnapperTransaction", Label("transaction"), func() {
 				})).To(Succeed())
 			})
 		})
+		It("stages a transaction", func() {
+			trans = startUpgradeTransaction()
+			sideEffects["snapper"] = func(args ...string) ([]byte, error) {
+				if slices.Contains(args, "create") {
+					return []byte("2\n"), nil
+				}
+				return runner.ReturnValue, runner.ReturnError
+			}
+			Expect(sn.Stage(trans, nil)).To(Succeed())
+			Expect(trans.IsStaged()).To(BeTrue())
+			Expect(runner.MatchMilestones([][]string{
+				{"snapper", "--no-dbus", "--root", "/.snapshots/5/snapshot", "modify", "--userdata", "staged=yes,update-in-progress=", "5"},
+			})).To(Succeed())
+			Expect(runner.IncludesCmds([][]string{{"snapper", "--no-dbus", "modify", "--default"}})).NotTo(Succeed())
+		})
+		Describe("with a staged snapshot", func() {
+			BeforeEach(func() {
+				sideEffects["snapper"] = func(args ...string) ([]byte, error) {
+					if slices.Contains(args, "list") {
+						return []byte(stagedSnapList), nil
+					}
+					return runner.ReturnValue, runner.ReturnError
+				}
+			})
+			It("returns the staged transaction", func() {
+				trans, err = sn.GetStaged()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(trans).NotTo(BeNil())
+				Expect(trans.ID).To(Equal(5))
+				Expect(trans.Path).To(Equal("/.snapshots/5/snapshot"))
+				Expect(trans.IsStaged()).To(BeTrue())
+			})
+			It("commits the staged transaction", func() {
+				trans, err = sn.GetStaged()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sn.Commit(trans, nil)).To(Succeed())
+				Expect(runner.MatchMilestones([][]string{
+					{"snapper", "--no-dbus", "modify", "--default", "--userdata"},
+				})).To(Succeed())
+				Expect(runner.IncludesCmds([][]string{{"snapper", "--no-dbus", "--root", "/.snapshots/5/snapshot", "-c", "etc", "create"}})).NotTo(Succeed())
+			})
+			It("warns about changes in snapshotted volumes done after staging the transaction", func() {
+				buf := &bytes.Buffer{}
+				s, err = sys.NewSystem(
+					sys.WithFS(tfs), sys.WithLogger(log.New(log.WithBuffer(buf))), sys.WithSyscall(syscall),
+					sys.WithRunner(runner), sys.WithMounter(mount),
+				)
+				Expect(err).NotTo(HaveOccurred())
+				runner.ClearCmds()
+				_ = initSnapperUpgrade(root)
+				sideEffects["snapper"] = func(args ...string) ([]byte, error) {
+					switch {
+					case slices.Contains(args, "list") && slices.Contains(args, "etc"):
+						return []byte(etcSnapList), nil
+					case slices.Contains(args, "list"):
+						return []byte(stagedSnapList), nil
+					case slices.Contains(args, "status"):
+						output := args[slices.Index(args, "--output")+1]
+						return nil, tfs.WriteFile(output, []byte(snapperStatus), vfs.FilePerm)
+					}
+					return runner.ReturnValue, runner.ReturnError
+				}
+				trans, err = sn.GetStaged()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sn.Commit(trans, nil)).To(Succeed())
+				Expect(runner.IncludesCmds([][]string{
+					{"snapper", "--no-dbus", "--root", "/.snapshots/4/snapshot", "-c", "etc", "status", "--output"},
+				})).To(Succeed())
+				Expect(runner.GetCmds()).To(ContainElement(ContainElement("3..0")))
+				Expect(buf.String()).To(ContainSubstring(
+					"Changes done in '/etc' after staging the transaction are not included and will be lost: " +
+						"/etc/createdFile, /etc/deletedFile, /etc/modifiedFile",
+				))
+			})
+			It("discards the staged transaction", func() {
+				trans, err = sn.GetStaged()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sn.Discard(trans)).To(Succeed())
+				Expect(runner.MatchMilestones([][]string{
+					{"btrfs", "subvolume", "delete", "-c", "-R", "/.snapshots/5/snapshot"},
+				})).To(Succeed())
+			})
+			It("fails to discard a non staged transaction", func() {
+				trans = startUpgradeTransaction()
+				Expect(sn.Discard(trans)).To(MatchError("transaction '5' is not staged"))
+			})
+		})
+		It("does not find any staged transaction", func() {
+			trans, err = sn.GetStaged()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(trans).To(BeNil())
+		})
 		It("it fails to start a transaction if it does not find previous snapshotted volumes", func() {
 			sideEffects["snapper"] = func(args ...string) ([]byte, error) {
 				if slices.Contains(args, "create") {
@@ -0,0 +1,246 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/suse/elemental/v3/pkg/btrfs"
+	"github.com/suse/elemental/v3/pkg/cleanstack"
+	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/rsync"
+	"github.com/suse/elemental/v3/pkg/snapper"
+	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+const (
+	// firstSnapshotID is the ID of the root snapshot of a new installation
+	firstSnapshotID = 1
+	// postSnapshotID is the ID of the post-transaction snapshot of snapshotted volumes of a new installation
+	postSnapshotID = 2
+)
+
+// SnapperTree prepares the snapper layout of a new installation within a plain directory which represents
+// the top level of the btrfs system partition. Subvolumes are plain directories and snapper metadata is
+// written directly, hence neither a btrfs filesystem nor root privileges are required. The resulting tree
+// is meant to be copied into a btrfs partition image creating the subvolumes listed by Subvolumes.
+type SnapperTree struct {
+	snapperContext
+	rootDir string
+}
+
+// NewSnapperTree returns a SnapperTree for the given deployment rooted at the given directory
+func NewSnapperTree(ctx context.Context, s *sys.System, rootDir string, d deployment.Deployment) *SnapperTree {
+	sc := snapperContext{
+		ctx:          ctx,
+		s:            s,
+		cleanStack:   cleanstack.NewCleanStack(),
+		snap:         snapper.New(s),
+		maxSnapshots: maxSnapshots,
+	}
+	for _, disk := range d.Disks {
+		sc.partitions = append(sc.partitions, disk.Partitions...)
+	}
+	return &SnapperTree{snapperContext: sc, rootDir: rootDir}
+}
+
+// Start creates the first root snapshot directory and returns its transaction
+func (st SnapperTree) Start() (*Transaction, error) {
+	path := filepath.Join(st.rootDir, btrfs.TopSubVol, fmt.Sprintf(snapshotPathTmpl, firstSnapshotID))
+	err := vfs.MkdirAll(st.s.FS(), path, vfs.DirPerm)
+	if err != nil {
+		return nil, fmt.Errorf("creating snapshot directory: %w", err)
+	}
+	return &Transaction{
+		ID:     firstSnapshotID,
+		Path:   path,
+		Merges: map[string]*Merge{},
+		status: started,
+	}, nil
+}
+
+// Merge sets the snapper configuration for root and snapshotted volumes and moves the content of non
+// snapshotted RW volumes to their subvolume paths. Snapshotted volumes get their stock snapshot, there
+// is nothing to merge in a new installation.
+func (st SnapperTree) Merge(trans *Transaction) (err error) {
+	defer func() { err = st.checkCancelled(err) }()
+	if trans.status != started {
+		return fmt.Errorf("transaction '%d' is not started", trans.ID)
+	}
+
+	st.s.Logger().Info("Configure snapper")
+	err = st.snap.ConfigureRoot(trans.Path, st.maxSnapshots)
+	if err != nil {
+		return fmt.Errorf("setting root configuration: %w", err)
+	}
+	// Quota groups can only be created on a mounted filesystem
+	err = st.snap.UpdateConfig(trans.Path, snapper.ConfigName("/"), map[string]string{"QGROUP": ""})
+	if err != nil {
+		return fmt.Errorf("disabling root quota group: %w", err)
+	}
+	err = vfs.MkdirAll(st.s.FS(), filepath.Join(trans.Path, snapper.SnapshotsPath), vfs.DirPerm)
+	if err != nil {
+		return fmt.Errorf("creating snapshots dir: %w", err)
+	}
+
+	for _, rwVol := range st.systemVolumes() {
+		if rwVol.Snapshotted {
+			continue
+		}
+		err = st.moveVolume(trans, rwVol)
+		if err != nil {
+			return fmt.Errorf("moving volume '%s': %w", rwVol.Path, err)
+		}
+	}
+
+	for _, rwVol := range st.partitions.GetSnapshottedVolumes() {
+		err = st.snap.ConfigureVolume(trans.Path, rwVol.Path)
+		if err != nil {
+			return fmt.Errorf("creating config for '%s': %w", rwVol.Path, err)
+		}
+		description := fmt.Sprintf("stock %s contents", rwVol.Path)
+		err = st.snapshotVolume(trans, rwVol, firstSnapshotID, description, snapper.Metadata{"stock": "true"})
+		if err != nil {
+			return fmt.Errorf("creating snapshot '%s': %w", rwVol.Path, err)
+		}
+	}
+	return nil
+}
+
+// Lock does nothing, the snapshot is set as read-only when creating the subvolumes listed by Subvolumes
+func (st SnapperTree) Lock(trans *Transaction) error {
+	if trans.status != started {
+		return fmt.Errorf("transaction '%d' is not started", trans.ID)
+	}
+	return nil
+}
+
+// Commit creates the post-transaction snapshots of snapshotted volumes and records the root snapshot
+// metadata including its provenance.
+func (st SnapperTree) Commit(trans *Transaction) (err error) {
+	defer func() { err = st.checkCancelled(err) }()
+	if trans.status != started {
+		return fmt.Errorf("transaction '%d' is not started", trans.ID)
+	}
+	st.s.Logger().Info("Committing transaction")
+
+	for _, rwVol := range st.partitions.GetSnapshottedVolumes() {
+		description := fmt.Sprintf("post-transaction %s snapshot", rwVol.Path)
+		err = st.snapshotVolume(trans, rwVol, postSnapshotID, description, snapper.Metadata{"post-transaction": "true"})
+		if err != nil {
+			return fmt.Errorf("creating post transaction snapshot '%s': %w", rwVol.Path, err)
+		}
+	}
+
+	err = st.snap.WriteSnapshotInfo(
+		filepath.Join(st.rootDir, btrfs.TopSubVol), trans.ID,
+		"first root filesystem, snapshot 1", st.provenance(trans).Metadata(),
+	)
+	if err != nil {
+		return fmt.Errorf("writing root snapshot metadata: %w", err)
+	}
+	trans.status = committed
+	return nil
+}
+
+// Subvolumes returns the btrfs subvolumes of the tree in systemd-repart Subvolumes syntax. Paths are
+// relative to the tree root and include the read-only and no copy on write flags where needed.
+func (st SnapperTree) Subvolumes(trans *Transaction) []string {
+	snapshot := filepath.Join("/", btrfs.TopSubVol, fmt.Sprintf(snapshotPathTmpl, trans.ID))
+	subvolumes := []string{
+		filepath.Join("/", btrfs.TopSubVol),
+		filepath.Join("/", btrfs.TopSubVol, snapper.SnapshotsPath),
+		snapshot + ":ro",
+	}
+	for _, rwVol := range st.systemVolumes() {
+		path := filepath.Join("/", btrfs.TopSubVol, rwVol.Path)
+		if rwVol.Snapshotted {
+			path = filepath.Join(snapshot, rwVol.Path)
+		}
+		if rwVol.NoCopyOnWrite {
+			subvolumes = append(subvolumes, path+":nodatacow")
+		} else {
+			subvolumes = append(subvolumes, path)
+		}
+		if rwVol.Snapshotted {
+			subvolumes = append(
+				subvolumes, filepath.Join(path, snapper.SnapshotsPath),
+				snapper.SnapshotPath(path, firstSnapshotID)+":ro",
+				snapper.SnapshotPath(path, postSnapshotID)+":ro",
+			)
+		}
+	}
+	return subvolumes
+}
+
+// DefaultSubvolume returns the path of the given transaction snapshot relative to the tree root
+func (st SnapperTree) DefaultSubvolume(trans *Transaction) string {
+	return filepath.Join("/", btrfs.TopSubVol, fmt.Sprintf(snapshotPathTmpl, trans.ID))
+}
+
+// systemVolumes returns the RW volumes of the system partition
+func (st SnapperTree) systemVolumes() deployment.RWVolumes {
+	for _, part := range st.partitions {
+		if part.Role == deployment.System && !part.Mirror {
+			return part.RWVolumes
+		}
+	}
+	return nil
+}
+
+// moveVolume moves the content of the given non snapshotted volume out of the snapshot to its subvolume
+// path and leaves an empty mountpoint behind. Volumes nested in an already moved volume are just created.
+func (st SnapperTree) moveVolume(trans *Transaction, rwVol deployment.RWVolume) error {
+	src := filepath.Join(trans.Path, rwVol.Path)
+	dst := filepath.Join(st.rootDir, btrfs.TopSubVol, rwVol.Path)
+
+	info, err := st.s.FS().Lstat(src)
+	if err != nil {
+		return vfs.MkdirAll(st.s.FS(), dst, vfs.DirPerm)
+	}
+	err = vfs.MkdirAll(st.s.FS(), filepath.Dir(dst), vfs.DirPerm)
+	if err != nil {
+		return err
+	}
+	err = st.s.FS().Rename(src, dst)
+	if err != nil {
+		return err
+	}
+	return vfs.MkdirAll(st.s.FS(), src, info.Mode().Perm())
+}
+
+// snapshotVolume copies the current content of the given snapshotted volume to a snapshot of the given ID
+func (st SnapperTree) snapshotVolume(
+	trans *Transaction, rwVol deployment.RWVolume, id int, description string, metadata snapper.Metadata,
+) error {
+	volPath := filepath.Join(trans.Path, rwVol.Path)
+	snapPath := snapper.SnapshotPath(volPath, id)
+	err := vfs.MkdirAll(st.s.FS(), snapPath, vfs.DirPerm)
+	if err != nil {
+		return err
+	}
+	r := rsync.NewRsync(st.s, rsync.WithContext(st.ctx))
+	err = r.SyncData(volPath, snapPath, filepath.Join("/", snapper.SnapshotsPath))
+	if err != nil {
+		return err
+	}
+	return st.snap.WriteSnapshotInfo(volPath, id, description, metadata)
+}
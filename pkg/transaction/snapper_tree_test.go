@@ -0,0 +1,104 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transaction_test
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+	"github.com/suse/elemental/v3/pkg/transaction"
+)
+
+var _ = Describe("SnapperTree", Label("transaction"), func() {
+	var root string
+	var tree *transaction.SnapperTree
+	var trans *transaction.Transaction
+	BeforeEach(func() {
+		snapperContextMock()
+		root = "/some/tree"
+		d = deployment.DefaultDeployment()
+		d.Disks[0].Partitions[0].UUID = "c60d1845-7b04-4fc4-8639-8c49eb7277d5"
+		d.Disks[0].Partitions[1].UUID = "34a8abb8-ddb3-48a2-8ecc-2443e92c7510"
+		tree = transaction.NewSnapperTree(ctx, s, root, *d)
+		trans, err = tree.Start()
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		cleanup()
+	})
+	It("starts the first snapshot", func() {
+		Expect(trans.ID).To(Equal(1))
+		Expect(trans.Path).To(Equal("/some/tree/@/.snapshots/1/snapshot"))
+		Expect(vfs.Exists(tfs, trans.Path)).To(BeTrue())
+	})
+	It("lays out the snapper tree of a new installation", func() {
+		template := filepath.Join(trans.Path, "/usr/share/snapper/config-templates/default")
+		Expect(vfs.MkdirAll(tfs, filepath.Dir(template), vfs.DirPerm)).To(Succeed())
+		Expect(tfs.WriteFile(template, []byte{}, vfs.FilePerm)).To(Succeed())
+		Expect(vfs.MkdirAll(tfs, filepath.Join(trans.Path, "/var/lib"), vfs.DirPerm)).To(Succeed())
+		Expect(vfs.MkdirAll(tfs, filepath.Join(trans.Path, "/etc/sysconfig"), vfs.DirPerm)).To(Succeed())
+		Expect(vfs.MkdirAll(tfs, filepath.Join(trans.Path, "/etc/snapper/configs"), vfs.DirPerm)).To(Succeed())
+
+		Expect(tree.Merge(trans)).To(Succeed())
+		Expect(vfs.Exists(tfs, "/some/tree/@/var/lib")).To(BeTrue())
+		Expect(vfs.Exists(tfs, "/some/tree/@/root")).To(BeTrue())
+		Expect(vfs.Exists(tfs, filepath.Join(trans.Path, "/var"))).To(BeTrue())
+		Expect(vfs.Exists(tfs, filepath.Join(trans.Path, "/var/lib"))).To(BeFalse())
+		Expect(vfs.Exists(tfs, filepath.Join(trans.Path, "/etc/snapper/configs/etc"))).To(BeTrue())
+		rootCfg, err := vfs.LoadEnvFile(tfs, filepath.Join(trans.Path, "/etc/snapper/configs/root"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rootCfg["QGROUP"]).To(BeEmpty())
+		data, err := tfs.ReadFile(filepath.Join(trans.Path, "/etc/.snapshots/1/info.xml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("<key>stock</key>"))
+		Expect(runner.MatchMilestones([][]string{{"rsync"}})).To(Succeed())
+		Expect(runner.MatchMilestones([][]string{{"snapper"}})).NotTo(Succeed())
+
+		Expect(tree.Lock(trans)).To(Succeed())
+		Expect(tree.Commit(trans)).To(Succeed())
+		data, err = tfs.ReadFile(filepath.Join(trans.Path, "/etc/.snapshots/2/info.xml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("<key>post-transaction</key>"))
+		data, err = tfs.ReadFile("/some/tree/@/.snapshots/1/info.xml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("<description>first root filesystem, snapshot 1</description>"))
+		Expect(tree.Commit(trans)).NotTo(Succeed())
+	})
+	It("lists the subvolumes of the tree", func() {
+		Expect(tree.Subvolumes(trans)).To(Equal([]string{
+			"/@", "/@/.snapshots", "/@/.snapshots/1/snapshot:ro",
+			"/@/var:nodatacow", "/@/root",
+			"/@/.snapshots/1/snapshot/etc",
+			"/@/.snapshots/1/snapshot/etc/.snapshots",
+			"/@/.snapshots/1/snapshot/etc/.snapshots/1/snapshot:ro",
+			"/@/.snapshots/1/snapshot/etc/.snapshots/2/snapshot:ro",
+			"/@/opt", "/@/srv", "/@/home", "/@/usr/local",
+		}))
+		Expect(tree.DefaultSubvolume(trans)).To(Equal("/@/.snapshots/1/snapshot"))
+		Expect(tree.GenerateKernelCmdline(trans)).To(Equal("rootfstype=btrfs rootflags=subvol=@/.snapshots/1/snapshot"))
+	})
+	It("fails to merge without snapper configuration templates", func() {
+		err = tree.Merge(trans)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("setting root configuration"))
+	})
+})
@@ -92,12 +92,17 @@ func (sc snapperContext) UpdateFstab(trans *Transaction) (err error) {
 
 	sc.s.Logger().Info("Updating fstab")
 	if ok, _ := vfs.Exists(sc.s.FS(), filepath.Join(trans.Path, fstab.File)); ok {
-		return sc.updateFstab(trans)
+		err = sc.updateFstab(trans)
+	} else if err = sc.createFstab(trans); err != nil {
+		err = fmt.Errorf("creating fstab: %w", err)
+	}
+	if err != nil {
+		return err
 	}
 
-	err = sc.createFstab(trans)
+	err = writeCrypttab(sc.s, trans.Path, sc.partitions)
 	if err != nil {
-		return fmt.Errorf("creating fstab: %w", err)
+		return fmt.Errorf("writing crypttab: %w", err)
 	}
 	return nil
 }
@@ -212,8 +217,8 @@ func (sc snapperContext) configureRWVolumes(trans *Transaction) error {
 }
 
 // merge runs a 3 way merge for snapshotted RW volumes.
-// Current implementation solves potential conflicts by always keeping
-// custom changes over changes coming from the OS image.
+// Conflicts between custom changes and changes coming from the OS image are solved
+// according to the merge policy of each volume.
 func (sc snapperContext) merge(trans *Transaction) (err error) {
 	var status, tmpDir string
 
@@ -240,7 +245,7 @@ func (sc snapperContext) merge(trans *Transaction) (err error) {
 			return err
 		}
 
-		err = sc.applyCustomChanges(status, rwVol.Path, m)
+		err = sc.applyCustomChanges(status, rwVol, m)
 		if err != nil {
 			return err
 		}
@@ -275,53 +280,71 @@ func (sc snapperContext) customChangesStatus(volPath string, merge *Merge, outpu
 }
 
 // applyCustomChanges reads the given status file and applies reported changes in to the target destination.
-// This method is the responsible of applying customizations to the new volume
-func (sc snapperContext) applyCustomChanges(status, rwVolPath string, merge *Merge) (err error) {
-	sc.s.Logger().Debug("rw volume path: %s", rwVolPath)
-	statusF, err := sc.s.FS().OpenFile(status, os.O_RDONLY, vfs.FilePerm)
+// This method is the responsible of applying customizations to the new volume. Custom changes on paths
+// also changed by the new OS image are handled according to the volume merge policy.
+func (sc snapperContext) applyCustomChanges(status string, rwVol deployment.RWVolume, merge *Merge) (err error) {
+	sc.s.Logger().Debug("rw volume path: %s", rwVol.Path)
+	policy := rwVol.GetMergePolicy()
+	sc.s.Logger().Info("Merging custom changes of '%s' with policy '%s'", rwVol.Path, policy)
+
+	changes, err := sc.parseStatus(status, rwVol.Path)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		e := statusF.Close()
-		if err != nil {
-			err = fmt.Errorf("failed closing status file: %w", e)
-		}
-	}()
 
-	syncFiles := filepath.Join(filepath.Dir(status), fmt.Sprintf("sync_%s", snapper.ConfigName(rwVolPath)))
+	syncFiles := filepath.Join(filepath.Dir(status), fmt.Sprintf("sync_%s", snapper.ConfigName(rwVol.Path)))
 	syncF, err := sc.s.FS().OpenFile(syncFiles, os.O_CREATE|os.O_WRONLY, vfs.FilePerm)
 	if err != nil {
 		return fmt.Errorf("failed opening modified files list: %w", err)
 	}
 
-	r := regexp.MustCompile(`(([-+ct.])[p.][u.][g.][x.][a.])\s+(.*)`)
-
-	scanner := bufio.NewScanner(statusF)
-	for scanner.Scan() {
-		line := scanner.Text()
-		match := r.FindStringSubmatch(line)
+	conflicts := []string{}
+	for _, change := range changes {
+		path := change.path
+		newPath := filepath.Join(merge.New, path)
 
-		switch {
-		case len(match) == 0:
-			continue
-		case match[1] == "....":
-			// Ignore extended attributes changes because the stock snapshot used for
-			// comparison was taken before SELINUX relabelling, hence this is likely to
-			// list almost every single file.
-			continue
-		case match[2] == "-":
-			err = sc.s.FS().RemoveAll(filepath.Join(merge.New, match[3]))
-			if err != nil {
-				_ = syncF.Close()
-				return err
+		conflict, err := imageChanged(sc.s.FS(), merge.Old, merge.New, path)
+		if err != nil {
+			_ = syncF.Close()
+			return fmt.Errorf("checking image changes of '%s': %w", filepath.Join(rwVol.Path, path), err)
+		}
+		if conflict {
+			conflicts = append(conflicts, filepath.Join(rwVol.Path, path))
+			switch policy {
+			case deployment.FailOnConflict:
+				continue
+			case deployment.PreferImage:
+				sc.s.Logger().Warn("Conflict on '%s', keeping image content", filepath.Join(rwVol.Path, path))
+				continue
+			case deployment.KeepBoth:
+				sc.s.Logger().Warn(
+					"Conflict on '%s', keeping custom changes and image content as '%s'",
+					filepath.Join(rwVol.Path, path), filepath.Join(rwVol.Path, path)+deployment.MergeNewSuffix,
+				)
+				if ok, _ := vfs.Exists(sc.s.FS(), newPath); ok {
+					err = sc.s.FS().Rename(newPath, newPath+deployment.MergeNewSuffix)
+					if err != nil {
+						_ = syncF.Close()
+						return err
+					}
+				}
+			default:
+				sc.s.Logger().Warn("Conflict on '%s', keeping custom changes", filepath.Join(rwVol.Path, path))
 			}
-		default:
-			_, err = fmt.Fprintln(syncF, strings.TrimPrefix(match[3], rwVolPath)) // #nosec G705
+		}
+
+		if change.deleted {
+			err = sc.s.FS().RemoveAll(newPath)
 			if err != nil {
 				_ = syncF.Close()
 				return err
 			}
+			continue
+		}
+		_, err = fmt.Fprintln(syncF, path) // #nosec G705
+		if err != nil {
+			_ = syncF.Close()
+			return err
 		}
 	}
 	err = syncF.Close()
@@ -329,6 +352,10 @@ func (sc snapperContext) applyCustomChanges(status, rwVolPath string, merge *Mer
 		return fmt.Errorf("failed closing modified files list: %w", err)
 	}
 
+	if policy == deployment.FailOnConflict && len(conflicts) > 0 {
+		return fmt.Errorf("custom changes conflict with the OS image on: %s", strings.Join(conflicts, ", "))
+	}
+
 	syncFlags := append(rsync.DefaultFlags(), "--files-from", syncFiles)
 
 	sync := rsync.NewRsync(sc.s, rsync.WithContext(sc.ctx), rsync.WithFlags(syncFlags...))
@@ -340,6 +367,50 @@ func (sc snapperContext) applyCustomChanges(status, rwVolPath string, merge *Mer
 	return nil
 }
 
+// customChange is a custom change listed in a snapper status file
+type customChange struct {
+	// path is relative to the RW volume path
+	path    string
+	deleted bool
+}
+
+// parseStatus parses the given snapper status file and returns the listed custom changes in order
+func (sc snapperContext) parseStatus(status, rwVolPath string) (changes []customChange, err error) {
+	statusF, err := sc.s.FS().OpenFile(status, os.O_RDONLY, vfs.FilePerm)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		e := statusF.Close()
+		if err != nil {
+			err = fmt.Errorf("failed closing status file: %w", e)
+		}
+	}()
+
+	r := regexp.MustCompile(`(([-+ct.])[p.][u.][g.][x.][a.])\s+(.*)`)
+
+	scanner := bufio.NewScanner(statusF)
+	for scanner.Scan() {
+		match := r.FindStringSubmatch(scanner.Text())
+
+		switch {
+		case len(match) == 0:
+			continue
+		case strings.HasPrefix(match[1], "...."):
+			// Ignore extended attributes only changes because the stock snapshot used for
+			// comparison was taken before SELINUX relabelling, hence this is likely to
+			// list almost every single file.
+			continue
+		default:
+			changes = append(changes, customChange{
+				path:    strings.TrimPrefix(match[3], rwVolPath),
+				deleted: match[2] == "-",
+			})
+		}
+	}
+	return changes, scanner.Err()
+}
+
 // snapshotIDFromPath determines the snapshot ID form the snapshot root path
 func snapshotIDFromPath(path string) (int, error) {
 	r := regexp.MustCompile(`.*/.snapshots/(\d+)/snapshot$`)
@@ -383,7 +454,7 @@ func (sc snapperContext) updateFstab(trans *Transaction) error {
 			opts := rwVol.MountOpts
 			oldLines = append(oldLines, fstab.Line{MountPoint: rwVol.Path})
 			newLines = append(newLines, fstab.Line{
-				Device:     fmt.Sprintf("PARTUUID=%s", part.UUID),
+				Device:     part.FstabDevice(),
 				MountPoint: rwVol.Path,
 				Options:    append(opts, fmt.Sprintf("subvol=%s", subVol)),
 				FileSystem: part.FileSystem.String(),
@@ -396,8 +467,13 @@ func (sc snapperContext) updateFstab(trans *Transaction) error {
 
 // createFstab creates the fstab file with the given transaction data
 func (sc snapperContext) createFstab(trans *Transaction) error {
+	return fstab.Write(sc.s, filepath.Join(trans.Path, fstab.File), snapperFstabLines(sc.partitions, trans.ID))
+}
+
+// snapperFstabLines returns the fstab lines of the given partitions for the given snapshot ID
+func snapperFstabLines(partitions deployment.Partitions, id int) []fstab.Line {
 	var fstabLines []fstab.Line
-	for _, part := range sc.partitions {
+	for _, part := range partitions {
 		if part.Hidden {
 			continue
 		}
@@ -413,7 +489,7 @@ func (sc snapperContext) createFstab(trans *Transaction) error {
 			if len(opts) == 0 {
 				opts = []string{"defaults"}
 			}
-			line.Device = fmt.Sprintf("PARTUUID=%s", part.UUID)
+			line.Device = part.FstabDevice()
 			line.MountPoint = part.MountPoint
 			line.Options = opts
 			line.FileSystem = part.FileSystem.String()
@@ -424,28 +500,27 @@ func (sc snapperContext) createFstab(trans *Transaction) error {
 			var line fstab.Line
 
 			if rwVol.Snapshotted {
-				subVol = filepath.Join(btrfs.TopSubVol, fmt.Sprintf(snapshotPathTmpl, trans.ID), rwVol.Path)
+				subVol = filepath.Join(btrfs.TopSubVol, fmt.Sprintf(snapshotPathTmpl, id), rwVol.Path)
 			} else {
 				subVol = filepath.Join(btrfs.TopSubVol, rwVol.Path)
 			}
 			opts := rwVol.MountOpts
 			opts = append(opts, fmt.Sprintf("subvol=%s", subVol))
-			line.Device = fmt.Sprintf("PARTUUID=%s", part.UUID)
+			line.Device = part.FstabDevice()
 			line.MountPoint = rwVol.Path
 			line.Options = opts
 			line.FileSystem = part.FileSystem.String()
 			fstabLines = append(fstabLines, line)
 		}
-		if part.Role == deployment.System {
+		if part.Role == deployment.System && !part.Mirror {
 			var line fstab.Line
 			subVol := filepath.Join(btrfs.TopSubVol, snapper.SnapshotsPath)
-			line.Device = fmt.Sprintf("PARTUUID=%s", part.UUID)
+			line.Device = part.FstabDevice()
 			line.MountPoint = filepath.Join("/", snapper.SnapshotsPath)
 			line.Options = []string{fmt.Sprintf("subvol=%s", subVol)}
 			line.FileSystem = part.FileSystem.String()
 			fstabLines = append(fstabLines, line)
 		}
 	}
-
-	return fstab.Write(sc.s, filepath.Join(trans.Path, fstab.File), fstabLines)
+	return fstabLines
 }
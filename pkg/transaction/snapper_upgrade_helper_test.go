@@ -19,13 +19,16 @@ package transaction_test
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"github.com/suse/elemental/v3/pkg/btrfs"
+	"github.com/suse/elemental/v3/pkg/deployment"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
 	"github.com/suse/elemental/v3/pkg/transaction"
 )
@@ -125,6 +128,62 @@ var _ = Describe("SnapperUpgradeHelper", Label("transaction"), func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(string(data)).To(Not(ContainSubstring("PARTUUID=d7dd841f-aeaa-4fe3-a383-8913f4e8d4de")))
 		})
+		It("creates fstab and crypttab for encrypted partitions", func() {
+			path := filepath.Join(root, btrfs.TopSubVol, ".snapshots/1/snapshot/etc")
+			Expect(vfs.MkdirAll(tfs, path, vfs.DirPerm)).To(Succeed())
+			d.Disks[0].Partitions[2].Encryption = &deployment.Encryption{
+				KeyFile: "/etc/keys/data.key", UUID: "8b9c0d1e-5b6d-4e7f-8a9b-0c1d2e3f4a5b",
+			}
+			Expect(vfs.MkdirAll(tfs, "/etc/keys", vfs.DirPerm)).To(Succeed())
+			Expect(tfs.WriteFile("/etc/keys/data.key", []byte("secret"), vfs.FilePerm)).To(Succeed())
+
+			Expect(upgradeH.UpdateFstab(trans)).To(Succeed())
+			data, err := tfs.ReadFile(filepath.Join(trans.Path, "/etc/fstab"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring("/dev/mapper/luks-8b9c0d1e-5b6d-4e7f-8a9b-0c1d2e3f4a5b /home"))
+			Expect(string(data)).NotTo(ContainSubstring("PARTUUID=2443e92c-ddb3-48a2-8ecc-34a8abb87510"))
+			data, err = tfs.ReadFile(filepath.Join(trans.Path, "/etc/crypttab"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("luks-8b9c0d1e-5b6d-4e7f-8a9b-0c1d2e3f4a5b " +
+				"UUID=8b9c0d1e-5b6d-4e7f-8a9b-0c1d2e3f4a5b " +
+				"/etc/cryptsetup-keys.d/luks-8b9c0d1e-5b6d-4e7f-8a9b-0c1d2e3f4a5b.key luks\n"))
+			keyFile := filepath.Join(trans.Path, "/etc/cryptsetup-keys.d/luks-8b9c0d1e-5b6d-4e7f-8a9b-0c1d2e3f4a5b.key")
+			data, err = tfs.ReadFile(keyFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("secret"))
+			info, err := tfs.Stat(keyFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0400)))
+		})
+		It("reuses the key file of the running system for encrypted partitions", func() {
+			path := filepath.Join(root, btrfs.TopSubVol, ".snapshots/1/snapshot/etc")
+			Expect(vfs.MkdirAll(tfs, path, vfs.DirPerm)).To(Succeed())
+			d.Disks[0].Partitions[2].Encryption = &deployment.Encryption{
+				KeyFile: "/etc/keys/data.key", UUID: "8b9c0d1e-5b6d-4e7f-8a9b-0c1d2e3f4a5b",
+			}
+			Expect(vfs.MkdirAll(tfs, "/etc/cryptsetup-keys.d", vfs.DirPerm)).To(Succeed())
+			Expect(tfs.WriteFile(
+				"/etc/cryptsetup-keys.d/luks-8b9c0d1e-5b6d-4e7f-8a9b-0c1d2e3f4a5b.key", []byte("secret"), vfs.FilePerm,
+			)).To(Succeed())
+
+			Expect(upgradeH.UpdateFstab(trans)).To(Succeed())
+			data, err := tfs.ReadFile(filepath.Join(
+				trans.Path, "/etc/cryptsetup-keys.d/luks-8b9c0d1e-5b6d-4e7f-8a9b-0c1d2e3f4a5b.key",
+			))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("secret"))
+		})
+		It("fails to write crypttab if the key file of an encrypted partition is missing", func() {
+			path := filepath.Join(root, btrfs.TopSubVol, ".snapshots/1/snapshot/etc")
+			Expect(vfs.MkdirAll(tfs, path, vfs.DirPerm)).To(Succeed())
+			d.Disks[0].Partitions[2].Encryption = &deployment.Encryption{
+				KeyFile: "/etc/keys/data.key", UUID: "8b9c0d1e-5b6d-4e7f-8a9b-0c1d2e3f4a5b",
+			}
+
+			err := upgradeH.UpdateFstab(trans)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("reading key file of encrypted partition 'DATA'"))
+		})
 		It("it fails to create fstab file if the path does not exist", func() {
 			err := upgradeH.UpdateFstab(trans)
 			Expect(err).To(HaveOccurred())
@@ -192,6 +251,57 @@ var _ = Describe("SnapperUpgradeHelper", Label("transaction"), func() {
 				{"rsync"},
 			})).To(Succeed())
 		})
+		It("reports the outcome of merging RW volumes", func() {
+			oldEtc := "/.snapshots/4/snapshot/etc/.snapshots/1/snapshot"
+			newEtc := "/.snapshots/5/snapshot/etc"
+			files := map[string]string{
+				oldEtc + "/modifiedFile": "stock",
+				oldEtc + "/deletedFile":  "stock",
+				oldEtc + "/imageChanged": "stock",
+				oldEtc + "/imageRemoved": "stock",
+				oldEtc + "/conflictFile": "stock",
+				newEtc + "/modifiedFile": "stock",
+				newEtc + "/deletedFile":  "stock",
+				newEtc + "/imageChanged": "new",
+				newEtc + "/imageAdded":   "new",
+				newEtc + "/conflictFile": "new",
+			}
+			for path, data := range files {
+				Expect(vfs.MkdirAll(tfs, filepath.Dir(path), vfs.DirPerm)).To(Succeed())
+				Expect(tfs.WriteFile(path, []byte(data), vfs.FilePerm)).To(Succeed())
+			}
+			Expect(vfs.MkdirAll(tfs, "/tmp/elemental_generic/.snapshots/4/snapshot/home/.snapshots/1/snapshot", vfs.DirPerm)).To(Succeed())
+			Expect(vfs.MkdirAll(tfs, "/.snapshots/5/snapshot/home", vfs.DirPerm)).To(Succeed())
+
+			status := snapperStatus + "c..... /etc/conflictFile\n"
+			sideEffects["snapper"] = func(args ...string) ([]byte, error) {
+				if slices.Contains(args, "status") {
+					output := args[slices.Index(args, "--output")+1]
+					if slices.Contains(args, "etc") {
+						return []byte{}, tfs.WriteFile(output, []byte(status), vfs.FilePerm)
+					}
+					return []byte{}, tfs.WriteFile(output, []byte{}, vfs.FilePerm)
+				}
+				return runner.ReturnValue, runner.ReturnError
+			}
+
+			report, err := upgradeH.MergeReport(trans)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Volumes).To(HaveLen(2))
+			etcReport := report.Volumes[slices.IndexFunc(report.Volumes, func(v transaction.VolumeReport) bool {
+				return v.Path == "/etc"
+			})]
+			Expect(etcReport.Changes).To(Equal([]transaction.FileChange{
+				{Path: "/etc/conflictFile", Change: transaction.ChangeConflict},
+				{Path: "/etc/createdFile", Change: transaction.ChangeKept},
+				{Path: "/etc/deletedFile", Change: transaction.ChangeKept},
+				{Path: "/etc/imageAdded", Change: transaction.ChangeAdded},
+				{Path: "/etc/imageChanged", Change: transaction.ChangeOverwritten},
+				{Path: "/etc/imageRemoved", Change: transaction.ChangeRemoved},
+				{Path: "/etc/modifiedFile", Change: transaction.ChangeKept},
+			}))
+			Expect(runner.IncludesCmds([][]string{{"rsync"}})).NotTo(Succeed())
+		})
 		It("updates fstab", func() {
 			fstab := filepath.Join(root, ".snapshots/5/snapshot/etc/fstab")
 			Expect(vfs.MkdirAll(tfs, filepath.Dir(fstab), vfs.DirPerm)).To(Succeed())
@@ -203,4 +313,115 @@ var _ = Describe("SnapperUpgradeHelper", Label("transaction"), func() {
 			Expect(string(data)).To(Not(ContainSubstring("PARTUUID=d7dd841f-aeaa-4fe3-a383-8913f4e8d4de")))
 		})
 	})
+	Describe("merge policies for an upgrade transaction", func() {
+		var policy deployment.MergePolicy
+		var synced []string
+		var etcStatus string
+
+		BeforeEach(func() {
+			etcStatus = "c..... /etc/conflictFile\nc..... /etc/localFile\n"
+		})
+
+		JustBeforeEach(func() {
+			root = "/"
+			d.Disks[0].Partitions[1].RWVolumes[2].MergePolicy = policy
+			upgradeH = initSnapperUpgrade(root)
+			trans = startUpgradeTransaction()
+
+			snapshotP := "/.snapshots/5/snapshot"
+			oldEtc := "/.snapshots/4/snapshot/etc/.snapshots/1/snapshot"
+			files := map[string]string{
+				snapshotP + "/usr/share/snapper/config-templates/default": "",
+				snapshotP + "/etc/sysconfig/snapper":                      "",
+				oldEtc + "/conflictFile":                                  "stock",
+				oldEtc + "/localFile":                                     "stock",
+				snapshotP + "/etc/conflictFile":                           "new",
+				snapshotP + "/etc/localFile":                              "stock",
+			}
+			for path, data := range files {
+				Expect(vfs.MkdirAll(tfs, filepath.Dir(path), vfs.DirPerm)).To(Succeed())
+				Expect(tfs.WriteFile(path, []byte(data), vfs.FilePerm)).To(Succeed())
+			}
+			Expect(vfs.MkdirAll(tfs, snapshotP+"/etc/snapper/configs", vfs.DirPerm)).To(Succeed())
+
+			synced = []string{}
+			sideEffects["snapper"] = func(args ...string) ([]byte, error) {
+				if slices.Contains(args, "status") {
+					status := []byte{}
+					if slices.Contains(args, "etc") {
+						status = []byte(etcStatus)
+					}
+					output := args[slices.Index(args, "--output")+1]
+					return []byte{}, tfs.WriteFile(output, status, vfs.FilePerm)
+				}
+				if slices.Contains(args, "create") {
+					return []byte("5\n"), nil
+				}
+				return runner.ReturnValue, runner.ReturnError
+			}
+			sideEffects["rsync"] = func(args ...string) ([]byte, error) {
+				data, err := tfs.ReadFile(args[slices.Index(args, "--files-from")+1])
+				Expect(err).NotTo(HaveOccurred())
+				synced = append(synced, strings.Fields(string(data))...)
+				return []byte{}, nil
+			}
+		})
+		When("preferring local changes", func() {
+			BeforeEach(func() {
+				policy = deployment.PreferLocal
+			})
+			It("syncs all custom changes", func() {
+				Expect(upgradeH.Merge(trans)).To(Succeed())
+				Expect(synced).To(Equal([]string{"/conflictFile", "/localFile"}))
+			})
+		})
+		When("merging custom changes of any kind", func() {
+			BeforeEach(func() {
+				policy = deployment.PreferLocal
+				etcStatus = snapperStatus
+			})
+			It("syncs created and modified files, removes deleted ones and skips relabelled ones", func() {
+				for _, path := range []string{
+					"/.snapshots/4/snapshot/etc/.snapshots/1/snapshot/deletedFile", "/.snapshots/5/snapshot/etc/deletedFile",
+				} {
+					Expect(tfs.WriteFile(path, []byte("stock"), vfs.FilePerm)).To(Succeed())
+				}
+				Expect(upgradeH.Merge(trans)).To(Succeed())
+				Expect(synced).To(Equal([]string{"/createdFile", "/modifiedFile"}))
+				Expect(vfs.Exists(tfs, "/.snapshots/5/snapshot/etc/deletedFile")).To(BeFalse())
+			})
+		})
+		When("preferring image content", func() {
+			BeforeEach(func() {
+				policy = deployment.PreferImage
+			})
+			It("skips conflicting custom changes", func() {
+				Expect(upgradeH.Merge(trans)).To(Succeed())
+				Expect(synced).To(Equal([]string{"/localFile"}))
+			})
+		})
+		When("keeping both versions", func() {
+			BeforeEach(func() {
+				policy = deployment.KeepBoth
+			})
+			It("syncs all custom changes and keeps the image content aside", func() {
+				Expect(upgradeH.Merge(trans)).To(Succeed())
+				Expect(synced).To(Equal([]string{"/conflictFile", "/localFile"}))
+				data, err := tfs.ReadFile("/.snapshots/5/snapshot/etc/conflictFile.elemental-new")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(Equal("new"))
+			})
+		})
+		When("failing on conflicts", func() {
+			BeforeEach(func() {
+				policy = deployment.FailOnConflict
+			})
+			It("fails to merge", func() {
+				err := upgradeH.Merge(trans)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("custom changes conflict with the OS image on: /etc/conflictFile"))
+				Expect(synced).To(BeEmpty())
+			})
+		})
+	})
 })
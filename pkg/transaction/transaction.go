@@ -20,10 +20,13 @@ package transaction
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 
 	"github.com/suse/elemental/v3/pkg/block/lsblk"
 	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/fstab"
 	"github.com/suse/elemental/v3/pkg/sys"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
 	"github.com/suse/elemental/v3/pkg/unpack"
 )
 
@@ -35,6 +38,7 @@ const (
 	started transactionState = iota + 1
 	committed
 	failed
+	staged
 )
 
 func New(ctx context.Context, s *sys.System, d *deployment.Deployment, name string) (Interface, error) {
@@ -62,19 +66,87 @@ type Transaction struct {
 	status transactionState
 }
 
+// IsStaged returns true if the transaction was staged to be committed later on
+func (t Transaction) IsStaged() bool {
+	return t.status == staged
+}
+
 type Interface interface {
 	Init(deployment.Deployment) (UpgradeHelper, error)
 	Start() (*Transaction, error)
 	Commit(trans *Transaction, cleanup func() error) error
 	Rollback(*Transaction, error) error
 
+	// Stage closes the given transaction without making it the default one, so it can be
+	// committed later on.
+	Stage(trans *Transaction, cleanup func() error) error
+	// GetStaged returns the staged transaction if any, nil otherwise
+	GetStaged() (*Transaction, error)
+	// Discard deletes the given staged transaction
+	Discard(trans *Transaction) error
+
 	GetActiveSnapshotIDs() ([]int, error)
 }
 
 type UpgradeHelper interface {
 	SyncImageContent(*deployment.ImageSource, *Transaction, ...unpack.Opt) error
 	Merge(*Transaction) error
+	MergeReport(*Transaction) (*MergeReport, error)
 	UpdateFstab(*Transaction) error
 	Lock(*Transaction) error
 	GenerateKernelCmdline(*Transaction) string
 }
+
+// writeCrypttab writes the crypttab file in the given root for all the encrypted partitions.
+// Nothing is written if there are no encrypted partitions.
+func writeCrypttab(s *sys.System, root string, parts deployment.Partitions) error {
+	var lines []fstab.CrypttabLine
+	for _, part := range parts {
+		if part.Encryption == nil {
+			continue
+		}
+		line := fstab.CrypttabLine{
+			Name:    part.MapperName(),
+			Device:  fmt.Sprintf("UUID=%s", part.Encryption.UUID),
+			Options: []string{"luks"},
+		}
+		if part.Encryption.TPM2 != nil {
+			line.Options = append(line.Options, "tpm2-device=auto")
+		} else {
+			keyFile, err := installKeyFile(s, root, part)
+			if err != nil {
+				return err
+			}
+			line.KeyFile = keyFile
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return fstab.WriteCrypttab(s, filepath.Join(root, fstab.CrypttabFile), lines)
+}
+
+// installKeyFile copies the key file of the given encrypted partition into the given root and returns
+// its path within the root. The key file of the running system is used if the configured one, which
+// refers to the installation host, is not found.
+func installKeyFile(s *sys.System, root string, part *deployment.Partition) (string, error) {
+	keyFile := filepath.Join(fstab.CryptKeysDir, part.MapperName()+".key")
+	src := part.Encryption.KeyFile
+	if ok, _ := vfs.Exists(s.FS(), src); !ok {
+		src = keyFile
+	}
+	data, err := s.FS().ReadFile(src)
+	if err != nil {
+		return "", fmt.Errorf("reading key file of encrypted partition '%s': %w", part.Label, err)
+	}
+	err = vfs.MkdirAll(s.FS(), filepath.Join(root, fstab.CryptKeysDir), 0700)
+	if err != nil {
+		return "", fmt.Errorf("creating key files directory: %w", err)
+	}
+	err = s.FS().WriteFile(filepath.Join(root, keyFile), data, 0400)
+	if err != nil {
+		return "", fmt.Errorf("writing key file of encrypted partition '%s': %w", part.Label, err)
+	}
+	return keyFile, nil
+}
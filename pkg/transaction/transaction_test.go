@@ -161,6 +161,53 @@ const upgradeSnapList = `{
   }
 `
 
+const stagedSnapList = `{
+	"root": [
+	  {
+		"number": 4,
+		"default": true,
+		"active": true,
+		"userdata": null
+	  },{
+		"number": 5,
+		"default": false,
+		"active": false,
+		"userdata": {
+		    "staged": "yes"
+		}
+	  }
+	]
+  }
+`
+
+const etcSnapList = `{
+	"etc": [
+	  {
+		"number": 1,
+		"default": false,
+		"active": false,
+		"userdata": {
+		    "stock": "true"
+		}
+	  },{
+		"number": 2,
+		"default": false,
+		"active": false,
+		"userdata": {
+		    "pre-transaction": "true"
+		}
+	  },{
+		"number": 3,
+		"default": false,
+		"active": false,
+		"userdata": {
+		    "pre-transaction": "true"
+		}
+	  }
+	]
+  }
+`
+
 const installSnapList = `{
 	"root": [
 	  {
@@ -0,0 +1,170 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unpack
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/client"
+	"github.com/google/go-containerregistry/pkg/name"
+	containerregistry "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/daemon"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+)
+
+// LocalSource is the store local images are read from
+type LocalSource string
+
+const (
+	// Docker reads local images from the Docker daemon
+	Docker LocalSource = "docker"
+	// Podman reads local images from the Docker compatible API of the podman service
+	Podman LocalSource = "podman"
+	// ContainersStorage reads local images from containers-storage by saving them with podman,
+	// it does not require the podman service to be running
+	ContainersStorage LocalSource = "containers-storage"
+)
+
+// LocalSources returns all the supported local image sources
+func LocalSources() []LocalSource {
+	return []LocalSource{Docker, Podman, ContainersStorage}
+}
+
+// ParseLocalSource parses the given local image source, empty defaults to Docker
+func ParseLocalSource(source string) (LocalSource, error) {
+	if source == "" {
+		return Docker, nil
+	}
+	for _, s := range LocalSources() {
+		if string(s) == source {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported local image source '%s', supported sources are: %s", source, localSourcesString())
+}
+
+func localSourcesString() string {
+	sources := make([]string, 0, len(LocalSources()))
+	for _, s := range LocalSources() {
+		sources = append(sources, string(s))
+	}
+	return strings.Join(sources, ", ")
+}
+
+// PodmanHost returns the address of the podman service API. CONTAINER_HOST takes precedence,
+// otherwise the rootless socket is used for unprivileged users and the system one for root.
+func PodmanHost() string {
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && os.Geteuid() != 0 {
+		return "unix://" + filepath.Join(dir, "podman", "podman.sock")
+	}
+	return "unix:///run/podman/podman.sock"
+}
+
+// localImage reads the image from the configured local source. The returned function releases the
+// resources held by the image and must be called once the image is no longer used.
+func (o OCI) localImage(ctx context.Context, ref name.Reference) (containerregistry.Image, func(), error) {
+	if scope := o.policy.Match(ref.Context().Name()); scope != nil {
+		return nil, nil, fmt.Errorf("signature verification of local image '%s' is not supported", ref.String())
+	}
+
+	source, err := ParseLocalSource(string(o.localSource))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	o.s.Logger().Info("Reading local image '%s' from %s", ref.String(), source)
+	switch source {
+	case Podman:
+		cli, err := client.NewClientWithOpts(client.WithHost(PodmanHost()), client.WithAPIVersionNegotiation())
+		if err != nil {
+			return nil, nil, fmt.Errorf("connecting to podman: %w", err)
+		}
+		img, err := daemon.Image(ref, daemon.WithContext(ctx), daemon.WithClient(cli))
+		if err != nil {
+			_ = cli.Close()
+			return nil, nil, fmt.Errorf("reading image '%s' from podman: %w", ref.String(), err)
+		}
+		return img, func() { _ = cli.Close() }, nil
+	case ContainersStorage:
+		return o.containersStorageImage(ctx, ref)
+	default:
+		img, err := daemon.Image(ref, daemon.WithContext(ctx))
+		if err != nil {
+			return nil, nil, err
+		}
+		return img, func() {}, nil
+	}
+}
+
+// containersStorageImage saves the image from containers-storage into a temporary OCI layout and
+// reads it from there. The temporary layout is removed on release.
+func (o OCI) containersStorageImage(ctx context.Context, ref name.Reference) (img containerregistry.Image, release func(), err error) {
+	tempDir, err := vfs.TempDir(o.s.FS(), "", "elemental-local-image-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	remove := func() {
+		_ = vfs.ForceRemoveAll(o.s.FS(), tempDir)
+	}
+	defer func() {
+		if err != nil {
+			remove()
+		}
+	}()
+
+	layoutDir, err := o.s.FS().RawPath(filepath.Join(tempDir, "image"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = o.s.Runner().RunContext(ctx, "podman", "image", "save", "--format", "oci-dir", "-o", layoutDir, ref.String())
+	if err != nil {
+		return nil, nil, fmt.Errorf("saving image '%s' from containers-storage: %w", ref.String(), err)
+	}
+
+	path, err := layout.FromPath(layoutDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading saved image '%s': %w", ref.String(), err)
+	}
+	index, err := path.ImageIndex()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading saved image '%s': %w", ref.String(), err)
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading saved image '%s': %w", ref.String(), err)
+	}
+	if len(manifest.Manifests) != 1 {
+		return nil, nil, fmt.Errorf("saved image '%s' contains %d manifests, expected one", ref.String(), len(manifest.Manifests))
+	}
+
+	img, err = index.Image(manifest.Manifests[0].Digest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading saved image '%s': %w", ref.String(), err)
+	}
+	return img, remove, nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unpack_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+
+	"github.com/suse/elemental/v3/pkg/log"
+	"github.com/suse/elemental/v3/pkg/signature"
+	"github.com/suse/elemental/v3/pkg/sys"
+	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
+	"github.com/suse/elemental/v3/pkg/sys/vfs"
+	"github.com/suse/elemental/v3/pkg/unpack"
+)
+
+var _ = Describe("LocalSource", Label("local"), func() {
+	It("Parses the supported local sources", func() {
+		source, err := unpack.ParseLocalSource("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source).To(Equal(unpack.Docker))
+
+		for _, s := range []string{"docker", "podman", "containers-storage"} {
+			source, err = unpack.ParseLocalSource(s)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(source)).To(Equal(s))
+		}
+
+		_, err = unpack.ParseLocalSource("cri-o")
+		Expect(err).To(MatchError(ContainSubstring("unsupported local image source 'cri-o'")))
+	})
+	It("Prefers CONTAINER_HOST as podman host", func() {
+		GinkgoT().Setenv("CONTAINER_HOST", "unix:///custom/podman.sock")
+		Expect(unpack.PodmanHost()).To(Equal("unix:///custom/podman.sock"))
+	})
+})
+
+var _ = Describe("OCIUnpacker with containers-storage", Label("local"), func() {
+	var tfs vfs.FS
+	var s *sys.System
+	var runner *sysmock.Runner
+	var cleanup func()
+	BeforeEach(func() {
+		var err error
+		tfs, cleanup, err = sysmock.TestFS(nil)
+		Expect(err).NotTo(HaveOccurred())
+		runner = sysmock.NewRunner()
+		s, err = sys.NewSystem(sys.WithFS(tfs), sys.WithRunner(runner), sys.WithLogger(log.New(log.WithDiscardAll())))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vfs.MkdirAll(tfs, "/target/root", vfs.DirPerm)).To(Succeed())
+	})
+	AfterEach(func() {
+		cleanup()
+	})
+	It("Unpacks an image saved from containers-storage", func() {
+		img, err := random.Image(1024, 2)
+		Expect(err).NotTo(HaveOccurred())
+		digest, err := img.Digest()
+		Expect(err).NotTo(HaveOccurred())
+
+		runner.SideEffect = func(cmd string, args ...string) ([]byte, error) {
+			if cmd != "podman" || len(args) != 7 {
+				return nil, fmt.Errorf("unexpected command %s %v", cmd, args)
+			}
+			p, err := layout.Write(args[5], empty.Index)
+			if err != nil {
+				return nil, err
+			}
+			return nil, p.AppendImage(img)
+		}
+
+		unpacker := unpack.NewOCIUnpacker(
+			s, "localhost/my-os:latest", unpack.WithPlatformRefOCI("linux/amd64"), unpack.WithLocalOCI(true),
+			unpack.WithLocalSourceOCI(unpack.ContainersStorage),
+		)
+		unpacked, err := unpacker.Unpack(context.Background(), "/target/root")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unpacked).To(Equal(digest.String()))
+
+		layoutDir, err := tfs.RawPath("/tmp/elemental-local-image-/image")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(runner.CmdsMatch([][]string{{
+			"podman", "image", "save", "--format", "oci-dir", "-o", layoutDir, "localhost/my-os:latest",
+		}})).To(Succeed())
+
+		exists, _ := vfs.Exists(tfs, "/tmp/elemental-local-image-")
+		Expect(exists).To(BeFalse())
+	})
+	It("Fails if the image can't be saved from containers-storage", func() {
+		runner.ReturnError = fmt.Errorf("image not known")
+		unpacker := unpack.NewOCIUnpacker(
+			s, "localhost/my-os:latest", unpack.WithLocalOCI(true), unpack.WithLocalSourceOCI(unpack.ContainersStorage),
+		)
+		_, err := unpacker.Unpack(context.Background(), "/target/root")
+		Expect(err).To(MatchError(ContainSubstring("saving image 'localhost/my-os:latest' from containers-storage")))
+
+		exists, _ := vfs.Exists(tfs, "/tmp/elemental-local-image-")
+		Expect(exists).To(BeFalse())
+	})
+	It("Fails on an unsupported local source", func() {
+		unpacker := unpack.NewOCIUnpacker(
+			s, "localhost/my-os:latest", unpack.WithLocalOCI(true), unpack.WithLocalSourceOCI("cri-o"),
+		)
+		_, err := unpacker.Unpack(context.Background(), "/target/root")
+		Expect(err).To(MatchError(ContainSubstring("unsupported local image source")))
+		Expect(runner.GetCmds()).To(BeEmpty())
+	})
+	It("Fails to verify the signature of an image from containers-storage", func() {
+		policy := &signature.Policy{Scopes: []signature.Scope{{Scope: "registry.example.com/my-os"}}}
+		unpacker := unpack.NewOCIUnpacker(
+			s, "registry.example.com/my-os:latest", unpack.WithLocalOCI(true), unpack.WithLocalSourceOCI(unpack.ContainersStorage),
+			unpack.WithSignaturePolicyOCI(policy),
+		)
+		_, err := unpacker.Unpack(context.Background(), "/target/root")
+		Expect(err).To(MatchError(ContainSubstring("signature verification of local image")))
+		Expect(runner.GetCmds()).To(BeEmpty())
+	})
+})
@@ -19,21 +19,27 @@ package unpack
 
 import (
 	"context"
-	"net/http"
+	"errors"
+	"fmt"
+	"io"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/schollz/progressbar/v3"
 
+	"github.com/suse/elemental/v3/pkg/bundle"
+	"github.com/suse/elemental/v3/pkg/cache"
+	"github.com/suse/elemental/v3/pkg/events"
+	"github.com/suse/elemental/v3/pkg/registry"
+	"github.com/suse/elemental/v3/pkg/signature"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/sys/vfs"
 
 	backoff "github.com/cenkalti/backoff/v4"
 	"github.com/containerd/containerd/v2/pkg/archive"
-	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	containerregistry "github.com/google/go-containerregistry/pkg/v1"
-	"github.com/google/go-containerregistry/pkg/v1/daemon"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 )
@@ -44,9 +50,14 @@ type OCI struct {
 	s           *sys.System
 	platformRef string
 	local       bool
+	localSource LocalSource
 	verify      bool
 	imageRef    string
 	rsyncFlags  []string
+	policy      *signature.Policy
+	bundle      *bundle.Bundle
+	cache       *cache.Cache
+	registries  *registry.Config
 }
 
 type OCIOpt func(*OCI)
@@ -57,12 +68,47 @@ func WithLocalOCI(local bool) OCIOpt {
 	}
 }
 
+// WithLocalSourceOCI sets the store local images are read from, defaults to Docker
+func WithLocalSourceOCI(source LocalSource) OCIOpt {
+	return func(o *OCI) {
+		o.localSource = source
+	}
+}
+
 func WithVerifyOCI(verify bool) OCIOpt {
 	return func(o *OCI) {
 		o.verify = verify
 	}
 }
 
+// WithSignaturePolicyOCI sets the signature policy the image is verified against before pulling it
+func WithSignaturePolicyOCI(policy *signature.Policy) OCIOpt {
+	return func(o *OCI) {
+		o.policy = policy
+	}
+}
+
+// WithBundleOCI sets the bundle the image is read from instead of pulling it
+func WithBundleOCI(b *bundle.Bundle) OCIOpt {
+	return func(o *OCI) {
+		o.bundle = b
+	}
+}
+
+// WithCacheOCI sets the cache the layers of pulled images are read from and stored to
+func WithCacheOCI(c *cache.Cache) OCIOpt {
+	return func(o *OCI) {
+		o.cache = c
+	}
+}
+
+// WithRegistriesOCI sets the credentials, mirrors and TLS settings used to pull the image
+func WithRegistriesOCI(c *registry.Config) OCIOpt {
+	return func(o *OCI) {
+		o.registries = c
+	}
+}
+
 func WithPlatformRefOCI(platform string) OCIOpt {
 	return func(o *OCI) {
 		o.platformRef = platform
@@ -136,12 +182,27 @@ func (o OCI) Unpack(ctx context.Context, destination string, excludes ...string)
 
 	var img containerregistry.Image
 
-	err = backoff.Retry(func() error {
-		img, err = fetchImage(ctx, ref, *platform, o.local)
-		return err
-	}, backoff.WithMaxRetries(backoff.NewConstantBackOff(3*time.Second), 3))
-	if err != nil {
-		return "", err
+	switch {
+	case o.bundle != nil:
+		img, err = o.bundleImage(*platform)
+		if err != nil {
+			return "", err
+		}
+	case o.local:
+		var release func()
+		img, release, err = o.localImage(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+		defer release()
+	default:
+		img, err = o.pull(ctx, ref, *platform, opts...)
+		if err != nil {
+			return "", err
+		}
+		if o.cache != nil {
+			img = o.cache.Image(img)
+		}
 	}
 
 	digest, err := img.Digest()
@@ -157,26 +218,157 @@ func (o OCI) Unpack(ctx context.Context, destination string, excludes ...string)
 		return "", err
 	}
 
-	bar := progressbar.DefaultBytes(-1, "Extracting")
-	defer bar.Close()
-
-	r := progressbar.NewReader(reader, bar)
+	o.s.Events().Digest(o.imageRef, digest.String())
+
+	var r io.Reader
+	if ev := o.s.Events(); ev.Enabled() {
+		// the progress bar would mix with the events written to stdout
+		r = ev.NewReader(reader, events.PhaseUnpack)
+	} else {
+		bar := progressbar.DefaultBytes(-1, "Extracting")
+		defer bar.Close()
+		pr := progressbar.NewReader(reader, bar)
+		r = &pr
+	}
 
 	filter := excludesFilter(destination, excludes...)
-	_, err = archive.Apply(ctx, destination, &r, archive.WithFilter(filter))
+	_, err = archive.Apply(ctx, destination, r, archive.WithFilter(filter))
 
 	return digest.String(), err
 }
 
-func fetchImage(ctx context.Context, ref name.Reference, platform containerregistry.Platform, local bool) (containerregistry.Image, error) {
-	if local {
-		return daemon.Image(ref, daemon.WithContext(ctx))
+// pull fetches the image from the first of its locations providing it, the mirrors of its registry
+// are tried before the registry location. The signature and the pinned tag of the image are verified
+// at each location.
+func (o OCI) pull(ctx context.Context, ref name.Reference, platform containerregistry.Platform, opts ...name.Option) (containerregistry.Image, error) {
+	locations, err := o.registries.Resolve(ref, opts...)
+	if err != nil {
+		return nil, err
+	}
+	remoteOpts, err := o.registries.RemoteOptions(o.s.FS())
+	if err != nil {
+		return nil, fmt.Errorf("configuring registries: %w", err)
+	}
+
+	var errs error
+	for _, location := range locations {
+		if location.Name() != ref.Name() {
+			o.s.Logger().Info("Pulling '%s' from '%s'", ref.String(), location.String())
+		}
+		img, err := o.pullFrom(ctx, ref, location, platform, remoteOpts)
+		if err == nil {
+			return img, nil
+		}
+		if len(locations) > 1 {
+			o.s.Logger().Warn("Pulling '%s' from '%s' failed: %v", ref.String(), location.String(), err)
+		}
+		errs = errors.Join(errs, err)
 	}
+	return nil, errs
+}
+
+// pullFrom fetches the image with the given reference from the given location
+func (o OCI) pullFrom(
+	ctx context.Context, ref, location name.Reference, platform containerregistry.Platform, remoteOpts []remote.Option,
+) (img containerregistry.Image, err error) {
+	err = o.checkPinnedTag(ctx, location, platform, remoteOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err = o.verifySignature(ctx, ref, location, remoteOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = backoff.Retry(func() error {
+		img, err = remote.Image(location, remoteOptions(ctx, platform, remoteOpts)...)
+		return err
+	}, backoff.WithMaxRetries(backoff.NewConstantBackOff(3*time.Second), 3))
+	return img, err
+}
+
+// checkPinnedTag verifies the tag of an image referenced as 'name:tag@digest' still resolves to the pinned
+// digest, either the one of the image index or the one of the image for the given platform. Images
+// referenced by digest only are verified on pull.
+func (o OCI) checkPinnedTag(ctx context.Context, ref name.Reference, platform containerregistry.Platform, remoteOpts []remote.Option) error {
+	pinned, ok := ref.(name.Digest)
+	if !ok {
+		return nil
+	}
+
+	tagged := strings.TrimSuffix(ref.String(), "@"+pinned.DigestStr())
+	i := strings.LastIndex(tagged, ":")
+	if i <= strings.LastIndex(tagged, "/") {
+		return nil
+	}
+	tag := pinned.Context().Tag(tagged[i+1:])
+
+	desc, err := remote.Get(tag, remoteOptions(ctx, platform, remoteOpts)...)
+	if err != nil {
+		return fmt.Errorf("resolving image tag '%s': %w", tag.String(), err)
+	}
+	if desc.Digest.String() == pinned.DigestStr() {
+		return nil
+	}
+	img, err := desc.Image()
+	if err != nil {
+		return fmt.Errorf("resolving image for platform %s: %w", platform.String(), err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return err
+	}
+	if digest.String() == pinned.DigestStr() {
+		return nil
+	}
+	return fmt.Errorf("image '%s' resolves to digest %s, expected %s", tag.String(), desc.Digest.String(), pinned.DigestStr())
+}
+
+// verifySignature checks the image signature if the reference is within any scope of the signature policy,
+// the image and its signatures are read from the given location. Returns the reference by digest of the
+// verified image at the location.
+func (o OCI) verifySignature(ctx context.Context, ref, location name.Reference, remoteOpts []remote.Option) (name.Reference, error) {
+	scope := o.policy.Match(ref.Context().Name())
+	if scope == nil {
+		return location, nil
+	}
+	o.s.Logger().Info("Verifying signature of '%s' against %s", ref.String(), scope.String())
+	verified, err := signature.NewVerifier(o.policy, remoteOpts...).VerifyAt(ctx, ref, location)
+	if err != nil {
+		return nil, fmt.Errorf("verifying image signature: %w", err)
+	}
+	o.s.Logger().Info("Signature verified, pulling '%s'", verified.String())
+	return verified, nil
+}
+
+// bundleImage reads the image from the bundle. Signatures are verified against the ones stored in the bundle
+// if the reference is within any scope of the signature policy.
+func (o OCI) bundleImage(platform containerregistry.Platform) (containerregistry.Image, error) {
+	entry, err := o.bundle.Lookup(o.imageRef, &platform)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := o.policy.Match(entry.Ref.Context().Name())
+	if scope != nil {
+		o.s.Logger().Info("Verifying signature of '%s' against %s", entry.Ref.String(), scope.String())
+		sigImg, err := o.bundle.Signature(entry)
+		if err != nil {
+			return nil, fmt.Errorf("verifying image signature: %w", err)
+		}
+		err = signature.NewVerifier(o.policy).CheckSignatures(entry.Ref, entry.Digest, sigImg)
+		if err != nil {
+			return nil, fmt.Errorf("verifying image signature: %w", err)
+		}
+	}
+
+	o.s.Logger().Info("Reading '%s' from bundle", entry.Ref.String())
+	return o.bundle.Image(entry)
+}
 
-	return remote.Image(ref,
-		remote.WithTransport(http.DefaultTransport),
-		remote.WithPlatform(platform),
-		remote.WithAuthFromKeychain(authn.DefaultKeychain),
-		remote.WithContext(ctx),
-	)
+// remoteOptions returns the options to pull images for the given platform, the given options
+// define the transport and credentials to access the registry
+func remoteOptions(ctx context.Context, platform containerregistry.Platform, opts []remote.Option) []remote.Option {
+	return append([]remote.Option{remote.WithPlatform(platform), remote.WithContext(ctx)}, opts...)
 }
@@ -19,11 +19,24 @@ package unpack_test
 
 import (
 	"context"
+	"io"
+	stdlog "log"
+	"net/http/httptest"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/suse/elemental/v3/pkg/bundle"
 	"github.com/suse/elemental/v3/pkg/log"
+	elementalregistry "github.com/suse/elemental/v3/pkg/registry"
+	"github.com/suse/elemental/v3/pkg/signature"
 	"github.com/suse/elemental/v3/pkg/sys"
 	sysmock "github.com/suse/elemental/v3/pkg/sys/mock"
 	"github.com/suse/elemental/v3/pkg/sys/runner"
@@ -71,6 +84,92 @@ var _ = Describe("OCIUnpacker", Label("oci", "rootlesskit"), func() {
 		Expect(exists).To(BeFalse())
 		Expect(digest).To(BeEmpty())
 	})
+	It("Fails to verify the signature of a local image", func() {
+		policy := &signature.Policy{Scopes: []signature.Scope{{Scope: "docker.io/library"}}}
+		unpacker := unpack.NewOCIUnpacker(s, alpineImageRef, unpack.WithLocalOCI(true), unpack.WithSignaturePolicyOCI(policy))
+		Expect(vfs.MkdirAll(tfs, "/target/root", vfs.DirPerm)).To(Succeed())
+		_, err := unpacker.Unpack(context.Background(), "/target/root")
+		Expect(err).To(MatchError(ContainSubstring("signature verification of local image")))
+	})
+	It("Unpacks an image pinned by tag and digest", func() {
+		srv := httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+		DeferCleanup(srv.Close)
+
+		img, err := random.Image(64, 1)
+		Expect(err).NotTo(HaveOccurred())
+		host := strings.TrimPrefix(srv.URL, "http://")
+		tag, err := name.NewTag(host+"/os/image:1.0", name.Insecure)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote.Write(tag, img)).To(Succeed())
+		digest, err := img.Digest()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(vfs.MkdirAll(tfs, "/target/root", vfs.DirPerm)).To(Succeed())
+		unpacker := unpack.NewOCIUnpacker(s, tag.String()+"@"+digest.String(), unpack.WithVerifyOCI(false))
+		unpacked, err := unpacker.Unpack(context.Background(), "/target/root")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unpacked).To(Equal(digest.String()))
+
+		By("moving the tag to another image")
+		other, err := random.Image(64, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote.Write(tag, other)).To(Succeed())
+		_, err = unpacker.Unpack(context.Background(), "/target/root")
+		Expect(err).To(MatchError(ContainSubstring("expected " + digest.String())))
+	})
+	It("Unpacks an image from a registry mirror", func() {
+		srv := httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+		DeferCleanup(srv.Close)
+
+		img, err := random.Image(64, 1)
+		Expect(err).NotTo(HaveOccurred())
+		host := strings.TrimPrefix(srv.URL, "http://")
+		tag, err := name.NewTag(host + "/mirror/os/image:1.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote.Write(tag, img)).To(Succeed())
+		digest, err := img.Digest()
+		Expect(err).NotTo(HaveOccurred())
+
+		registries := &elementalregistry.Config{Registries: []elementalregistry.Registry{{
+			Prefix:  "registry.invalid.",
+			Mirrors: []string{host + "/mirror"},
+		}}}
+		Expect(vfs.MkdirAll(tfs, "/target/root", vfs.DirPerm)).To(Succeed())
+		unpacker := unpack.NewOCIUnpacker(s, "registry.invalid./os/image:1.0@"+digest.String(), unpack.WithRegistriesOCI(registries))
+		unpacked, err := unpacker.Unpack(context.Background(), "/target/root")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unpacked).To(Equal(digest.String()))
+	})
+	It("Unpacks an image from a bundle", func() {
+		srv := httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+		img, err := random.Image(64, 1)
+		Expect(err).NotTo(HaveOccurred())
+		host := strings.TrimPrefix(srv.URL, "http://")
+		tag, err := name.NewTag(host + "/os/image:1.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote.Write(tag, img)).To(Succeed())
+		digest, err := img.Digest()
+		Expect(err).NotTo(HaveOccurred())
+
+		platform, err := v1.ParsePlatform(s.Platform().String())
+		Expect(err).NotTo(HaveOccurred())
+		b, err := bundle.New(s, "/bundle", *platform)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b.AddImage(context.Background(), host+"/os/image:1.0", bundle.OperatingSystem)).To(Succeed())
+		srv.Close()
+
+		Expect(vfs.MkdirAll(tfs, "/target/root", vfs.DirPerm)).To(Succeed())
+		unpacker := unpack.NewOCIUnpacker(s, host+"/os/image:1.0@"+digest.String(), unpack.WithBundleOCI(b))
+		unpacked, err := unpacker.Unpack(context.Background(), "/target/root")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unpacked).To(Equal(digest.String()))
+
+		By("requiring signatures stored in the bundle")
+		policy := &signature.Policy{Scopes: []signature.Scope{{Scope: host + "/os"}}}
+		unpacker = unpack.NewOCIUnpacker(s, host+"/os/image:1.0", unpack.WithBundleOCI(b), unpack.WithSignaturePolicyOCI(policy))
+		_, err = unpacker.Unpack(context.Background(), "/target/root")
+		Expect(err).To(MatchError(ContainSubstring("looking up signatures")))
+	})
 	It("Unpacks a local alpine image", Serial, func() {
 		_, err := s.Runner().Run("docker", "pull", alpineImageRef)
 		Expect(err).NotTo(HaveOccurred())
@@ -21,7 +21,11 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/suse/elemental/v3/pkg/bundle"
+	"github.com/suse/elemental/v3/pkg/cache"
 	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/registry"
+	"github.com/suse/elemental/v3/pkg/signature"
 	"github.com/suse/elemental/v3/pkg/sys"
 )
 
@@ -56,6 +60,17 @@ func WithLocal(local bool) Opt {
 	}
 }
 
+// WithLocalSource sets the store local OCI images are read from
+func WithLocalSource(source LocalSource) Opt {
+	return func(srcType deployment.ImageSrcType, o *options) {
+		switch srcType {
+		case deployment.OCI:
+			o.ociOpts = append(o.ociOpts, WithLocalSourceOCI(source))
+		default:
+		}
+	}
+}
+
 func WithVerify(verify bool) Opt {
 	return func(srcType deployment.ImageSrcType, o *options) {
 		switch srcType {
@@ -66,6 +81,50 @@ func WithVerify(verify bool) Opt {
 	}
 }
 
+// WithSignaturePolicy sets the signature policy OCI images are verified against
+func WithSignaturePolicy(policy *signature.Policy) Opt {
+	return func(srcType deployment.ImageSrcType, o *options) {
+		switch srcType {
+		case deployment.OCI:
+			o.ociOpts = append(o.ociOpts, WithSignaturePolicyOCI(policy))
+		default:
+		}
+	}
+}
+
+// WithBundle sets the bundle OCI images are read from instead of pulling them
+func WithBundle(b *bundle.Bundle) Opt {
+	return func(srcType deployment.ImageSrcType, o *options) {
+		switch srcType {
+		case deployment.OCI:
+			o.ociOpts = append(o.ociOpts, WithBundleOCI(b))
+		default:
+		}
+	}
+}
+
+// WithCache sets the cache the layers of pulled OCI images are read from and stored to
+func WithCache(c *cache.Cache) Opt {
+	return func(srcType deployment.ImageSrcType, o *options) {
+		switch srcType {
+		case deployment.OCI:
+			o.ociOpts = append(o.ociOpts, WithCacheOCI(c))
+		default:
+		}
+	}
+}
+
+// WithRegistries sets the credentials, mirrors and TLS settings used to pull OCI images
+func WithRegistries(c *registry.Config) Opt {
+	return func(srcType deployment.ImageSrcType, o *options) {
+		switch srcType {
+		case deployment.OCI:
+			o.ociOpts = append(o.ociOpts, WithRegistriesOCI(c))
+		default:
+		}
+	}
+}
+
 func WithPlatformRef(platform string) Opt {
 	return func(srcType deployment.ImageSrcType, o *options) {
 		switch srcType {
@@ -29,9 +29,11 @@ import (
 	"github.com/suse/elemental/v3/pkg/chroot"
 	"github.com/suse/elemental/v3/pkg/cleanstack"
 	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/events"
 	"github.com/suse/elemental/v3/pkg/fips"
 	"github.com/suse/elemental/v3/pkg/firmware"
 	"github.com/suse/elemental/v3/pkg/rsync"
+	"github.com/suse/elemental/v3/pkg/sbom"
 	"github.com/suse/elemental/v3/pkg/selinux"
 	"github.com/suse/elemental/v3/pkg/sys"
 	"github.com/suse/elemental/v3/pkg/transaction"
@@ -53,6 +55,7 @@ type Upgrader struct {
 	bm         *firmware.EfiBootManager
 	b          bootloader.Bootloader
 	unpackOpts []unpack.Opt
+	sbom       *sbom.SBOM
 }
 
 func WithTransaction(t transaction.Interface) Option {
@@ -85,6 +88,13 @@ func WithUnpackOpts(opts ...unpack.Opt) Option {
 	}
 }
 
+// WithSBOM adds the RPM packages of the upgraded OS to the given SBOM
+func WithSBOM(sb *sbom.SBOM) Option {
+	return func(u *Upgrader) {
+		u.sbom = sb
+	}
+}
+
 func New(ctx context.Context, s *sys.System, opts ...Option) *Upgrader {
 	up := &Upgrader{
 		s:   s,
@@ -102,8 +112,96 @@ func New(ctx context.Context, s *sys.System, opts ...Option) *Upgrader {
 	return up
 }
 
+// Upgrade upgrades the system to the given deployment and sets the new snapshot as the default one.
+func (u Upgrader) Upgrade(d *deployment.Deployment) error {
+	return u.upgrade(d, false)
+}
+
+// Stage prepares a new snapshot for the given deployment without setting it as the default one.
+// The staged snapshot is activated with ApplyStaged or deleted with DiscardStaged.
+func (u Upgrader) Stage(d *deployment.Deployment) error {
+	return u.upgrade(d, true)
+}
+
+// DryRun syncs the given deployment into a new transaction and reports the outcome of merging
+// the snapshotted RW volumes. The transaction is always discarded, nothing is committed.
+func (u Upgrader) DryRun(d *deployment.Deployment) (report *transaction.MergeReport, err error) {
+	cleanup := cleanstack.NewCleanStack()
+	defer func() { err = cleanup.Cleanup(err) }()
+
+	uh, err := u.t.Init(*d)
+	if err != nil {
+		return nil, fmt.Errorf("initializing transaction: %w", err)
+	}
+
+	trans, err := u.t.Start()
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	cleanup.Push(func() error { return u.t.Rollback(trans, err) })
+
+	err = uh.SyncImageContent(d.SourceOS, trans, u.imageUnpackOpts(d)...)
+	if err != nil {
+		return nil, fmt.Errorf("syncing OS image content: %w", err)
+	}
+
+	report, err = uh.MergeReport(trans)
+	if err != nil {
+		return nil, fmt.Errorf("computing merge report: %w", err)
+	}
+	return report, nil
+}
+
+// ApplyStaged installs the bootloader for the staged snapshot and sets it as the default one.
+// The given deployment describes the running system.
+func (u Upgrader) ApplyStaged(d *deployment.Deployment) error {
+	uh, err := u.t.Init(*d)
+	if err != nil {
+		return fmt.Errorf("initializing transaction: %w", err)
+	}
+
+	trans, err := u.t.GetStaged()
+	if err != nil {
+		return fmt.Errorf("getting staged transaction: %w", err)
+	} else if trans == nil {
+		return fmt.Errorf("no staged upgrade found")
+	}
+
+	stagedD, err := deployment.Parse(u.s, trans.Path)
+	if err != nil {
+		return fmt.Errorf("parsing deployment of staged snapshot %d: %w", trans.ID, err)
+	} else if stagedD == nil {
+		return fmt.Errorf("deployment of staged snapshot %d not found", trans.ID)
+	}
+
+	esp := stagedD.GetEfiPartition()
+	if esp == nil {
+		return fmt.Errorf("no %s partition defined in deployment", deployment.EfiLabel)
+	}
+
+	u.s.Logger().Info("Applying staged snapshot %d", trans.ID)
+	return u.activate(stagedD, uh, trans, esp.MountPoint)
+}
+
+// DiscardStaged deletes the staged snapshot. The given deployment describes the running system.
+func (u Upgrader) DiscardStaged(d *deployment.Deployment) error {
+	_, err := u.t.Init(*d)
+	if err != nil {
+		return fmt.Errorf("initializing transaction: %w", err)
+	}
+
+	trans, err := u.t.GetStaged()
+	if err != nil {
+		return fmt.Errorf("getting staged transaction: %w", err)
+	} else if trans == nil {
+		return fmt.Errorf("no staged upgrade found")
+	}
+
+	return u.t.Discard(trans)
+}
+
 //nolint:gocyclo
-func (u Upgrader) Upgrade(d *deployment.Deployment) (err error) {
+func (u Upgrader) upgrade(d *deployment.Deployment, stage bool) (err error) {
 	cleanup := cleanstack.NewCleanStack()
 	defer func() { err = cleanup.Cleanup(err) }()
 
@@ -119,17 +217,33 @@ func (u Upgrader) Upgrade(d *deployment.Deployment) (err error) {
 		return fmt.Errorf("initializing transaction: %w", err)
 	}
 
+	staged, err := u.t.GetStaged()
+	if err != nil {
+		return fmt.Errorf("getting staged transaction: %w", err)
+	} else if staged != nil {
+		return fmt.Errorf("snapshot %d is already staged, apply or discard it first", staged.ID)
+	}
+
 	trans, err := u.t.Start()
 	if err != nil {
 		return fmt.Errorf("starting transaction: %w", err)
 	}
 	cleanup.PushErrorOnly(func() error { return u.t.Rollback(trans, err) })
 
-	err = uh.SyncImageContent(d.SourceOS, trans, u.unpackOpts...)
+	u.s.Events().Start(events.PhaseDeploy)
+	err = uh.SyncImageContent(d.SourceOS, trans, u.imageUnpackOpts(d)...)
+	u.s.Events().Finish(events.PhaseDeploy, err)
 	if err != nil {
 		return fmt.Errorf("syncing OS image content: %w", err)
 	}
 
+	if u.sbom != nil {
+		err = u.sbom.AddRPMs(u.ctx, u.s, trans.Path)
+		if err != nil {
+			return fmt.Errorf("listing OS packages: %w", err)
+		}
+	}
+
 	err = uh.Merge(trans)
 	if err != nil {
 		return fmt.Errorf("merging RW volumes: %w", err)
@@ -165,6 +279,7 @@ func (u Upgrader) Upgrade(d *deployment.Deployment) (err error) {
 	if d.OverlayTree != nil && !d.OverlayTree.IsEmpty() {
 		unpacker, err := unpack.NewUnpacker(
 			u.s, d.OverlayTree, unpack.WithRsyncFlags(rsync.OverlayTreeSyncFlags()...),
+			unpack.WithSignaturePolicy(d.GetSignaturePolicy()), unpack.WithRegistries(d.Registries),
 		)
 		if err != nil {
 			return fmt.Errorf("initializing unpacker: %w", err)
@@ -182,6 +297,37 @@ func (u Upgrader) Upgrade(d *deployment.Deployment) (err error) {
 		}
 	}
 
+	if stage {
+		err = u.t.Stage(trans, nil)
+		if err != nil {
+			return fmt.Errorf("staging transaction: %w", err)
+		}
+		u.s.Events().Snapshot(trans.ID)
+		return nil
+	}
+
+	return u.activate(d, uh, trans, filepath.Join(trans.Path, esp.MountPoint))
+}
+
+// activate installs the bootloader for the given transaction and commits it, which sets
+// its snapshot as the default one.
+func (u Upgrader) activate(
+	d *deployment.Deployment, uh transaction.UpgradeHelper, trans *transaction.Transaction, espDir string,
+) (err error) {
+	cleanup := cleanstack.NewCleanStack()
+	defer func() { err = cleanup.Cleanup(err) }()
+
+	esp := d.GetEfiPartition()
+	if esp == nil {
+		return fmt.Errorf("no %s partition defined in deployment", deployment.EfiLabel)
+	}
+
+	// u is a copy of the upgrader, the mirrored bootloader only applies to this activation
+	u.b, err = u.mirroredBootloader(cleanup, d)
+	if err != nil {
+		return fmt.Errorf("setting up ESP mirrors: %w", err)
+	}
+
 	cmdline := ""
 	if d.BootConfig != nil {
 		cmdline = d.BootConfig.KernelCmdline
@@ -193,12 +339,20 @@ func (u Upgrader) Upgrade(d *deployment.Deployment) (err error) {
 		recKernelCmdline = strings.TrimSpace(fmt.Sprintf("%s %s", d.RecoveryKernelCmdline(), d.Installer.KernelCmdline))
 	}
 
-	espDir := filepath.Join(trans.Path, esp.MountPoint)
+	u.s.Events().Start(events.PhaseBootloader)
 	err = u.b.Install(trans.Path, espDir, esp.Label, strconv.Itoa(trans.ID), kernelCmdline, recKernelCmdline)
+	u.s.Events().Finish(events.PhaseBootloader, err)
 	if err != nil {
 		return fmt.Errorf("installing bootloader: %w", err)
 	}
 
+	if d.BootConfig != nil && d.BootConfig.BootTries > 0 {
+		err = u.enableBootCounting(cleanup, espDir, trans.ID, d.BootConfig.BootTries)
+		if err != nil {
+			return fmt.Errorf("enabling boot counting: %w", err)
+		}
+	}
+
 	if d.Firmware != nil {
 		err = u.bm.CreateBootEntries(d.Firmware.BootEntries)
 		if err != nil {
@@ -212,17 +366,60 @@ func (u Upgrader) Upgrade(d *deployment.Deployment) (err error) {
 			return fmt.Errorf("get active snapshots: %w", err)
 		}
 
-		return u.b.Prune(trans.Path, filepath.Join(trans.Path, esp.MountPoint), snapshots)
+		return u.b.Prune(trans.Path, espDir, snapshots)
 	}
 
 	err = u.t.Commit(trans, commitCleanup)
 	if err != nil {
 		return fmt.Errorf("committing transaction: %w", err)
 	}
+	u.s.Events().Snapshot(trans.ID)
 
 	return nil
 }
 
+// mirroredBootloader mounts the ESP mirrors of the deployment, if any, and returns the bootloader
+// wrapped to keep them in sync. Unmounting the mirrors is added to the given cleanup stack.
+func (u Upgrader) mirroredBootloader(cleanup *cleanstack.CleanStack, d *deployment.Deployment) (bootloader.Bootloader, error) {
+	return bootloader.MountMirrors(u.s, cleanup, u.b, d.GetMirrorPartitions(deployment.EFI).UUIDs()...)
+}
+
+// enableBootCounting sets the bootloader to fall back to the most recent snapshot prior to the
+// given one if booting the new snapshot fails for the given number of tries. Boot counting is
+// disabled again if the activation fails later on.
+func (u Upgrader) enableBootCounting(cleanup *cleanstack.CleanStack, espDir string, transID, tries int) error {
+	snapshots, err := u.t.GetActiveSnapshotIDs()
+	if err != nil {
+		return fmt.Errorf("get active snapshots: %w", err)
+	}
+
+	fallback := 0
+	for _, id := range snapshots {
+		if id < transID && id > fallback {
+			fallback = id
+		}
+	}
+	if fallback == 0 {
+		u.s.Logger().Info("No previous snapshot to fall back to, skipping boot counting")
+		return nil
+	}
+
+	err = u.b.SetBootCounter(espDir, tries, strconv.Itoa(fallback))
+	if err != nil {
+		return err
+	}
+	cleanup.PushErrorOnly(func() error { return u.b.SetBootCounter(espDir, 0, "") })
+	return nil
+}
+
+// imageUnpackOpts returns the unpack options for the OS image including the signature policy and the
+// registries of the deployment
+func (u Upgrader) imageUnpackOpts(d *deployment.Deployment) []unpack.Opt {
+	return append(
+		slices.Clone(u.unpackOpts), unpack.WithSignaturePolicy(d.GetSignaturePolicy()), unpack.WithRegistries(d.Registries),
+	)
+}
+
 func (u Upgrader) configHook(config string, root string) error {
 	u.s.Logger().Info("Running transaction hook")
 	callback := func() error {
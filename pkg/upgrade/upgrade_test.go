@@ -18,7 +18,9 @@ limitations under the License.
 package upgrade_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -26,7 +28,9 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/suse/elemental/v3/pkg/bootloader"
 	"github.com/suse/elemental/v3/pkg/deployment"
+	"github.com/suse/elemental/v3/pkg/events"
 	"github.com/suse/elemental/v3/pkg/firmware"
 	"github.com/suse/elemental/v3/pkg/log"
 	"github.com/suse/elemental/v3/pkg/sys"
@@ -101,6 +105,34 @@ var _ = Describe("Upgrade", Label("upgrade"), func() {
 			{"/etc/elemental/config.sh"},
 		}))
 	})
+	It("reports the upgrade phases and the new snapshot as events", func() {
+		buf := &bytes.Buffer{}
+		ev := events.New(buf)
+		var err error
+		s, err = sys.NewSystem(
+			sys.WithMounter(mounter), sys.WithRunner(runner),
+			sys.WithFS(fs), sys.WithLogger(log.New(log.WithDiscardAll())),
+			sys.WithSyscall(syscall), sys.WithEvents(ev),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		u = upgrade.New(context.Background(), s, upgrade.WithTransaction(t), upgrade.WithBootManager(firmware.NewEfiBootManager(s)))
+		Expect(u.Upgrade(d)).To(Succeed())
+		ev.Done("upgrade", nil)
+
+		var phases []string
+		var result events.Event
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var e events.Event
+			Expect(json.Unmarshal([]byte(line), &e)).To(Succeed())
+			if e.Type == events.PhaseStarted {
+				phases = append(phases, e.Phase)
+			}
+			result = e
+		}
+		Expect(phases).To(Equal([]string{events.PhaseDeploy, events.PhaseBootloader}))
+		Expect(result.Outcome.Snapshot).To(Equal(2))
+		Expect(result.Outcome.Success).To(BeTrue())
+	})
 	It("fails on transaction initialization", func() {
 		t.InitErr = fmt.Errorf("init failed")
 		err := u.Upgrade(d)
@@ -204,4 +236,129 @@ var _ = Describe("Upgrade", Label("upgrade"), func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(efiBootMgrCalled).To(BeTrue())
 	})
+	It("enables boot counting falling back to the previous snapshot", func() {
+		b := &bootCounterRecorder{None: bootloader.NewNone(s)}
+		u = upgrade.New(
+			context.Background(), s, upgrade.WithTransaction(t), upgrade.WithBootloader(b),
+			upgrade.WithBootManager(firmware.NewEfiBootManager(s)),
+		)
+		t.ActiveSnapshotIDs = []int{3, 1, 2}
+		trans.ID = 3
+		d.BootConfig.BootTries = 3
+
+		Expect(u.Upgrade(d)).To(Succeed())
+		Expect(b.tries).To(Equal(3))
+		Expect(b.fallbackID).To(Equal("2"))
+	})
+	It("disables boot counting if the transaction can't be committed", func() {
+		b := &bootCounterRecorder{None: bootloader.NewNone(s)}
+		u = upgrade.New(
+			context.Background(), s, upgrade.WithTransaction(t), upgrade.WithBootloader(b),
+			upgrade.WithBootManager(firmware.NewEfiBootManager(s)),
+		)
+		t.ActiveSnapshotIDs = []int{3, 1, 2}
+		t.CommitErr = fmt.Errorf("commit failed")
+		trans.ID = 3
+		d.BootConfig.BootTries = 3
+
+		Expect(u.Upgrade(d)).To(MatchError(ContainSubstring("commit failed")))
+		Expect(b.tries).To(Equal(0))
+		Expect(b.fallbackID).To(BeEmpty())
+	})
+	It("skips boot counting if there is no previous snapshot", func() {
+		b := &bootCounterRecorder{None: bootloader.NewNone(s)}
+		u = upgrade.New(
+			context.Background(), s, upgrade.WithTransaction(t), upgrade.WithBootloader(b),
+			upgrade.WithBootManager(firmware.NewEfiBootManager(s)),
+		)
+		t.ActiveSnapshotIDs = []int{1}
+		trans.ID = 1
+		d.BootConfig.BootTries = 3
+
+		Expect(u.Upgrade(d)).To(Succeed())
+		Expect(b.tries).To(Equal(0))
+	})
+	It("reports the merge outcome and discards the transaction on dry-run", func() {
+		t.UpgradeHelper.Report = &transaction.MergeReport{Volumes: []transaction.VolumeReport{{
+			Path:    "/etc",
+			Changes: []transaction.FileChange{{Path: "/etc/hosts", Change: transaction.ChangeKept}},
+		}}}
+		t.CommitErr = fmt.Errorf("commit should not be called")
+		report, err := u.DryRun(d)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.IsEmpty()).To(BeFalse())
+		Expect(report.Volumes[0].Changes[0].Change).To(Equal(transaction.ChangeKept))
+		Expect(t.RollbackCalled()).To(BeTrue())
+	})
+	It("fails on image sync on dry-run", func() {
+		t.UpgradeHelper.SyncError = fmt.Errorf("failed sync")
+		_, err := u.DryRun(d)
+		Expect(err).To(MatchError("syncing OS image content: failed sync"))
+		Expect(t.RollbackCalled()).To(BeTrue())
+	})
+	It("stages the given deployment without committing it", func() {
+		t.CommitErr = fmt.Errorf("commit should not be called")
+		Expect(u.Stage(d)).To(Succeed())
+		Expect(t.RollbackCalled()).To(BeFalse())
+	})
+	It("fails on staging transaction", func() {
+		t.StageErr = fmt.Errorf("stage failed")
+		err := u.Stage(d)
+		Expect(err).To(HaveOccurred())
+		Expect(err).To(MatchError("staging transaction: stage failed"))
+		Expect(t.RollbackCalled()).To(BeTrue())
+	})
+	It("fails to upgrade if there is a staged snapshot", func() {
+		t.Staged = &transaction.Transaction{ID: 3}
+		err := u.Upgrade(d)
+		Expect(err).To(HaveOccurred())
+		Expect(err).To(MatchError("snapshot 3 is already staged, apply or discard it first"))
+		Expect(t.RollbackCalled()).To(BeFalse())
+	})
+	It("applies the staged snapshot", func() {
+		Expect(d.WriteDeploymentFile(s, trans.Path)).To(Succeed())
+		t.Staged = trans
+		Expect(u.ApplyStaged(d)).To(Succeed())
+	})
+	It("fails to apply a staged snapshot without deployment file", func() {
+		t.Staged = trans
+		err := u.ApplyStaged(d)
+		Expect(err).To(HaveOccurred())
+		Expect(err).To(MatchError("deployment of staged snapshot 2 not found"))
+	})
+	It("fails to apply if there is no staged snapshot", func() {
+		Expect(u.ApplyStaged(d)).To(MatchError("no staged upgrade found"))
+	})
+	It("fails to commit the staged snapshot", func() {
+		Expect(d.WriteDeploymentFile(s, trans.Path)).To(Succeed())
+		t.Staged = trans
+		t.CommitErr = fmt.Errorf("commit failed")
+		err := u.ApplyStaged(d)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("commit failed"))
+		Expect(t.RollbackCalled()).To(BeFalse())
+	})
+	It("discards the staged snapshot", func() {
+		t.Staged = trans
+		Expect(u.DiscardStaged(d)).To(Succeed())
+
+		t.DiscardErr = fmt.Errorf("discard failed")
+		Expect(u.DiscardStaged(d)).To(MatchError("discard failed"))
+	})
+	It("fails to discard if there is no staged snapshot", func() {
+		Expect(u.DiscardStaged(d)).To(MatchError("no staged upgrade found"))
+	})
 })
+
+// bootCounterRecorder is a no-op bootloader recording the boot counter settings
+type bootCounterRecorder struct {
+	*bootloader.None
+	tries      int
+	fallbackID string
+}
+
+func (b *bootCounterRecorder) SetBootCounter(_ string, tries int, fallbackID string) error {
+	b.tries = tries
+	b.fallbackID = fallbackID
+	return nil
+}
@@ -0,0 +1,44 @@
+/*
+Copyright © 2025-2026 SUSE LLC
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package version
+
+import "fmt"
+
+var (
+	version = "v0.0.1"
+	// gitCommit is the git sha1
+	gitCommit = ""
+)
+
+// Get returns the version of the elemental binaries
+func Get() string {
+	return version
+}
+
+// GetCommit returns the short git commit the elemental binaries were built from
+func GetCommit() string {
+	if len(gitCommit) > 7 {
+		return gitCommit[:7]
+	}
+	return gitCommit
+}
+
+// String returns the full version string including the git commit
+func String() string {
+	return fmt.Sprintf("%s+g%s", Get(), GetCommit())
+}